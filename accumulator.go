@@ -0,0 +1,431 @@
+package sfntshape
+
+import "math"
+import "sync"
+import "runtime"
+
+// A software rasterizer that accumulates signed area coverage in a float32
+// buffer, like [vector.Rasterizer] does internally with its floating point
+// path, but exposing the accumulation stage so we can apply different fill
+// rules or get unquantized coverage values.
+//
+// The algorithm and curve flattening heuristics have been ported from
+// golang.org/x/image/vector (raster_floating.go and vector.go), keeping the
+// explicit float32 conversions that disable FMA so results stay bit-exact
+// with vector's. Portable accumulators also round the few operations vector
+// leaves unconverted and never mimic SIMD accumulation, so their results
+// are the same on every platform.
+type accumulator struct {
+	buffer []float32
+	padded []float32 // buffer with an extra row above, only used for bands
+	bandY int32 // first row of the band, only used for bands
+	width int
+	height int
+	penX, penY float32
+	firstX, firstY float32
+	scratch []uint8 // reusable buffer for intermediate results
+	portable bool // if true, never mimics SIMD accumulation
+}
+
+var accumulatorPool = sync.Pool {
+	New: func() any { return &accumulator{} },
+}
+
+func getAccumulator(width, height int) *accumulator {
+	acc := accumulatorPool.Get().(*accumulator)
+	acc.Reset(width, height)
+	return acc
+}
+
+func releaseAccumulator(acc *accumulator) {
+	accumulatorPool.Put(acc)
+}
+
+// Resets the accumulator for a new rasterization of the given size.
+func (self *accumulator) Reset(width, height int) {
+	self.reset(width, height, 0)
+	self.padded = nil
+	self.bandY = 0
+}
+
+// Like Reset, but for rasterizing only the rows [minY, maxY) of a bigger
+// image. The outline must not be translated, as that would change the
+// floating point results. Band results match the corresponding rows of
+// the full rasterization exactly (before accumulation): contributions
+// that overflow the right edge of the row above the band and land on
+// the first pixel of the band are preserved, in the same order, by
+// keeping an extra padding row on top.
+func (self *accumulator) ResetBand(width, minY, maxY int) {
+	self.reset(width, maxY - minY, width)
+	self.padded = self.buffer
+	self.buffer = self.buffer[width : ]
+	self.bandY = int32(minY)
+}
+
+func (self *accumulator) reset(width, height, padding int) {
+	n := width*height + padding
+	buffer := self.buffer
+	if self.padded != nil { buffer = self.padded }
+	if n > cap(buffer) {
+		self.buffer = make([]float32, n)
+	} else {
+		self.buffer = buffer[0 : n]
+		for i := range self.buffer { self.buffer[i] = 0 }
+	}
+	self.width, self.height = width, height
+	self.penX, self.penY = 0, 0
+	self.firstX, self.firstY = 0, 0
+	self.portable = false
+}
+
+// Returns a reusable buffer of the given size. The contents are
+// undefined, and the buffer is only valid until the accumulator
+// is released.
+func (self *accumulator) Scratch(size int) []uint8 {
+	if size > cap(self.scratch) { self.scratch = make([]uint8, size) }
+	return self.scratch[0 : size]
+}
+
+func (self *accumulator) MoveTo(x, y float32) {
+	self.firstX, self.firstY = x, y
+	self.penX, self.penY = x, y
+}
+
+func (self *accumulator) LineTo(bx, by float32) {
+	ax, ay := self.penX, self.penY
+	self.penX, self.penY = bx, by
+	dir := float32(1)
+	if ay > by {
+		dir, ax, ay, bx, by = -1, bx, by, ax, ay
+	}
+	// (almost) horizontal lines yield no change in coverage
+	if by - ay <= 0.000001 { return }
+	dxdy := (bx - ax)/(by - ay)
+
+	x := ax
+	y := accFloor(ay)
+	yMax := accCeil(by)
+	if yMax > self.bandY + int32(self.height) { yMax = self.bandY + int32(self.height) }
+	width := int32(self.width)
+
+	for ; y < yMax; y++ {
+		dy := accMin(float32(y + 1), by) - accMax(float32(y), ay)
+		xNext := x + float32(dy*dxdy)
+		var buf []float32
+		if y >= self.bandY {
+			buf = self.buffer[(y - self.bandY)*width : ]
+		} else if y == self.bandY - 1 && self.padded != nil {
+			buf = self.padded
+		} else {
+			x = xNext
+			continue
+		}
+		d := float32(dy*dir)
+		x0, x1 := x, xNext
+		if x > xNext { x0, x1 = x1, x0 }
+		x0i := accFloor(x0)
+		x0Floor := float32(x0i)
+		x1i := accCeil(x1)
+		x1Ceil := float32(x1i)
+
+		if x1i <= x0i + 1 {
+			xmf := float32(0.5*(x + xNext)) - x0Floor
+			if i := accClamp(x0i + 0, width); i < uint(len(buf)) {
+				buf[i] += d - float32(d*xmf)
+			}
+			if i := accClamp(x0i + 1, width); i < uint(len(buf)) {
+				buf[i] += float32(d*xmf)
+			}
+		} else {
+			s := 1/(x1 - x0)
+			x0f := x0 - x0Floor
+			oneMinusX0f := 1 - x0f
+			a0 := float32(0.5*s*oneMinusX0f*oneMinusX0f)
+			x1f := x1 - x1Ceil + 1
+			am := float32(0.5*s*x1f*x1f)
+
+			if i := accClamp(x0i, width); i < uint(len(buf)) {
+				buf[i] += float32(d*a0)
+			}
+
+			if x1i == x0i + 2 {
+				if i := accClamp(x0i + 1, width); i < uint(len(buf)) {
+					buf[i] += float32(d*(1 - a0 - am))
+				}
+			} else {
+				a1 := float32(s*(1.5 - x0f))
+				if i := accClamp(x0i + 1, width); i < uint(len(buf)) {
+					buf[i] += float32(d*(a1 - a0))
+				}
+				dTimesS := float32(d*s)
+				for xi := x0i + 2; xi < x1i - 1; xi++ {
+					if i := accClamp(xi, width); i < uint(len(buf)) {
+						buf[i] += dTimesS
+					}
+				}
+				a2 := a1 + float32(s*float32(x1i - x0i - 3))
+				if i := accClamp(x1i - 1, width); i < uint(len(buf)) {
+					buf[i] += float32(d*(1 - a2 - am))
+				}
+			}
+
+			if i := accClamp(x1i, width); i < uint(len(buf)) {
+				buf[i] += float32(d*am)
+			}
+		}
+
+		x = xNext
+	}
+}
+
+func (self *accumulator) QuadTo(bx, by, cx, cy float32) {
+	ax, ay := self.penX, self.penY
+	devsq := self.devSquared(ax, ay, bx, by, cx, cy)
+	if devsq >= 0.333 {
+		const tol = 3
+		n := 1 + int(math.Sqrt(math.Sqrt(tol*float64(devsq))))
+		t, nInv := float32(0), 1/float32(n)
+		for i := 0; i < n - 1; i++ {
+			t += nInv
+			abx, aby := self.lerp(t, ax, ay, bx, by)
+			bcx, bcy := self.lerp(t, bx, by, cx, cy)
+			self.LineTo(self.lerp(t, abx, aby, bcx, bcy))
+		}
+	}
+	self.LineTo(cx, cy)
+}
+
+func (self *accumulator) CubeTo(bx, by, cx, cy, dx, dy float32) {
+	ax, ay := self.penX, self.penY
+	devsq := self.devSquared(ax, ay, bx, by, dx, dy)
+	if devsqAlt := self.devSquared(ax, ay, cx, cy, dx, dy); devsq < devsqAlt {
+		devsq = devsqAlt
+	}
+	if devsq >= 0.333 {
+		const tol = 3
+		n := 1 + int(math.Sqrt(math.Sqrt(tol*float64(devsq))))
+		t, nInv := float32(0), 1/float32(n)
+		for i := 0; i < n - 1; i++ {
+			t += nInv
+			abx, aby := self.lerp(t, ax, ay, bx, by)
+			bcx, bcy := self.lerp(t, bx, by, cx, cy)
+			cdx, cdy := self.lerp(t, cx, cy, dx, dy)
+			abcx, abcy := self.lerp(t, abx, aby, bcx, bcy)
+			bcdx, bcdy := self.lerp(t, bcx, bcy, cdx, cdy)
+			self.LineTo(self.lerp(t, abcx, abcy, bcdx, bcdy))
+		}
+	}
+	self.LineTo(dx, dy)
+}
+
+// Accumulates the buffer with the given fill rule and writes the
+// resulting coverage into dst, which must have the same size as the
+// accumulator buffer. The buffer is consumed in the process, so all
+// the accumulation methods can only be called once.
+func (self *accumulator) AccumulateInto(dst []uint8, rule FillRule) {
+	self.AccumulateIntoFrom(dst, rule, 0)
+}
+
+// Like AccumulateInto, but starting the accumulation from the given
+// value instead of zero. Used to continue the accumulation of bands.
+func (self *accumulator) AccumulateIntoFrom(dst []uint8, rule FillRule, acc float32) {
+	if len(dst) < len(self.buffer) { panic("dst too small") }
+	self.prefixSums(acc)
+	likeSIMD := self.likeSIMD()
+	for i, value := range self.buffer {
+		dst[i] = quantizeCoverageLike(applyFillRule(value, rule), likeSIMD)
+	}
+}
+
+// Like AccumulateInto, but writing the unquantized coverage values.
+func (self *accumulator) CoverageInto(dst []float32, rule FillRule) {
+	if len(dst) < len(self.buffer) { panic("dst too small") }
+	self.prefixSums(0)
+	for i, value := range self.buffer {
+		dst[i] = applyFillRule(value, rule)
+	}
+}
+
+// Like CoverageInto, but returning the sum of all the coverage values.
+func (self *accumulator) CoverageSum(rule FillRule) float64 {
+	self.prefixSums(0)
+	var sum float64
+	for _, value := range self.buffer {
+		sum += float64(applyFillRule(value, rule))
+	}
+	return sum
+}
+
+// Like AccumulateInto, but setting pixels to either 0x00 or 0xFF
+// depending on whether their coverage reaches the given cutoff.
+// A small epsilon is applied so pixels split exactly at the cutoff
+// aren't lost due to floating point errors.
+func (self *accumulator) AccumulateThresholdInto(dst []uint8, rule FillRule, cutoff float32) {
+	self.AccumulateThresholdIntoFrom(dst, rule, cutoff, 0)
+}
+
+// Like AccumulateThresholdInto, but starting the accumulation from
+// the given value instead of zero.
+func (self *accumulator) AccumulateThresholdIntoFrom(dst []uint8, rule FillRule, cutoff float32, acc float32) {
+	if len(dst) < len(self.buffer) { panic("dst too small") }
+	cutoff -= 1.0/65536.0
+	self.prefixSums(acc)
+	for i, value := range self.buffer {
+		if applyFillRule(value, rule) >= cutoff {
+			dst[i] = 0xFF
+		} else {
+			dst[i] = 0x00
+		}
+	}
+}
+
+// Returns an accumulator sharing the rows [minY, maxY) of the buffer, so
+// they can be accumulated separately by starting from the last running
+// sum of the previous rows (which is left at the end of their buffer
+// after accumulating them). When mimicking SIMD accumulation, the rows
+// must start at a multiple of 4 values, and all views but the last must
+// also have a multiple of 4 values.
+func (self *accumulator) Rows(minY, maxY int) *accumulator {
+	return &accumulator{
+		buffer: self.buffer[minY*self.width : maxY*self.width],
+		width: self.width,
+		height: maxY - minY,
+		portable: self.portable,
+	}
+}
+
+// Returns whether the accumulation must mimic vector's SIMD
+// accumulation. See accumulateLikeSIMD.
+func (self *accumulator) likeSIMD() bool {
+	return accumulateLikeSIMD && !self.portable
+}
+
+// Replaces the buffer values with their running sum, starting from the
+// given value, using the same order of operations as vector does.
+func (self *accumulator) prefixSums(acc float32) {
+	buffer := self.buffer
+	n := 0
+	if self.likeSIMD() { n = len(buffer) &^ 3 }
+	for i := 0; i < n; i += 4 {
+		group := buffer[i : i + 4 : i + 4]
+		group[0], group[1], group[2], group[3] = simdPrefixSums(group, acc)
+		acc = group[3]
+	}
+	for i := n; i < len(buffer); i++ {
+		acc += buffer[i]
+		buffer[i] = acc
+	}
+}
+
+// Returns the result of prefixSums for the given values without storing
+// the intermediate results. Values (or groups of values, when mimicking
+// SIMD accumulation) that are all zero can be removed with compactSums
+// without changing the result.
+func accumulateSum(values []float32, acc float32) float32 {
+	n := 0
+	if accumulateLikeSIMD { n = len(values) &^ 3 }
+	for i := 0; i < n; i += 4 {
+		_, _, _, acc = simdPrefixSums(values[i : i + 4], acc)
+	}
+	for _, value := range values[n : ] { acc += value }
+	return acc
+}
+
+// Appends the values that can affect accumulateSum to dst.
+func compactSums(dst, values []float32) []float32 {
+	n := 0
+	if accumulateLikeSIMD { n = len(values) &^ 3 }
+	for i := 0; i < n; i += 4 {
+		group := values[i : i + 4]
+		if group[0] != 0 || group[1] != 0 || group[2] != 0 || group[3] != 0 {
+			dst = append(dst, group...)
+		}
+	}
+	for _, value := range values[n : ] {
+		if value != 0 { dst = append(dst, value) }
+	}
+	return dst
+}
+
+// On amd64, vector accumulates and quantizes with SSE4.1 instructions,
+// processing groups of 4 values with a different order of operations,
+// which can lead to small differences in the results. We assume SSE4.1
+// is available, which is the case for virtually all amd64 processors.
+var accumulateLikeSIMD = (runtime.GOARCH == "amd64")
+
+// Computes the running sums of a group of 4 values like vector's SIMD
+// accumulation does.
+func simdPrefixSums(group []float32, acc float32) (float32, float32, float32, float32) {
+	s0, s1, s2, s3 := group[0], group[1], group[2], group[3]
+	x1, x2, x3 := s0 + s1, s1 + s2, s2 + s3
+	x2, x3 = x2 + s0, x3 + x1
+	return s0 + acc, x1 + acc, x2 + acc, x3 + acc
+}
+
+// Quantizes coverage in [0, 1] to [0x00, 0xFF] like vector does.
+func quantizeCoverage(coverage float32) uint8 {
+	return quantizeCoverageLike(coverage, accumulateLikeSIMD)
+}
+
+// Like quantizeCoverage, but choosing explicitly whether to mimic
+// vector's SIMD quantization or the scalar one.
+func quantizeCoverageLike(coverage float32, likeSIMD bool) uint8 {
+	if likeSIMD {
+		return uint8(uint32(coverage*almost65536) >> 8)
+	}
+	return uint8(almost256*coverage)
+}
+
+// Maps an accumulated signed area value to coverage in [0, 1].
+func applyFillRule(acc float32, rule FillRule) float32 {
+	if acc < 0 { acc = -acc }
+	if rule == FillEvenOdd {
+		if acc > 2 { acc -= 2*float32(math.Floor(float64(acc/2))) }
+		if acc > 1 { acc = 2 - acc }
+		return acc
+	}
+	if acc > 1 { return 1 }
+	return acc
+}
+
+// Like vector's almost256, scales [0, 1] to [0x00, 0xFF] with truncation.
+const almost256 = 255.99998
+
+// Like vector's flAlmost65536 (0x477fffff), used on SIMD quantization.
+const almost65536 = float32(65535.99609375)
+
+func accFloor(x float32) int32 { return int32(math.Floor(float64(x))) }
+func accCeil(x float32) int32  { return int32(math.Ceil(float64(x))) }
+
+func accMin(x, y float32) float32 {
+	if x < y { return x }
+	return y
+}
+
+func accMax(x, y float32) float32 {
+	if x > y { return x }
+	return y
+}
+
+func accClamp(i, width int32) uint {
+	if i < 0 { return 0 }
+	if i < width { return uint(i) }
+	return uint(width)
+}
+
+// Like vector's lerp, which doesn't prevent fused multiply-adds on
+// the platforms that support them, unless the accumulator is portable.
+func (self *accumulator) lerp(t, px, py, qx, qy float32) (x, y float32) {
+	if self.portable {
+		return px + float32(t*(qx - px)), py + float32(t*(qy - py))
+	}
+	return px + t*(qx - px), py + t*(qy - py)
+}
+
+// Like vector's devSquared. See lerp.
+func (self *accumulator) devSquared(ax, ay, bx, by, cx, cy float32) float32 {
+	devx := ax - 2*bx + cx
+	devy := ay - 2*by + cy
+	if self.portable { return float32(devx*devx) + float32(devy*devy) }
+	return devx*devx + devy*devy
+}
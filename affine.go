@@ -0,0 +1,209 @@
+package sfntshape
+
+import "math"
+import "image"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// A 2D affine transformation, mapping (x, y) to:
+//   (XX*x + XY*y + X0, YX*x + YY*y + Y0)
+// Transformations apply to the coordinates of the stored segments,
+// which are in the same space as the rasterized masks (y grows
+// downwards, unless [Shape.InvertY]() was used). The zero value is
+// not the identity; use [AffineIdentity]() instead.
+type Affine struct {
+	XX, XY, X0 float64
+	YX, YY, Y0 float64
+}
+
+// Returns the identity transformation.
+func AffineIdentity() Affine {
+	return Affine{ XX: 1, YY: 1 }
+}
+
+// Returns a rotation by the given angle, in radians (see [Deg]()),
+// around (0, 0). Since y grows downwards, positive angles rotate
+// clockwise on screen.
+func AffineRotation(angle float64) Affine {
+	sin, cos := sincos(angle)
+	return Affine{ XX: cos, XY: -sin, YX: sin, YY: cos }
+}
+
+// Returns a scaling by the given factors.
+func AffineScale(sx, sy float64) Affine {
+	return Affine{ XX: sx, YY: sy }
+}
+
+// Returns a translation by the given offset, in pixels.
+func AffineTranslation(dx, dy float64) Affine {
+	return Affine{ XX: 1, X0: dx, YY: 1, Y0: dy }
+}
+
+// Returns the transformation that applies self first and then other.
+func (self Affine) Then(other Affine) Affine {
+	return Affine{
+		XX: other.XX*self.XX + other.XY*self.YX,
+		XY: other.XX*self.XY + other.XY*self.YY,
+		X0: other.XX*self.X0 + other.XY*self.Y0 + other.X0,
+		YX: other.YX*self.XX + other.YY*self.YX,
+		YY: other.YX*self.XY + other.YY*self.YY,
+		Y0: other.YX*self.X0 + other.YY*self.Y0 + other.Y0,
+	}
+}
+
+// Returns the result of applying the transformation to (x, y).
+func (self Affine) Apply(x, y float64) (float64, float64) {
+	return self.XX*x + self.XY*y + self.X0, self.YX*x + self.YY*y + self.Y0
+}
+
+// Returns the inverse transformation, or false if the transformation
+// can't be inverted (its determinant is zero or not finite).
+func (self Affine) Invert() (Affine, bool) {
+	det := self.XX*self.YY - self.XY*self.YX
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) { return Affine{}, false }
+	inv := 1/det
+	xx, xy := self.YY*inv, -self.XY*inv
+	yx, yy := -self.YX*inv, self.XX*inv
+	return Affine{
+		XX: xx, XY: xy, X0: -(xx*self.X0 + xy*self.Y0),
+		YX: yx, YY: yy, Y0: -(yx*self.X0 + yy*self.Y0),
+	}, true
+}
+
+// Rasterizes the shape with the given transformation applied to its
+// points on the fly, as they are fed to the rasterizer. The stored
+// segments are not modified or copied, so the same shape can be
+// rendered at many rotations or scales cheaply.
+//
+// The mask is sized to the transformed control points, and its Rect
+// is in the transformed coordinates, like [Shape.Rasterize]() would
+// return for the transformed segments.
+func (self *Shape) RasterizeTransformed(m Affine) (*image.Alpha, error) {
+	if self.err != nil { return nil, self.err }
+	if self.IsEmpty() { return nil, nothingToDraw() }
+
+	minX, minY, maxX, maxY := transformedBounds(self.segments, m)
+	rect := image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+	)
+	width, height := rect.Dx(), rect.Dy()
+	if err := checkMaskSize(width, height); err != nil { return nil, err }
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	resetRasterizer(rasterizer, width, height)
+
+	// normalize coordinates to the mask origin
+	m.X0 -= float64(rect.Min.X)
+	m.Y0 -= float64(rect.Min.Y)
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	processOutline(&transformedProcessor{ rasterizer, newAffine32(m) }, self.segments, 0, 0)
+	rasterizer.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	mask.Rect = rect
+	return mask, nil
+}
+
+// Applies the transformation to all the stored segments and anchors
+// (see [Shape.SetAnchor]()) of the shape, in place. The results are
+// rounded to 26.6 values (see [Shape.SetRounding]()), and saturated
+// (recording an [ErrCoordinateOverflow]) if they fall out of range.
+// Unlike [Shape.RasterizeTransformed](), curves remain exact curves,
+// but repeated transformations accumulate rounding errors. Positions
+// returned by [Shape.Pos]() are not transformed, as they refer to the
+// coordinates originally passed to the shape.
+func (self *Shape) Transform(m Affine) {
+	apply := func(point fixed.Point26_6) fixed.Point26_6 {
+		x, y := m.Apply(fixedToF64(point.X), fixedToF64(point.Y))
+		return fixed.Point26_6{ X: self.saturateFloat(x*64), Y: self.saturateFloat(y*64) }
+	}
+	for i := range self.segments {
+		segment := &self.segments[i]
+		for j := 0; j < segmentArgsCount(segment.Op); j++ { segment.Args[j] = apply(segment.Args[j]) }
+	}
+	for name, point := range self.anchors { self.anchors[name] = apply(point) }
+	self.dirty.markAll()
+	self.recomputeBounds()
+}
+
+// Multiplies all the stored coordinates (segments and anchors) by the
+// given factor, like [Shape.Transform]() with [AffineScale](factor,
+// factor). Coordinates are scaled in float64 and rounded only once, so
+// an already built shape can be resized (e.g., authored at 100 units
+// and then rescaled to the target size). This is different from
+// [Shape.SetScale](), which doesn't modify existing segments and only
+// affects the coordinates of subsequent commands (and is applied on top
+// of them, before rescaling). The current scale is not modified.
+func (self *Shape) Rescale(factor float64) {
+	self.Transform(AffineScale(factor, factor))
+}
+
+// Like saturate, for float64 values, which are rounded first according
+// to the shape's rounding mode (NaNs saturate too).
+func (self *Shape) saturateFloat(value float64) Fract {
+	value = self.rounding.round(value)
+	if value > math.MaxInt32 || value != value { return self.saturate(math.MaxInt64) }
+	if value < -math.MaxInt32 { return self.saturate(math.MinInt64) }
+	return Fract(value)
+}
+
+// Returns the bounds of the transformed control points of the
+// segments, in pixels.
+func transformedBounds(segments []sfnt.Segment, m Affine) (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for _, segment := range segments {
+		for i := 0; i < segmentArgsCount(segment.Op); i++ {
+			point := segment.Args[i]
+			x, y := m.Apply(fixedToF64(point.X), fixedToF64(point.Y))
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+			minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		}
+	}
+	return minX, minY, maxX, maxY
+}
+
+// An Affine transformation in float32, as used while
+// feeding rasterizers.
+type affine32 struct {
+	xx, xy, x0 float32
+	yx, yy, y0 float32
+}
+
+func newAffine32(m Affine) affine32 {
+	return affine32{
+		float32(m.XX), float32(m.XY), float32(m.X0),
+		float32(m.YX), float32(m.YY), float32(m.Y0),
+	}
+}
+
+func (self *affine32) apply(x, y float32) (float32, float32) {
+	return self.xx*x + self.xy*y + self.x0, self.yx*x + self.yy*y + self.y0
+}
+
+// A pathProcessor wrapper that transforms all coordinates.
+type transformedProcessor struct {
+	processor pathProcessor
+	m affine32
+}
+
+func (self *transformedProcessor) MoveTo(x, y float32) {
+	self.processor.MoveTo(self.m.apply(x, y))
+}
+
+func (self *transformedProcessor) LineTo(x, y float32) {
+	self.processor.LineTo(self.m.apply(x, y))
+}
+
+func (self *transformedProcessor) QuadTo(bx, by, cx, cy float32) {
+	bx, by = self.m.apply(bx, by)
+	cx, cy = self.m.apply(cx, cy)
+	self.processor.QuadTo(bx, by, cx, cy)
+}
+
+func (self *transformedProcessor) CubeTo(bx, by, cx, cy, dx, dy float32) {
+	bx, by = self.m.apply(bx, by)
+	cx, cy = self.m.apply(cx, cy)
+	dx, dy = self.m.apply(dx, dy)
+	self.processor.CubeTo(bx, by, cx, cy, dx, dy)
+}
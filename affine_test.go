@@ -0,0 +1,133 @@
+package sfntshape
+
+import "math"
+import "testing"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+import "golang.org/x/image/vector"
+
+import "github.com/tinne26/sfntshape/internal/maskcmp"
+
+// Returns the segments transformed by m, rounded to 26.6 values.
+func transformSegments(segments []sfnt.Segment, m Affine) []sfnt.Segment {
+	transformed := make([]sfnt.Segment, len(segments))
+	for i, segment := range segments {
+		for j := 0; j < segmentArgsCount(segment.Op); j++ {
+			x, y := m.Apply(fixedToF64(segment.Args[j].X), fixedToF64(segment.Args[j].Y))
+			segment.Args[j] = fixed.Point26_6{ X: Fract(math.Round(x*64)), Y: Fract(math.Round(y*64)) }
+		}
+		transformed[i] = segment
+	}
+	return transformed
+}
+
+func TestRasterizeTransformed(t *testing.T) {
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.LineTo(40, 0)
+	shape.QuadTo(40, 20, 20, 20)
+	shape.CubeTo(10, 20, 5, 30, 0, 10)
+	shape.LineTo(0, 0)
+	original := append([]sfnt.Segment(nil), shape.Segments()...)
+
+	identity, err := shape.RasterizeTransformed(AffineIdentity())
+	if err != nil { t.Fatal(err) }
+	expected, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	if err := maskcmp.Compare(identity, expected, 0, 0); err != nil {
+		t.Fatalf("identity transform changed the result: %s", err)
+	}
+
+	for _, m := range []Affine{
+		AffineRotation(math.Pi/6),
+		AffineRotation(-2).Then(AffineTranslation(12.3, -7.9)),
+		AffineScale(1.5, 0.5).Then(AffineRotation(1)),
+	}{
+		mask, err := shape.RasterizeTransformed(m)
+		if err != nil { t.Fatal(err) }
+		expected, err := Rasterize(transformSegments(shape.Segments(), m), vector.NewRasterizer(0, 0), 0, 0)
+		if err != nil { t.Fatal(err) }
+		// curves may be flattened differently, so edges can move slightly
+		if err := maskcmp.Compare(mask, expected, 64, 0); err != nil {
+			t.Fatalf("transform %+v: %s", m, err)
+		}
+		if diff := math.Abs(maskArea(mask) - maskArea(expected)); diff > maskArea(expected)*0.005 {
+			t.Fatalf("transform %+v: area difference %.2f", m, diff)
+		}
+		if !sameSegments(original, shape.Segments()) { t.Fatalf("segments modified") }
+	}
+
+	// composition order
+	p := AffineTranslation(1, 0).Then(AffineScale(2, 3))
+	if x, y := p.Apply(1, 1); x != 4 || y != 3 {
+		t.Fatalf("unexpected composition result (%v, %v)", x, y)
+	}
+}
+
+func BenchmarkRasterizeTransformed(b *testing.B) {
+	shape := testPolygon(64, 128)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = shape.RasterizeTransformed(AffineRotation(float64(i)*0.01))
+	}
+}
+
+func TestAffineInvert(t *testing.T) {
+	m := AffineScale(2, 3).Then(AffineRotation(0.7)).Then(AffineTranslation(5, -4))
+	inverse, ok := m.Invert()
+	if !ok { t.Fatal("expected invertible transformation") }
+	x, y := inverse.Apply(m.Apply(1.5, -7))
+	if math.Abs(x - 1.5) > 1e-9 || math.Abs(y + 7) > 1e-9 {
+		t.Fatalf("expected (1.5, -7) after inverting, got (%f, %f)", x, y)
+	}
+	if _, ok := AffineScale(1, 0).Invert(); ok { t.Fatal("expected non-invertible transformation") }
+}
+
+func TestShapeTransform(t *testing.T) {
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.LineTo(40, 0)
+	shape.QuadTo(40, 20, 20, 20)
+	shape.CubeTo(10, 20, 5, 30, 0, 10)
+	shape.LineTo(0, 0)
+	m := AffineRotation(0.3).Then(AffineScale(1.5, 0.75)).Then(AffineTranslation(3.3, -7.1))
+	expected := transformSegments(shape.Segments(), m)
+	shape.Transform(m)
+	for i, segment := range shape.Segments() {
+		if segment != expected[i] { t.Fatalf("segment %d: expected %v, got %v", i, expected[i], segment) }
+	}
+	if shape.Err() != nil { t.Fatalf("unexpected error %v", shape.Err()) }
+
+	shape.Transform(AffineScale(1e9, 1))
+	if shape.Err() != ErrCoordinateOverflow { t.Fatalf("expected ErrCoordinateOverflow, got %v", shape.Err()) }
+}
+
+func TestRescale(t *testing.T) {
+	square := func(shape *Shape, size int) {
+		shape.MoveTo(0, 0)
+		shape.LineTo(size, 0)
+		shape.LineTo(size, size)
+		shape.LineTo(0, size)
+		shape.LineTo(0, 0)
+	}
+	rescaled, doubled := New(), New()
+	square(&rescaled, 1)
+	square(&doubled, 2)
+	rescaled.SetAnchor("corner", 64, 64)
+	rescaled.Rescale(2)
+	if !rescaled.Equal(&doubled) { t.Fatalf("expected a square of size 2, got %v", &rescaled) }
+	if x, y, _ := rescaled.Anchor("corner"); x != 128 || y != -128 { t.Fatalf("unexpected anchor at (%d, %d)", x, y) }
+	if rescaled.GetScale() != 64 { t.Fatal("expected Rescale to keep the scale") }
+	if rescaled.Bounds() != doubled.Bounds() { t.Fatal("unexpected bounds after Rescale") }
+
+	// round trips stay within 1 fract unit
+	original := bigTestShape(100)
+	original.QuadToFract(333, 777, 1001, -5)
+	roundTrip := original.Clone()
+	roundTrip.Rescale(0.5)
+	roundTrip.Rescale(2)
+	if !roundTrip.ApproxEqual(&original, 1) {
+		t.Fatalf("round trip drifted, %s", roundTrip.Diff(&original, 1))
+	}
+}
@@ -0,0 +1,37 @@
+package sfntshape
+
+import "golang.org/x/image/math/fixed"
+
+// Stores a named point on the shape, replacing any previous anchor with
+// the same name. The point goes through the same pipeline as the
+// coordinates of [Shape.MoveToFract]() and similar methods (scale and
+// InvertY are applied), and further transformations of the segments
+// (like [Shape.Transform]()) also transform the anchors, so they can be
+// used to tag attachment points or label positions while building a
+// shape and query them later. Anchors don't affect rasterization, and
+// they are cleared by [Shape.Reset]().
+func (self *Shape) SetAnchor(name string, x, y Fract) {
+	x, y = self.transform(x, y)
+	if self.anchors == nil { self.anchors = make(map[string]fixed.Point26_6) }
+	self.anchors[name] = fixed.Point26_6{ X: x, Y: y }
+}
+
+// Returns the anchor with the given name (see [Shape.SetAnchor]()), in
+// the same coordinates as [Shape.Segments](), or false if there's none.
+func (self *Shape) Anchor(name string) (Fract, Fract, bool) {
+	point, found := self.anchors[name]
+	return point.X, point.Y, found
+}
+
+// Returns a copy of all the anchors of the shape, in the same
+// coordinates as [Shape.Segments]().
+func (self *Shape) Anchors() map[string][2]Fract {
+	anchors := make(map[string][2]Fract, len(self.anchors))
+	for name, point := range self.anchors { anchors[name] = [2]Fract{ point.X, point.Y } }
+	return anchors
+}
+
+// Removes the anchor with the given name, if any.
+func (self *Shape) DeleteAnchor(name string) {
+	delete(self.anchors, name)
+}
@@ -0,0 +1,46 @@
+package sfntshape
+
+import "math"
+import "testing"
+
+func TestAnchors(t *testing.T) {
+	shape := New()
+	shape.SetScale(2)
+	shape.MoveTo(0, 0)
+	shape.LineTo(10, 0)
+	shape.LineTo(10, 10)
+	shape.SetAnchor("tail-attach", 10*64, 10*64) // at the last LineTo
+	shape.SetAnchor("label-center", 5*64, 2*64)
+	if _, _, found := shape.Anchor("missing"); found { t.Fatal("unexpected anchor") }
+	x, y, found := shape.Anchor("tail-attach")
+	if end := shape.Segments()[2].Args[0]; !found || x != end.X || y != end.Y {
+		t.Fatalf("expected the anchor at the segment end %v, got (%d, %d)", end, x, y)
+	}
+
+	// anchors follow transformations
+	m := AffineRotation(math.Pi/2).Then(AffineTranslation(7, -3))
+	shape.Transform(m)
+	x, y, _ = shape.Anchor("tail-attach")
+	if end := shape.Segments()[2].Args[0]; x != end.X || y != end.Y {
+		t.Fatalf("expected the anchor to move with the segments, %v vs (%d, %d)", end, x, y)
+	}
+	x, y, _ = shape.Anchor("label-center")
+	ex, ey := m.Apply(10, -4) // scaled, and with y inverted
+	if math.Abs(fixedToF64(x) - ex) > 1.0/64 || math.Abs(fixedToF64(y) - ey) > 1.0/64 {
+		t.Fatalf("expected (%f, %f), got (%f, %f)", ex, ey, fixedToF64(x), fixedToF64(y))
+	}
+
+	anchors := shape.Anchors()
+	if len(anchors) != 2 || anchors["label-center"] != [2]Fract{ x, y } { t.Fatalf("unexpected anchors %v", anchors) }
+	anchors["label-center"] = [2]Fract{}
+	if x2, _, _ := shape.Anchor("label-center"); x2 != x { t.Fatal("expected Anchors to return a copy") }
+
+	clone := shape.Clone()
+	clone.SetAnchor("label-center", 0, 0)
+	if x2, _, _ := shape.Anchor("label-center"); x2 != x { t.Fatal("expected clones to have their own anchors") }
+	shape.DeleteAnchor("label-center")
+	if _, _, found := shape.Anchor("label-center"); found { t.Fatal("expected the anchor to be deleted") }
+	if _, _, found := clone.Anchor("label-center"); !found { t.Fatal("expected the clone to keep its anchor") }
+	shape.Reset()
+	if len(shape.Anchors()) != 0 { t.Fatal("expected Reset to clear the anchors") }
+}
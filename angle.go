@@ -0,0 +1,32 @@
+package sfntshape
+
+import "math"
+
+// Converts an angle in degrees to radians, for [AffineRotation](),
+// [Shape.LineToAngle]() and the other methods taking angles. Multiples
+// of 90 degrees are converted to the exact values those methods
+// recognize as right angles, so rotating by Deg(90) four times (or by
+// Deg(180) twice) gets back exactly to the original coordinates,
+// without any floating point error leaking into them.
+func Deg(degrees float64) float64 {
+	if quarter := degrees/90; quarter == math.Trunc(quarter) && math.Abs(quarter) < 1 << 52 {
+		return quarter*(math.Pi/2)
+	}
+	return degrees*(math.Pi/180)
+}
+
+// Like [math.Sincos](), but exact for multiples of Pi/2 (as returned
+// by [Deg]()), where math.Sincos returns tiny non-zero values instead
+// of zeros (e.g., cos(Pi/2) = 6.1e-17).
+func sincos(radians float64) (float64, float64) {
+	quarter := math.Round(radians/(math.Pi/2))
+	if quarter*(math.Pi/2) == radians && math.Abs(quarter) < 1 << 52 {
+		switch int64(quarter) & 3 {
+		case 0: return 0, 1
+		case 1: return 1, 0
+		case 2: return 0, -1
+		default: return -1, 0
+		}
+	}
+	return math.Sincos(radians)
+}
@@ -0,0 +1,45 @@
+package sfntshape
+
+import "math"
+import "testing"
+
+func TestDeg(t *testing.T) {
+	for _, test := range []struct{ degrees, radians float64 }{
+		{ 0, 0 }, { 90, math.Pi/2 }, { 180, math.Pi }, { 270, 3*math.Pi/2 },
+		{ -90, -math.Pi/2 }, { 360, 2*math.Pi }, { 45, math.Pi/4 }, { 30, math.Pi/6 },
+	} {
+		if got := Deg(test.degrees); math.Abs(got - test.radians) > 1e-15 {
+			t.Fatalf("Deg(%v) = %v, expected %v", test.degrees, got, test.radians)
+		}
+	}
+	for _, degrees := range []float64{ 0, 90, 180, 270, -90, 360, 450, -720 } {
+		sin, cos := sincos(Deg(degrees))
+		quarter := int(degrees/90) & 3
+		expectSin, expectCos := []float64{ 0, 1, 0, -1 }[quarter], []float64{ 1, 0, -1, 0 }[quarter]
+		if sin != expectSin || cos != expectCos {
+			t.Fatalf("inexact sincos for %v degrees: %v, %v", degrees, sin, cos)
+		}
+	}
+
+	// rotating four times by 90 degrees gets back exactly to the original
+	shape := bigTestShape(100)
+	shape.QuadToFract(333, 777, 1001, -5)
+	shape.SetAnchor("dot", 17, 29)
+	original := shape.Clone()
+	for i := 0; i < 4; i++ {
+		shape.Transform(AffineRotation(Deg(90)))
+		if i == 1 {
+			half := original.Clone()
+			half.Transform(AffineRotation(Deg(180)))
+			if !shape.Equal(&half) { t.Fatal("expected two 90 degree rotations to match one of 180") }
+		}
+	}
+	if !shape.Equal(&original) { t.Fatalf("rotations drifted, %s", shape.Diff(&original, 0)) }
+	if x, y, _ := shape.Anchor("dot"); x != 17 || y != -29 { t.Fatalf("anchor drifted to (%d, %d)", x, y) }
+
+	// exact polar commands too
+	turtle := New()
+	turtle.MoveTo(1, 1)
+	turtle.LineToAngle(0.123, Deg(90))
+	if x, y, _ := turtle.Pos(); x != 64 || y != 64 + 8 { t.Fatalf("unexpected position (%d, %d)", x, y) }
+}
@@ -0,0 +1,57 @@
+package sfntshape
+
+import "image"
+import "errors"
+
+import "golang.org/x/image/math/fixed"
+
+// Stamps the shape into dst at each of the given placements, adding
+// the coverage with saturation (like [AccumulateAdd]). Each placement is
+// the origin the shape is drawn at, in the same coordinates as dst.Rect:
+// its fractional part is the subpixel phase the shape is rasterized at,
+// and its integer part the translation applied to the resulting mask.
+// Anything falling outside dst's bounds is ignored.
+//
+// The shape is rasterized only once per unique subpixel phase, like
+// [Shape.RasterizePhases]() would, so stamping a shape hundreds of
+// times is barely more expensive than blitting the masks. Quantizing
+// the placements (e.g., to 1/4th of a pixel) reduces the number of
+// phases and makes this even cheaper.
+func RasterizeAtlas(shape *Shape, placements []fixed.Point26_6, dst *image.Alpha) error {
+	if dst == nil { return errors.New("nil dst mask") }
+	if shape.err != nil { return shape.err }
+	if !hasDrawingOps(shape.segments) || len(placements) == 0 { return nil }
+
+	rasterizer, custom := shape.acquireRasterizer()
+	defer shape.releaseRasterizer(rasterizer, custom)
+	masks := make(map[fixed.Point26_6]*image.Alpha)
+	for _, placement := range placements {
+		phase := fixed.Point26_6{ X: fixedFract(placement.X), Y: fixedFract(placement.Y) }
+		mask, found := masks[phase]
+		if !found {
+			var err error
+			mask, err = Rasterize(shape.segments, rasterizer, phase.X, phase.Y)
+			if err != nil { return err }
+			masks[phase] = mask
+		}
+		offset := image.Pt(fixedToIntFloor(placement.X - phase.X), fixedToIntFloor(placement.Y - phase.Y))
+		addMask(dst, mask, offset)
+	}
+	return nil
+}
+
+// Adds the coverage of the mask translated by the given offset to dst,
+// saturating and clipping to dst's bounds.
+func addMask(dst, mask *image.Alpha, offset image.Point) {
+	rect := mask.Rect.Add(offset).Intersect(dst.Rect)
+	if rect.Empty() { return }
+	width := rect.Dx()
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		srcRow := mask.Pix[mask.PixOffset(rect.Min.X - offset.X, y - offset.Y) : ][ : width]
+		dstRow := dst.Pix[dst.PixOffset(rect.Min.X, y) : ][ : width]
+		for x, sa := range srcRow {
+			if sa == 0 { continue }
+			dstRow[x] = uint8N(uint32(dstRow[x]) + uint32(sa))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package sfntshape
+
+import "image"
+import "bytes"
+import "testing"
+
+import "golang.org/x/image/math/fixed"
+
+// Pseudo-random placements partially spilling out of a 200x150 atlas.
+func testPlacements(count int) []fixed.Point26_6 {
+	placements := make([]fixed.Point26_6, count)
+	seed := uint32(7)
+	next := func(limit int) Fract {
+		seed = seed*1664525 + 1013904223
+		return Fract(int(seed >> 8) % (limit << 6)) - 20*64
+	}
+	for i := range placements {
+		placements[i] = fixed.Point26_6{ X: next(240), Y: next(190) }
+	}
+	return placements
+}
+
+func naiveAtlas(shape *Shape, placements []fixed.Point26_6, dst *image.Alpha) error {
+	for _, placement := range placements {
+		mask, err := shape.RasterizeFract(placement.X, placement.Y)
+		if err != nil { return err }
+		offset := image.Pt(placement.X.Floor(), placement.Y.Floor())
+		for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+			for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+				point := image.Pt(x, y).Add(offset)
+				if !point.In(dst.Rect) { continue }
+				sum := uint32(dst.AlphaAt(point.X, point.Y).A) + uint32(mask.AlphaAt(x, y).A)
+				dst.Pix[dst.PixOffset(point.X, point.Y)] = uint8N(sum)
+			}
+		}
+	}
+	return nil
+}
+
+func TestRasterizeAtlas(t *testing.T) {
+	shape := testStar(12)
+	placements := testPlacements(300)
+	placements = append(placements, fixed.Point26_6{ X: -5*64 - 17, Y: 148*64 + 3 })
+
+	expected := image.NewAlpha(image.Rect(-10, 20, 190, 170))
+	if err := naiveAtlas(&shape, placements, expected); err != nil { t.Fatal(err) }
+	atlas := image.NewAlpha(expected.Rect)
+	if err := RasterizeAtlas(&shape, placements, atlas); err != nil { t.Fatal(err) }
+	if !bytes.Equal(atlas.Pix, expected.Pix) { t.Fatal("expected atlas to match the naive loop") }
+	if maskArea(atlas) == 0 { t.Fatal("expected some coverage") }
+
+	// a sub-image is only written within its bounds
+	full := image.NewAlpha(image.Rect(0, 0, 100, 100))
+	sub := full.SubImage(image.Rect(20, 20, 60, 60)).(*image.Alpha)
+	if err := RasterizeAtlas(&shape, placements, sub); err != nil { t.Fatal(err) }
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			if !image.Pt(x, y).In(sub.Rect) && full.AlphaAt(x, y).A != 0 {
+				t.Fatalf("unexpected coverage outside the sub-image at (%d, %d)", x, y)
+			}
+		}
+	}
+
+	if err := RasterizeAtlas(&shape, placements, nil); err == nil { t.Fatal("expected nil dst error") }
+}
+
+func BenchmarkRasterizeAtlas(b *testing.B) {
+	shape := testStar(12)
+	placements := testPlacements(500)
+	for i := range placements { // quarter pixel phases
+		placements[i].X &^= 15
+		placements[i].Y &^= 15
+	}
+	atlas := image.NewAlpha(image.Rect(-10, 20, 190, 170))
+	b.Run("atlas", func(b *testing.B) {
+		for i := 0; i < b.N; i++ { _ = RasterizeAtlas(&shape, placements, atlas) }
+	})
+	b.Run("naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ { _ = naiveAtlas(&shape, placements, atlas) }
+	})
+}
@@ -0,0 +1,46 @@
+package sfntshape
+
+import "image"
+
+// Rasterizes the shape and packs it into a 1-bit bitmap, as commonly
+// required by monochrome displays. Pixels with a coverage greater or
+// equal to the given threshold are set (128 is a reasonable default).
+//
+// Each row is packed in stride bytes, with 8 pixels per byte and the
+// most significant bit corresponding to the leftmost pixel. The stride
+// is rounded up to whole bytes, and the unused trailing bits of each
+// row are always zero. The returned bounds are the same that the mask
+// returned by [Shape.Rasterize]() would have. For empty shapes, bits
+// is nil and bounds is empty. See also [BitmapToAlpha]().
+func (self *Shape) Bitmap(threshold uint8) (bits []byte, bounds image.Rectangle, stride int, err error) {
+	mask, err := self.Rasterize()
+	if err != nil || mask == nil { return nil, image.Rectangle{}, 0, err }
+
+	width, height := mask.Rect.Dx(), mask.Rect.Dy()
+	stride = (width + 7) >> 3
+	bits = make([]byte, stride*height)
+	for y := 0; y < height; y++ {
+		row := mask.Pix[y*mask.Stride : ][ : width]
+		out := bits[y*stride : ][ : stride]
+		for x, value := range row {
+			if value >= threshold { out[x >> 3] |= 0x80 >> (x & 7) }
+		}
+	}
+	return bits, mask.Rect, stride, nil
+}
+
+// Expands a 1-bit bitmap like the ones created by [Shape.Bitmap]()
+// back into an [*image.Alpha], with set bits as 0xFF and unset bits
+// as 0x00. Mostly useful for testing and previews.
+func BitmapToAlpha(bits []byte, bounds image.Rectangle, stride int) *image.Alpha {
+	mask := image.NewAlpha(bounds)
+	width, height := bounds.Dx(), bounds.Dy()
+	for y := 0; y < height; y++ {
+		row := bits[y*stride : ][ : stride]
+		out := mask.Pix[y*mask.Stride : ][ : width]
+		for x := range out {
+			if row[x >> 3] & (0x80 >> (x & 7)) != 0 { out[x] = 0xFF }
+		}
+	}
+	return mask
+}
@@ -0,0 +1,52 @@
+package sfntshape
+
+import "testing"
+import "math/bits"
+
+func TestBitmap(t *testing.T) {
+	star := testStar(20)
+	circle := New()
+	testCircle(&circle, 0, 0, 9)
+	triangle := New() // 13 pixels wide, not a multiple of 8
+	triangle.MoveTo(0, 0)
+	triangle.LineTo(13, 0)
+	triangle.LineTo(0, 5)
+	triangle.LineTo(0, 0)
+
+	for i, shape := range []*Shape{ &star, &circle, &triangle } {
+		for _, threshold := range []uint8{ 1, 128, 255 } {
+			mask, err := shape.Rasterize()
+			if err != nil { t.Fatal(err) }
+			packed, bounds, stride, err := shape.Bitmap(threshold)
+			if err != nil { t.Fatal(err) }
+			if bounds != mask.Rect { t.Fatalf("shape %d: expected bounds %v, got %v", i, mask.Rect, bounds) }
+			if stride != (bounds.Dx() + 7)/8 { t.Fatalf("shape %d: unexpected stride %d", i, stride) }
+
+			expected := 0
+			for _, value := range mask.Pix {
+				if value >= threshold { expected += 1 }
+			}
+			setBits := 0
+			for _, b := range packed { setBits += bits.OnesCount8(b) }
+			if setBits != expected {
+				t.Fatalf("shape %d, threshold %d: expected %d set bits, got %d", i, threshold, expected, setBits)
+			}
+
+			expanded := BitmapToAlpha(packed, bounds, stride)
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					set := mask.AlphaAt(x, y).A >= threshold
+					if (expanded.AlphaAt(x, y).A == 0xFF) != set {
+						t.Fatalf("shape %d, threshold %d: round trip mismatch at (%d, %d)", i, threshold, x, y)
+					}
+				}
+			}
+		}
+	}
+
+	empty := New()
+	packed, bounds, stride, err := empty.Bitmap(128)
+	if packed != nil || !bounds.Empty() || stride != 0 || err != nil {
+		t.Fatal("expected no bitmap for an empty shape")
+	}
+}
@@ -0,0 +1,55 @@
+package sfntshape
+
+import "image"
+import "image/draw"
+import "image/color"
+import "testing"
+
+func TestPaintBlend(t *testing.T) {
+	// rectangle with full coverage except for a 50% column at x = 4
+	shape := New()
+	shape.InvertY(true)
+	shape.MoveTo(0, 0)
+	shape.LineToFract(4*64 + 32, 0)
+	shape.LineToFract(4*64 + 32, 4*64)
+	shape.LineTo(0, 4)
+	shape.LineTo(0, 0)
+
+	src, back := color.RGBA{ 200, 100, 50, 255 }, color.RGBA{ 100, 200, 255, 255 }
+	tests := []struct {
+		mode BlendMode
+		full, half color.RGBA // at full and 50% coverage
+	}{
+		{ BlendMultiply, color.RGBA{ 78, 78, 50, 255 }, color.RGBA{ 89, 139, 152, 255 } },
+		{ BlendScreen, color.RGBA{ 222, 222, 255, 255 }, color.RGBA{ 161, 211, 255, 255 } },
+		{ BlendOverlay, color.RGBA{ 157, 188, 255, 255 }, color.RGBA{ 129, 194, 255, 255 } },
+		{ BlendDarken, color.RGBA{ 100, 100, 50, 255 }, color.RGBA{ 100, 150, 152, 255 } },
+		{ BlendLighten, color.RGBA{ 200, 200, 255, 255 }, color.RGBA{ 150, 200, 255, 255 } },
+		{ BlendAdd, color.RGBA{ 255, 255, 255, 255 }, color.RGBA{ 178, 228, 255, 255 } },
+	}
+	for _, test := range tests {
+		for _, dst := range []draw.Image{ image.NewRGBA(image.Rect(0, 0, 8, 4)), image.NewNRGBA(image.Rect(0, 0, 8, 4)) } {
+			draw.Draw(dst, dst.Bounds(), image.NewUniform(back), image.Point{}, draw.Src)
+			if err := shape.PaintBlend(dst, image.Point{}, src, test.mode); err != nil { t.Fatal(err) }
+			for _, check := range []struct { x int; expected color.RGBA }{ { 1, test.full }, { 4, test.half }, { 6, back } } {
+				got := color.RGBAModel.Convert(dst.At(check.x, 2)).(color.RGBA)
+				if !rgbaClose(got, check.expected, 1) {
+					t.Fatalf("mode %d, %T, x = %d: expected %v, got %v", test.mode, dst, check.x, check.expected, got)
+				}
+			}
+		}
+	}
+
+	// over a transparent backdrop, the source is used as it is
+	dst := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	if err := shape.PaintBlend(dst, image.Point{}, src, BlendMultiply); err != nil { t.Fatal(err) }
+	if got := dst.RGBAAt(1, 2); got != src { t.Fatalf("expected %v over transparency, got %v", src, got) }
+	if err := shape.PaintBlend(dst, image.Point{}, src, BlendMode(99)); err == nil {
+		t.Fatal("expected error for an invalid blend mode")
+	}
+}
+
+func rgbaClose(a, b color.RGBA, tolerance int) bool {
+	return absInt(int(a.R) - int(b.R)) <= tolerance && absInt(int(a.G) - int(b.G)) <= tolerance &&
+		absInt(int(a.B) - int(b.B)) <= tolerance && absInt(int(a.A) - int(b.A)) <= tolerance
+}
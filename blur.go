@@ -0,0 +1,131 @@
+package sfntshape
+
+import "math"
+import "image"
+
+// Returns a copy of the mask blurred with an approximation of a gaussian
+// blur with the given standard deviation, in pixels. The blur is computed
+// with three successive box blurs on each axis, which closely matches a
+// gaussian kernel while taking constant time per pixel regardless of the
+// sigma.
+//
+// Pixels outside the mask are considered transparent. The returned mask's
+// Rect is the original one enlarged by at least ceil(3*sigma) pixels on
+// each side (enough to contain the whole blur, so no coverage is lost),
+// still in the same coordinates. A sigma <= 0 returns an unmodified copy
+// of the mask.
+func BlurMask(mask *image.Alpha, sigma float64) *image.Alpha {
+	if !(sigma > 0) || math.IsInf(sigma, 0) {
+		blurred := image.NewAlpha(mask.Rect)
+		copyMask(blurred, mask)
+		return blurred
+	}
+
+	radii := blurBoxRadii(sigma)
+	margin := blurMargin(sigma)
+	rect := mask.Rect.Inset(-margin)
+	width, height := rect.Dx(), rect.Dy()
+
+	// load the mask into a float buffer, blur it and quantize it back
+	values := make([]float32, width*height)
+	scratch := make([]float32, width*height)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		row := mask.Pix[mask.PixOffset(mask.Rect.Min.X, y) : ][ : mask.Rect.Dx()]
+		offset := (y - rect.Min.Y)*width + margin
+		for x, value := range row { values[offset + x] = float32(value) }
+	}
+	for _, radius := range radii {
+		boxBlurRows(scratch, values, width, height, radius)
+		boxBlurColumns(values, scratch, width, height, radius)
+	}
+
+	blurred := image.NewAlpha(rect)
+	for i, value := range values {
+		if value >= 255 {
+			blurred.Pix[i] = 255
+		} else if value > 0 {
+			blurred.Pix[i] = uint8(value + 0.5)
+		}
+	}
+	return blurred
+}
+
+// Returns the number of pixels a blur with the given sigma adds
+// on each side of a mask.
+func blurMargin(sigma float64) int {
+	radii := blurBoxRadii(sigma)
+	margin := int(math.Ceil(3*sigma))
+	if reach := radii[0] + radii[1] + radii[2]; reach > margin { margin = reach }
+	return margin
+}
+
+// Returns the radii of the three box blurs approximating a gaussian
+// blur with the given sigma. See "Fast Almost-Gaussian Filtering"
+// (Peter Kovesi, 2010).
+func blurBoxRadii(sigma float64) [3]int {
+	const passes = 3
+	ideal := math.Sqrt(12*sigma*sigma/passes + 1)
+	lower := int(math.Floor(ideal))
+	if lower % 2 == 0 { lower -= 1 }
+	upper := lower + 2
+	fl := float64(lower)
+	lowerCount := int(math.Round((12*sigma*sigma - passes*fl*fl - 4*passes*fl - 3*passes)/(-4*fl - 4)))
+
+	var radii [3]int
+	for i := range radii {
+		size := upper
+		if i < lowerCount { size = lower }
+		radii[i] = (size - 1)/2
+	}
+	return radii
+}
+
+// Writes to dst the src values averaged over horizontal windows of
+// 2*radius + 1 pixels, with values outside the rows considered zero.
+func boxBlurRows(dst, src []float32, width, height, radius int) {
+	scale := 1/float32(2*radius + 1)
+	for y := 0; y < height; y++ {
+		in, out := src[y*width : (y + 1)*width], dst[y*width : (y + 1)*width]
+		var sum float32
+		for x := 0; x < radius && x < width; x++ { sum += in[x] }
+		for x := range out {
+			if x + radius < width { sum += in[x + radius] }
+			out[x] = sum*scale
+			if x - radius >= 0 { sum -= in[x - radius] }
+		}
+	}
+}
+
+// Like boxBlurRows, but averaging over vertical windows.
+func boxBlurColumns(dst, src []float32, width, height, radius int) {
+	scale := 1/float32(2*radius + 1)
+	sums := make([]float32, width)
+	for y := 0; y < radius && y < height; y++ {
+		for x, value := range src[y*width : (y + 1)*width] { sums[x] += value }
+	}
+	for y := 0; y < height; y++ {
+		if y + radius < height {
+			for x, value := range src[(y + radius)*width : (y + radius + 1)*width] { sums[x] += value }
+		}
+		out := dst[y*width : (y + 1)*width]
+		for x, sum := range sums { out[x] = sum*scale }
+		if y - radius >= 0 {
+			for x, value := range src[(y - radius)*width : (y - radius + 1)*width] { sums[x] -= value }
+		}
+	}
+}
+
+// Rasterizes the shape and blurs it with [BlurMask](), translating the
+// result by the given offset. Useful for soft drop shadows, which can be
+// drawn before the shape itself.
+func (self *Shape) Shadow(sigma float64, offsetX, offsetY int) (*image.Alpha, error) {
+	mask, err := self.Rasterize()
+	if err != nil || mask == nil { return mask, err }
+	if sigma > 0 && !math.IsInf(sigma, 0) {
+		rect := mask.Rect.Inset(-blurMargin(sigma))
+		if err := checkMaskSize(rect.Dx(), rect.Dy()); err != nil { return nil, err }
+	}
+	shadow := BlurMask(mask, sigma)
+	shadow.Rect = shadow.Rect.Add(image.Pt(offsetX, offsetY))
+	return shadow, nil
+}
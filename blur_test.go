@@ -0,0 +1,54 @@
+package sfntshape
+
+import "math"
+import "image"
+import "testing"
+
+func TestBlurMask(t *testing.T) {
+	shape := testPolygon(30, 64)
+	mask, err := shape.RasterizeFract(10, 50)
+	if err != nil { t.Fatal(err) }
+	area := maskArea(mask)
+	for _, sigma := range []float64{ 0, 0.5, 1, 2.5, 4, 10 } {
+		blurred := BlurMask(mask, sigma)
+		margin := int(math.Ceil(3*sigma))
+		if !mask.Rect.Inset(-margin).In(blurred.Rect) {
+			t.Fatalf("sigma %v: Rect %v doesn't contain the expanded Rect", sigma, blurred.Rect)
+		}
+		if diff := math.Abs(maskArea(blurred) - area); diff > area*0.01 {
+			t.Fatalf("sigma %v: coverage not conserved (%.2f vs %.2f)", sigma, maskArea(blurred), area)
+		}
+		for _, edge := range []image.Point{ blurred.Rect.Min, blurred.Rect.Max.Sub(image.Pt(1, 1)) } {
+			if blurred.AlphaAt(edge.X, edge.Y).A != 0 { t.Fatalf("sigma %v: clipped blur", sigma) }
+		}
+		if sigma == 0 && maskArea(blurred) != area { t.Fatalf("zero sigma must copy the mask") }
+	}
+
+	// a single pixel spreads symmetrically, like a gaussian
+	dot := image.NewAlpha(image.Rect(5, 5, 6, 6))
+	dot.Pix[0] = 0xFF
+	blurred := BlurMask(dot, 1.5)
+	center := blurred.AlphaAt(5, 5).A
+	if center == 0 || blurred.AlphaAt(4, 5).A != blurred.AlphaAt(6, 5).A || blurred.AlphaAt(5, 4).A != blurred.AlphaAt(5, 6).A {
+		t.Fatalf("unexpected blurred dot")
+	}
+}
+
+func TestShadow(t *testing.T) {
+	shape := testPolygon(20, 32)
+	mask, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	shadow, err := shape.Shadow(3, 4, -2)
+	if err != nil { t.Fatal(err) }
+	expected := BlurMask(mask, 3)
+	if shadow.Rect != expected.Rect.Add(image.Pt(4, -2)) {
+		t.Fatalf("unexpected shadow Rect %v", shadow.Rect)
+	}
+	if shadow.AlphaAt(4, -2).A != expected.AlphaAt(0, 0).A { t.Fatalf("shadow not translated") }
+}
+
+func BenchmarkBlurMask(b *testing.B) {
+	mask := image.NewAlpha(image.Rect(0, 0, 256, 256))
+	for i := range mask.Pix { mask.Pix[i] = uint8(i*7) }
+	for i := 0; i < b.N; i++ { _ = BlurMask(mask, 4) }
+}
@@ -0,0 +1,50 @@
+package sfntshape
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Returns the bounds of the shape segments, like [sfnt.Segments.Bounds]()
+// (control points included, and the zero rectangle for empty shapes).
+// The bounds are cached and kept up to date by all the shape methods,
+// so this is O(1) and rasterizing big shapes doesn't iterate all the
+// segments an extra time. Modifying the segments returned by
+// [Shape.Segments]() directly is not detected; use [Shape.SetSegment]()
+// instead.
+func (self *Shape) Bounds() fixed.Rectangle26_6 { return self.bounds }
+
+// Updates the cached bounds for a segment about to be appended.
+func (self *Shape) expandBounds(segment sfnt.Segment) {
+	if len(self.segments) == 0 {
+		point := segment.Args[0]
+		self.bounds = fixed.Rectangle26_6{ Min: point, Max: point }
+	}
+	self.includeInBounds(segment)
+}
+
+// Expands the cached bounds to include the points of the segment.
+func (self *Shape) includeInBounds(segment sfnt.Segment) {
+	for i := 0; i < segmentArgsCount(segment.Op); i++ {
+		point := segment.Args[i]
+		if point.X < self.bounds.Min.X { self.bounds.Min.X = point.X }
+		if point.X > self.bounds.Max.X { self.bounds.Max.X = point.X }
+		if point.Y < self.bounds.Min.Y { self.bounds.Min.Y = point.Y }
+		if point.Y > self.bounds.Max.Y { self.bounds.Max.Y = point.Y }
+	}
+}
+
+// Recomputes the cached bounds from scratch, after modifications
+// that may have shrunk them.
+func (self *Shape) recomputeBounds() {
+	self.bounds = sfnt.Segments(self.segments).Bounds()
+}
+
+// Returns whether any point of the segment lies on the edges of the
+// cached bounds, in which case removing it may shrink them.
+func (self *Shape) touchesBounds(segment sfnt.Segment) bool {
+	for i := 0; i < segmentArgsCount(segment.Op); i++ {
+		point := segment.Args[i]
+		if point.X == self.bounds.Min.X || point.X == self.bounds.Max.X { return true }
+		if point.Y == self.bounds.Min.Y || point.Y == self.bounds.Max.Y { return true }
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package sfntshape
+
+import "math"
+import "math/rand"
+import "testing"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+import "golang.org/x/image/vector"
+
+func TestCachedBounds(t *testing.T) {
+	rng := rand.New(rand.NewSource(467))
+	coord := func() int { return rng.Intn(200) - 100 }
+	shape := New()
+	if shape.Bounds() != (fixed.Rectangle26_6{}) { t.Fatal("expected zero bounds for empty shapes") }
+	for step := 0; step < 5000; step++ {
+		var action string
+		switch n := rng.Intn(20); {
+		case n < 4:
+			action = "MoveTo"
+			shape.MoveTo(coord(), coord())
+		case n < 9:
+			action = "LineTo"
+			shape.LineTo(coord(), coord())
+		case n < 11:
+			action = "QuadTo"
+			shape.QuadTo(coord(), coord(), coord(), coord())
+		case n < 13:
+			action = "CubeTo"
+			shape.CubeTo(coord(), coord(), coord(), coord(), coord(), coord())
+		case n < 16:
+			action = "Pop"
+			shape.Pop()
+		case n < 17:
+			action = "PopSubpath"
+			shape.PopSubpath()
+		case n < 18:
+			action = "Transform"
+			shape.Transform(AffineRotation(rng.Float64()*math.Pi).Then(AffineScale(0.9, 0.9)))
+		case n < 19 && shape.Len() > 0:
+			action = "SetSegment"
+			index := rng.Intn(shape.Len())
+			segment := shape.segments[index]
+			segment.Args[0] = fixed.P(coord(), coord())
+			shape.SetSegment(index, segment)
+		default:
+			action = "DeleteSubpath"
+			if shape.NumSubpaths() > 0 { _ = shape.DeleteSubpath(0) }
+		}
+		if rng.Intn(500) == 0 {
+			action = "Reset"
+			shape.Reset()
+		}
+		if got, expected := shape.Bounds(), shape.Segments().Bounds(); got != expected {
+			t.Fatalf("step %d (%s): cached bounds %v, expected %v", step, action, got, expected)
+		}
+	}
+
+	hinted := bigTestShape(100)
+	hinted.Hint(1)
+	if hinted.Bounds() != hinted.Segments().Bounds() { t.Fatal("unexpected bounds after Hint") }
+	clone := hinted.Clone()
+	if clone.Bounds() != hinted.Bounds() || hinted.Freeze().Bounds() != hinted.Bounds() {
+		t.Fatal("expected copies to keep the bounds")
+	}
+}
+
+func BenchmarkRasterizeBounds(b *testing.B) {
+	shape := testPolygon(64, 20000)
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ { _, _ = shape.Rasterize() }
+	})
+	b.Run("recomputed", func(b *testing.B) {
+		rasterizer := vector.NewRasterizer(0, 0)
+		for i := 0; i < b.N; i++ { _, _ = Rasterize(shape.Segments(), rasterizer, 0, 0) }
+	})
+	b.Run("bounds-only", func(b *testing.B) {
+		var sink fixed.Rectangle26_6
+		for i := 0; i < b.N; i++ { sink = sfnt.Segments(shape.segments).Bounds() }
+		_ = sink
+	})
+}
@@ -0,0 +1,89 @@
+package sfntshape
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// A chainable wrapper over a [Shape], created with [Shape.Builder](),
+// so long shape definitions can be written as a single expression:
+//   shape.Builder().MoveTo(0, 0).LineTo(10, 0).LineTo(10, 10).Close()
+//
+// Builders only hold a pointer to the shape, so they are cheap to copy
+// and pass around by value, and each method simply forwards to the
+// shape's method with the same name (so the segments are appended to
+// the shape directly, applying its scale and [Shape.InvertY]() as
+// usual, and builder and shape calls can be freely interleaved).
+type Builder struct {
+	shape *Shape
+}
+
+// Returns a [Builder] for the shape.
+func (self *Shape) Builder() Builder { return Builder{ self } }
+
+// Returns the shape being built.
+func (self Builder) Shape() *Shape { return self.shape }
+
+// See [Shape.MoveTo]().
+func (self Builder) MoveTo(x, y int) Builder {
+	self.shape.MoveTo(x, y)
+	return self
+}
+
+// See [Shape.MoveToFract]().
+func (self Builder) MoveToFract(x, y Fract) Builder {
+	self.shape.MoveToFract(x, y)
+	return self
+}
+
+// See [Shape.LineTo]().
+func (self Builder) LineTo(x, y int) Builder {
+	self.shape.LineTo(x, y)
+	return self
+}
+
+// See [Shape.LineToFract]().
+func (self Builder) LineToFract(x, y Fract) Builder {
+	self.shape.LineToFract(x, y)
+	return self
+}
+
+// See [Shape.QuadTo]().
+func (self Builder) QuadTo(ctrlX, ctrlY, x, y int) Builder {
+	self.shape.QuadTo(ctrlX, ctrlY, x, y)
+	return self
+}
+
+// See [Shape.QuadToFract]().
+func (self Builder) QuadToFract(ctrlX, ctrlY, x, y Fract) Builder {
+	self.shape.QuadToFract(ctrlX, ctrlY, x, y)
+	return self
+}
+
+// See [Shape.CubeTo]().
+func (self Builder) CubeTo(cx1, cy1, cx2, cy2, x, y int) Builder {
+	self.shape.CubeTo(cx1, cy1, cx2, cy2, x, y)
+	return self
+}
+
+// See [Shape.CubeToFract]().
+func (self Builder) CubeToFract(cx1, cy1, cx2, cy2, x, y Fract) Builder {
+	self.shape.CubeToFract(cx1, cy1, cx2, cy2, x, y)
+	return self
+}
+
+// Closes the current subpath with a straight line back to its starting
+// point (the last MoveTo, or (0, 0) if there's none), unless the current
+// position is already there.
+func (self Builder) Close() Builder {
+	segments := self.shape.segments
+	var start, userStart fixed.Point26_6
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i].Op == sfnt.SegmentOpMoveTo {
+			start, userStart = segments[i].Args[0], self.shape.positions[i]
+			break
+		}
+	}
+	if len(segments) > 0 && self.shape.pen() != start {
+		self.shape.appendSegment(userStart, sfnt.Segment{ Op: sfnt.SegmentOpLineTo, Args: [3]fixed.Point26_6{ start } })
+	}
+	return self
+}
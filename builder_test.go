@@ -0,0 +1,45 @@
+package sfntshape
+
+import "reflect"
+import "testing"
+
+func TestBuilder(t *testing.T) {
+	for _, invertY := range []bool{ false, true } {
+		imperative := New()
+		imperative.InvertY(invertY)
+		imperative.SetScale(1.5)
+		imperative.MoveTo(0, 0)
+		imperative.LineTo(10, 0)
+		imperative.QuadToFract(15*64, 5*64, 10*64, 10*64)
+		imperative.CubeTo(8, 12, 2, 12, 0, 10)
+		imperative.LineTo(0, 0)
+		imperative.MoveToFract(200, 300)
+		imperative.LineTo(4, 5)
+		imperative.LineToFract(100, 300)
+		imperative.LineToFract(200, 300)
+
+		fluent := New()
+		fluent.InvertY(invertY)
+		fluent.SetScale(1.5)
+		builder := fluent.Builder().
+			MoveTo(0, 0).LineTo(10, 0).
+			QuadToFract(15*64, 5*64, 10*64, 10*64).
+			CubeTo(8, 12, 2, 12, 0, 10).Close().
+			MoveToFract(200, 300).LineTo(4, 5).LineToFract(100, 300).Close().
+			Close() // already closed, no-op
+		if builder.Shape() != &fluent { t.Fatal("expected the builder to reference the shape") }
+		if !reflect.DeepEqual(imperative.Segments(), fluent.Segments()) {
+			t.Fatalf("invertY %t: fluent segments differ:\n%v\n%v", invertY, &imperative, &fluent)
+		}
+	}
+
+	// no copies: the segments are appended to the shape directly
+	shape := New()
+	builder := shape.Builder().MoveTo(1, 1)
+	shape.LineTo(5, 1)
+	builder.LineTo(5, 5).Close()
+	if len(shape.Segments()) != 4 { t.Fatalf("expected 4 segments, got %d", len(shape.Segments())) }
+	empty := New()
+	empty.Builder().Close()
+	if len(empty.Segments()) != 0 { t.Fatal("expected closing an empty shape to do nothing") }
+}
@@ -0,0 +1,148 @@
+package sfntshape
+
+import "sync"
+import "image"
+import "container/list"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/vector"
+
+// A cache of rasterized masks, keyed by shape content and fractional
+// offset, with LRU eviction based on a byte budget. Useful when the same
+// shapes are rasterized repeatedly (e.g., each frame). Safe for concurrent
+// use. Create with [NewMaskCache]().
+//
+// The cache keeps its own copy of the segments of each cached shape,
+// so modifying or reusing a shape after a [MaskCache.Get]() call never
+// affects the cached results.
+type MaskCache struct {
+	mutex sync.Mutex
+	budget int
+	bytes int
+	entries map[maskCacheKey]*list.Element
+	lru *list.List // front is most recently used
+	hits, misses uint64
+}
+
+type maskCacheKey struct {
+	hash uint64
+	fractX, fractY Fract
+}
+
+type maskCacheEntry struct {
+	key maskCacheKey
+	segments []sfnt.Segment
+	mask *image.Alpha
+}
+
+// Creates a new mask cache with the given budget in bytes (based on the
+// length of the cached masks' Pix). A budget <= 0 means no limit.
+func NewMaskCache(budget int) *MaskCache {
+	return &MaskCache {
+		budget: budget,
+		entries: make(map[maskCacheKey]*list.Element),
+		lru: list.New(),
+	}
+}
+
+// Returns the mask for the given shape and offset, like
+// [Shape.RasterizeFract]() would, rasterizing it only when it's not
+// already cached. Only the fractional part of the offsets is relevant.
+// The returned mask is shared with the cache and other callers, so it
+// must not be modified. The shape's rasterizer is not used, so this can
+// be called concurrently even for the same shape.
+//
+// Masks bigger than the whole budget are returned but not cached.
+// Errors and empty shapes are never cached either.
+func (self *MaskCache) Get(shape *Shape, offsetX, offsetY Fract) (*image.Alpha, error) {
+	if shape.err != nil { return nil, shape.err }
+	key := maskCacheKey{ shape.Hash(), fixedFract(offsetX), fixedFract(offsetY) }
+	self.mutex.Lock()
+	if element, found := self.entries[key]; found {
+		entry := element.Value.(*maskCacheEntry)
+		if sameSegments(entry.segments, shape.segments) {
+			self.lru.MoveToFront(element)
+			self.hits += 1
+			self.mutex.Unlock()
+			return entry.mask, nil
+		}
+	}
+	self.misses += 1
+	self.mutex.Unlock()
+
+	// rasterize without holding the lock
+	rasterizer := vectorRasterizers.Get().(*vector.Rasterizer)
+	mask, err := Rasterize(shape.Segments(), rasterizer, key.fractX, key.fractY)
+	vectorRasterizers.Put(rasterizer)
+	if err != nil || mask == nil { return mask, err }
+
+	size := len(mask.Pix)
+	if self.budget > 0 && size > self.budget { return mask, nil }
+	entry := &maskCacheEntry{
+		key: key,
+		segments: append([]sfnt.Segment(nil), shape.segments...),
+		mask: mask,
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if element, found := self.entries[key]; found { // replace (collision or concurrent miss)
+		self.removeElement(element)
+	}
+	self.entries[key] = self.lru.PushFront(entry)
+	self.bytes += size
+	for self.budget > 0 && self.bytes > self.budget {
+		self.removeElement(self.lru.Back())
+	}
+	return mask, nil
+}
+
+func (self *MaskCache) removeElement(element *list.Element) {
+	entry := self.lru.Remove(element).(*maskCacheEntry)
+	delete(self.entries, entry.key)
+	self.bytes -= len(entry.mask.Pix)
+}
+
+// Returns the number of cached masks.
+func (self *MaskCache) Len() int {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.lru.Len()
+}
+
+// Returns the total size in bytes of the cached masks.
+func (self *MaskCache) Bytes() int {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.bytes
+}
+
+// Returns the number of [MaskCache.Get]() calls that were served
+// from the cache and the number of calls that weren't.
+func (self *MaskCache) Stats() (hits, misses uint64) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.hits, self.misses
+}
+
+// Removes all the cached masks. Stats are preserved.
+func (self *MaskCache) Clear() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.entries = make(map[maskCacheKey]*list.Element)
+	self.lru.Init()
+	self.bytes = 0
+}
+
+// Returns whether the two segment lists are equal, ignoring
+// the unused arguments of each segment.
+func sameSegments(a, b []sfnt.Segment) bool {
+	if len(a) != len(b) { return false }
+	for i := range a {
+		if a[i].Op != b[i].Op { return false }
+		for j := 0; j < segmentArgsCount(a[i].Op); j++ {
+			if a[i].Args[j] != b[i].Args[j] { return false }
+		}
+	}
+	return true
+}
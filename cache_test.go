@@ -0,0 +1,91 @@
+package sfntshape
+
+import "sync"
+import "bytes"
+import "testing"
+
+func TestMaskCache(t *testing.T) {
+	cache := NewMaskCache(0)
+	shape := testSquare(10)
+	first, err := cache.Get(&shape, 0, 0)
+	if err != nil { t.Fatal(err) }
+	second, err := cache.Get(&shape, 64*3, 0) // only the fractional part matters
+	if err != nil { t.Fatal(err) }
+	if first != second { t.Fatal("expected cache hit") }
+	shifted, err := cache.Get(&shape, 32, 0)
+	if err != nil { t.Fatal(err) }
+	if shifted == first { t.Fatal("expected different offsets to be cached separately") }
+	if hits, misses := cache.Stats(); hits != 1 || misses != 2 {
+		t.Fatalf("expected 1 hit and 2 misses, got %d and %d", hits, misses)
+	}
+	expected, _ := shape.RasterizeFract(32, 0)
+	if shifted.Rect != expected.Rect || !bytes.Equal(shifted.Pix, expected.Pix) {
+		t.Fatal("expected cached mask to match RasterizeFract")
+	}
+
+	// mutating the shape must not poison the cache
+	shape.Reset()
+	shape.MoveTo(0, 0)
+	shape.LineTo(5, 0)
+	shape.LineTo(0, 5)
+	shape.LineTo(0, 0)
+	triangle, err := cache.Get(&shape, 0, 0)
+	if err != nil { t.Fatal(err) }
+	if triangle == first || triangle.Rect == first.Rect { t.Fatal("expected a new mask for the mutated shape") }
+	shape = testSquare(10)
+	again, err := cache.Get(&shape, 0, 0)
+	if err != nil { t.Fatal(err) }
+	if again != first { t.Fatal("expected original mask to still be cached") }
+
+	cache.Clear()
+	if cache.Len() != 0 || cache.Bytes() != 0 { t.Fatal("expected empty cache after Clear") }
+}
+
+func TestMaskCacheEviction(t *testing.T) {
+	a, b, c := testSquare(10), testSquare(11), testSquare(12)
+	maskA, _ := a.Rasterize()
+	maskB, _ := b.Rasterize()
+	maskC, _ := c.Rasterize()
+	cache := NewMaskCache(len(maskA.Pix) + len(maskB.Pix) + len(maskC.Pix) - 1)
+
+	get := func(shape *Shape) {
+		if _, err := cache.Get(shape, 0, 0); err != nil { t.Fatal(err) }
+	}
+	get(&a)
+	get(&b)
+	get(&a) // a becomes the most recently used
+	get(&c) // evicts b
+	if cache.Len() != 2 || cache.Bytes() != len(maskA.Pix) + len(maskC.Pix) {
+		t.Fatalf("unexpected cache state: %d masks, %d bytes", cache.Len(), cache.Bytes())
+	}
+	_, missesBefore := cache.Stats()
+	get(&a)
+	get(&c)
+	if _, misses := cache.Stats(); misses != missesBefore { t.Fatal("expected a and c to be cached") }
+	get(&b)
+	if _, misses := cache.Stats(); misses != missesBefore + 1 { t.Fatal("expected b to be evicted") }
+
+	// masks bigger than the budget aren't cached
+	huge := testSquare(100)
+	get(&huge)
+	if cache.Bytes() > len(maskA.Pix) + len(maskB.Pix) + len(maskC.Pix) { t.Fatal("budget exceeded") }
+}
+
+func TestMaskCacheConcurrent(t *testing.T) {
+	cache := NewMaskCache(4096)
+	var group sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		group.Add(1)
+		go func(worker int) {
+			defer group.Done()
+			for i := 0; i < 200; i++ {
+				shape := testSquare(4 + (worker + i) % 9)
+				mask, err := cache.Get(&shape, Fract(i % 4)*16, 0)
+				if err != nil || mask == nil { t.Error("unexpected cache failure"); return }
+				if mask.Rect.Dx() < 4 { t.Error("unexpected mask size"); return }
+			}
+		}(worker)
+	}
+	group.Wait()
+	if cache.Bytes() > 4096 { t.Fatal("budget exceeded") }
+}
@@ -0,0 +1,165 @@
+package sfntshape
+
+import "image"
+import "context"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Number of outline segments processed between context checks.
+const taskSegmentsInterval = 4096
+
+// Approximate number of pixels accumulated between context checks.
+const taskPixelsInterval = 65536
+
+// Like [RasterizeWithOptions](), but checking the given context while the
+// outline segments are processed and while the coverage rows are being
+// accumulated, returning ctx.Err() promptly if it's cancelled. Useful to
+// bail out of rasterizing huge user-supplied shapes. See also the
+// Progress callback of [RasterizeOptions].
+//
+// Shapes bigger than 512x512 pixels are rasterized with an internal
+// rasterizer that reproduces [vector.Rasterizer] results exactly, as
+// the vector rasterizer can only accumulate the whole mask at once.
+// Custom rasterizers don't have that problem, but their Draw method
+// can't be interrupted either, so for them the context is only checked
+// while processing outline segments.
+//
+// Cancellation doesn't corrupt the rasterizer, as it's reset at the
+// start of every rasterization anyway.
+func RasterizeCtx(ctx context.Context, outline sfnt.Segments, rasterizer Rasterizer, opts RasterizeOptions) (*image.Alpha, error) {
+	return boundedRasterizeCtx(ctx, outline, outline.Bounds(), rasterizer, opts)
+}
+
+// Like [RasterizeCtx](), but with the outline bounds already known
+// (e.g., cached by a shape). All the option-based rasterizations go
+// through here.
+func boundedRasterizeCtx(ctx context.Context, outline sfnt.Segments, bounds fixed.Rectangle26_6, rasterizer Rasterizer, opts RasterizeOptions) (*image.Alpha, error) {
+	if err := opts.validate(); err != nil { return nil, err }
+	if err := validateOps(outline); err != nil { return nil, err }
+	if err := ctx.Err(); err != nil { return nil, err }
+	var carry image.Point
+	opts.OffsetX, carry.X = quantizeOffset(opts.OffsetX, opts.QuantizeX)
+	opts.OffsetY, carry.Y = quantizeOffset(opts.OffsetY, opts.QuantizeY)
+	opts.Viewport = opts.Viewport.Sub(carry)
+	if opts.FlipY {
+		outline = flipSegmentsY(make([]sfnt.Segment, 0, len(outline)), outline, bounds)
+		bounds = flipBoundsY(bounds)
+	}
+	task := &rasterTask{ ctx: ctx, progress: opts.Progress }
+	var mask *image.Alpha
+	var err error
+	if opts.Padding > 0 && opts.Viewport.Empty() {
+		// rasterize directly into the padded mask
+		bounds, err = padBounds(bounds, opts.Padding)
+		if err != nil { return nil, err }
+	}
+	if !hasDrawingOps(outline) {
+		err = nothingToDraw()
+	} else if !opts.Viewport.Empty() {
+		mask, err = viewportRasterize(task, outline, bounds, rasterizer, opts)
+	} else if !opts.isDefault() {
+		mask, err = accumulatorRasterize(task, outline, bounds, opts)
+	} else if !task.isTracked() {
+		mask, err = boundedRasterize(outline, bounds, rasterizer, opts.OffsetX, opts.OffsetY)
+	} else if isVectorRasterizer(rasterizer) && usesFloatingPoint(bounds, opts) {
+		mask, err = accumulatorRasterize(task, outline, bounds, opts)
+	} else {
+		mask, err = taskRasterize(task, outline, bounds, rasterizer, opts.OffsetX, opts.OffsetY)
+	}
+	if err != nil || mask == nil { return mask, err }
+	mask.Rect = mask.Rect.Add(carry)
+
+	if opts.Gamma != 0 && opts.Gamma != 1 {
+		applyGamma(mask.Pix, opts.Gamma)
+	}
+	return mask, nil
+}
+
+// Like [Shape.RasterizeOpts](), but stopping early if the context is
+// cancelled. See [RasterizeCtx]() for details.
+func (self *Shape) RasterizeCtx(ctx context.Context, opts RasterizeOptions) (*image.Alpha, error) {
+	if self.err != nil { return nil, self.err }
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	if self.deterministic { opts.Deterministic = true }
+	return boundedRasterizeCtx(ctx, self.segments, self.bounds, rasterizer, opts)
+}
+
+// Returns whether rasterizing an outline with the given bounds with
+// [vector.Rasterizer] and the given options would use floating point math.
+func usesFloatingPoint(bounds fixed.Rectangle26_6, opts RasterizeOptions) bool {
+	width, height, _, _, _ := figureOutBounds(bounds, opts.OffsetX, opts.OffsetY)
+	return width > vectorFloatingPointThreshold || height > vectorFloatingPointThreshold
+}
+
+// Like etxtLikeRasterize, but checking the task while processing
+// the outline segments.
+func taskRasterize(task *rasterTask, outline sfnt.Segments, bounds fixed.Rectangle26_6, rasterizer Rasterizer, originX, originY Fract) (*image.Alpha, error) {
+	width, height, normOffsetX, normOffsetY, rectOffset := figureOutBounds(bounds, originX, originY)
+	if err := checkMaskSize(width, height); err != nil { return nil, err }
+	task.total = len(outline) + height
+	resetRasterizer(rasterizer, width, height)
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	err := processOutlineTask(task, rasterizer, outline, normOffsetX, normOffsetY)
+	if err != nil { return nil, err }
+	rasterizer.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	if err := task.advance(height); err != nil { return nil, err }
+	mask.Rect = mask.Rect.Add(rectOffset)
+	return mask, nil
+}
+
+// Tracks the cancellation and progress of a rasterization.
+type rasterTask struct {
+	ctx context.Context
+	progress func(done, total int)
+	done, total int
+}
+
+// Returns whether the task can be cancelled or reports progress.
+func (self *rasterTask) isTracked() bool {
+	return self.ctx.Done() != nil || self.progress != nil
+}
+
+// Adds the given amount of work to the progress and reports it,
+// unless the context has been cancelled, in which case its error
+// is returned instead.
+func (self *rasterTask) advance(work int) error {
+	if err := self.ctx.Err(); err != nil { return err }
+	self.done += work
+	if self.progress != nil { self.progress(self.done, self.total) }
+	return nil
+}
+
+// Like processOutline, but advancing the task every few segments.
+func processOutlineTask(task *rasterTask, processor pathProcessor, outline sfnt.Segments, offsetX, offsetY Fract) error {
+	for len(outline) > 0 {
+		n := len(outline)
+		if n > taskSegmentsInterval { n = taskSegmentsInterval }
+		processOutline(processor, outline[ : n], offsetX, offsetY)
+		outline = outline[n : ]
+		if err := task.advance(n); err != nil { return err }
+	}
+	return nil
+}
+
+// Accumulates the rows of acc into dst in chunks, advancing the task
+// after each chunk. The accumulation of each chunk is delegated to the
+// given function, which receives the rows to accumulate, the part of dst
+// where the results must be written and the value to start from.
+func accumulateRowsTask(task *rasterTask, acc *accumulator, dst []uint8, accumulate func(*accumulator, []uint8, float32)) error {
+	if acc.width == 0 { return task.advance(acc.height) }
+	chunk := (taskPixelsInterval/acc.width) &^ 3 // keep SIMD groups aligned
+	if chunk < 4 { chunk = 4 }
+
+	var from float32
+	for minY := 0; minY < acc.height; minY += chunk {
+		maxY := minY + chunk
+		if maxY > acc.height { maxY = acc.height }
+		rows := acc.Rows(minY, maxY)
+		accumulate(rows, dst[minY*acc.width : maxY*acc.width], from)
+		from = rows.buffer[len(rows.buffer) - 1]
+		if err := task.advance(maxY - minY); err != nil { return err }
+	}
+	return nil
+}
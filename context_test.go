@@ -0,0 +1,97 @@
+package sfntshape
+
+import "bytes"
+import "errors"
+import "context"
+import "testing"
+
+import "golang.org/x/image/vector"
+
+func TestRasterizeCtx(t *testing.T) {
+	for _, radius := range []float64{ 100, 600 } {
+		shape := testPolygon(radius, 999)
+		for _, opts := range []RasterizeOptions{
+			{}, { OffsetX: 13, OffsetY: 40 }, { FillRule: FillEvenOdd },
+			{ Supersample: 2 }, { HardEdges: true }, { Gamma: 2, Padding: 3 },
+		} {
+			expected, err := shape.RasterizeOpts(opts)
+			if err != nil { t.Fatal(err) }
+
+			var calls, lastDone, lastTotal int
+			opts.Progress = func(done, total int) {
+				if done <= lastDone { t.Fatalf("progress not increasing: %d after %d", done, lastDone) }
+				calls, lastDone, lastTotal = calls + 1, done, total
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			mask, err := shape.RasterizeCtx(ctx, opts)
+			cancel()
+			if err != nil { t.Fatal(err) }
+			if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+				t.Fatalf("radius %v, opts %+v: RasterizeCtx differs from RasterizeOpts", radius, opts)
+			}
+			if calls == 0 || lastDone != lastTotal {
+				t.Fatalf("radius %v: unexpected final progress %d/%d", radius, lastDone, lastTotal)
+			}
+		}
+	}
+
+	// already cancelled context
+	shape := testPolygon(100, 99)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := shape.RasterizeCtx(ctx, RasterizeOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRasterizeCtxCancel(t *testing.T) {
+	shape := testPolygon(1500, 100000)
+	segments := len(shape.Segments())
+	vecRast := vector.NewRasterizer(0, 0)
+	custom := &recordingRasterizer{ Rasterizer: vector.NewRasterizer(0, 0) }
+	tests := []struct { rasterizer Rasterizer; cancelAt int }{
+		{ vecRast, segments/2 }, // while processing segments
+		{ vecRast, segments + 1 }, // while accumulating rows
+		{ custom, segments/2 }, // custom rasterizers can't be cancelled on Draw
+	}
+	for _, test := range tests {
+		shape.SetRasterizer(test.rasterizer)
+		ctx, cancel := context.WithCancel(context.Background())
+		var cancelled bool
+		var doneAtCancel int
+		opts := RasterizeOptions{ Progress: func(done, total int) {
+			if cancelled { t.Fatalf("progress reported after cancellation") }
+			if done >= test.cancelAt {
+				cancelled, doneAtCancel = true, done
+				cancel()
+			}
+		}}
+		mask, err := shape.RasterizeCtx(ctx, opts)
+		if !errors.Is(err, context.Canceled) || mask != nil {
+			t.Fatalf("cancelAt %d: expected context.Canceled, got %v", test.cancelAt, err)
+		}
+		if test.cancelAt < segments && doneAtCancel >= segments {
+			t.Fatalf("cancellation too late (%d/%d segments)", doneAtCancel, segments)
+		}
+	}
+
+	// the rasterizers must still work after cancellations
+	small := testPolygon(50, 77)
+	expected, err := Rasterize(small.Segments(), vector.NewRasterizer(0, 0), 0, 0)
+	if err != nil { t.Fatal(err) }
+	for _, rasterizer := range []Rasterizer{ vecRast, custom } {
+		small.SetRasterizer(rasterizer)
+		mask, err := small.Rasterize()
+		if err != nil { t.Fatal(err) }
+		if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+			t.Fatalf("rasterizer corrupted after cancellation")
+		}
+	}
+}
+
+func BenchmarkRasterizeCtx(b *testing.B) {
+	shape := testPolygon(600, 999)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < b.N; i++ { _, _ = shape.RasterizeCtx(ctx, RasterizeOptions{}) }
+}
@@ -0,0 +1,125 @@
+package sfntshape
+
+import "math"
+import "testing"
+
+func TestCoverage(t *testing.T) {
+	// above 512px, vector uses floating point math and matches exactly
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.CubeTo(300, 700, 400, -100, 600, 600)
+	shape.QuadTo(100, 500, 0, 0)
+	mask, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	cov, bounds, err := shape.Coverage()
+	if err != nil { t.Fatal(err) }
+	if bounds != mask.Rect || len(cov) != len(mask.Pix) {
+		t.Fatalf("expected bounds %v, got %v", mask.Rect, bounds)
+	}
+	for i, value := range cov {
+		if value < 0 || value > 1 { t.Fatalf("coverage out of range: %f", value) }
+		if quantizeCoverage(value) != mask.Pix[i] {
+			t.Fatalf("pixel %d: coverage %f doesn't match mask value %d", i, value, mask.Pix[i])
+		}
+	}
+
+	// small shapes use fixed point math on vector, so allow tiny differences
+	shape.Reset()
+	shape.MoveTo(0, 0)
+	shape.LineTo(30, 0)
+	shape.LineTo(0, 7) // diagonal edge with non-trivial coverage
+	shape.LineTo(0, 0)
+	mask, err = shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	cov, bounds, err = shape.Coverage()
+	if err != nil { t.Fatal(err) }
+	if bounds != mask.Rect { t.Fatalf("expected bounds %v, got %v", mask.Rect, bounds) }
+	extraPrecision := false
+	for i, value := range cov {
+		delta := int(math.Round(float64(value)*255)) - int(mask.Pix[i])
+		if delta < -1 || delta > 1 {
+			t.Fatalf("pixel %d: coverage %f too far from mask value %d", i, value, mask.Pix[i])
+		}
+		scaled := float64(value)*255
+		if math.Abs(scaled - math.Round(scaled)) > 0.01 { extraPrecision = true }
+	}
+	if !extraPrecision { t.Fatal("expected some coverage values not representable in 8 bits") }
+
+	shape.Reset()
+	cov, bounds, err = shape.Coverage()
+	if cov != nil || !bounds.Empty() || err != nil { t.Fatal("expected no coverage for an empty shape") }
+}
+
+func TestCoverageArea(t *testing.T) {
+	for _, radius := range []float64{ 5, 33.3, 150, 800 } {
+		shape := testPolygon(radius, 90)
+		area, err := shape.CoverageArea()
+		if err != nil { t.Fatal(err) }
+
+		// analytic area of the polygon (shoelace formula)
+		var expected float64
+		points := flattenSegments(shape.Segments(), flattenTolerance)[0]
+		for i, a := range points {
+			b := points[(i + 1) % len(points)]
+			expected += (a.X*b.Y - b.X*a.Y)/2
+		}
+		if expected < 0 { expected = -expected }
+		if math.Abs(area - expected) > expected*0.001 {
+			t.Fatalf("radius %v: expected area %.3f, got %.3f", radius, expected, area)
+		}
+	}
+
+	empty := New()
+	empty.MoveTo(4, 4)
+	if area, err := empty.CoverageArea(); area != 0 || err != nil {
+		t.Fatalf("expected 0 area for empty shape, got %v (%v)", area, err)
+	}
+}
+
+func TestCoverageAt(t *testing.T) {
+	// above 512px, so vector uses floating point and rasterizes exactly
+	shape := testPolygon(300.3, 7)
+	shape.MoveTo(-80, -80) // a hole, so there are interior edges too
+	shape.LineTo(-80, 80)
+	shape.LineTo(80, 80)
+	shape.LineTo(80, -80)
+	shape.LineTo(-80, -80)
+	mask, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+
+	var edges, interior, exterior int
+	for y := mask.Rect.Min.Y - 1; y <= mask.Rect.Max.Y; y += 3 {
+		for x := mask.Rect.Min.X - 1; x <= mask.Rect.Max.X; x += 5 {
+			expected := int(mask.AlphaAt(x, y).A)
+			coverage := shape.CoverageAt(float64(x) + 0.5, float64(y) + 0.5, 32)
+			if coverage < 0 || coverage > 1 { t.Fatalf("coverage out of range: %f", coverage) }
+			got := int(math.Round(coverage*255))
+			if absInt(got - expected) > 3 {
+				t.Fatalf("pixel (%d, %d): expected coverage ~%d, got %d", x, y, expected, got)
+			}
+			switch expected {
+			case 0   : exterior += 1
+			case 255 : interior += 1
+			default  : edges += 1
+			}
+		}
+	}
+	if edges == 0 || interior == 0 || exterior == 0 {
+		t.Fatalf("expected edge, interior and exterior pixels, got %d, %d, %d", edges, interior, exterior)
+	}
+
+	// a single sample is the same as Contains
+	for _, point := range [][2]float64{ { 0, 0 }, { 20, 3 }, { 39, 1 }, { 50, 50 } } {
+		contains := shape.Contains(point[0], point[1])
+		if (shape.CoverageAt(point[0], point[1], 0) == 1) != contains {
+			t.Fatalf("CoverageAt(%v, 0) inconsistent with Contains", point)
+		}
+	}
+
+	// shapes with recorded errors have no coverage
+	if shape.CoverageAt(150, 0, 4) != 1 { t.Fatal("expected full coverage inside the ring") }
+	shape.LineTo(1 << 30, 0)
+	if shape.Err() == nil || shape.CoverageAt(150, 0, 4) != 0 {
+		t.Fatal("expected no coverage after an overflow")
+	}
+}
@@ -0,0 +1,98 @@
+package sfntshape
+
+import "image"
+
+// Dithering methods for [DitherMask]().
+type DitherMethod uint8
+const (
+	// Ordered dithering with a 4x4 Bayer matrix. Fast, deterministic and
+	// stable under animation (pixels only change where the coverage
+	// changes), but with a visible regular pattern.
+	DitherBayer4 DitherMethod = iota
+
+	// Like DitherBayer4, but with an 8x8 matrix, for finer gradations
+	// with fewer levels.
+	DitherBayer8
+
+	// Floyd-Steinberg error diffusion. Less regular patterns, but
+	// changes anywhere in the mask can affect the rest of it.
+	DitherFloydSteinberg
+)
+
+// 4x4 Bayer matrix for ordered dithering, with thresholds in [0, 16).
+var bayerMatrix = [4][4]uint8{
+	{  0,  8,  2, 10 },
+	{ 12,  4, 14,  6 },
+	{  3, 11,  1,  9 },
+	{ 15,  7, 13,  5 },
+}
+
+// 8x8 Bayer matrix for ordered dithering, with thresholds in [0, 64).
+var bayerMatrix8 = [8][8]uint8{
+	{  0, 32,  8, 40,  2, 34, 10, 42 },
+	{ 48, 16, 56, 24, 50, 18, 58, 26 },
+	{ 12, 44,  4, 36, 14, 46,  6, 38 },
+	{ 60, 28, 52, 20, 62, 30, 54, 22 },
+	{  3, 35, 11, 43,  1, 33,  9, 41 },
+	{ 51, 19, 59, 27, 49, 17, 57, 25 },
+	{ 15, 47,  7, 39, 13, 45,  5, 37 },
+	{ 63, 31, 55, 23, 61, 29, 53, 21 },
+}
+
+// Returns a copy of the mask with the coverage quantized to the given
+// number of evenly spaced levels (e.g., 32 for the 5 bits of the red
+// and blue channels of RGB565), dithered with the given method so the
+// average coverage of each area is preserved and soft gradients don't
+// band. Levels are clamped to [2, 256]; 256 levels result in an exact
+// copy. Ordered dithering matrices are aligned to the mask's coordinate
+// space, so masks dithered separately tile seamlessly.
+func DitherMask(mask *image.Alpha, levels int, method DitherMethod) *image.Alpha {
+	if levels < 2 { levels = 2 }
+	dithered := image.NewAlpha(mask.Rect)
+	copyMask(dithered, mask)
+	if levels >= 256 { return dithered }
+
+	steps := float64(levels - 1)
+	quantize := func(level float64) uint8 { return uint8(level*255/steps + 0.5) }
+	width := mask.Rect.Dx()
+	if method == DitherFloydSteinberg {
+		errs := make([]float64, 2*(width + 2)) // current and next rows, padded
+		for y := 0; y < mask.Rect.Dy(); y++ {
+			current, next := errs[ : width + 2], errs[width + 2 : ]
+			row := dithered.Pix[y*dithered.Stride : ][ : width]
+			for x, value := range row {
+				target := float64(value) + current[x + 1]
+				level := float64(int(target*steps/255 + 0.5))
+				if level < 0 { level = 0 } else if level > steps { level = steps }
+				row[x] = quantize(level)
+				diff := target - float64(row[x])
+				current[x + 2] += diff*7/16
+				next[x] += diff*3/16
+				next[x + 1] += diff*5/16
+				next[x + 2] += diff*1/16
+			}
+			copy(current, next)
+			for i := range next { next[i] = 0 }
+		}
+		return dithered
+	}
+
+	for y := 0; y < mask.Rect.Dy(); y++ {
+		row := dithered.Pix[y*dithered.Stride : ][ : width]
+		my := mask.Rect.Min.Y + y
+		for x, value := range row {
+			mx := mask.Rect.Min.X + x
+			var threshold float64
+			if method == DitherBayer8 {
+				threshold = (float64(bayerMatrix8[my & 7][mx & 7]) + 0.5)/64
+			} else {
+				threshold = (float64(bayerMatrix[my & 3][mx & 3]) + 0.5)/16
+			}
+			level := float64(value)*steps/255
+			base := float64(int(level))
+			if level - base > threshold { base += 1 }
+			row[x] = quantize(base)
+		}
+	}
+	return dithered
+}
@@ -0,0 +1,46 @@
+package sfntshape
+
+import "bytes"
+import "image"
+import "testing"
+
+func TestDitherMask(t *testing.T) {
+	gray := image.NewAlpha(image.Rect(-5, 3, 59, 67))
+	for i := range gray.Pix { gray.Pix[i] = 128 }
+	mean := func(mask *image.Alpha) float64 {
+		sum := 0
+		for _, value := range mask.Pix { sum += int(value) }
+		return float64(sum)/float64(len(mask.Pix))/255
+	}
+
+	for _, method := range []DitherMethod{ DitherBayer4, DitherBayer8, DitherFloydSteinberg } {
+		for _, levels := range []int{ 2, 3, 5, 32 } {
+			dithered := DitherMask(gray, levels, method)
+			if dithered.Rect != gray.Rect { t.Fatalf("method %d: unexpected bounds %v", method, dithered.Rect) }
+			allowed := make(map[uint8]bool)
+			for level := 0; level < levels; level++ { allowed[uint8(float64(level)*255/float64(levels - 1) + 0.5)] = true }
+			for i, value := range dithered.Pix {
+				if !allowed[value] { t.Fatalf("method %d, %d levels: pixel %d has value %d", method, levels, i, value) }
+			}
+			if delta := mean(dithered) - mean(gray); delta < -0.01 || delta > 0.01 {
+				t.Fatalf("method %d, %d levels: mean coverage changed by %f", method, levels, delta)
+			}
+			if !bytes.Equal(dithered.Pix, DitherMask(gray, levels, method).Pix) {
+				t.Fatalf("method %d: dithering is not deterministic", method)
+			}
+		}
+	}
+
+	// ordered dithering with 2 levels follows the matrix
+	dithered := DitherMask(gray, 2, DitherBayer4)
+	for y := gray.Rect.Min.Y; y < gray.Rect.Min.Y + 4; y++ {
+		for x := gray.Rect.Min.X; x < gray.Rect.Min.X + 4; x++ {
+			expected := uint8(0)
+			if bayerMatrix[y & 3][x & 3] < 8 { expected = 255 } // 128/255 is just above 50%
+			if got := dithered.AlphaAt(x, y).A; got != expected {
+				t.Fatalf("pixel (%d, %d): expected %d, got %d", x, y, expected, got)
+			}
+		}
+	}
+	if !bytes.Equal(DitherMask(gray, 256, DitherBayer8).Pix, gray.Pix) { t.Fatal("expected exact copy with 256 levels") }
+}
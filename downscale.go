@@ -0,0 +1,74 @@
+package sfntshape
+
+import "image"
+
+// Returns a copy of the mask downscaled by the given integer factor,
+// where each pixel is the exact average of the factor x factor pixels
+// it covers in the original mask (rounded to the nearest value, ties
+// up). Unlike nearest or bilinear scaling, this preserves the total
+// coverage, so thin features fade instead of disappearing.
+//
+// The returned mask's Rect is the original one divided by the factor,
+// with Min rounded down and Max rounded up (towards -inf and +inf), so
+// it always contains the scaled area. Pixels of the original mask's
+// coordinate space outside its Rect are considered transparent, so masks
+// not aligned to the factor get their partially covered border pixels
+// averaged with zeros. Factors <= 1 return an unmodified copy of the mask.
+func DownscaleMask(mask *image.Alpha, factor int) *image.Alpha {
+	if factor <= 1 {
+		downscaled := image.NewAlpha(mask.Rect)
+		copyMask(downscaled, mask)
+		return downscaled
+	}
+
+	rect := image.Rect(
+		floorDiv(mask.Rect.Min.X, factor), floorDiv(mask.Rect.Min.Y, factor),
+		ceilDiv(mask.Rect.Max.X, factor), ceilDiv(mask.Rect.Max.Y, factor),
+	)
+	downscaled := image.NewAlpha(rect)
+	width := rect.Dx()
+	sums := make([]int, width)
+	area, half := factor*factor, (factor*factor)/2
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for i := range sums { sums[i] = 0 }
+		for sy := y*factor; sy < (y + 1)*factor; sy++ {
+			if sy < mask.Rect.Min.Y || sy >= mask.Rect.Max.Y { continue }
+			row := mask.Pix[mask.PixOffset(mask.Rect.Min.X, sy) : ][ : mask.Rect.Dx()]
+			for i, value := range row {
+				sums[floorDiv(mask.Rect.Min.X + i, factor) - rect.Min.X] += int(value)
+			}
+		}
+		out := downscaled.Pix[downscaled.PixOffset(rect.Min.X, y) : ][ : width]
+		for i, sum := range sums { out[i] = uint8((sum + half)/area) }
+	}
+	return downscaled
+}
+
+// Returns the mipmap chain for the mask: the mask itself (not copied)
+// followed by up to the given number of levels, each one half the size
+// of the previous one as with [DownscaleMask](). Each level is computed
+// directly from the original mask, so rounding errors don't accumulate.
+// The chain stops early once the levels stop shrinking, which happens
+// at 1x1 pixels (or 2x2, for masks containing the origin, since Rects
+// are rounded outwards).
+func MaskMipmaps(mask *image.Alpha, levels int) []*image.Alpha {
+	mipmaps := []*image.Alpha{ mask }
+	for level := 1; level <= levels; level++ {
+		last := mipmaps[len(mipmaps) - 1]
+		if last.Rect.Dx() <= 1 && last.Rect.Dy() <= 1 { break }
+		next := DownscaleMask(mask, 1 << level)
+		if next.Rect.Size() == last.Rect.Size() { break }
+		mipmaps = append(mipmaps, next)
+	}
+	return mipmaps
+}
+
+func floorDiv(value, divisor int) int {
+	quotient := value/divisor
+	if value % divisor != 0 && value < 0 { quotient -= 1 }
+	return quotient
+}
+
+func ceilDiv(value, divisor int) int {
+	return -floorDiv(-value, divisor)
+}
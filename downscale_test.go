@@ -0,0 +1,49 @@
+package sfntshape
+
+import "math"
+import "image"
+import "testing"
+
+func TestDownscaleMask(t *testing.T) {
+	checkerboard := image.NewAlpha(image.Rect(-4, 6, 12, 14))
+	for y := checkerboard.Rect.Min.Y; y < checkerboard.Rect.Max.Y; y++ {
+		for x := checkerboard.Rect.Min.X; x < checkerboard.Rect.Max.X; x++ {
+			if (x + y) % 2 == 0 { checkerboard.Pix[checkerboard.PixOffset(x, y)] = 0xFF }
+		}
+	}
+	downscaled := DownscaleMask(checkerboard, 2)
+	if downscaled.Rect != image.Rect(-2, 3, 6, 7) { t.Fatalf("unexpected Rect %v", downscaled.Rect) }
+	for i, value := range downscaled.Pix {
+		if value != 128 { t.Fatalf("expected uniform 128s, got %d at %d", value, i) }
+	}
+
+	// unaligned rects round outwards
+	dot := image.NewAlpha(image.Rect(-5, 3, -4, 4))
+	dot.Pix[0] = 0xFF
+	downscaled = DownscaleMask(dot, 4)
+	if downscaled.Rect != image.Rect(-2, 0, -1, 1) { t.Fatalf("unexpected Rect %v", downscaled.Rect) }
+	if downscaled.Pix[0] != 16 { t.Fatalf("expected 16, got %d", downscaled.Pix[0]) }
+}
+
+func TestMaskMipmaps(t *testing.T) {
+	shape := testPolygon(100, 48)
+	mask, err := shape.RasterizeFract(13, 29)
+	if err != nil { t.Fatal(err) }
+	mipmaps := MaskMipmaps(mask, 20)
+	if mipmaps[0] != mask { t.Fatal("expected the first level to be the original mask") }
+	last := mipmaps[len(mipmaps) - 1].Rect
+	if last.Dx() > 2 || last.Dy() > 2 { t.Fatalf("expected the chain to end at 2x2, got %v", last) }
+	if len(mipmaps) > 12 { t.Fatalf("unexpected number of levels %d", len(mipmaps)) }
+
+	area := maskArea(mask)
+	for level, mipmap := range mipmaps[1 : ] {
+		factor := float64(int(2) << level)
+		// each pixel may round by half a unit
+		scaled := maskArea(mipmap)*factor*factor
+		tolerance := float64(len(mipmap.Pix))*factor*factor*0.5/255
+		if math.Abs(scaled - area) > tolerance {
+			t.Fatalf("level %d: coverage %.2f vs %.2f", level + 1, scaled, area)
+		}
+	}
+	if len(MaskMipmaps(mask, 2)) != 3 { t.Fatal("expected the mask and 2 levels") }
+}
@@ -0,0 +1,179 @@
+package sfntshape
+
+import "image"
+import "errors"
+import "image/draw"
+import "image/color"
+
+// Rasterizes the shape and composites it directly onto dst with the
+// given fill color, using [draw.Over] or [draw.Src] as the operation.
+//
+// The at point anchors the shape's origin (0, 0) (as in the coordinates
+// of the stored segments), not the top-left corner of its bounds: a
+// shape whose segments range from (-8, -8) to (-2, -2) drawn at (10, 10)
+// will cover the dst region from (2, 2) to (8, 8). Anything falling
+// outside dst's bounds is clipped.
+//
+// This is equivalent to using [draw.DrawMask] with the result of
+// [Shape.Rasterize](), but *image.RGBA and *image.NRGBA destinations
+// have fast paths that write the pixels directly.
+func (self *Shape) Draw(dst draw.Image, at image.Point, fill color.Color, op draw.Op) error {
+	if op != draw.Over && op != draw.Src {
+		return errors.New("unsupported draw.Op")
+	}
+	mask, err := self.rasterizeForPaint()
+	if errors.Is(err, ErrNothingToDraw) { return nil }
+	if err != nil || mask == nil { return err }
+	drawMask(dst, mask, at, fill, op)
+	return nil
+}
+
+// Rasterizes the shape and composites it over the existing contents of
+// dst with the given fill color, with the shape's origin displaced by
+// the given offset. This is a shorthand for [Shape.Draw]() with
+// [draw.Over], which is the right operation for building scenes out of
+// multiple shapes without allocating intermediate images (unlike
+// drawing the results of [Shape.Paint]() over each other). Empty shapes
+// are not considered an error.
+func (self *Shape) PaintOnto(dst draw.Image, offset image.Point, fill color.Color) error {
+	return self.Draw(dst, offset, fill, draw.Over)
+}
+
+// Like [Shape.Paint](), but painting the shape over a background image
+// instead of a flat color. The at point anchors the shape's origin in
+// bg's coordinates, like in [Shape.Draw](), and the result is a new image
+// covering the mask's bounds displaced by at (so its Rect is also in
+// bg's coordinates), with the corresponding region of bg copied and the
+// shape composited over it. Parts of the region outside bg's bounds are
+// transparent. The background is not modified; to get the full bg with
+// the shape on top, copy it and use [Shape.PaintOnto]() instead.
+//
+// Returns nil for the same reasons as [Shape.Paint](). Use
+// [Shape.PaintOverChecked]() if you need to know the cause.
+func (self *Shape) PaintOver(bg image.Image, at image.Point, fill color.Color) *image.RGBA {
+	rgba, err := self.PaintOverChecked(bg, at, fill)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return rgba
+}
+
+// Like [Shape.PaintOver](), but also returning any rasterization error.
+func (self *Shape) PaintOverChecked(bg image.Image, at image.Point, fill color.Color) (*image.RGBA, error) {
+	if bg == nil { return nil, errors.New("nil background image") }
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+	rect := mask.Rect.Add(at)
+	rgba := image.NewRGBA(rect)
+	draw.Draw(rgba, rect, bg, rect.Min, draw.Src)
+	drawMask(rgba, mask, at, fill, draw.Over)
+	return rgba, nil
+}
+
+// Colors of the [PaintPreview]() checkerboard.
+var previewCheckers = [2]color.RGBA{ { 204, 204, 204, 255 }, { 153, 153, 153, 255 } }
+
+// Paints the shape with the given fill color over a light and dark gray
+// checkerboard (the usual pattern for transparency), so alpha mistakes
+// are easy to see when inspecting exported images. Cells are cell pixels
+// wide, or 8 if cell is not positive, and they are aligned to the masks'
+// coordinates instead of the image's top-left corner, so previews of
+// adjacent shapes (or the same shape at different positions) tile
+// consistently.
+//
+// Returns nil for the same reasons as [Shape.Paint]().
+func PaintPreview(shape *Shape, fill color.Color, cell int) *image.RGBA {
+	if shape == nil { return nil }
+	mask, err := shape.rasterizeForPaint()
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA(image.Rectangle{}) }
+	if err != nil || mask == nil { return nil }
+	if cell <= 0 { cell = 8 }
+
+	rgba := image.NewRGBA(mask.Rect)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		row := rgba.Pix[rgba.PixOffset(mask.Rect.Min.X, y) : ][ : mask.Rect.Dx()*4]
+		parity := floorDiv(y, cell) & 1
+		for i, x := 0, mask.Rect.Min.X; x < mask.Rect.Max.X; i, x = i + 4, x + 1 {
+			checker := previewCheckers[(floorDiv(x, cell) + parity) & 1]
+			row[i], row[i + 1], row[i + 2], row[i + 3] = checker.R, checker.G, checker.B, checker.A
+		}
+	}
+	drawMask(rgba, mask, image.Point{}, fill, draw.Over)
+	return rgba
+}
+
+// Composites the fill color through the mask onto dst, with the mask
+// displaced by the given offset.
+func drawMask(dst draw.Image, mask *image.Alpha, offset image.Point, fill color.Color, op draw.Op) {
+	rect := mask.Rect.Add(offset).Intersect(dst.Bounds())
+	if rect.Empty() { return }
+	maskPt := rect.Min.Sub(offset)
+
+	var rgba *image.RGBA
+	switch typed := dst.(type) {
+	case *image.RGBA:
+		rgba = typed
+	case *image.NRGBA:
+		drawMaskNRGBA(typed, rect, mask, maskPt, fill, op)
+		return
+	default:
+		draw.DrawMask(dst, rect, image.NewUniform(fill), image.Point{}, mask, maskPt, op)
+		return
+	}
+
+	// fast path, same formulas as image/draw
+	const m = 0xFFFF
+	sr, sg, sb, sa := fill.RGBA()
+	width := rect.Dx()
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		maskRow := mask.Pix[mask.PixOffset(maskPt.X, maskPt.Y + y - rect.Min.Y) : ][ : width]
+		dstRow  := rgba.Pix[rgba.PixOffset(rect.Min.X, y) : ][ : width*4]
+		for x, ma := range maskRow {
+			if ma == 0 && op == draw.Over { continue }
+			ma := uint32(ma)*0x101
+			d := dstRow[x*4 : x*4 + 4 : x*4 + 4]
+			if op == draw.Over {
+				a := (m - (sa*ma/m))*0x101
+				d[0] = uint8((uint32(d[0])*a + sr*ma)/m >> 8)
+				d[1] = uint8((uint32(d[1])*a + sg*ma)/m >> 8)
+				d[2] = uint8((uint32(d[2])*a + sb*ma)/m >> 8)
+				d[3] = uint8((uint32(d[3])*a + sa*ma)/m >> 8)
+			} else {
+				d[0] = uint8(sr*ma/m >> 8)
+				d[1] = uint8(sg*ma/m >> 8)
+				d[2] = uint8(sb*ma/m >> 8)
+				d[3] = uint8(sa*ma/m >> 8)
+			}
+		}
+	}
+}
+
+// Like the *image.RGBA fast path of drawMask, but for non-premultiplied
+// destinations. The formulas match the generic path of image/draw, which
+// converts the pixels to premultiplied 16-bit colors and back.
+func drawMaskNRGBA(dst *image.NRGBA, rect image.Rectangle, mask *image.Alpha, maskPt image.Point, fill color.Color, op draw.Op) {
+	const m = 0xFFFF
+	sr, sg, sb, sa := fill.RGBA()
+	width := rect.Dx()
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		maskRow := mask.Pix[mask.PixOffset(maskPt.X, maskPt.Y + y - rect.Min.Y) : ][ : width]
+		dstRow  := dst.Pix[dst.PixOffset(rect.Min.X, y) : ][ : width*4]
+		for x, ma := range maskRow {
+			if ma == 0 && op == draw.Over { continue }
+			ma := uint32(ma)*0x101
+			d := dstRow[x*4 : x*4 + 4 : x*4 + 4]
+			var r, g, b, a uint32
+			if op == draw.Over {
+				da := uint32(d[3])*0x101
+				dr, dg, db := uint32(d[0])*0x101*da/m, uint32(d[1])*0x101*da/m, uint32(d[2])*0x101*da/m
+				inv := m - (sa*ma/m)
+				r, g, b, a = (dr*inv + sr*ma)/m, (dg*inv + sg*ma)/m, (db*inv + sb*ma)/m, (da*inv + sa*ma)/m
+			} else {
+				r, g, b, a = sr*ma/m, sg*ma/m, sb*ma/m, sa*ma/m
+			}
+			if a != 0 && a != m {
+				r, g, b = (r*m)/a, (g*m)/a, (b*m)/a
+			}
+			d[0], d[1], d[2], d[3] = uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)
+		}
+	}
+}
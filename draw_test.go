@@ -0,0 +1,183 @@
+package sfntshape
+
+import "math"
+import "bytes"
+import "image"
+import "image/draw"
+import "image/color"
+import "testing"
+
+func TestShapeDraw(t *testing.T) {
+	// square living at negative coordinates, from (-8, -8) to (-2, -2)
+	shape := New()
+	shape.InvertY(true)
+	shape.MoveTo(-8, -8)
+	shape.LineTo(-2, -8)
+	shape.LineTo(-2, -2)
+	shape.LineTo(-8, -2)
+	shape.LineTo(-8, -8)
+
+	fill := color.RGBA{128, 0, 64, 200}
+	for _, op := range []draw.Op{draw.Over, draw.Src} {
+		canvas := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.RGBA{10, 20, 30, 255}), image.Point{}, draw.Src)
+		reference := image.NewRGBA(canvas.Bounds())
+		copy(reference.Pix, canvas.Pix)
+
+		err := shape.Draw(canvas, image.Pt(10, 10), fill, op)
+		if err != nil { t.Fatal(err) }
+		if canvas.RGBAAt(2, 2) == canvas.RGBAAt(0, 0) || canvas.RGBAAt(8, 8) != canvas.RGBAAt(0, 0) {
+			t.Fatal("expected the shape origin to be anchored at the given point")
+		}
+
+		// compare against the generic path
+		mask, _ := shape.Rasterize()
+		maskRect := mask.Rect.Add(image.Pt(10, 10))
+		draw.DrawMask(reference, maskRect, image.NewUniform(fill), image.Point{}, mask, mask.Rect.Min, op)
+		if !bytes.Equal(canvas.Pix, reference.Pix) {
+			t.Fatalf("op %v: RGBA fast path differs from draw.DrawMask", op)
+		}
+
+		// NRGBA path with clipping
+		nrgba := image.NewNRGBA(image.Rect(0, 0, 12, 12))
+		err = shape.Draw(nrgba, image.Pt(15, 15), fill, op)
+		if err != nil { t.Fatal(err) }
+		if nrgba.NRGBAAt(11, 11).A == 0 || nrgba.NRGBAAt(6, 6).A != 0 {
+			t.Fatal("unexpected result on NRGBA path")
+		}
+		nrgbaReference := image.NewNRGBA(nrgba.Rect)
+		draw.DrawMask(nrgbaReference, mask.Rect.Add(image.Pt(15, 15)), image.NewUniform(fill), image.Point{}, mask, mask.Rect.Min, op)
+		if !bytes.Equal(nrgba.Pix, nrgbaReference.Pix) {
+			t.Fatalf("op %v: NRGBA fast path differs from draw.DrawMask", op)
+		}
+
+		// generic path
+		gray := image.NewGray(image.Rect(0, 0, 12, 12))
+		err = shape.Draw(gray, image.Pt(15, 15), fill, op)
+		if err != nil { t.Fatal(err) }
+		if gray.GrayAt(11, 11).Y == 0 || gray.GrayAt(6, 6).Y != 0 {
+			t.Fatal("unexpected result on generic draw.Image path")
+		}
+	}
+
+	err := shape.Draw(image.NewRGBA(image.Rect(0, 0, 4, 4)), image.Point{}, fill, draw.Op(7))
+	if err == nil { t.Fatal("expected error on unsupported op") }
+}
+
+func TestPaintOnto(t *testing.T) {
+	fills := []color.NRGBA{ { 255, 0, 0, 128 }, { 0, 200, 0, 100 }, { 0, 0, 255, 170 } }
+	offsets := []image.Point{ { 30, 30 }, { 50, 35 }, { 40, 55 } }
+	for _, canvas := range []draw.Image{ image.NewRGBA(image.Rect(0, 0, 80, 80)), image.NewNRGBA(image.Rect(0, 0, 80, 80)) } {
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.RGBA{ 20, 20, 20, 255 }), image.Point{}, draw.Src)
+		expected := image.NewRGBA(canvas.Bounds())
+		draw.Draw(expected, expected.Bounds(), canvas, image.Point{}, draw.Src)
+		reference := image.NewNRGBA(canvas.Bounds()) // for image/draw's results with NRGBA
+		draw.Draw(reference, reference.Bounds(), canvas, image.Point{}, draw.Src)
+		for i, fill := range fills {
+			circle := New()
+			testCircle(&circle, 0, 0, 20)
+			if err := circle.PaintOnto(canvas, offsets[i], fill); err != nil { t.Fatal(err) }
+			mask, _ := circle.Rasterize()
+			draw.DrawMask(reference, mask.Rect.Add(offsets[i]), image.NewUniform(fill), image.Point{}, mask, mask.Rect.Min, draw.Over)
+
+			// manual composition of the separately painted images
+			img := circle.PaintAlpha(fill)
+			draw.Draw(expected, img.Rect.Add(offsets[i]), img, img.Rect.Min, draw.Over)
+		}
+
+		bounds := canvas.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				got, want := color.RGBAModel.Convert(canvas.At(x, y)).(color.RGBA), expected.RGBAAt(x, y)
+				for c, value := range []uint8{ got.R, got.G, got.B, got.A } {
+					wantValue := []uint8{ want.R, want.G, want.B, want.A }[c]
+					if absInt(int(value) - int(wantValue)) > 1 {
+						t.Fatalf("%T: pixel (%d, %d) is %v, expected %v", canvas, x, y, got, want)
+					}
+				}
+			}
+		}
+
+		// the NRGBA fast path must match image/draw exactly
+		if nrgba, ok := canvas.(*image.NRGBA); ok && !bytes.Equal(nrgba.Pix, reference.Pix) {
+			t.Fatal("NRGBA fast path differs from image/draw")
+		}
+	}
+}
+
+func TestPaintOver(t *testing.T) {
+	// horizontal gray ramp background
+	bg := image.NewNRGBA(image.Rect(0, 0, 64, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 64; x++ { bg.SetNRGBA(x, y, color.NRGBA{ uint8(x*4), uint8(x*4), uint8(x*4), 255 }) }
+	}
+	shape := New()
+	testCircle(&shape, 0, 0, 12)
+	fill := color.RGBA{ 200, 0, 0, 255 }
+	at := image.Pt(54, 20) // partially outside the right edge
+	img := shape.PaintOver(bg, at, fill)
+	mask, _ := shape.Rasterize()
+	if img == nil || img.Rect != mask.Rect.Add(at) {
+		t.Fatalf("expected bounds %v", mask.Rect.Add(at))
+	}
+
+	partial := 0
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			alpha := int(mask.AlphaAt(x - at.X, y - at.Y).A)
+			var back [4]int // transparent outside bg
+			if (image.Point{ x, y }).In(bg.Rect) { back = [4]int{ x*4, x*4, x*4, 255 } }
+			if alpha > 0 && alpha < 255 && back[3] > 0 { partial += 1 }
+			got := img.RGBAAt(x, y)
+			src := [4]int{ int(fill.R), int(fill.G), int(fill.B), int(fill.A) }
+			for c, value := range []uint8{ got.R, got.G, got.B, got.A } {
+				expected := (src[c]*alpha + back[c]*(255 - alpha) + 127)/255
+				if absInt(int(value) - expected) > 1 {
+					t.Fatalf("pixel (%d, %d), channel %d: expected %d, got %d", x, y, c, expected, value)
+				}
+			}
+		}
+	}
+	if partial == 0 { t.Fatal("expected partially covered edge pixels over the background") }
+}
+
+func TestPaintPreview(t *testing.T) {
+	shape := New()
+	testCircle(&shape, 20.3, 20.7, 15.2)
+	mask, _ := shape.Rasterize()
+	fill := color.NRGBA{ 230, 30, 30, 128 }
+	img := PaintPreview(&shape, fill, 5)
+	if img.Rect != mask.Rect { t.Fatalf("expected rect %v, got %v", mask.Rect, img.Rect) }
+
+	// checkerboard aligned to absolute coordinates, shape over it
+	cellOf := func(value int) int { return int(math.Floor(float64(value)/5)) }
+	board := image.NewRGBA(mask.Rect)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			board.SetRGBA(x, y, previewCheckers[(cellOf(x) + cellOf(y)) & 1])
+		}
+	}
+	draw.DrawMask(board, board.Rect, image.NewUniform(fill), image.Point{}, mask, mask.Rect.Min, draw.Over)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			if img.RGBAAt(x, y) != board.RGBAAt(x, y) {
+				t.Fatalf("pixel (%d, %d): expected %v, got %v", x, y, board.RGBAAt(x, y), img.RGBAAt(x, y))
+			}
+		}
+	}
+
+	// adjacent previews tile consistently
+	moved := New()
+	testCircle(&moved, 20.3 + 7, 20.7, 15.2)
+	other := PaintPreview(&moved, color.Transparent, 5)
+	for y := other.Rect.Min.Y; y < other.Rect.Max.Y; y++ {
+		for x := other.Rect.Min.X; x < other.Rect.Max.X; x++ {
+			if !image.Pt(x, y).In(img.Rect) { continue }
+			if mask.AlphaAt(x, y).A == 0 && other.RGBAAt(x, y) != img.RGBAAt(x, y) {
+				t.Fatalf("pixel (%d, %d): checkerboards not aligned", x, y)
+			}
+		}
+	}
+	if PaintPreview(nil, fill, 5) != nil { t.Fatal("expected nil for nil shape") }
+	if !bytes.Equal(PaintPreview(&shape, fill, 0).Pix, PaintPreview(&shape, fill, 8).Pix) { t.Fatal("expected default cell size 8") }
+}
@@ -0,0 +1,77 @@
+package sfntshape
+
+import "math"
+import "image"
+import "errors"
+import "image/draw"
+import "image/color"
+
+// Paints the shape filled with the fill color over the background, with
+// a glow of the given radius (in pixels) around it. Hard glows are made
+// by dilating the mask (see [DilateMask]()), which results in a solid
+// outline with the radius rounded to the nearest integer; soft glows are
+// made by dilating the mask by half the radius and blurring it (see
+// [BlurMask]()) with a sigma of radius/6, so the glow fades out around
+// the radius. The original coverage is subtracted from the glow, which
+// is tinted with the glow color and composited under the shape.
+//
+// The image covers the mask's bounds grown by the radius (soft glows may
+// grow a few extra pixels, as the blur needs them).
+func (self *Shape) PaintGlow(fill, glow color.Color, radius float64, soft bool, backColor color.Color) (*image.RGBA, error) {
+	if !(radius >= 0) || math.IsInf(radius, 0) {
+		return nil, errors.New("glow radius must be non-negative and finite")
+	}
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+
+	var glowMask *image.Alpha
+	if soft {
+		dilation, sigma := int(math.Round(radius/2)), radius/6
+		margin := dilation
+		if sigma > 0 { margin += blurMargin(sigma) }
+		if err := checkMaskSize(mask.Rect.Dx() + 2*margin, mask.Rect.Dy() + 2*margin); err != nil { return nil, err }
+		glowMask = BlurMask(DilateMask(mask, dilation), sigma)
+	} else {
+		dilation := int(math.Round(radius))
+		if err := checkMaskSize(mask.Rect.Dx() + 2*dilation, mask.Rect.Dy() + 2*dilation); err != nil { return nil, err }
+		glowMask = DilateMask(mask, dilation)
+	}
+	ring := CombineMasks(glowMask, mask, MaskSubtract)
+
+	rgba := image.NewRGBA(ring.Rect)
+	draw.Draw(rgba, rgba.Rect, image.NewUniform(backColor), image.Point{}, draw.Src)
+	drawMask(rgba, ring, image.Point{}, glow, draw.Over)
+	drawMask(rgba, mask, image.Point{}, fill, draw.Over)
+	return rgba, nil
+}
+
+// Paints the shape filled with the fill color over the background, with
+// a drop shadow below it. The shadow is the mask blurred with the given
+// sigma and displaced by the given offset, as with [Shape.Shadow](),
+// tinted with the shadow color (whose alpha scales the shadow's
+// opacity). The image covers both the shape and the whole shadow.
+//
+// Returns nil for the same reasons as [Shape.Paint](). Use
+// [Shape.PaintWithShadowChecked]() if you need to know the cause.
+func (self *Shape) PaintWithShadow(fill, shadow color.Color, offsetX, offsetY int, sigma float64, backColor color.Color) *image.RGBA {
+	rgba, err := self.PaintWithShadowChecked(fill, shadow, offsetX, offsetY, sigma, backColor)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return rgba
+}
+
+// Like [Shape.PaintWithShadow](), but also returning any rasterization error.
+func (self *Shape) PaintWithShadowChecked(fill, shadow color.Color, offsetX, offsetY int, sigma float64, backColor color.Color) (*image.RGBA, error) {
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+	shadowMask, err := self.paintCoverage(self.Shadow(sigma, offsetX, offsetY))
+	if err != nil { return nil, err }
+
+	rect := mask.Rect.Union(shadowMask.Rect)
+	if err := checkMaskSize(rect.Dx(), rect.Dy()); err != nil { return nil, err }
+	rgba := image.NewRGBA(rect)
+	draw.Draw(rgba, rect, image.NewUniform(backColor), image.Point{}, draw.Src)
+	drawMask(rgba, shadowMask, image.Point{}, shadow, draw.Over)
+	drawMask(rgba, mask, image.Point{}, fill, draw.Over)
+	return rgba, nil
+}
@@ -0,0 +1,87 @@
+package sfntshape
+
+import "math"
+import "image"
+import "image/color"
+import "testing"
+
+func TestPaintGlow(t *testing.T) {
+	shape := New()
+	shape.InvertY(true)
+	shape.MoveTo(0, 0)
+	shape.LineTo(20, 0)
+	shape.LineTo(20, 20)
+	shape.LineTo(0, 20)
+	shape.LineTo(0, 0)
+	fill, glow := color.RGBA{ 0, 0, 255, 255 }, color.RGBA{ 255, 0, 0, 255 }
+	black := color.RGBA{ 0, 0, 0, 255 }
+	mask, _ := shape.Rasterize()
+
+	img, err := shape.PaintGlow(fill, glow, 4, false, black)
+	if err != nil { t.Fatal(err) }
+	if img.Rect != mask.Rect.Inset(-4) { t.Fatalf("expected bounds %v, got %v", mask.Rect.Inset(-4), img.Rect) }
+	width := 0
+	for x := 20; x < img.Rect.Max.X; x++ {
+		if img.RGBAAt(x, 10) == glow { width += 1 }
+	}
+	if width != 4 { t.Fatalf("expected a 4px glow ring, got %d", width) }
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			if img.RGBAAt(x, y) != fill { t.Fatalf("interior pixel (%d, %d) is not the fill color", x, y) }
+		}
+	}
+
+	img, err = shape.PaintGlow(fill, glow, 6, true, black)
+	if err != nil { t.Fatal(err) }
+	if !img.Rect.In(mask.Rect.Inset(-8)) || img.Rect.In(mask.Rect.Inset(-5)) {
+		t.Fatalf("unexpected soft glow bounds %v", img.Rect)
+	}
+	prev := uint8(255)
+	for x := 20; x < img.Rect.Max.X; x++ {
+		red := img.RGBAAt(x, 10).R
+		if red > prev { t.Fatalf("expected the soft glow to fade outwards at x = %d", x) }
+		prev = red
+	}
+	if img.RGBAAt(20, 10).R < 128 || img.RGBAAt(img.Rect.Max.X - 1, 10).R > 16 { t.Fatal("unexpected soft glow intensity") }
+	if img.RGBAAt(10, 10) != fill || img.RGBAAt(1, 1) != fill { t.Fatal("expected the fill color on the interior") }
+	if _, err := shape.PaintGlow(fill, glow, -1, true, black); err == nil { t.Fatal("expected error for a negative radius") }
+}
+
+func TestPaintWithShadow(t *testing.T) {
+	shape := testStar(20)
+	mask, _ := shape.Rasterize()
+	white := color.RGBA{ 255, 255, 255, 255 }
+	centroid := func(weightAt func(x, y int) float64, rect image.Rectangle) (float64, float64) {
+		var sum, sumX, sumY float64
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				weight := weightAt(x, y)
+				sum, sumX, sumY = sum + weight, sumX + weight*float64(x), sumY + weight*float64(y)
+			}
+		}
+		return sumX/sum, sumY/sum
+	}
+
+	// shadow alone (transparent fill), as darkening of a white background
+	img := shape.PaintWithShadow(color.Transparent, color.Black, 7, -5, 3, white)
+	if img == nil { t.Fatal("unexpected nil image") }
+	shadow, _ := shape.Shadow(3, 7, -5)
+	if img.Rect != mask.Rect.Union(shadow.Rect) { t.Fatalf("unexpected bounds %v", img.Rect) }
+	shapeX, shapeY := centroid(func(x, y int) float64 { return float64(mask.AlphaAt(x, y).A) }, mask.Rect)
+	shadowX, shadowY := centroid(func(x, y int) float64 { return float64(255 - img.RGBAAt(x, y).R) }, img.Rect)
+	if math.Abs(shadowX - shapeX - 7) > 0.05 || math.Abs(shadowY - shapeY + 5) > 0.05 {
+		t.Fatalf("expected the shadow centroid displaced by (7, -5), got (%f, %f)", shadowX - shapeX, shadowY - shapeY)
+	}
+
+	// no shadow under the opaque shape
+	fill := color.RGBA{ 0, 120, 255, 255 }
+	img = shape.PaintWithShadow(fill, color.NRGBA{ 0, 0, 0, 128 }, 7, -5, 3, white)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			if mask.AlphaAt(x, y).A == 255 && img.RGBAAt(x, y) != fill {
+				t.Fatalf("pixel (%d, %d) under the shape has shadow contributions", x, y)
+			}
+		}
+	}
+	if far := img.RGBAAt(img.Rect.Max.X - 1, img.Rect.Min.Y); far.R < 250 { t.Fatalf("expected background on the corners, got %v", far) }
+}
@@ -0,0 +1,164 @@
+package sfntshape
+
+import "errors"
+import "strconv"
+import "strings"
+import "sync/atomic"
+
+import "golang.org/x/image/font/sfnt"
+
+// Returned (wrapped into a [*MaskTooLargeError]) by rasterization
+// functions when the mask to allocate would exceed the limits set
+// through [SetMaxRasterSize]().
+var ErrMaskTooLarge = errors.New("mask too large")
+
+// Error returned by rasterization functions when the mask to allocate
+// would exceed the limits set through [SetMaxRasterSize](). Matches
+// [ErrMaskTooLarge] with [errors.Is]().
+type MaskTooLargeError struct {
+	Width, Height int // attempted mask size
+	MaxWidth, MaxHeight int // limits at the time of the error
+}
+
+// Implements the error interface.
+func (self *MaskTooLargeError) Error() string {
+	return "sfntshape: mask too large (" + strconv.Itoa(self.Width) + "x" +
+		strconv.Itoa(self.Height) + ", max " + strconv.Itoa(self.MaxWidth) +
+		"x" + strconv.Itoa(self.MaxHeight) + ")"
+}
+
+// Returns [ErrMaskTooLarge].
+func (self *MaskTooLargeError) Unwrap() error { return ErrMaskTooLarge }
+
+// Default values for [SetMaxRasterSize]().
+const (
+	DefaultMaxRasterWidth  = 16384
+	DefaultMaxRasterHeight = 16384
+)
+
+var maxRasterWidth  int64 = DefaultMaxRasterWidth
+var maxRasterHeight int64 = DefaultMaxRasterHeight
+
+// Sets the maximum mask width and height that rasterization functions
+// are allowed to allocate. Exceeding them results in a [*MaskTooLargeError]
+// instead of a potentially gigantic allocation. Values <= 0 disable the
+// corresponding limit. Defaults to [DefaultMaxRasterWidth] x
+// [DefaultMaxRasterHeight]. Safe for concurrent use.
+func SetMaxRasterSize(maxWidth, maxHeight int) {
+	atomic.StoreInt64(&maxRasterWidth, int64(maxWidth))
+	atomic.StoreInt64(&maxRasterHeight, int64(maxHeight))
+}
+
+// Returns the limits set with [SetMaxRasterSize]().
+func MaxRasterSize() (maxWidth, maxHeight int) {
+	return int(atomic.LoadInt64(&maxRasterWidth)), int(atomic.LoadInt64(&maxRasterHeight))
+}
+
+// Recorded by [Shape] when a coordinate falls outside the range of
+// 26.6 fixed point values. See [Shape.Err]().
+var ErrCoordinateOverflow = errors.New("coordinate overflow")
+
+// Recorded by [Shape] in strict mode when a drawing command is issued
+// before any MoveTo. See [Shape.SetStrict]().
+var ErrMissingMoveTo = errors.New("drawing command before MoveTo")
+
+// Returned (wrapped into an [*InvalidCommandError]) by rasterization
+// functions when the outline contains segments with unknown ops, and
+// recorded by [Shape.SetSegment]() when setting them.
+var ErrInvalidCommand = errors.New("invalid segment op")
+
+// Error for segments with unknown ops. Matches [ErrInvalidCommand]
+// with [errors.Is]().
+type InvalidCommandError struct {
+	Index int // index of the first invalid segment
+	Op sfnt.SegmentOp
+}
+
+// Implements the error interface.
+func (self *InvalidCommandError) Error() string {
+	return "sfntshape: invalid segment " + OpName(self.Op) + " at index " + strconv.Itoa(self.Index)
+}
+
+// Returns [ErrInvalidCommand].
+func (self *InvalidCommandError) Unwrap() error { return ErrInvalidCommand }
+
+// Returns an [*InvalidCommandError] for the first segment with an
+// unknown op, or nil if there's none.
+func validateOps(outline []sfnt.Segment) error {
+	for i, segment := range outline {
+		if segment.Op > sfnt.SegmentOpCubeTo {
+			return &InvalidCommandError{ Index: i, Op: segment.Op }
+		}
+	}
+	return nil
+}
+
+// Returned by rasterization functions when there's nothing to draw (the
+// shape is empty or only contains MoveTo commands), but only after
+// enabling it with [SetNothingToDrawErrors]().
+var ErrNothingToDraw = errors.New("nothing to draw")
+
+// Same as [ErrNothingToDraw], for consistency with the naming of the
+// other errors.
+var ErrEmptyShape = ErrNothingToDraw
+
+var nothingToDrawErrors int32
+
+// By default, rasterization functions return a nil mask and a nil error
+// when there's nothing to draw. This is easy to miss and can lead to nil
+// pointer dereferences, so this compatibility switch makes them return
+// [ErrNothingToDraw] instead. Similarly, [Shape.Paint]() returns an
+// empty image instead of nil. Compositing functions like [Shape.Draw]()
+// and [AccumulateInto]() still succeed without doing anything. Disabled
+// by default for backwards compatibility. Safe for concurrent use.
+func SetNothingToDrawErrors(enabled bool) {
+	var value int32
+	if enabled { value = 1 }
+	atomic.StoreInt32(&nothingToDrawErrors, value)
+}
+
+// Returns whether [SetNothingToDrawErrors]() is enabled.
+func NothingToDrawErrors() bool {
+	return atomic.LoadInt32(&nothingToDrawErrors) != 0
+}
+
+// Returns the error to be returned when there's nothing to draw.
+func nothingToDraw() error {
+	if NothingToDrawErrors() { return ErrNothingToDraw }
+	return nil
+}
+
+func checkMaskSize(width, height int) error {
+	maxWidth, maxHeight := MaxRasterSize()
+	if (maxWidth > 0 && width > maxWidth) || (maxHeight > 0 && height > maxHeight) {
+		return &MaskTooLargeError{
+			Width: width, Height: height,
+			MaxWidth: maxWidth, MaxHeight: maxHeight,
+		}
+	}
+	return nil
+}
+
+// Returned (wrapped into a [*ShapeNameError]) by [Library] methods
+// when no shape is registered with the requested name.
+var ErrUnknownShape = errors.New("unknown shape name")
+
+// Returned (wrapped into a [*ShapeNameError]) by [Library.Register]()
+// when the name is already in use.
+var ErrDuplicateShape = errors.New("duplicate shape name")
+
+// Error for [Library] operations involving a specific shape name.
+// Matches [ErrUnknownShape] or [ErrDuplicateShape] with [errors.Is](),
+// or the error recorded in a shape rejected by [Library.Register]().
+type ShapeNameError struct {
+	Name string
+	Err error // ErrUnknownShape, ErrDuplicateShape or the shape's error
+}
+
+// Implements the error interface.
+func (self *ShapeNameError) Error() string {
+	return "sfntshape: " + strings.TrimPrefix(self.Err.Error(), "sfntshape: ") + " " + strconv.Quote(self.Name)
+}
+
+// Returns the underlying error.
+func (self *ShapeNameError) Unwrap() error { return self.Err }
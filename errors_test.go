@@ -0,0 +1,216 @@
+package sfntshape
+
+import "image"
+import "errors"
+import "runtime"
+import "testing"
+import "image/draw"
+import "image/color"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/vector"
+
+func TestMaxRasterSize(t *testing.T) {
+	if w, h := MaxRasterSize(); w != DefaultMaxRasterWidth || h != DefaultMaxRasterHeight {
+		t.Fatalf("unexpected default limits %dx%d", w, h)
+	}
+
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.LineTo(1000000, 0)
+	shape.LineTo(1000000, 1000000)
+	shape.LineTo(0, 0)
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	allocated := stats.TotalAlloc
+	checks := map[string]error{}
+	_, checks["Rasterize"] = shape.Rasterize()
+	_, checks["RasterizeFract"] = shape.RasterizeFract(32, 32)
+	_, checks["RasterizeOpts"] = shape.RasterizeOpts(RasterizeOptions{ FillRule: FillEvenOdd })
+	_, checks["RasterizeInto"] = shape.RasterizeInto(&image.Alpha{}, 0, 0)
+	_, checks["PaintChecked"] = shape.PaintChecked(color.White, color.Black)
+	runtime.ReadMemStats(&stats)
+	allocated = stats.TotalAlloc - allocated
+	if allocated > 1 << 20 {
+		t.Fatalf("expected no big allocations, but %d bytes were allocated", allocated)
+	}
+
+	for name, err := range checks {
+		if !errors.Is(err, ErrMaskTooLarge) {
+			t.Fatalf("%s: expected ErrMaskTooLarge, got %v", name, err)
+		}
+		var sizeErr *MaskTooLargeError
+		if !errors.As(err, &sizeErr) || sizeErr.Width < 1000000 || sizeErr.Height < 1000000 {
+			t.Fatalf("%s: expected *MaskTooLargeError with the attempted size, got %v", name, err)
+		}
+	}
+	if shape.Paint(color.White, color.Black) != nil {
+		t.Fatal("expected Paint to return nil for too large masks")
+	}
+
+	// clipping avoids the limit, and limits can be changed or disabled
+	_, err := shape.RasterizeClipped(image.Rect(0, -64, 64, 0), 0, 0)
+	if err != nil { t.Fatalf("unexpected error on clipped rasterization: %s", err) }
+	SetMaxRasterSize(10, 0)
+	defer SetMaxRasterSize(DefaultMaxRasterWidth, DefaultMaxRasterHeight)
+	_, err = shape.RasterizeClipped(image.Rect(0, -64, 64, 0), 0, 0)
+	if !errors.Is(err, ErrMaskTooLarge) { t.Fatalf("expected ErrMaskTooLarge, got %v", err) }
+	shape.Reset()
+	shape.MoveTo(0, 0)
+	shape.LineTo(8, 0)
+	shape.LineTo(8, 2000)
+	shape.LineTo(0, 0)
+	if _, err := shape.Rasterize(); err != nil {
+		t.Fatalf("expected disabled height limit, got %v", err)
+	}
+}
+
+func TestNothingToDraw(t *testing.T) {
+	shape := New()
+	shape.MoveTo(3, 3) // MoveTo only
+	other := New()
+	other.MoveTo(0, 0)
+	other.LineTo(4, 0)
+	other.LineTo(0, 4)
+	other.LineTo(0, 0)
+
+	entryPoints := shapeEntryPoints(&shape)
+
+	isNil := func(value any) bool {
+		switch typed := value.(type) {
+		case nil: return true
+		case *image.Alpha: return typed == nil
+		case *image.Gray: return typed == nil
+		case *image.RGBA: return typed == nil
+		case *image.NRGBA: return typed == nil
+		case *image.RGBA64: return typed == nil
+		default: return false
+		}
+	}
+
+	for _, enabled := range []bool{ false, true } {
+		SetNothingToDrawErrors(enabled)
+		if NothingToDrawErrors() != enabled { t.Fatal("unexpected NothingToDrawErrors value") }
+		for name, fn := range entryPoints {
+			result, err := fn()
+			if !isNil(result) { t.Fatalf("%s: expected nil result, got %v", name, result) }
+			if enabled && !errors.Is(err, ErrNothingToDraw) {
+				t.Fatalf("%s: expected ErrNothingToDraw, got %v", name, err)
+			} else if !enabled && err != nil {
+				t.Fatalf("%s: expected nil error, got %v", name, err)
+			}
+		}
+
+		img := shape.Paint(color.White, color.Black)
+		if enabled && (img == nil || !img.Rect.Empty()) { t.Fatal("expected Paint to return an empty image") }
+		if !enabled && img != nil { t.Fatal("expected Paint to return nil") }
+
+		// compositing nothing is never an error
+		dst := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		if err := shape.Draw(dst, image.Point{}, color.White, draw.Over); err != nil { t.Fatal(err) }
+		if err := shape.AccumulateInto(image.NewAlpha(dst.Rect), 0, 0, AccumulateAdd); err != nil { t.Fatal(err) }
+		mask, err := RasterizeGroup([]GroupEntry{ { Shape: &other }, { Shape: &shape, Op: GroupSubtract } })
+		if err != nil || mask == nil { t.Fatalf("expected group with an empty entry to work, got %v", err) }
+	}
+	SetNothingToDrawErrors(false)
+}
+
+// Returns the main rasterization and paint entry points of the shape,
+// for tests checking that all of them report the same errors. Results
+// are returned as untyped nils when there's nothing in them.
+func shapeEntryPoints(shape *Shape) map[string]func() (any, error) {
+	return map[string]func() (any, error) {
+		"Rasterize": func() (any, error) { return shape.Rasterize() },
+		"RasterizeFract": func() (any, error) { return shape.RasterizeFract(5, 5) },
+		"RasterizeGray": func() (any, error) { return shape.RasterizeGray(0, 0) },
+		"RasterizeOpts": func() (any, error) { return shape.RasterizeOpts(RasterizeOptions{ FillRule: FillEvenOdd }) },
+		"RasterizeClipped": func() (any, error) { return shape.RasterizeClipped(image.Rect(0, 0, 8, 8), 0, 0) },
+		"RasterizeParallel": func() (any, error) { return shape.RasterizeParallel(2) },
+		"RasterizeGroup": func() (any, error) { return RasterizeGroup([]GroupEntry{ { Shape: shape } }) },
+		"PaintChecked": func() (any, error) { return shape.PaintChecked(color.White, color.Black) },
+		"PaintAlphaChecked": func() (any, error) { return shape.PaintAlphaChecked(color.White) },
+		"PaintNRGBAChecked": func() (any, error) { return shape.PaintNRGBAChecked(color.White, color.Black) },
+		"Paint64Checked": func() (any, error) { return shape.Paint64Checked(color.White, color.Black) },
+		"RasterizeInto": func() (any, error) {
+			rect, err := shape.RasterizeInto(&image.Alpha{}, 0, 0)
+			if rect.Empty() { return nil, err }
+			return rect, err
+		},
+		"Coverage": func() (any, error) {
+			cov, _, err := shape.Coverage()
+			if cov == nil { return nil, err }
+			return cov, err
+		},
+		"Bitmap": func() (any, error) {
+			bits, _, _, err := shape.Bitmap(128)
+			if bits == nil { return nil, err }
+			return bits, err
+		},
+		"SDF": func() (any, error) {
+			sdf, _, err := shape.SDF(2)
+			if sdf == nil { return nil, err }
+			return sdf, err
+		},
+		"MSDF": func() (any, error) {
+			msdf, _, err := shape.MSDF(2)
+			if msdf == nil { return nil, err }
+			return msdf, err
+		},
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	overflow := New()
+	testTriangle(&overflow)
+	overflow.LineTo(1 << 30, 0)
+	invalid := New()
+	testTriangle(&invalid)
+	invalid.SetSegment(2, sfnt.Segment{ Op: 9 })
+	var cmdErr *InvalidCommandError
+	if !errors.As(invalid.Err(), &cmdErr) || cmdErr.Index != 2 || cmdErr.Op != 9 {
+		t.Fatalf("expected *InvalidCommandError at index 2, got %v", invalid.Err())
+	}
+
+	for _, test := range []struct{ shape *Shape; target error }{
+		{ &overflow, ErrCoordinateOverflow },
+		{ &invalid, ErrInvalidCommand },
+	} {
+		for name, fn := range shapeEntryPoints(test.shape) {
+			if _, err := fn(); !errors.Is(err, test.target) {
+				t.Fatalf("%s: expected %v, got %v", name, test.target, err)
+			}
+		}
+		if _, err := test.shape.Freeze().Rasterize(); !errors.Is(err, test.target) {
+			t.Fatalf("Freeze: expected %v, got %v", test.target, err)
+		}
+	}
+
+	// package-level functions check the ops too
+	outline := invalid.Segments()
+	rasterizer := vector.NewRasterizer(0, 0)
+	checks := map[string]error{}
+	_, checks["Rasterize"] = Rasterize(outline, rasterizer, 0, 0)
+	_, checks["RasterizeInto"] = RasterizeInto(&image.Alpha{}, outline, rasterizer, 0, 0)
+	_, checks["RasterizeGray"] = RasterizeGray(outline, rasterizer, 0, 0)
+	_, checks["RasterizePhases"] = RasterizePhases(outline, rasterizer, 2, 2)
+	_, checks["RasterizeClipped"] = RasterizeClipped(outline, rasterizer, image.Rect(0, -64, 64, 0), 0, 0)
+	_, checks["RasterizeWithOptions"] = RasterizeWithOptions(outline, rasterizer, RasterizeOptions{})
+	_, checks["RasterizeParallel"] = RasterizeParallel(outline, 2)
+	checks["AccumulateInto"] = AccumulateInto(image.NewAlpha(image.Rect(0, -64, 64, 0)), outline, rasterizer, 0, 0, AccumulateAdd)
+	other := New()
+	if err := other.ReplaceSubpath(0, &invalid); !errors.Is(err, ErrInvalidCommand) {
+		t.Fatalf("ReplaceSubpath: expected ErrInvalidCommand, got %v", err)
+	}
+	for name, err := range checks {
+		if !errors.As(err, &cmdErr) || cmdErr.Index != 2 { t.Fatalf("%s: expected *InvalidCommandError, got %v", name, err) }
+	}
+
+	// empty shapes
+	SetNothingToDrawErrors(true)
+	defer SetNothingToDrawErrors(false)
+	var empty Shape
+	if _, err := empty.Rasterize(); !errors.Is(err, ErrEmptyShape) || !errors.Is(err, ErrNothingToDraw) {
+		t.Fatalf("expected ErrEmptyShape, got %v", err)
+	}
+}
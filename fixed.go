@@ -42,3 +42,11 @@ func fixedFromFloat64(value float64) fixed.Int26_6 {
 	if value - fp64Approx >= 0.0078125 { fractApprox += 1 }
 	return fractApprox
 }
+
+// Returns value*num/den rounded to the nearest integer, with ties
+// away from zero. den must be positive.
+func fixedMulDivRound(value, num fixed.Int26_6, den int64) fixed.Int26_6 {
+	product := int64(value)*int64(num)
+	if product < 0 { return -fixed.Int26_6((-product + den/2)/den) }
+	return fixed.Int26_6((product + den/2)/den)
+}
@@ -0,0 +1,101 @@
+package sfntshape
+
+import "math"
+
+// Creates a square of the given size at (0, 0).
+func testSquare(size int) Shape {
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.LineTo(size, 0)
+	shape.LineTo(size, size)
+	shape.LineTo(0, size)
+	shape.LineTo(0, 0)
+	return shape
+}
+
+// Appends a small closed triangle.
+func testTriangle(shape *Shape) {
+	shape.MoveTo(0, 0)
+	shape.LineTo(30, 0)
+	shape.LineTo(15, 30)
+	shape.LineTo(0, 0)
+}
+
+// Creates a polygon approximating a circle with the given number of sides.
+func testPolygon(radius float64, sides int) Shape {
+	shape := New()
+	shape.MoveTo(int(radius), 0)
+	for i := 1; i < sides; i++ {
+		angle := 2*math.Pi*float64(i)/float64(sides)
+		shape.LineToFract(fixedFromFloat64(radius*math.Cos(angle)), fixedFromFloat64(radius*math.Sin(angle)))
+	}
+	shape.LineTo(int(radius), 0)
+	return shape
+}
+
+// Five-pointed self-intersecting star centered at (0, 0).
+func testStar(radius float64) Shape {
+	shape := New()
+	for i := 0; i < 5; i++ {
+		angle := math.Pi/2 + float64(i*2)*(2*math.Pi/5)
+		x := fixedFromFloat64(radius*math.Cos(angle))
+		y := fixedFromFloat64(radius*math.Sin(angle))
+		if i == 0 {
+			shape.MoveToFract(x, y)
+		} else {
+			shape.LineToFract(x, y)
+		}
+	}
+	shape.LineToFract(0, fixedFromFloat64(radius))
+	return shape
+}
+
+// Appends a counter-clockwise circle made of four cubic curves.
+func testCircle(shape *Shape, cx, cy, radius float64) {
+	appendTestCircle(shape, cx, cy, radius, 1)
+}
+
+// Like testCircle, but the circle winds in the opposite direction.
+func testReversedCircle(shape *Shape, cx, cy, radius float64) {
+	appendTestCircle(shape, cx, cy, radius, -1)
+}
+
+func appendTestCircle(shape *Shape, cx, cy, radius float64, dir float64) {
+	const k = 0.5522847498
+	pt := func(x, y float64) (Fract, Fract) {
+		return fixedFromFloat64(cx + x*radius), fixedFromFloat64(cy + dir*y*radius)
+	}
+	cube := func(x1, y1, x2, y2, x3, y3 float64) {
+		ax, ay := pt(x1, y1)
+		bx, by := pt(x2, y2)
+		ex, ey := pt(x3, y3)
+		shape.CubeToFract(ax, ay, bx, by, ex, ey)
+	}
+	shape.MoveToFract(pt(1, 0))
+	cube(1, k, k, 1, 0, 1)
+	cube(-k, 1, -1, k, -1, 0)
+	cube(-1, -k, -k, -1, 0, -1)
+	cube(k, -1, 1, -k, 1, 0)
+}
+
+// A big ring with a wavy outer edge, spanning roughly size x size.
+func bigTestShape(size int) Shape {
+	shape := New()
+	testRing(&shape, size)
+	return shape
+}
+
+// Appends the subpaths of [bigTestShape]() to the given shape.
+func testRing(shape *Shape, size int) {
+	half := size/2
+	shape.MoveTo(0, half)
+	shape.CubeTo(half/2, half + half/3, half, half/2, half, 0)
+	shape.QuadTo(half, -half, 0, -half)
+	shape.CubeTo(-half/2, -half - half/4, -half, -half/2, -half, 0)
+	shape.QuadTo(-half, half, 0, half)
+	shape.MoveTo(0, half/3)
+	shape.QuadTo(-half/3, half/3, -half/3, 0)
+	shape.QuadTo(-half/3, -half/3, 0, -half/3)
+	shape.QuadTo(half/3, -half/3, half/3, 0)
+	shape.QuadTo(half/3, half/3, 0, half/3)
+}
@@ -0,0 +1,119 @@
+package sfntshape
+
+import "strconv"
+import "strings"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Default maximum number of segments listed by [Shape.String]().
+const DefaultStringLimit = 32
+
+// Returns a multi-line, human-readable representation of the shape,
+// mostly intended for debugging and test failure messages. See
+// [Shape.StringN]() for details on the format.
+func (self *Shape) String() string {
+	return self.StringN(DefaultStringLimit)
+}
+
+// Like [Shape.String](), but listing at most the given number of
+// segments. Remaining segments are summarized in a final "... N more
+// segments" line. A negative limit means no limit.
+//
+// The first line is a header with the segment count, subpath count
+// (see [Shape.NumSubpaths]()) and bounds of the shape. Each following
+// line describes a segment with its op name and coordinates in pixels,
+// exactly as stored (this means that the effects of [Shape.SetScale]()
+// and [Shape.InvertY]() are already applied). Subpaths are separated by blank lines:
+//   Shape{segments: 3, subpaths: 1, bounds: (0, -8)-(8, 0)}
+//   MoveTo (0, 0)
+//   LineTo (8, -8)
+//   QuadTo (8, 0) (0, 0)
+func (self *Shape) StringN(limit int) string {
+	var builder strings.Builder
+	builder.WriteString("Shape{segments: ")
+	builder.WriteString(strconv.Itoa(len(self.segments)))
+	builder.WriteString(", subpaths: ")
+	builder.WriteString(strconv.Itoa(countSubpaths(self.segments)))
+	builder.WriteString(", bounds: ")
+	if len(self.segments) == 0 {
+		builder.WriteString("empty")
+	} else {
+		bounds := self.bounds
+		writePoint(&builder, bounds.Min)
+		builder.WriteByte('-')
+		writePoint(&builder, bounds.Max)
+	}
+	builder.WriteByte('}')
+
+	for i, segment := range self.segments {
+		if limit >= 0 && i >= limit {
+			builder.WriteString("\n... ")
+			builder.WriteString(strconv.Itoa(len(self.segments) - i))
+			builder.WriteString(" more segments")
+			break
+		}
+		if i > 0 && segment.Op == sfnt.SegmentOpMoveTo {
+			builder.WriteByte('\n')
+		}
+		builder.WriteByte('\n')
+		writeSegment(&builder, segment)
+	}
+	return builder.String()
+}
+
+// Counts the number of subpaths in the given segments that contain any
+// drawing commands, as described in [Shape.NumSubpaths]().
+func countSubpaths(segments []sfnt.Segment) int {
+	count := 0
+	drawing := false // whether the current subpath has been counted
+	for _, segment := range segments {
+		if segment.Op == sfnt.SegmentOpMoveTo {
+			drawing = false
+		} else if !drawing {
+			count += 1
+			drawing = true
+		}
+	}
+	return count
+}
+
+// Returns the name of the given op, as used by [Shape.String]():
+// "MoveTo", "LineTo", "QuadTo" or "CubeTo". Unknown ops are formatted
+// as "op(N)", with N being the op value.
+func OpName(op sfnt.SegmentOp) string {
+	switch op {
+	case sfnt.SegmentOpMoveTo: return "MoveTo"
+	case sfnt.SegmentOpLineTo: return "LineTo"
+	case sfnt.SegmentOpQuadTo: return "QuadTo"
+	case sfnt.SegmentOpCubeTo: return "CubeTo"
+	default:
+		return "op(" + strconv.Itoa(int(op)) + ")"
+	}
+}
+
+// Returns a human-readable representation of the segment, like the
+// lines of [Shape.String](): the op name (see [OpName]()) followed by
+// the points the op uses, in pixels, e.g. "QuadTo (8, 0) (0, 0.5)".
+// Unknown ops are formatted without points.
+func FormatSegment(segment sfnt.Segment) string {
+	var builder strings.Builder
+	writeSegment(&builder, segment)
+	return builder.String()
+}
+
+func writeSegment(builder *strings.Builder, segment sfnt.Segment) {
+	builder.WriteString(OpName(segment.Op))
+	for i := 0; i < segmentArgsCount(segment.Op); i++ {
+		builder.WriteByte(' ')
+		writePoint(builder, segment.Args[i])
+	}
+}
+
+func writePoint(builder *strings.Builder, point fixed.Point26_6) {
+	builder.WriteByte('(')
+	builder.WriteString(strconv.FormatFloat(fixedToF64(point.X), 'f', -1, 64))
+	builder.WriteString(", ")
+	builder.WriteString(strconv.FormatFloat(fixedToF64(point.Y), 'f', -1, 64))
+	builder.WriteByte(')')
+}
@@ -0,0 +1,96 @@
+package sfntshape
+
+import "testing"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+func TestShapeString(t *testing.T) {
+	shape := New()
+	want := "Shape{segments: 0, subpaths: 0, bounds: empty}"
+	if got := shape.String(); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	shape.MoveTo(0, 0)
+	shape.LineTo(8, 8)
+	shape.QuadTo(8, 0, 0, 0)
+	shape.MoveToFract(16, 0)
+	shape.CubeToFract(32, 64, 96, 128, 160, 0)
+	want = "Shape{segments: 5, subpaths: 2, bounds: (0, -8)-(8, 0)}\n" +
+		"MoveTo (0, 0)\n" +
+		"LineTo (8, -8)\n" +
+		"QuadTo (8, 0) (0, 0)\n" +
+		"\n" +
+		"MoveTo (0.25, 0)\n" +
+		"CubeTo (0.5, -1) (1.5, -2) (2.5, 0)"
+	if got := shape.String(); got != want {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	want = "Shape{segments: 5, subpaths: 2, bounds: (0, -8)-(8, 0)}\n" +
+		"MoveTo (0, 0)\n" +
+		"LineTo (8, -8)\n" +
+		"... 3 more segments"
+	if got := shape.StringN(2); got != want {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	// MoveTo-only subpaths are not counted, like in NumSubpaths
+	shape.MoveTo(1, 1)
+	want = "Shape{segments: 6, subpaths: 2, bounds: (0, -8)-(8, 0)}\n... 6 more segments"
+	if got := shape.StringN(0); got != want || shape.NumSubpaths() != 2 {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestFormatSegment(t *testing.T) {
+	for _, test := range []struct{ op sfnt.SegmentOp; want string }{
+		{ sfnt.SegmentOpMoveTo, "MoveTo" }, { sfnt.SegmentOpLineTo, "LineTo" },
+		{ sfnt.SegmentOpQuadTo, "QuadTo" }, { sfnt.SegmentOpCubeTo, "CubeTo" },
+		{ 7, "op(7)" },
+	} {
+		if got := OpName(test.op); got != test.want { t.Fatalf("expected %q, got %q", test.want, got) }
+	}
+
+	args := [3]fixed.Point26_6{ { X: 32, Y: -64 }, { X: 1, Y: 640 }, { X: -3*64 - 16, Y: 0 } }
+	for _, test := range []struct{ op sfnt.SegmentOp; want string }{
+		{ sfnt.SegmentOpMoveTo, "MoveTo (0.5, -1)" },
+		{ sfnt.SegmentOpLineTo, "LineTo (0.5, -1)" },
+		{ sfnt.SegmentOpQuadTo, "QuadTo (0.5, -1) (0.015625, 10)" },
+		{ sfnt.SegmentOpCubeTo, "CubeTo (0.5, -1) (0.015625, 10) (-3.25, 0)" },
+		{ 200, "op(200)" },
+	} {
+		if got := FormatSegment(sfnt.Segment{ Op: test.op, Args: args }); got != test.want {
+			t.Fatalf("expected %q, got %q", test.want, got)
+		}
+	}
+
+	shape := New()
+	testTriangle(&shape)
+	shape.SetSegment(3, sfnt.Segment{ Op: 9 })
+	want := "Shape{segments: 4, subpaths: 1, bounds: (0, -30)-(30, 0)}\n" +
+		"MoveTo (0, 0)\n" +
+		"LineTo (30, 0)\n" +
+		"LineTo (15, -30)\n" +
+		"op(9)"
+	if got := shape.String(); got != want { t.Fatalf("expected:\n%s\ngot:\n%s", want, got) }
+	want = "sfntshape: invalid segment op(9) at index 3"
+	if got := shape.Err().Error(); got != want { t.Fatalf("expected %q, got %q", want, got) }
+}
+
+func TestShapeDiff(t *testing.T) {
+	a, b := New(), New()
+	testTriangle(&a)
+	testTriangle(&b)
+	if diff := a.Diff(&b, 0); diff != "" { t.Fatalf("expected no differences, got %q", diff) }
+	b.SetSegment(2, sfnt.Segment{ Op: sfnt.SegmentOpLineTo, Args: [3]fixed.Point26_6{ { X: 15*64, Y: -30*64 - 32 } } })
+	want := "segment 2: LineTo (15, -30) != LineTo (15, -30.5)"
+	if diff := a.Diff(&b, 0); diff != want { t.Fatalf("expected %q, got %q", want, diff) }
+	if diff := a.Diff(&b, 32); diff != "" { t.Fatalf("expected no differences within tolerance, got %q", diff) }
+	b.Pop()
+	b.Pop()
+	want = "segment 2: LineTo (15, -30) != none"
+	if diff := a.Diff(&b, 0); diff != want { t.Fatalf("expected %q, got %q", want, diff) }
+	if diff := b.Diff(&a, 0); diff != "segment 2: none != LineTo (15, -30)" { t.Fatalf("unexpected diff %q", diff) }
+}
@@ -0,0 +1,77 @@
+package sfntshape
+
+import "image"
+import "image/color"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// An immutable snapshot of a [Shape], created with [Shape.Freeze]().
+// Frozen shapes have no mutating methods and always rasterize with
+// pooled rasterizers, so they can be shared and used by multiple
+// goroutines at once without any synchronization.
+type FrozenShape struct {
+	shape Shape // private copy, never modified after Freeze
+}
+
+// Returns an immutable snapshot of the shape, which can be safely
+// shared across goroutines (see [FrozenShape]). The segments are copied
+// once, so later changes to the shape don't affect the snapshot. The
+// opacity, linear blending, deterministic mode and any recorded error
+// are kept, but custom rasterizers are not (frozen shapes always use
+// pooled rasterizers), and neither are positions, anchors or change
+// tracking.
+func (self *Shape) Freeze() *FrozenShape {
+	return &FrozenShape{ shape: Shape {
+		segments: append(make([]sfnt.Segment, 0, len(self.segments)), self.segments...),
+		bounds: self.bounds,
+		err: self.err,
+		linearBlending: self.linearBlending,
+		deterministic: self.deterministic,
+		transparency: self.transparency,
+	}}
+}
+
+// Returns a copy of the frozen segments. See also [FrozenShape.Segment]()
+// to read segments without copying them all.
+func (self *FrozenShape) Segments() sfnt.Segments {
+	return append(make([]sfnt.Segment, 0, len(self.shape.segments)), self.shape.segments...)
+}
+
+// Returns the segment at the given index.
+func (self *FrozenShape) Segment(index int) sfnt.Segment { return self.shape.segments[index] }
+
+// Like [Shape.Len]().
+func (self *FrozenShape) Len() int { return len(self.shape.segments) }
+
+// Like [Shape.IsEmpty]().
+func (self *FrozenShape) IsEmpty() bool { return self.shape.IsEmpty() }
+
+// Returns the error the shape had recorded when frozen, if any.
+// See [Shape.Err]().
+func (self *FrozenShape) Err() error { return self.shape.err }
+
+// Like [Shape.Bounds]().
+func (self *FrozenShape) Bounds() fixed.Rectangle26_6 { return self.shape.bounds }
+
+// Like [Shape.Hash](). Frozen shapes hash the same as the shapes
+// they were created from (at the time of freezing).
+func (self *FrozenShape) Hash() uint64 { return self.shape.Hash() }
+
+// Like [Shape.Rasterize]().
+func (self *FrozenShape) Rasterize() (*image.Alpha, error) { return self.shape.Rasterize() }
+
+// Like [Shape.RasterizeFract]().
+func (self *FrozenShape) RasterizeFract(offsetX, offsetY Fract) (*image.Alpha, error) {
+	return self.shape.RasterizeFract(offsetX, offsetY)
+}
+
+// Like [Shape.Paint]().
+func (self *FrozenShape) Paint(drawColor, backColor color.Color) *image.RGBA {
+	return self.shape.Paint(drawColor, backColor)
+}
+
+// Like [Shape.PaintChecked]().
+func (self *FrozenShape) PaintChecked(drawColor, backColor color.Color) (*image.RGBA, error) {
+	return self.shape.PaintChecked(drawColor, backColor)
+}
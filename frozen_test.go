@@ -0,0 +1,67 @@
+package sfntshape
+
+import "sync"
+import "bytes"
+import "testing"
+import "image/color"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/vector"
+
+func TestFrozenShape(t *testing.T) {
+	shape := New()
+	testTriangle(&shape)
+	shape.SetOpacity(0.5)
+	expected, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	expectedRGBA := shape.Paint(color.White, color.Black)
+	hash := shape.Hash()
+
+	frozen := shape.Freeze()
+	if frozen.Hash() != hash || frozen.Len() != shape.Len() || frozen.IsEmpty() {
+		t.Fatal("frozen shape doesn't match the original")
+	}
+	if frozen.Bounds() != shape.Segments().Bounds() { t.Fatal("unexpected frozen bounds") }
+
+	// mutating the original (or the returned segments) must not matter
+	shape.SetSegment(1, sfnt.Segment{ Op: sfnt.SegmentOpLineTo })
+	shape.LineTo(50, 50)
+	shape.SetRasterizer(vector.NewRasterizer(0, 0))
+	segments := frozen.Segments()
+	segments[2].Args[0].X = 0
+	if frozen.Hash() != hash || frozen.Len() != 4 { t.Fatal("frozen shape changed after freezing") }
+	if frozen.Segment(2).Args[0].X == 0 { t.Fatal("Segments didn't return a copy") }
+
+	// concurrent use
+	var group sync.WaitGroup
+	errs := make(chan string, 16)
+	for i := 0; i < 8; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for j := 0; j < 20; j++ {
+				mask, err := frozen.Rasterize()
+				if err != nil {
+					errs <- err.Error()
+					return
+				}
+				rgba := frozen.Paint(color.White, color.Black)
+				if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) || !bytes.Equal(rgba.Pix, expectedRGBA.Pix) {
+					errs <- "concurrent rasterization mismatch"
+					return
+				}
+			}
+		}()
+	}
+	group.Wait()
+	close(errs)
+	for msg := range errs { t.Fatal(msg) }
+
+	// recorded errors are kept
+	var broken Shape
+	broken.SetStrict(true)
+	broken.LineTo(1, 1)
+	if _, err := broken.Freeze().Rasterize(); err == nil || err != broken.Err() {
+		t.Fatalf("expected the recorded error, got %v", err)
+	}
+}
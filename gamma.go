@@ -0,0 +1,119 @@
+package sfntshape
+
+import "math"
+import "sync"
+import "sync/atomic"
+import "image/color"
+
+// Recommended gamma for [RasterizeOptions].Gamma when drawing dark shapes
+// on light backgrounds (e.g., text-like uses). It makes thin antialiased
+// features look less washed out. For light shapes on dark backgrounds,
+// values below 1 (e.g. 1/1.43) can be used instead.
+const RecommendedDarkOnLightGamma = 1.43
+
+type gammaLUT struct {
+	gamma float64
+	table [256]uint8
+}
+
+// Single-entry cache, as most programs use a single gamma value.
+var cachedGammaLUT atomic.Value // *gammaLUT
+
+// Returns the lookup table mapping coverage values c to pow(c, 1/gamma).
+func getGammaLUT(gamma float64) *[256]uint8 {
+	if lut, ok := cachedGammaLUT.Load().(*gammaLUT); ok && lut.gamma == gamma {
+		return &lut.table
+	}
+
+	lut := &gammaLUT{ gamma: gamma }
+	invGamma := 1.0/gamma
+	for i := 0; i < 256; i++ {
+		lut.table[i] = uint8(math.Round(255*math.Pow(float64(i)/255.0, invGamma)))
+	}
+	cachedGammaLUT.Store(lut)
+	return &lut.table
+}
+
+// Applies the gamma correction LUT to the given coverage values.
+func applyGamma(pix []uint8, gamma float64) {
+	lut := getGammaLUT(gamma)
+	for i, value := range pix { pix[i] = lut[value] }
+}
+
+// Linear light values for each 8-bit sRGB value, in [0, 1].
+var srgb8ToLinear [256]float64
+
+func init() {
+	for i := range srgb8ToLinear { srgb8ToLinear[i] = srgbToLinear(float64(i)/255.0) }
+}
+
+// 16-bit sRGB values for each 16-bit linear light value. Built on first
+// use by linearPaintLUT, as it's 128KiB.
+var linearToSRGB16 *[65536]uint16
+var linearToSRGB16Once sync.Once
+
+func getLinearToSRGB16() *[65536]uint16 {
+	linearToSRGB16Once.Do(func() {
+		table := new([65536]uint16)
+		for i := range table {
+			table[i] = uint16(math.Round(65535*linearToSRGB(float64(i)/65535.0)))
+		}
+		linearToSRGB16 = table
+	})
+	return linearToSRGB16
+}
+
+func srgbToLinear(value float64) float64 {
+	if value <= 0.04045 { return value/12.92 }
+	return math.Pow((value + 0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) float64 {
+	if value <= 0.0031308 { return value*12.92 }
+	return 1.055*math.Pow(value, 1/2.4) - 0.055
+}
+
+// Converts a 16-bit sRGB value to linear light, using the 8-bit LUT
+// for 8-bit values.
+func decodeSRGB16(value uint32) float64 {
+	if value % 0x101 == 0 { return srgb8ToLinear[value/0x101] }
+	return srgbToLinear(float64(value)/65535.0)
+}
+
+// Like paintLUT, but blending in linear light: the non-premultiplied
+// colors are converted to linear light, composited with source-over
+// there, and the result converted back to premultiplied sRGB. Pixels
+// with only one of the colors keep its exact value, without going
+// through the conversions.
+func linearPaintLUT(drawColor color.Color, back color.RGBA64) [256][4]uint16 {
+	var lut [256][4]uint16
+	src := color.NRGBA64Model.Convert(drawColor).(color.NRGBA64)
+	dst := color.NRGBA64Model.Convert(back).(color.NRGBA64)
+	srcLin := [3]float64{ decodeSRGB16(uint32(src.R)), decodeSRGB16(uint32(src.G)), decodeSRGB16(uint32(src.B)) }
+	dstLin := [3]float64{ decodeSRGB16(uint32(dst.R)), decodeSRGB16(uint32(dst.G)), decodeSRGB16(uint32(dst.B)) }
+	srcAlpha, dstAlpha := float64(src.A)/65535.0, float64(dst.A)/65535.0
+	encode := getLinearToSRGB16()
+	srcPremult := color.RGBA64Model.Convert(drawColor).(color.RGBA64)
+	for i := range lut {
+		if i == 0 || src.A == 0 {
+			lut[i] = [4]uint16{ back.R, back.G, back.B, back.A }
+			continue
+		}
+		if i == 255 && src.A == 0xFFFF {
+			lut[i] = [4]uint16{ srcPremult.R, srcPremult.G, srcPremult.B, srcPremult.A }
+			continue
+		}
+		alpha := srcAlpha*float64(i)/255.0
+		backAlpha := dstAlpha*(1 - alpha)
+		outAlpha := alpha + backAlpha
+		if outAlpha <= 0 { continue }
+		out := &lut[i]
+		for c := 0; c < 3; c++ {
+			linear := (srcLin[c]*alpha + dstLin[c]*backAlpha)/outAlpha
+			srgb := float64(encode[int(math.Round(linear*65535))])
+			out[c] = uint16(math.Round(srgb*outAlpha))
+		}
+		out[3] = uint16(math.Round(outAlpha*65535))
+	}
+	return lut
+}
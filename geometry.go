@@ -0,0 +1,182 @@
+package sfntshape
+
+import "math"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Default tolerance (in pixels) used to flatten curves into polylines
+// for geometric queries like [Shape.Contains]().
+const flattenTolerance = 1.0/32.0
+
+// A point with float64 coordinates, in pixels.
+type PointF struct { X, Y float64 }
+
+func pointFromFixed(point fixed.Point26_6) PointF {
+	return PointF{ fixedToF64(point.X), fixedToF64(point.Y) }
+}
+
+// Flattens the given segments into polylines, one per subpath, with
+// curves approximated by line segments deviating at most tolerance
+// pixels from them. Polylines are not explicitly closed.
+func flattenSegments(segments []sfnt.Segment, tolerance float64) [][]PointF {
+	var contours [][]PointF
+	var current []PointF
+	var pen PointF
+	for _, segment := range segments {
+		if segment.Op == sfnt.SegmentOpMoveTo {
+			if len(current) > 0 { contours = append(contours, current) }
+			pen = pointFromFixed(segment.Args[0])
+			current = []PointF{ pen }
+			continue
+		}
+
+		if len(current) == 0 { current = append(current, pen) }
+		current, pen = flattenSegment(current, pen, segment, tolerance)
+	}
+	if len(current) > 0 { contours = append(contours, current) }
+	return contours
+}
+
+// Appends the points approximating the given drawing segment starting
+// at pen (excluding pen itself) to dst, and returns the extended slice
+// and the new pen position. MoveTo segments are ignored.
+func flattenSegment(dst []PointF, pen PointF, segment sfnt.Segment, tolerance float64) ([]PointF, PointF) {
+	switch segment.Op {
+	case sfnt.SegmentOpLineTo:
+		pen = pointFromFixed(segment.Args[0])
+		dst = append(dst, pen)
+	case sfnt.SegmentOpQuadTo:
+		ctrl, end := pointFromFixed(segment.Args[0]), pointFromFixed(segment.Args[1])
+		dx, dy := pen.X - 2*ctrl.X + end.X, pen.Y - 2*ctrl.Y + end.Y
+		n := flattenSteps(2*math.Hypot(dx, dy), tolerance)
+		for i := 1; i <= n; i++ {
+			t := float64(i)/float64(n)
+			dst = append(dst, quadPoint(pen, ctrl, end, t))
+		}
+		pen = end
+	case sfnt.SegmentOpCubeTo:
+		c1, c2 := pointFromFixed(segment.Args[0]), pointFromFixed(segment.Args[1])
+		end := pointFromFixed(segment.Args[2])
+		d1 := math.Hypot(pen.X - 2*c1.X + c2.X, pen.Y - 2*c1.Y + c2.Y)
+		d2 := math.Hypot(c1.X - 2*c2.X + end.X, c1.Y - 2*c2.Y + end.Y)
+		n := flattenSteps(6*math.Max(d1, d2), tolerance)
+		for i := 1; i <= n; i++ {
+			t := float64(i)/float64(n)
+			dst = append(dst, cubePoint(pen, c1, c2, end, t))
+		}
+		pen = end
+	}
+	return dst, pen
+}
+
+// Returns the number of uniform steps needed to approximate a curve whose
+// second derivative is bounded by maxSecondDeriv within the tolerance.
+func flattenSteps(maxSecondDeriv, tolerance float64) int {
+	n := int(math.Ceil(math.Sqrt(maxSecondDeriv/(8*tolerance))))
+	if n < 1 { return 1 }
+	return n
+}
+
+func quadPoint(a, b, c PointF, t float64) PointF {
+	u := 1 - t
+	return PointF{
+		u*u*a.X + 2*u*t*b.X + t*t*c.X,
+		u*u*a.Y + 2*u*t*b.Y + t*t*c.Y,
+	}
+}
+
+func cubePoint(a, b, c, d PointF, t float64) PointF {
+	u := 1 - t
+	return PointF{
+		u*u*u*a.X + 3*u*u*t*b.X + 3*u*t*t*c.X + t*t*t*d.X,
+		u*u*u*a.Y + 3*u*u*t*b.Y + 3*u*t*t*c.Y + t*t*t*d.Y,
+	}
+}
+
+// Returns the winding number of the given point with respect to the
+// polylines, which are considered implicitly closed.
+func windingNumber(contours [][]PointF, x, y float64) int {
+	winding := 0
+	for _, contour := range contours {
+		n := len(contour)
+		for i := 0; i < n; i++ {
+			a, b := contour[i], contour[(i + 1) % n]
+			if a.Y <= y {
+				if b.Y > y && crossSign(a, b, x, y) > 0 { winding += 1 }
+			} else {
+				if b.Y <= y && crossSign(a, b, x, y) < 0 { winding -= 1 }
+			}
+		}
+	}
+	return winding
+}
+
+// Returns a positive value if (x, y) is to the left of the line a->b,
+// negative if it's to the right, and zero if it's on the line.
+func crossSign(a, b PointF, x, y float64) float64 {
+	return (b.X - a.X)*(y - a.Y) - (x - a.X)*(b.Y - a.Y)
+}
+
+// Returns the distance from (x, y) to the segment a->b.
+func segmentDistance(a, b PointF, x, y float64) float64 {
+	dx, dy := b.X - a.X, b.Y - a.Y
+	lenSq := dx*dx + dy*dy
+	t := 0.0
+	if lenSq > 0 {
+		t = ((x - a.X)*dx + (y - a.Y)*dy)/lenSq
+		if t < 0 { t = 0 } else if t > 1 { t = 1 }
+	}
+	return math.Hypot(x - (a.X + t*dx), y - (a.Y + t*dy))
+}
+
+// Returns whether the point (x, y) is inside the shape according to the
+// non-zero fill rule. Coordinates are in pixels and in the same space as
+// the stored segments and rasterized masks (so, for example, the center
+// of the mask pixel at (x, y) is at (x + 0.5, y + 0.5)). Subpaths are
+// considered implicitly closed and curves are approximated with a
+// tolerance of 1/32 pixels.
+func (self *Shape) Contains(x, y float64) bool {
+	contours := flattenSegments(self.segments, flattenTolerance)
+	return windingNumber(contours, x, y) != 0
+}
+
+// Returns the approximate coverage in [0, 1] of the unit pixel centered
+// at (x, y), in the same coordinates as [Shape.Contains]() (so, the mask
+// pixel at (x, y) is centered at (x + 0.5, y + 0.5)). The coverage is
+// the fraction of the samples x samples grid of points evenly spread
+// over the pixel that are inside the shape according to the non-zero
+// fill rule, which approximates the antialiased values produced by
+// [Shape.Rasterize]() better for higher sample counts (the error is at
+// most around 1/(2*samples) per edge crossing the pixel, though notice
+// [vector.Rasterizer] itself is a few alpha steps off for masks under
+// 512 pixels, where it uses fixed point math). Samples < 1 are treated
+// as 1, which is equivalent to Contains(x, y).
+//
+// Unlike rasterizing, this doesn't allocate any mask (only the flattened
+// contours), so it's suitable for hit testing with partial coverage or
+// querying a few pixels of big shapes. Returns 0 if the shape has
+// recorded an error (see [Shape.Err]()), as its segments may not be
+// what was requested.
+func (self *Shape) CoverageAt(x, y float64, samples int) float64 {
+	if self.err != nil { return 0 }
+	if samples < 1 { samples = 1 }
+	contours := flattenSegments(self.segments, flattenTolerance)
+	step := 1.0/float64(samples)
+	minX, minY := x - 0.5 + step/2, y - 0.5 + step/2
+	var crossings []windingCrossing
+	inside := 0
+	for i := 0; i < samples; i++ {
+		crossings = rowCrossings(crossings[ : 0], contours, minY + float64(i)*step)
+		if len(crossings) == 0 { continue }
+		for j := 0; j < samples; j++ {
+			sx := minX + float64(j)*step
+			winding := 0 // sum of dirs of the crossings with x' > sx
+			for _, crossing := range crossings {
+				if crossing.x > sx { winding += crossing.dir }
+			}
+			if winding != 0 { inside += 1 }
+		}
+	}
+	return float64(inside)/float64(samples*samples)
+}
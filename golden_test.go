@@ -0,0 +1,171 @@
+package sfntshape_test
+
+import "flag"
+import "math"
+import "image"
+import "image/color"
+import "testing"
+
+import "github.com/tinne26/sfntshape"
+import "github.com/tinne26/sfntshape/shapetest"
+
+var update = flag.Bool("update", false, "update golden images under testdata/")
+
+func TestGoldenShapes(t *testing.T) {
+	shape := sfntshape.New()
+	shape.MoveTo(0, 50)
+	shape.CubeTo(-25, -25, -25, 25, 0, -50)
+	shape.QuadTo(-50, 0, 0, 50)
+	shape.MoveTo(0, 50)
+	shape.CubeTo(25, -25, 25, 25, 0, -50)
+	shape.QuadTo(50, 0, 0, 50)
+	shapetest.AssertGolden(t, &shape, "testdata/half_moon.png", *update)
+
+	shape.Reset()
+	shape.MoveTo(0, 0)
+	shape.LineTo(32, 0)
+	shape.LineTo(16, 24)
+	shape.LineTo(0, 0)
+	shape.MoveTo(8, 4)
+	shape.LineTo(16, 16)
+	shape.LineTo(24, 4)
+	shape.LineTo(8, 4)
+	shapetest.AssertGolden(t, &shape, "testdata/triangle_hole.png", *update)
+}
+
+func TestCompareMasks(t *testing.T) {
+	a := image.NewAlpha(image.Rect(0, 0, 10, 10))
+	b := image.NewAlpha(image.Rect(0, 0, 10, 10))
+	a.Pix[0], b.Pix[0] = 10, 12
+	if err := shapetest.CompareMasks(a, b, 2, 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := shapetest.CompareMasks(a, b, 1, 0); err == nil {
+		t.Fatal("expected delta error")
+	}
+	if err := shapetest.CompareMasks(a, b, 1, 0.01); err != nil {
+		t.Fatalf("unexpected error with 1%% tolerance: %s", err)
+	}
+
+	c := image.NewAlpha(image.Rect(1, 0, 11, 10))
+	if err := shapetest.CompareMasks(a, c, 255, 1); err == nil {
+		t.Fatal("expected bounds mismatch error")
+	}
+	if err := shapetest.CompareMasks(a, nil, 255, 1); err == nil {
+		t.Fatal("expected bounds mismatch error with nil mask")
+	}
+	if err := shapetest.CompareMasks(nil, nil, 0, 0); err != nil {
+		t.Fatalf("unexpected error comparing nil masks: %s", err)
+	}
+}
+
+func TestGoldenGradients(t *testing.T) {
+	shape := sfntshape.New()
+	roundedRect(&shape, 0, 0, 96, 64, 12)
+	shape.SetDeterministic(true)
+	stops := []sfntshape.GradientStop{
+		{ Offset: 1, Color: color.NRGBA{ 20, 60, 220, 255 } },
+		{ Offset: 0, Color: color.NRGBA{ 240, 40, 40, 255 } },
+		{ Offset: 0.5, Color: color.NRGBA{ 250, 220, 30, 128 } },
+	}
+	bounds := shape.Segments().Bounds()
+	minX, maxX := float64(bounds.Min.X)/64, float64(bounds.Max.X)/64
+	minY, maxY := float64(bounds.Min.Y)/64, float64(bounds.Max.Y)/64
+	gradients := map[string]sfntshape.LinearGradient{
+		"horizontal": { X0: minX, Y0: 0, X1: maxX, Y1: 0, Stops: stops },
+		"diagonal": { X0: minX, Y0: minY, X1: maxX, Y1: maxY, Stops: stops },
+		"repeat": { X0: minX, Y0: minY, X1: minX + 20, Y1: minY + 10, Stops: stops, Spread: sfntshape.SpreadRepeat },
+	}
+	for name, gradient := range gradients {
+		img, err := shape.PaintGradientChecked(gradient, color.Black)
+		if err != nil { t.Fatal(err) }
+		shapetest.AssertGoldenImage(t, img, "testdata/gradient_" + name + ".png", *update)
+	}
+}
+
+// Appends a rounded rectangle with corners approximated by cubic curves.
+func roundedRect(shape *sfntshape.Shape, x, y, width, height, radius int) {
+	k := radius*45/100 // distance from the corner to the control points
+	shape.MoveTo(x + radius, y)
+	shape.LineTo(x + width - radius, y)
+	shape.CubeTo(x + width - k, y, x + width, y + k, x + width, y + radius)
+	shape.LineTo(x + width, y + height - radius)
+	shape.CubeTo(x + width, y + height - k, x + width - k, y + height, x + width - radius, y + height)
+	shape.LineTo(x + radius, y + height)
+	shape.CubeTo(x + k, y + height, x, y + height - k, x, y + height - radius)
+	shape.LineTo(x, y + radius)
+	shape.CubeTo(x, y + k, x + k, y, x + radius, y)
+}
+
+func TestGoldenConic(t *testing.T) {
+	// progress ring: outer circle and a hole in the opposite direction
+	shape := sfntshape.New()
+	circle(&shape, 0, 0, 40, false)
+	circle(&shape, 0, 0, 28, true)
+	shape.SetDeterministic(true)
+	stops := []sfntshape.GradientStop{
+		{ Offset: 0, Color: color.NRGBA{ 30, 200, 90, 255 } },
+		{ Offset: 1, Color: color.NRGBA{ 40, 80, 230, 255 } },
+	}
+	img, err := shape.PaintConicChecked(0, 0, -math.Pi/2, stops, color.Black)
+	if err != nil { t.Fatal(err) }
+	shapetest.AssertGoldenImage(t, img, "testdata/conic_ring.png", *update)
+}
+
+// Appends a circle made of four cubic curves.
+func circle(shape *sfntshape.Shape, cx, cy, radius float64, clockwise bool) {
+	const k = 0.5522847498
+	fract := func(value float64) sfntshape.Fract { return sfntshape.Fract(math.Round(value*64)) }
+	points := [][2]float64{ { 1, k }, { k, 1 }, { 0, 1 }, { -k, 1 }, { -1, k }, { -1, 0 }, { -1, -k }, { -k, -1 }, { 0, -1 }, { k, -1 }, { 1, -k }, { 1, 0 } }
+	flip := 1.0
+	if clockwise { flip = -1 }
+	at := func(i int) (sfntshape.Fract, sfntshape.Fract) {
+		return fract(cx + points[i][0]*radius), fract(cy + flip*points[i][1]*radius)
+	}
+	shape.MoveToFract(fract(cx + radius), fract(cy))
+	for i := 0; i < len(points); i += 3 {
+		ax, ay := at(i)
+		bx, by := at(i + 1)
+		ex, ey := at(i + 2)
+		shape.CubeToFract(ax, ay, bx, by, ex, ey)
+	}
+}
+
+func TestGoldenFillStroke(t *testing.T) {
+	shape := sfntshape.New()
+	roundedRect(&shape, 0, 0, 80, 28, 8)
+	shape.SetDeterministic(true)
+	fills := map[string]color.Color{
+		"opaque": color.NRGBA{ 60, 130, 230, 255 },
+		"translucent": color.NRGBA{ 60, 130, 230, 96 },
+	}
+	for name, fill := range fills {
+		img, err := shape.PaintFillStroke(fill, color.NRGBA{ 20, 40, 90, 255 }, 2, color.White)
+		if err != nil { t.Fatal(err) }
+		mask, _ := shape.Rasterize()
+		if !img.Rect.Eq(mask.Rect.Inset(-1)) { t.Fatalf("expected bounds %v, got %v", mask.Rect.Inset(-1), img.Rect) }
+		shapetest.AssertGoldenImage(t, img, "testdata/button_" + name + ".png", *update)
+	}
+}
+
+func TestGoldenHeatmap(t *testing.T) {
+	// two rings, the second one with its hole wound in the wrong direction
+	shape := sfntshape.New()
+	circle(&shape, 24, 24, 20, false)
+	circle(&shape, 24, 24, 11.5, true)
+	circle(&shape, 72, 24, 20, false)
+	circle(&shape, 72, 24, 11.5, false)
+	shape.SetDeterministic(true)
+	img := shape.PaintHeatmapMarkers()
+	if img == nil { t.Fatal("unexpected nil heatmap") }
+	shapetest.AssertGoldenImage(t, img, "testdata/heatmap_rings.png", *update)
+}
+
+func TestGoldenPreview(t *testing.T) {
+	shape := sfntshape.New()
+	circle(&shape, 20, 20, 17.5, false)
+	shape.SetDeterministic(true)
+	img := sfntshape.PaintPreview(&shape, color.NRGBA{ 40, 110, 230, 140 }, 6)
+	if img == nil { t.Fatal("unexpected nil preview") }
+	shapetest.AssertGoldenImage(t, img, "testdata/preview_circle.png", *update)
+}
@@ -0,0 +1,226 @@
+package sfntshape
+
+import "math"
+import "sort"
+import "image"
+import "errors"
+import "image/color"
+
+// How a gradient is extended beyond the ends of its axis.
+type GradientSpread uint8
+const (
+	// The colors of the first and last stops extend indefinitely.
+	// This is the default.
+	SpreadClamp GradientSpread = iota
+
+	// The gradient repeats itself after each end of the axis, so
+	// the last stop is followed by the first one again.
+	SpreadRepeat
+)
+
+// A gradient color stop. Offsets are positions along the gradient's
+// axis, in [0, 1].
+type GradientStop struct {
+	Offset float64
+	Color color.Color
+}
+
+// A linear gradient for [Shape.PaintGradient](). The gradient goes
+// from (X0, Y0), where its offset is 0, to (X1, Y1), where its offset
+// is 1. Coordinates are in pixels and in the same space as the
+// rasterized masks (so, the center of the pixel at (x, y) is at
+// (x + 0.5, y + 0.5), like in [Shape.Contains]()).
+//
+// Colors are interpolated in non-premultiplied RGBA space. Stops don't
+// need to be sorted, but stops with the same offset keep their relative
+// order, which can be used to create hard transitions.
+type LinearGradient struct {
+	X0, Y0, X1, Y1 float64
+	Stops []GradientStop
+	Spread GradientSpread
+}
+
+// Like [Shape.Paint](), but filling the shape with the given gradient
+// instead of a flat color. The gradient's colors are modulated by the
+// coverage of each pixel and composited over backColor.
+//
+// Returns nil if the gradient is invalid (see [LinearGradient.Validate]())
+// or for the same reasons as [Shape.Paint](). Use
+// [Shape.PaintGradientChecked]() if you need to know the cause.
+func (self *Shape) PaintGradient(gradient LinearGradient, backColor color.Color) *image.RGBA {
+	rgba, err := self.PaintGradientChecked(gradient, backColor)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return rgba
+}
+
+// Like [Shape.PaintGradient](), but also returning any validation or
+// rasterization error.
+func (self *Shape) PaintGradientChecked(gradient LinearGradient, backColor color.Color) (*image.RGBA, error) {
+	if err := gradient.Validate(); err != nil { return nil, err }
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+
+	stops := sortStops(gradient.Stops)
+	dx, dy := gradient.X1 - gradient.X0, gradient.Y1 - gradient.Y0
+	lenSq := dx*dx + dy*dy
+	return paintMaskWith(mask, backColor, func(x, y int) color.RGBA64 {
+		var offset float64 // degenerate axes use the first stop
+		if lenSq > 0 {
+			px, py := float64(x) + 0.5 - gradient.X0, float64(y) + 0.5 - gradient.Y0
+			offset = gradient.Spread.apply((px*dx + py*dy)/lenSq)
+		}
+		return premultiply(gradientColorAt(stops, offset))
+	}), nil
+}
+
+// Like paintMask, but with a color per pixel: colorAt returns the
+// premultiplied color for the pixel at (x, y), with the same source-over
+// math as paintMask for its coverage. The background is resolved once
+// and colorAt is only called for pixels with some coverage.
+func paintMaskWith(mask *image.Alpha, backColor color.Color, colorAt func(x, y int) color.RGBA64) *image.RGBA {
+	const m = 0xFFFF
+	back := color.RGBAModel.Convert(backColor).(color.RGBA) // 8-bit, like paintMask
+	br, bg, bb, ba := uint32(back.R)*0x101, uint32(back.G)*0x101, uint32(back.B)*0x101, uint32(back.A)*0x101
+	rgba := image.NewRGBA(mask.Rect)
+	width := mask.Rect.Dx()
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		maskRow := mask.Pix[mask.PixOffset(mask.Rect.Min.X, y) : ][ : width]
+		dstRow  := rgba.Pix[rgba.PixOffset(mask.Rect.Min.X, y) : ][ : width*4]
+		for x, value := range maskRow {
+			d := dstRow[x*4 : x*4 + 4 : x*4 + 4]
+			if value == 0 {
+				d[0], d[1], d[2], d[3] = back.R, back.G, back.B, back.A
+				continue
+			}
+			src := colorAt(mask.Rect.Min.X + x, y)
+			ma := uint32(value)*0x101
+			a := m - (uint32(src.A)*ma/m)
+			d[0] = uint8((br*a + uint32(src.R)*ma)/m >> 8)
+			d[1] = uint8((bg*a + uint32(src.G)*ma)/m >> 8)
+			d[2] = uint8((bb*a + uint32(src.B)*ma)/m >> 8)
+			d[3] = uint8((ba*a + uint32(src.A)*ma)/m >> 8)
+		}
+	}
+	return rgba
+}
+
+func premultiply(nrgba color.NRGBA64) color.RGBA64 {
+	r, g, b, a := nrgba.RGBA()
+	return color.RGBA64{ uint16(r), uint16(g), uint16(b), uint16(a) }
+}
+
+// Returns an error if the gradient has no stops, or any of them has an
+// offset outside [0, 1] or a nil color.
+func (self *LinearGradient) Validate() error {
+	return validateStops(self.Stops)
+}
+
+// Like [Shape.PaintGradient](), but with a conic (or sweep) gradient
+// around (cx, cy), where the color of each pixel depends on the angle of
+// its center around that point. Coordinates are in the same space as
+// the rasterized masks, like in [LinearGradient]. Offset 0 corresponds
+// to startAngle, in radians, and angles grow clockwise on screen (from
+// +X towards +Y in mask coordinates), with offset 1 after a full turn.
+//
+// The gradient wraps around smoothly, interpolating between the last
+// and the first stops, unless there are stops at both offsets 0 and 1,
+// which creates a hard transition at startAngle when their colors
+// differ (e.g., for progress indicators). Notice that colors are
+// evaluated once per pixel, so hard transitions (at the seam or between
+// stops at the same offset) are not antialiased; only the edges of the
+// shape are.
+//
+// Returns nil if the stops are invalid (see [LinearGradient.Validate]())
+// or for the same reasons as [Shape.Paint]().
+func (self *Shape) PaintConic(cx, cy float64, startAngle float64, stops []GradientStop, backColor color.Color) *image.RGBA {
+	rgba, err := self.PaintConicChecked(cx, cy, startAngle, stops, backColor)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return rgba
+}
+
+// Like [Shape.PaintConic](), but also returning any validation or
+// rasterization error.
+func (self *Shape) PaintConicChecked(cx, cy float64, startAngle float64, stops []GradientStop, backColor color.Color) (*image.RGBA, error) {
+	if err := validateStops(stops); err != nil { return nil, err }
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+
+	sorted := sortStops(stops)
+	return paintMaskWith(mask, backColor, func(x, y int) color.RGBA64 {
+		angle := math.Atan2(float64(y) + 0.5 - cy, float64(x) + 0.5 - cx) - startAngle
+		offset := angle/(2*math.Pi)
+		return premultiply(conicColorAt(sorted, offset - math.Floor(offset)))
+	}), nil
+}
+
+func validateStops(stops []GradientStop) error {
+	if len(stops) == 0 { return errors.New("gradient without stops") }
+	for _, stop := range stops {
+		if !(stop.Offset >= 0 && stop.Offset <= 1) { // also catches NaNs
+			return errors.New("gradient stop offset outside [0, 1]")
+		}
+		if stop.Color == nil { return errors.New("gradient stop without color") }
+	}
+	return nil
+}
+
+type nrgbaStop struct {
+	offset float64
+	color color.NRGBA64
+}
+
+// Returns the stops sorted by offset, with their colors converted.
+func sortStops(gradientStops []GradientStop) []nrgbaStop {
+	stops := make([]nrgbaStop, len(gradientStops))
+	for i, stop := range gradientStops {
+		stops[i] = nrgbaStop{ stop.Offset, straightNRGBA64(stop.Color) }
+	}
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].offset < stops[j].offset })
+	return stops
+}
+
+// Maps the given position along the gradient axis to [0, 1].
+func (self GradientSpread) apply(offset float64) float64 {
+	switch self {
+	case SpreadRepeat:
+		return offset - math.Floor(offset)
+	default:
+		if offset < 0 { return 0 }
+		if offset > 1 { return 1 }
+		return offset
+	}
+}
+
+// Returns the interpolated color at the given offset. The stops
+// must be sorted.
+func gradientColorAt(stops []nrgbaStop, offset float64) color.NRGBA64 {
+	if offset <= stops[0].offset { return stops[0].color }
+	for i := 1; i < len(stops); i++ {
+		next := stops[i]
+		if offset >= next.offset { continue }
+		prev := stops[i - 1]
+		return lerpNRGBA64(prev.color, next.color, (offset - prev.offset)/(next.offset - prev.offset))
+	}
+	return stops[len(stops) - 1].color
+}
+
+// Like gradientColorAt, but for offsets in [0, 1) that wrap around:
+// offsets before the first stop or after the last one interpolate
+// between the last and the first stops.
+func conicColorAt(stops []nrgbaStop, offset float64) color.NRGBA64 {
+	first, last := stops[0], stops[len(stops) - 1]
+	if offset >= first.offset && offset < last.offset { return gradientColorAt(stops, offset) }
+	span := first.offset + 1 - last.offset
+	if span <= 0 { return first.color } // unreachable with stops at 0 and 1
+	if offset < first.offset { offset += 1 }
+	return lerpNRGBA64(last.color, first.color, (offset - last.offset)/span)
+}
+
+func lerpNRGBA64(a, b color.NRGBA64, t float64) color.NRGBA64 {
+	lerp := func(a, b uint16) uint16 {
+		return uint16(math.Round(float64(a) + t*(float64(b) - float64(a))))
+	}
+	return color.NRGBA64{ R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: lerp(a.A, b.A) }
+}
@@ -0,0 +1,82 @@
+package sfntshape
+
+import "bytes"
+import "image/color"
+import "testing"
+
+func TestPaintGradient(t *testing.T) {
+	shape := testPolygon(20, 6)
+	red, blue := color.NRGBA{ 255, 0, 0, 255 }, color.NRGBA{ 0, 0, 255, 255 }
+
+	// invalid gradients
+	invalid := []LinearGradient{
+		{ X1: 10 },
+		{ X1: 10, Stops: []GradientStop{ { Offset: 1.5, Color: red } } },
+		{ X1: 10, Stops: []GradientStop{ { Offset: 0.5 } } },
+	}
+	for i, gradient := range invalid {
+		if _, err := shape.PaintGradientChecked(gradient, color.Black); err == nil {
+			t.Fatalf("gradient #%d: expected validation error", i)
+		}
+		if shape.PaintGradient(gradient, color.Black) != nil { t.Fatalf("gradient #%d: expected nil image", i) }
+	}
+
+	// degenerate axis, first stop (after sorting) everywhere
+	stops := []GradientStop{ { Offset: 1, Color: blue }, { Offset: 0, Color: red } }
+	img := shape.PaintGradient(LinearGradient{ X0: 3, Y0: 3, X1: 3, Y1: 3, Stops: stops }, color.Black)
+	if img == nil { t.Fatal("unexpected nil image") }
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{ 255, 0, 0, 255 }) {
+		t.Fatalf("expected the first stop's color on a degenerate axis, got %v", got)
+	}
+
+	// clamped and repeated ends
+	gradient := LinearGradient{ X0: -10, X1: 10, Stops: stops }
+	img = shape.PaintGradient(gradient, color.Black)
+	left, right := img.RGBAAt(-15, 0), img.RGBAAt(15, 0)
+	if left != (color.RGBA{ 255, 0, 0, 255 }) || right != (color.RGBA{ 0, 0, 255, 255 }) {
+		t.Fatalf("unexpected clamped colors %v and %v", left, right)
+	}
+	gradient.Spread = SpreadRepeat
+	img = shape.PaintGradient(gradient, color.Black)
+	if got := img.RGBAAt(10, 0); got.R < 230 || got.B > 25 { // offset 1.025, restarts with red
+		t.Fatalf("expected repeated gradient to restart near red, got %v", got)
+	}
+
+	// single stops composite like Paint
+	translucent, back := color.NRGBA{ 200, 30, 90, 140 }, color.NRGBA{ 10, 220, 40, 100 }
+	flat := shape.PaintGradient(LinearGradient{ X1: 10, Stops: []GradientStop{ { Color: translucent } } }, back)
+	expected := shape.Paint(translucent, back)
+	if flat.Rect != expected.Rect || !bytes.Equal(flat.Pix, expected.Pix) {
+		t.Fatal("expected single stop gradients to match Paint")
+	}
+}
+
+func TestPaintConic(t *testing.T) {
+	shape := testPolygon(20, 32)
+	stops := []GradientStop{
+		{ Offset: 0.00, Color: color.NRGBA{ 255, 0, 0, 255 } },
+		{ Offset: 0.25, Color: color.NRGBA{ 0, 255, 0, 255 } },
+		{ Offset: 0.50, Color: color.NRGBA{ 0, 0, 255, 255 } },
+		{ Offset: 0.75, Color: color.NRGBA{ 255, 255, 255, 255 } },
+	}
+	img := shape.PaintConic(0.5, 0.5, 0, stops, color.Black)
+	if img == nil { t.Fatal("unexpected nil image") }
+	cardinals := map[[2]int]color.RGBA{
+		{ 10, 0 }: { 255, 0, 0, 255 }, // right
+		{ 0, 10 }: { 0, 255, 0, 255 }, // down on screen
+		{ -10, 0 }: { 0, 0, 255, 255 }, // left
+		{ 0, -10 }: { 255, 255, 255, 255 }, // up on screen
+	}
+	for point, expected := range cardinals {
+		if got := img.RGBAAt(point[0], point[1]); got != expected {
+			t.Fatalf("pixel %v: expected %v, got %v", point, expected, got)
+		}
+	}
+
+	// smooth wrapping between the last and first stops
+	wrapped := img.RGBAAt(10, -3) // a bit before offset 1
+	if wrapped.R != 255 || wrapped.G < 30 || wrapped.B < 30 || wrapped.G > 225 {
+		t.Fatalf("expected a mix of white and red near the seam, got %v", wrapped)
+	}
+	if shape.PaintConic(0, 0, 0, nil, color.Black) != nil { t.Fatal("expected nil image without stops") }
+}
@@ -0,0 +1,122 @@
+package sfntshape
+
+import "image"
+import "errors"
+
+// Operations used to combine the shapes of a [RasterizeGroup]() call.
+type GroupOp uint8
+const (
+	// Adds the shape coverage to the accumulated mask, saturating.
+	GroupAdd GroupOp = iota
+
+	// Subtracts the shape coverage from the accumulated mask, saturating
+	// at zero. Subtracting a shape fully contained in the accumulated one
+	// gives the same results as defining it as a hole.
+	GroupSubtract
+
+	// Keeps the minimum of the shape coverage and the accumulated mask.
+	// Regions outside the shape are cleared.
+	GroupIntersect
+
+	// Replaces the accumulated mask with the shape coverage, but only
+	// within the bounds of the shape.
+	GroupReplace
+)
+
+// An entry for [RasterizeGroup]().
+type GroupEntry struct {
+	Shape *Shape
+	OffsetX Fract
+	OffsetY Fract
+	Op GroupOp
+}
+
+// Rasterizes multiple shapes into a single mask, combining each one with
+// the mask accumulated from the previous entries through the entry's
+// operation. The first entry is applied over an empty mask, so it should
+// typically be a [GroupAdd]. Each shape is rasterized with its own
+// rasterizer at the fractional part of its offset, like
+// [Shape.RasterizeFract]() would, and then translated by the integer
+// part, so offsets are positions in the coordinates of the result.
+//
+// The resulting mask covers the union of the bounds of all the entries,
+// and all the shapes are rasterized into a single scratch buffer, so the
+// only allocations happen at the start. If no entry has anything to draw,
+// the function returns (nil, nil) (or [ErrNothingToDraw], see
+// [SetNothingToDrawErrors]()).
+func RasterizeGroup(entries []GroupEntry) (*image.Alpha, error) {
+	var bounds image.Rectangle
+	for _, entry := range entries {
+		if entry.Shape == nil { return nil, errors.New("nil shape in group entry") }
+		if entry.Op > GroupReplace { return nil, errors.New("invalid group op") }
+		if entry.Shape.err != nil { return nil, entry.Shape.err }
+		if !hasDrawingOps(entry.Shape.segments) { continue }
+		fractX, fractY, shift := entry.splitOffset()
+		width, height, _, _, rectOffset := figureOutBounds(entry.Shape.bounds, fractX, fractY)
+		bounds = bounds.Union(image.Rect(0, 0, width, height).Add(rectOffset.Add(shift)))
+	}
+	if bounds.Empty() { return nil, nothingToDraw() }
+	if err := checkMaskSize(bounds.Dx(), bounds.Dy()); err != nil { return nil, err }
+
+	group := image.NewAlpha(bounds)
+	var scratch image.Alpha
+	for _, entry := range entries {
+		fractX, fractY, shift := entry.splitOffset()
+		rect, err := entry.Shape.RasterizeInto(&scratch, fractX, fractY)
+		if err != nil && !errors.Is(err, ErrNothingToDraw) { return nil, err }
+		if !rect.Empty() {
+			rect = rect.Add(shift)
+			scratch.Rect = rect
+		}
+		if entry.Op == GroupIntersect { clearOutside(group, rect) }
+		if rect.Empty() { continue }
+		combineGroupMask(group, &scratch, entry.Op)
+	}
+	return group, nil
+}
+
+// Splits the entry offset into its fractional part, which is the phase
+// the shape is rasterized at, and its integer part, in pixels.
+func (self *GroupEntry) splitOffset() (Fract, Fract, image.Point) {
+	fractX, fractY := fixedFract(self.OffsetX), fixedFract(self.OffsetY)
+	return fractX, fractY, image.Pt(fixedToIntFloor(self.OffsetX - fractX), fixedToIntFloor(self.OffsetY - fractY))
+}
+
+// Clears all the pixels of the mask outside the given rectangle.
+func clearOutside(mask *image.Alpha, rect image.Rectangle) {
+	rect = rect.Intersect(mask.Rect)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		row := mask.Pix[mask.PixOffset(mask.Rect.Min.X, y) : ][ : mask.Rect.Dx()]
+		if y < rect.Min.Y || y >= rect.Max.Y {
+			for x := range row { row[x] = 0 }
+			continue
+		}
+		for x := range row[ : rect.Min.X - mask.Rect.Min.X] { row[x] = 0 }
+		for x := rect.Max.X - mask.Rect.Min.X; x < len(row); x++ { row[x] = 0 }
+	}
+}
+
+// Combines the src mask into dst, which must contain it.
+func combineGroupMask(dst, src *image.Alpha, op GroupOp) {
+	width := src.Rect.Dx()
+	for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+		srcRow := src.Pix[src.PixOffset(src.Rect.Min.X, y) : ][ : width]
+		dstRow := dst.Pix[dst.PixOffset(src.Rect.Min.X, y) : ][ : width]
+		switch op {
+		case GroupAdd:
+			for x, value := range srcRow {
+				dstRow[x] = uint8N(uint32(dstRow[x]) + uint32(value))
+			}
+		case GroupSubtract:
+			for x, value := range srcRow {
+				if value >= dstRow[x] { dstRow[x] = 0 } else { dstRow[x] -= value }
+			}
+		case GroupIntersect:
+			for x, value := range srcRow {
+				if value < dstRow[x] { dstRow[x] = value }
+			}
+		case GroupReplace:
+			copy(dstRow, srcRow)
+		}
+	}
+}
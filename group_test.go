@@ -0,0 +1,91 @@
+package sfntshape
+
+import "bytes"
+import "testing"
+import "image"
+
+import "github.com/tinne26/sfntshape/internal/maskcmp"
+
+func TestRasterizeGroup(t *testing.T) {
+	rect := New()
+	rect.MoveTo( 0,  0)
+	rect.LineTo(40,  0)
+	rect.LineTo(40, 40)
+	rect.LineTo( 0, 40)
+	rect.LineTo( 0,  0)
+	circle := New()
+	testCircle(&circle, 20.3, 19.6, 11.2)
+
+	// the frame is the rect with a hole defined in the opposite direction
+	frame := New()
+	frame.MoveTo( 0,  0)
+	frame.LineTo( 0, 40)
+	frame.LineTo(40, 40)
+	frame.LineTo(40,  0)
+	frame.LineTo( 0,  0)
+	testCircle(&frame, 20.3, 19.6, 11.2)
+	expected, err := frame.Rasterize()
+	if err != nil { t.Fatal(err) }
+	if expected.AlphaAt(20, 20).A != 0 { t.Fatal("expected frame to have a hole") }
+
+	mask, err := RasterizeGroup([]GroupEntry{
+		{ Shape: &rect, Op: GroupAdd },
+		{ Shape: &circle, Op: GroupSubtract },
+	})
+	if err != nil { t.Fatal(err) }
+	if mask.Rect != expected.Rect { t.Fatalf("expected rect %v, got %v", expected.Rect, mask.Rect) }
+	if err := maskcmp.Compare(mask, expected, 1, 0); err != nil {
+		t.Fatalf("expected subtraction to match the frame: %s", err)
+	}
+
+	// intersecting partially overlapping squares
+	other := New()
+	other.MoveTo(0, 0)
+	other.LineTo(40, 0)
+	other.LineTo(40, 40)
+	other.LineTo(0, 40)
+	other.LineTo(0, 0)
+	half := Fract(20 << 6)
+	mask, err = RasterizeGroup([]GroupEntry{
+		{ Shape: &rect, Op: GroupAdd },
+		{ Shape: &other, OffsetX: half, OffsetY: half, Op: GroupIntersect },
+	})
+	if err != nil { t.Fatal(err) }
+	if mask.Rect != image.Rect(0, -40, 60, 20) { t.Fatalf("expected union bounds, got %v", mask.Rect) }
+	for y := -40; y < 20; y++ {
+		for x := 0; x < 60; x++ {
+			inside := x >= 20 && x < 40 && y >= -20 && y < 0
+			value := mask.AlphaAt(x, y).A
+			if inside && value != 255 || !inside && value != 0 {
+				t.Fatalf("unexpected intersection value %d at (%d, %d)", value, x, y)
+			}
+		}
+	}
+
+	// replace only affects the shape bounds
+	mask, err = RasterizeGroup([]GroupEntry{
+		{ Shape: &rect, Op: GroupAdd },
+		{ Shape: &circle, OffsetX: half, OffsetY: half, Op: GroupReplace },
+	})
+	if err != nil { t.Fatal(err) }
+	if mask.AlphaAt(5, -5).A != 255 || mask.AlphaAt(30, -10).A != 0 || mask.AlphaAt(45, 5).A != 255 {
+		t.Fatal("unexpected replace results")
+	}
+
+	// non-integer offsets: rasterized at the fractional part, translated by the rest
+	offsetX, offsetY := Fract(20 << 6 + 21), Fract(-(3 << 6) - 40)
+	mask, err = RasterizeGroup([]GroupEntry{ { Shape: &circle, OffsetX: offsetX, OffsetY: offsetY } })
+	if err != nil { t.Fatal(err) }
+	expected, err = circle.RasterizeFract(offsetX, offsetY)
+	if err != nil { t.Fatal(err) }
+	shifted := expected.Rect.Add(image.Pt(20, -4))
+	if mask.Rect != shifted || !bytes.Equal(mask.Pix, expected.Pix) {
+		t.Fatalf("expected the RasterizeFract mask at %v, got %v", shifted, mask.Rect)
+	}
+
+	empty := New()
+	mask, err = RasterizeGroup([]GroupEntry{ { Shape: &empty } })
+	if mask != nil || err != nil { t.Fatal("expected (nil, nil) for an empty group") }
+	_, err = RasterizeGroup([]GroupEntry{ { Shape: nil } })
+	if err == nil { t.Fatal("expected error for nil shape") }
+}
@@ -0,0 +1,117 @@
+package sfntshape
+
+import "strconv"
+import "hash/fnv"
+import "encoding/binary"
+
+import "golang.org/x/image/font/sfnt"
+
+// Version of the canonical encoding used by [Shape.Hash](). If the
+// encoding ever changes, this value will be incremented, so it can be
+// included in persistent cache keys if necessary.
+const HashVersion = 1
+
+// Returns a 64-bit FNV-1a hash of the shape segments. The hash is stable
+// across program runs, platforms and versions of this package as long as
+// [HashVersion] doesn't change, so it can be used as a cache key for
+// rasterized masks.
+//
+// The canonical encoding is the [HashVersion] byte followed by, for each
+// segment, the op as a byte and then the X and Y coordinates of the
+// points used by that op (1 for MoveTo and LineTo, 2 for QuadTo and 3
+// for CubeTo) as little-endian int32 values. Unused segment arguments,
+// slice capacity and rasterizer state don't affect the result.
+//
+// The scale and [Shape.InvertY] configurations are deliberately not
+// included: they only affect how future commands are appended, so two
+// shapes with the same segments always hash the same regardless of them.
+func (self *Shape) Hash() uint64 {
+	return hashSegments(self.segments)
+}
+
+func hashSegments(segments []sfnt.Segment) uint64 {
+	hash := fnv.New64a()
+	var buffer [1 + 3*8]byte
+	buffer[0] = HashVersion
+	_, _ = hash.Write(buffer[0 : 1])
+	for _, segment := range segments {
+		buffer[0] = byte(segment.Op)
+		n := segmentArgsCount(segment.Op)
+		for i := 0; i < n; i++ {
+			binary.LittleEndian.PutUint32(buffer[1 + i*8 : ], uint32(segment.Args[i].X))
+			binary.LittleEndian.PutUint32(buffer[5 + i*8 : ], uint32(segment.Args[i].Y))
+		}
+		_, _ = hash.Write(buffer[0 : 1 + n*8])
+	}
+	return hash.Sum64()
+}
+
+// Returns whether both shapes have exactly the same segments. Like
+// [Shape.Hash](), only the ops and the points they use are compared:
+// unused segment arguments, scale, [Shape.InvertY] and rasterizer state
+// are not considered.
+func (self *Shape) Equal(other *Shape) bool {
+	return self.FirstDifference(other, 0) == -1
+}
+
+// Like [Shape.Equal](), but allowing each coordinate of the segments to
+// differ by up to tolerance. The ops must still be the same.
+func (self *Shape) ApproxEqual(other *Shape, tolerance Fract) bool {
+	return self.FirstDifference(other, tolerance) == -1
+}
+
+// Returns the index of the first segment that differs between the shapes
+// (as in [Shape.ApproxEqual]() with the given tolerance), or -1 if there
+// are no differences. If one shape's segments are a prefix of the
+// other's, the length of the shorter one is returned. Useful for test
+// failure messages.
+func (self *Shape) FirstDifference(other *Shape, tolerance Fract) int {
+	a, b := self.segments, other.segments
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].Op != b[i].Op { return i }
+		for j := 0; j < segmentArgsCount(a[i].Op); j++ {
+			pa, pb := a[i].Args[j], b[i].Args[j]
+			if fractDelta(pa.X, pb.X) > int64(tolerance) || fractDelta(pa.Y, pb.Y) > int64(tolerance) { return i }
+		}
+	}
+	if len(a) != len(b) {
+		if len(a) < len(b) { return len(a) }
+		return len(b)
+	}
+	return -1
+}
+
+// Returns the absolute difference between two Fract values, computed in
+// int64 as it can overflow Fract for coordinates of opposite signs.
+func fractDelta(a, b Fract) int64 {
+	delta := int64(a) - int64(b)
+	if delta < 0 { delta = -delta }
+	return delta
+}
+
+// Returns a description of the first difference between the shapes (see
+// [Shape.FirstDifference]()), or an empty string if there's none, e.g.:
+//   segment 3: LineTo (8, -8) != LineTo (8, -8.5)
+// Missing segments are shown as "none". Intended for test failure
+// messages.
+func (self *Shape) Diff(other *Shape, tolerance Fract) string {
+	index := self.FirstDifference(other, tolerance)
+	if index == -1 { return "" }
+	describe := func(segments []sfnt.Segment) string {
+		if index >= len(segments) { return "none" }
+		return FormatSegment(segments[index])
+	}
+	return "segment " + strconv.Itoa(index) + ": " + describe(self.segments) + " != " + describe(other.segments)
+}
+
+// Returns the number of meaningful points in the Args of a
+// segment with the given op.
+func segmentArgsCount(op sfnt.SegmentOp) int {
+	switch op {
+	case sfnt.SegmentOpMoveTo, sfnt.SegmentOpLineTo: return 1
+	case sfnt.SegmentOpQuadTo: return 2
+	case sfnt.SegmentOpCubeTo: return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,87 @@
+package sfntshape
+
+import "testing"
+
+import "golang.org/x/image/font/sfnt"
+
+func TestShapeHash(t *testing.T) {
+	a, b := New(), New()
+	if a.Hash() != b.Hash() {
+		t.Fatal("expected empty shapes to hash equal")
+	}
+
+	// same content, different capacities and flags set afterwards
+	for i := 0; i < 64; i++ { b.LineTo(i, i) } // force growth
+	b.Reset()
+	for _, shape := range []*Shape{&a, &b} {
+		shape.MoveToFract(0, 0)
+		shape.LineToFract(640, 0)
+		shape.QuadToFract(640, 640, 0, 0)
+	}
+	b.SetScale(3) // scale and InvertY only affect later commands
+	b.InvertY(true)
+	if a.GetScale() == b.GetScale() || a.HasInvertY() == b.HasInvertY() { t.Fatal("expected different flags") }
+	if a.Hash() != b.Hash() {
+		t.Fatalf("expected equal segments to hash equal regardless of flags (%v vs %v)", a.Segments(), b.Segments())
+	}
+
+	// different content, a single coordinate changing
+	b.Reset()
+	b.SetScale(1)
+	b.InvertY(false)
+	b.MoveToFract(0, 0)
+	b.LineToFract(640, 0)
+	b.QuadToFract(640, 640, 0, 1)
+	if a.Hash() == b.Hash() {
+		t.Fatal("expected different segments to hash differently")
+	}
+
+	// unused args must not matter
+	b.Reset()
+	b.MoveToFract(0, 0)
+	c := New()
+	c.MoveToFract(0, 0)
+	c.segments[0].Args[2].X = 99
+	if b.Hash() != c.Hash() {
+		t.Fatal("expected unused segment args to be ignored")
+	}
+}
+
+func TestShapeEqual(t *testing.T) {
+	build := func(ctrlX Fract) Shape {
+		shape := New()
+		shape.MoveTo(0, 0)
+		shape.LineTo(10, 0)
+		shape.QuadToFract(ctrlX, 320, 640, 640)
+		shape.CubeTo(8, 12, 2, 12, 0, 10)
+		shape.LineTo(0, 0)
+		return shape
+	}
+	a, b, c := build(960), build(960), build(961)
+	b.SetScale(3) // settings are not compared
+	b.InvertY(true)
+	if !a.Equal(&b) || a.FirstDifference(&b, 0) != -1 { t.Fatal("expected equal shapes") }
+	if a.Equal(&c) || a.FirstDifference(&c, 0) != 2 { t.Fatalf("expected difference at 2, got %d", a.FirstDifference(&c, 0)) }
+	if !a.ApproxEqual(&c, 1) || a.ApproxEqual(&c, 0) { t.Fatal("expected approximately equal shapes within 1 fract unit") }
+
+	// different ops, lengths and unused arguments
+	d := build(960)
+	d.SetSegment(1, sfnt.Segment{ Op: sfnt.SegmentOpMoveTo, Args: d.Segments()[1].Args })
+	if a.FirstDifference(&d, 1000) != 1 { t.Fatal("expected different ops to differ regardless of tolerance") }
+	e := build(960)
+	e.LineTo(5, 5)
+	if a.FirstDifference(&e, 0) != 5 || e.FirstDifference(&a, 0) != 5 { t.Fatal("expected prefix difference at 5") }
+	f := build(960)
+	segment := f.Segments()[1]
+	segment.Args[2].X = 12345
+	f.SetSegment(1, segment)
+	if !a.Equal(&f) { t.Fatal("expected unused arguments to be ignored") }
+	far, opposite := New(), New() // differences overflowing Fract
+	far.MoveToFract(1 << 30, 0)
+	opposite.MoveToFract(-(1 << 30), 0)
+	if far.Equal(&opposite) || far.ApproxEqual(&opposite, 1) || far.FirstDifference(&opposite, 1) != 0 {
+		t.Fatal("expected opposite coordinates near the limits to differ")
+	}
+	empty, other := New(), Shape{}
+	if !empty.Equal(&other) { t.Fatal("expected empty shapes to be equal") }
+}
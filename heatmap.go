@@ -0,0 +1,99 @@
+package sfntshape
+
+import "image"
+import "errors"
+import "image/color"
+
+import "golang.org/x/image/font/sfnt"
+
+// Key colors of the heatmap ramp (approximating viridis), evenly spaced
+// from coverage 0 to 255.
+var heatmapKeys = [...][3]uint8{
+	{  68,   1,  84 }, {  71,  44, 122 }, {  59,  81, 139 },
+	{  44, 113, 142 }, {  33, 144, 141 }, {  39, 173, 129 },
+	{  92, 200,  99 }, { 170, 220,  50 }, { 253, 231,  37 },
+}
+
+// Colors of the checkerboard used for pixels without any coverage,
+// which can't be confused with the darkest colors of the ramp.
+var heatmapCheckers = [2]color.RGBA{ { 24, 24, 24, 255 }, { 48, 48, 48, 255 } }
+
+// Size of the checkerboard cells, in pixels.
+const heatmapCheckerSize = 4
+
+// Colors of the subpath start markers, and their radius in pixels.
+var heatmapMarkerColors = [2]color.RGBA{ { 255, 0, 255, 255 }, { 255, 255, 255, 255 } }
+const heatmapMarkerRadius = 2
+
+var heatmapLUT = func() (lut [256]color.RGBA) {
+	last := len(heatmapKeys) - 1
+	for i := range lut {
+		pos := i*last
+		key, rem := pos/255, pos % 255
+		if key == last { key, rem = last - 1, 255 }
+		a, b := heatmapKeys[key], heatmapKeys[key + 1]
+		lerp := func(a, b uint8) uint8 { return uint8((int(a)*(255 - rem) + int(b)*rem + 127)/255) }
+		lut[i] = color.RGBA{ lerp(a[0], b[0]), lerp(a[1], b[1]), lerp(a[2], b[2]), 255 }
+	}
+	return lut
+}()
+
+// Paints the raw coverage of the shape as a false color image, for
+// debugging. Coverage values are mapped through a perceptual ramp from
+// dark purple (1) to yellow (255), while pixels without any coverage are
+// drawn as a dark gray checkerboard, so "almost zero" and "zero" can be
+// told apart. This makes winding problems (e.g., holes defined in the
+// wrong direction) and tiny leaks easy to spot. The opacity of the
+// shape is ignored (see [Shape.SetOpacity]()).
+//
+// Returns nil for the same reasons as [Shape.Paint]().
+func (self *Shape) PaintHeatmap() *image.RGBA {
+	rgba, err := self.paintHeatmap(false)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return rgba
+}
+
+// Like [Shape.PaintHeatmap](), but also marking the start point of each
+// subpath (its MoveTo) with a small magenta square outlined in white.
+// Markers are clipped to the bounds of the image.
+func (self *Shape) PaintHeatmapMarkers() *image.RGBA {
+	rgba, err := self.paintHeatmap(true)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return rgba
+}
+
+func (self *Shape) paintHeatmap(markers bool) (*image.RGBA, error) {
+	mask, err := self.rasterizeCoverage()
+	if err != nil || mask == nil { return nil, err }
+	rgba := image.NewRGBA(mask.Rect)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			value := mask.AlphaAt(x, y).A
+			if value != 0 {
+				rgba.SetRGBA(x, y, heatmapLUT[value])
+			} else {
+				cell := (floorDiv(x, heatmapCheckerSize) + floorDiv(y, heatmapCheckerSize)) & 1
+				rgba.SetRGBA(x, y, heatmapCheckers[cell])
+			}
+		}
+	}
+	if !markers { return rgba, nil }
+
+	for _, segment := range self.segments {
+		if segment.Op != sfnt.SegmentOpMoveTo { continue }
+		cx, cy := fixedToIntFloor(segment.Args[0].X), fixedToIntFloor(segment.Args[0].Y)
+		for dy := -heatmapMarkerRadius; dy <= heatmapMarkerRadius; dy++ {
+			for dx := -heatmapMarkerRadius; dx <= heatmapMarkerRadius; dx++ { // outlined square
+				r := heatmapMarkerRadius
+				if dx == -r || dx == r || dy == -r || dy == r {
+					rgba.SetRGBA(cx + dx, cy + dy, heatmapMarkerColors[1])
+				} else {
+					rgba.SetRGBA(cx + dx, cy + dy, heatmapMarkerColors[0])
+				}
+			}
+		}
+	}
+	return rgba, nil
+}
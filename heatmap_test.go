@@ -0,0 +1,46 @@
+package sfntshape
+
+import "image/color"
+import "testing"
+
+func TestPaintHeatmap(t *testing.T) {
+	// ramp endpoints and luminance, which must grow monotonically
+	if heatmapLUT[1] == heatmapCheckers[0] || heatmapLUT[1] == heatmapCheckers[1] {
+		t.Fatal("expected the lowest coverage to be distinguishable from zero")
+	}
+	first, last := heatmapKeys[0], heatmapKeys[len(heatmapKeys) - 1]
+	if heatmapLUT[0] != (color.RGBA{ first[0], first[1], first[2], 255 }) { t.Fatalf("unexpected ramp start %v", heatmapLUT[0]) }
+	if heatmapLUT[255] != (color.RGBA{ last[0], last[1], last[2], 255 }) { t.Fatalf("unexpected ramp end %v", heatmapLUT[255]) }
+	luma := func(c color.RGBA) int { return 299*int(c.R) + 587*int(c.G) + 114*int(c.B) }
+	for i := 2; i < 256; i++ {
+		if luma(heatmapLUT[i]) < luma(heatmapLUT[i - 1]) { t.Fatalf("ramp luminance decreases at %d", i) }
+	}
+
+	shape := New()
+	testCircle(&shape, 20.3, 20.7, 15.2)
+	mask, _ := shape.Rasterize()
+	img := shape.PaintHeatmap()
+	if img.Rect != mask.Rect { t.Fatalf("expected rect %v, got %v", mask.Rect, img.Rect) }
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			value, got := mask.AlphaAt(x, y).A, img.RGBAAt(x, y)
+			if value != 0 && got != heatmapLUT[value] { t.Fatalf("pixel (%d, %d): expected %v, got %v", x, y, heatmapLUT[value], got) }
+			if value == 0 && got != heatmapCheckers[0] && got != heatmapCheckers[1] {
+				t.Fatalf("pixel (%d, %d): expected a checkerboard color, got %v", x, y, got)
+			}
+		}
+	}
+
+	// markers at the subpath starts, clipped to the image
+	start := shape.Segments()[0].Args[0]
+	px, py := fixedToIntFloor(start.X), fixedToIntFloor(start.Y)
+	marked := shape.PaintHeatmapMarkers()
+	if got := marked.RGBAAt(px, py); got != heatmapMarkerColors[0] { t.Fatalf("expected marker center, got %v", got) }
+	if got := marked.RGBAAt(px, py - heatmapMarkerRadius); got != heatmapMarkerColors[1] { t.Fatalf("expected marker outline, got %v", got) }
+	if !marked.Rect.Eq(img.Rect) { t.Fatal("expected markers to be clipped to the image") }
+	if got := marked.RGBAAt(mask.Rect.Min.X, mask.Rect.Min.Y); got != img.RGBAAt(mask.Rect.Min.X, mask.Rect.Min.Y) {
+		t.Fatal("expected pixels away from markers to be unchanged")
+	}
+
+	if img := (&Shape{}).PaintHeatmap(); img != nil && !img.Rect.Empty() { t.Fatal("expected empty result for empty shape") }
+}
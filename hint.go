@@ -0,0 +1,110 @@
+package sfntshape
+
+import "math"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Maximum deviation (in 26.6 units) from a perfectly horizontal or
+// vertical line for [Shape.Hint]() to consider it axis-aligned.
+const hintMaxDeviation = 8 // 1/8th of a pixel
+
+// Aligns the horizontal and vertical lines of the shape to pixel
+// boundaries, so thin axis-aligned features like 1px dividers or box
+// borders are rasterized crisply instead of blurred across two pixels.
+//
+// Lines deviating at most 1/8th of a pixel from being perfectly
+// horizontal or vertical (and at least 8 times longer than that
+// deviation) are moved to the closest pixel boundary in the stored
+// coordinates (the ones used by the rasterized masks, with a zero
+// offset). Since the endpoints of consecutive segments are shared,
+// connected segments move coherently and corners don't tear. Curve
+// control points and lines that are not axis-aligned are not modified,
+// though their endpoints may move if they are connected to hinted lines.
+//
+// The strength, clamped to [0, 1], blends between the original (0)
+// and the snapped (1) positions. The segments are modified in place.
+// Notice that hinting only makes sense for the offset the shape will
+// be rasterized at, as fractional offsets misalign the boundaries again.
+func (self *Shape) Hint(strength float64) {
+	if !(strength > 0) { return }
+	if strength > 1 { strength = 1 }
+	self.dirty.markAll()
+
+	n := len(self.segments)
+	targets := make([]hintTarget, n)
+	subpathStart := -1
+	for i, segment := range self.segments {
+		if segment.Op == sfnt.SegmentOpMoveTo {
+			self.linkClosedSubpath(targets, subpathStart, i - 1)
+			subpathStart = i
+			continue
+		}
+		if i == 0 || segment.Op != sfnt.SegmentOpLineTo { continue }
+
+		from, to := segmentEnd(self.segments[i - 1]), segmentEnd(segment)
+		dx, dy := absFract(to.X - from.X), absFract(to.Y - from.Y)
+		if dx <= hintMaxDeviation && dy > dx*8 { // vertical
+			x := fixedRoundToPixel((from.X + to.X)/2)
+			targets[i - 1].setX(x)
+			targets[i].setX(x)
+		} else if dy <= hintMaxDeviation && dx > dy*8 { // horizontal
+			y := fixedRoundToPixel((from.Y + to.Y)/2)
+			targets[i - 1].setY(y)
+			targets[i].setY(y)
+		}
+	}
+	self.linkClosedSubpath(targets, subpathStart, n - 1)
+
+	for i := range self.segments {
+		end := &self.segments[i].Args[segmentArgsCount(self.segments[i].Op) - 1]
+		if targets[i].hasX { end.X = hintBlend(end.X, targets[i].x, strength) }
+		if targets[i].hasY { end.Y = hintBlend(end.Y, targets[i].y, strength) }
+	}
+	self.recomputeBounds()
+}
+
+// Snapping targets for the endpoint of a segment.
+type hintTarget struct {
+	x, y Fract
+	hasX, hasY bool
+}
+
+func (self *hintTarget) setX(x Fract) {
+	if !self.hasX { self.x, self.hasX = x, true }
+}
+
+func (self *hintTarget) setY(y Fract) {
+	if !self.hasY { self.y, self.hasY = y, true }
+}
+
+// If the subpath between the given segment indices is explicitly closed
+// (ends at its starting point), shares the snapping targets of the first
+// and last points, as they are the same vertex.
+func (self *Shape) linkClosedSubpath(targets []hintTarget, start, end int) {
+	if start < 0 || end <= start { return }
+	if segmentEnd(self.segments[start]) != segmentEnd(self.segments[end]) { return }
+	first, last := &targets[start], &targets[end]
+	if first.hasX { last.x, last.hasX = first.x, true } else if last.hasX { first.setX(last.x) }
+	if first.hasY { last.y, last.hasY = first.y, true } else if last.hasY { first.setY(last.y) }
+}
+
+// Returns the point where the segment ends.
+func segmentEnd(segment sfnt.Segment) fixed.Point26_6 {
+	n := segmentArgsCount(segment.Op)
+	if n == 0 { return segment.Args[0] } // unknown op, see ErrInvalidCommand
+	return segment.Args[n - 1]
+}
+
+func fixedRoundToPixel(value Fract) Fract {
+	return fixedFloor(value + 32)
+}
+
+func absFract(value Fract) Fract {
+	if value < 0 { return -value }
+	return value
+}
+
+func hintBlend(original, target Fract, strength float64) Fract {
+	return original + Fract(math.Round(float64(target - original)*strength))
+}
@@ -0,0 +1,61 @@
+package sfntshape
+
+import "bytes"
+import "testing"
+
+func TestHint(t *testing.T) {
+	newBar := func() Shape {
+		bar := New()
+		bar.InvertY(true)
+		bar.MoveToFract(10*64 + 32, 0)
+		bar.LineToFract(11*64 + 32, 0)
+		bar.LineToFract(11*64 + 32 + 3, 20*64)
+		bar.LineToFract(10*64 + 32 + 3, 20*64 + 5)
+		bar.LineToFract(10*64 + 32, 0)
+		return bar
+	}
+
+	bar := newBar()
+	mask, err := bar.Rasterize()
+	if err != nil { t.Fatal(err) }
+	if isCrisp(mask.Pix) { t.Fatal("expected blurry bar before hinting") }
+	bar.Hint(1)
+	mask, err = bar.Rasterize()
+	if err != nil { t.Fatal(err) }
+	if !isCrisp(mask.Pix) { t.Fatalf("expected crisp bar after hinting") }
+	if mask.Rect.Min.X != 11 || mask.Rect.Dx() != 1 || mask.Rect.Dy() != 20 {
+		t.Fatalf("unexpected hinted bar Rect %v", mask.Rect)
+	}
+
+	// partial strength
+	half := newBar()
+	half.Hint(0.5)
+	if x := half.Segments()[0].Args[0].X; x != 10*64 + 48 {
+		t.Fatalf("expected x = 10.75 with strength 0.5, got %v", x)
+	}
+	if first, last := half.Segments()[0].Args[0], half.Segments()[4].Args[0]; first != last {
+		t.Fatalf("closing point torn: %v vs %v", first, last)
+	}
+
+	// curves and diagonals are untouched
+	circle := New()
+	testCircle(&circle, 0, 0, 12.3)
+	circle.MoveTo(30, 0)
+	circle.LineTo(40, 7)
+	circle.LineTo(31, 9)
+	before, err := circle.RasterizeFract(0, 0)
+	if err != nil { t.Fatal(err) }
+	circle.Hint(1)
+	after, err := circle.RasterizeFract(0, 0)
+	if err != nil { t.Fatal(err) }
+	if before.Rect != after.Rect || !bytes.Equal(before.Pix, after.Pix) {
+		t.Fatal("hinting modified curves or diagonals")
+	}
+}
+
+func isCrisp(pix []uint8) bool {
+	for _, value := range pix {
+		if value != 0 && value != 0xFF { return false }
+	}
+	return true
+}
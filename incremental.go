@@ -0,0 +1,130 @@
+package sfntshape
+
+import "image"
+import "context"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Pixels added around the dirty region in [Shape.RasterizeIncremental]().
+const incrementalMargin = 1
+
+// Area modified since the last incremental rasterization.
+type dirtyRegion struct {
+	tracking bool
+	all bool // everything must be rasterized again
+	empty bool // nothing has been modified (unless all is set)
+	rect fixed.Rectangle26_6
+}
+
+func (self *dirtyRegion) markAll() {
+	if self.tracking { self.all = true }
+}
+
+func (self *dirtyRegion) add(point fixed.Point26_6) {
+	if self.empty {
+		self.rect = fixed.Rectangle26_6{ Min: point, Max: point }
+		self.empty = false
+		return
+	}
+	if point.X < self.rect.Min.X { self.rect.Min.X = point.X }
+	if point.X > self.rect.Max.X { self.rect.Max.X = point.X }
+	if point.Y < self.rect.Min.Y { self.rect.Min.Y = point.Y }
+	if point.Y > self.rect.Max.Y { self.rect.Max.Y = point.Y }
+}
+
+// Adds the control points of the segment starting at from.
+func (self *dirtyRegion) addSegment(from fixed.Point26_6, segment sfnt.Segment) {
+	if segment.Op == sfnt.SegmentOpMoveTo { return }
+	self.add(from)
+	for i := 0; i < segmentArgsCount(segment.Op); i++ { self.add(segment.Args[i]) }
+}
+
+func (self *dirtyRegion) clear() {
+	self.all, self.empty = false, true
+}
+
+// Starts tracking the regions modified by further changes to the shape,
+// so [Shape.RasterizeIncremental]() can rasterize only those again.
+func (self *Shape) BeginTracking() {
+	self.dirty = dirtyRegion{ tracking: true, all: true }
+}
+
+// Replaces the segment at the given index, which must be in the same
+// coordinates as [Shape.Segments]() (no scaling or InvertY is applied).
+// This is the way to edit existing segments when tracking changes (see
+// [Shape.BeginTracking]()) or relying on the cached [Shape.Bounds](),
+// as editing the segments directly can't be detected. Segments with
+// unknown ops record an [*InvalidCommandError] in [Shape.Err]().
+func (self *Shape) SetSegment(index int, segment sfnt.Segment) {
+	old := self.segments[index]
+	if self.dirty.tracking {
+		var from fixed.Point26_6
+		if index > 0 { from = segmentEnd(self.segments[index - 1]) }
+		self.dirty.addSegment(from, old)
+		self.dirty.addSegment(from, segment)
+		if index + 1 < len(self.segments) { // the start of the next segment moves too
+			next := self.segments[index + 1]
+			self.dirty.addSegment(segmentEnd(old), next)
+			self.dirty.addSegment(segmentEnd(segment), next)
+		}
+	}
+	self.segments[index] = segment
+	if segment.Op > sfnt.SegmentOpCubeTo && self.err == nil {
+		self.err = &InvalidCommandError{ Index: index, Op: segment.Op }
+	}
+	if self.touchesBounds(old) {
+		self.recomputeBounds()
+	} else {
+		self.includeInBounds(segment)
+	}
+}
+
+// Rasterizes the shape into prev, which must be the mask returned by the
+// previous call to this method, re-rasterizing only the region modified
+// since then (see [Shape.BeginTracking]()). The result is the same as
+// [Shape.Rasterize]() would return, except for ±1 differences in some
+// partially covered pixels: rasterizers accumulate coverage along the
+// rows, so edits also shift the rounding of pixels far from them.
+//
+// Since prev is overwritten, it's returned for convenience, but a new
+// mask is rasterized and returned instead if prev is nil, the changes
+// can't be tracked (e.g., after [Shape.Reset]() or [Shape.Hint](), or
+// before the first call after BeginTracking) or the bounds of the shape
+// changed.
+//
+// The modified region is estimated from the control points of the added
+// and replaced segments. This is only correct if all the subpaths are
+// closed both before and after the changes (as required to get proper
+// fills anyway), because the coverage of open subpaths leaks to the
+// rest of the mask.
+func (self *Shape) RasterizeIncremental(prev *image.Alpha) (*image.Alpha, error) {
+	if self.err != nil { return nil, self.err }
+	if self.IsEmpty() { return nil, nothingToDraw() }
+	width, height, _, _, rectOffset := figureOutBounds(self.bounds, 0, 0)
+	rect := image.Rect(0, 0, width, height).Add(rectOffset)
+	full := (prev == nil || !self.dirty.tracking || self.dirty.all || prev.Rect != rect)
+	if !full && self.dirty.empty { return prev, nil }
+
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	if full {
+		mask, err := Rasterize(self.segments, rasterizer, 0, 0)
+		if err == nil && self.dirty.tracking { self.dirty.clear() }
+		return mask, err
+	}
+
+	dirty := self.dirty.rect
+	window := image.Rect(
+		fixedToIntFloor(dirty.Min.X), fixedToIntFloor(dirty.Min.Y),
+		fixedToIntFloor(fixedCeil(dirty.Max.X)), fixedToIntFloor(fixedCeil(dirty.Max.Y)),
+	).Inset(-incrementalMargin).Intersect(rect)
+	if !window.Empty() {
+		opts := RasterizeOptions{ Viewport: window }
+		mask, err := RasterizeCtx(context.Background(), self.segments, rasterizer, opts)
+		if err != nil { return nil, err }
+		if mask != nil { copyMask(prev, mask) }
+	}
+	self.dirty.clear()
+	return prev, nil
+}
@@ -0,0 +1,101 @@
+package sfntshape
+
+import "image"
+import "bytes"
+import "testing"
+
+import "golang.org/x/image/math/fixed"
+
+import "github.com/tinne26/sfntshape/internal/maskcmp"
+
+// Compares an incremental result with a full rasterization. As documented
+// in [Shape.RasterizeIncremental](), rasterizers accumulate coverage along
+// the rows, so redrawing only the dirty region can round some partially
+// covered pixels differently. No pixel may be off by more than one, and
+// only a few may be off at all (about 0.09% of them in these tests).
+func compareIncremental(mask, expected *image.Alpha) error {
+	err := maskcmp.Compare(mask, expected, 1, 0)
+	if err != nil { return err }
+	return maskcmp.Compare(mask, expected, 0, 0.002)
+}
+
+func TestRasterizeIncremental(t *testing.T) {
+	for _, radius := range []float64{ 200, 700 } {
+		shape := testPolygon(radius, 500)
+		shape.BeginTracking()
+		mask, err := shape.RasterizeIncremental(nil)
+		if err != nil { t.Fatal(err) }
+
+		for frame, index := range []int{ 123, 124, 300, 499 } {
+			segment := shape.segments[index]
+			if index == len(shape.segments) - 1 { // keep the subpath closed
+				first := shape.segments[0]
+				first.Args[0].Y += 64*3
+				shape.SetSegment(0, first)
+				segment.Args[0] = first.Args[0]
+			} else {
+				segment.Args[0].X = segment.Args[0].X*9/10 + Fract(frame*5)
+				segment.Args[0].Y = segment.Args[0].Y*9/10 - 17
+			}
+			shape.SetSegment(index, segment)
+			prev := mask
+			mask, err = shape.RasterizeIncremental(mask)
+			if err != nil { t.Fatal(err) }
+			if mask != prev { t.Fatalf("radius %v, frame %d: expected prev to be reused", radius, frame) }
+
+			expected, err := shape.Rasterize()
+			if err != nil { t.Fatal(err) }
+			if mask.Rect != expected.Rect { t.Fatalf("radius %v, frame %d: unexpected Rect %v", radius, frame, mask.Rect) }
+			if err := compareIncremental(mask, expected); err != nil {
+				t.Fatalf("radius %v, frame %d: incremental result differs: %s", radius, frame, err)
+			}
+		}
+
+		// appending a new closed subpath
+		shape.MoveTo(0, 0)
+		shape.LineTo(10, 0)
+		shape.LineTo(10, 10)
+		shape.LineTo(0, 0)
+		prev := mask
+		mask, err = shape.RasterizeIncremental(mask)
+		if err != nil { t.Fatal(err) }
+		expected, err := shape.Rasterize()
+		if err != nil { t.Fatal(err) }
+		if mask != prev { t.Fatalf("radius %v: expected prev to be reused after appending a subpath", radius) }
+		if err := compareIncremental(mask, expected); err != nil {
+			t.Fatalf("radius %v: unexpected result after appending a subpath: %s", radius, err)
+		}
+
+		// growing bounds fall back to a full rasterization
+		segment := shape.segments[10]
+		segment.Args[0].X += fixed.I(50)
+		shape.SetSegment(10, segment)
+		mask, err = shape.RasterizeIncremental(mask)
+		if err != nil { t.Fatal(err) }
+		if mask == prev { t.Fatalf("radius %v: expected a new mask", radius) }
+		expected, err = shape.Rasterize()
+		if err != nil { t.Fatal(err) }
+		if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+			t.Fatalf("radius %v: unexpected result after growing", radius)
+		}
+	}
+}
+
+func TestRasterizeIncrementalUntracked(t *testing.T) {
+	shape := testPolygon(50, 20)
+	mask, err := shape.RasterizeIncremental(image.NewAlpha(image.Rect(0, 0, 1, 1)))
+	if err != nil { t.Fatal(err) }
+	expected, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+		t.Fatal("expected a full rasterization without tracking")
+	}
+
+	shape.BeginTracking()
+	mask, _ = shape.RasterizeIncremental(nil)
+	again, _ := shape.RasterizeIncremental(mask)
+	if again != mask { t.Fatal("expected the unchanged mask to be returned") }
+	shape.Hint(1)
+	again, _ = shape.RasterizeIncremental(mask)
+	if again == mask { t.Fatal("expected hinting to force a full rasterization") }
+}
@@ -0,0 +1,49 @@
+// Package maskcmp implements the mask comparisons behind
+// [shapetest.CompareMasks](), so sfntshape's own tests can use them
+// without importing shapetest (which would be an import cycle).
+package maskcmp
+
+import "fmt"
+import "image"
+
+// Compares two masks and returns an error describing the differences if
+// they are not similar enough. See shapetest.CompareMasks for details.
+func Compare(a, b *image.Alpha, maxPerPixelDelta uint8, maxDifferingFraction float64) error {
+	rectA, rectB := Rect(a), Rect(b)
+	if rectA.Empty() && rectB.Empty() { return nil }
+	if rectA != rectB {
+		return fmt.Errorf("mask bounds mismatch: %v vs %v", rectA, rectB)
+	}
+
+	differing := 0
+	var maxDelta uint8
+	var firstDiff image.Point
+	for y := rectA.Min.Y; y < rectA.Max.Y; y++ {
+		rowA := a.Pix[a.PixOffset(rectA.Min.X, y) : ]
+		rowB := b.Pix[b.PixOffset(rectA.Min.X, y) : ]
+		for x := 0; x < rectA.Dx(); x++ {
+			delta := rowA[x] - rowB[x]
+			if rowB[x] > rowA[x] { delta = rowB[x] - rowA[x] }
+			if delta <= maxPerPixelDelta { continue }
+			if differing == 0 { firstDiff = image.Pt(rectA.Min.X + x, y) }
+			if delta > maxDelta { maxDelta = delta }
+			differing += 1
+		}
+	}
+
+	total := rectA.Dx()*rectA.Dy()
+	if float64(differing) > maxDifferingFraction*float64(total) {
+		return fmt.Errorf(
+			"%d of %d pixels differ by more than %d (max delta %d, first at %v: %d vs %d)",
+			differing, total, maxPerPixelDelta, maxDelta, firstDiff,
+			a.AlphaAt(firstDiff.X, firstDiff.Y).A, b.AlphaAt(firstDiff.X, firstDiff.Y).A,
+		)
+	}
+	return nil
+}
+
+// Returns the mask's bounds, or an empty rectangle for nil masks.
+func Rect(mask *image.Alpha) image.Rectangle {
+	if mask == nil { return image.Rectangle{} }
+	return mask.Rect
+}
@@ -0,0 +1,170 @@
+package sfntshape
+
+import "math"
+import "sort"
+import "sync"
+import "image"
+import "errors"
+import "io/fs"
+import "path"
+import "strings"
+
+// A registry of named, reusable shapes (e.g., the icons of a game or
+// application). Shapes are frozen when registered (see [FrozenShape]),
+// so a library can be shared and used from multiple goroutines at once.
+// The zero value is an empty library ready to use.
+type Library struct {
+	mutex sync.RWMutex
+	shapes map[string]*FrozenShape
+	scaled map[scaledShapeKey]*FrozenShape // see Library.RasterizeScaled
+	cache *MaskCache
+}
+
+type scaledShapeKey struct {
+	name string
+	scale float64
+}
+
+// Registers a snapshot of the shape under the given name. Later changes
+// to the shape don't affect the registered snapshot. Returns a
+// [*ShapeNameError] matching [ErrDuplicateShape] if the name is
+// already in use, or wrapping the shape's error if it has one recorded
+// (see [Shape.Err]()), in which case nothing is registered.
+func (self *Library) Register(name string, shape *Shape) error {
+	if shape.err != nil { return &ShapeNameError{ Name: name, Err: shape.err } }
+	frozen := shape.Freeze()
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if _, found := self.shapes[name]; found {
+		return &ShapeNameError{ Name: name, Err: ErrDuplicateShape }
+	}
+	if self.shapes == nil { self.shapes = make(map[string]*FrozenShape) }
+	self.shapes[name] = frozen
+	return nil
+}
+
+// Returns the shape registered under the given name, if any.
+func (self *Library) Get(name string) (*FrozenShape, bool) {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+	shape, found := self.shapes[name]
+	return shape, found
+}
+
+// Returns the names of all the registered shapes, sorted.
+func (self *Library) Names() []string {
+	self.mutex.RLock()
+	names := make([]string, 0, len(self.shapes))
+	for name := range self.shapes { names = append(names, name) }
+	self.mutex.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Returns the number of registered shapes.
+func (self *Library) Len() int {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+	return len(self.shapes)
+}
+
+// Sets a cache for [Library.Rasterize](). The cache can be shared with
+// other libraries or uses. Nil (the default) disables caching.
+func (self *Library) SetCache(cache *MaskCache) {
+	self.mutex.Lock()
+	self.cache = cache
+	self.mutex.Unlock()
+}
+
+// Rasterizes the shape registered under the given name, like
+// [FrozenShape.RasterizeFract]() would, going through the cache if
+// one was set with [Library.SetCache](). Cached masks are shared, so
+// they must not be modified. Shapes are rasterized at the size they were
+// registered with, see [Library.RasterizeScaled]() for other sizes.
+//
+// Returns a [*ShapeNameError] matching [ErrUnknownShape] if there's
+// no shape with the given name.
+func (self *Library) Rasterize(name string, offsetX, offsetY Fract) (*image.Alpha, error) {
+	self.mutex.RLock()
+	shape, found := self.shapes[name]
+	cache := self.cache
+	self.mutex.RUnlock()
+	if !found { return nil, &ShapeNameError{ Name: name, Err: ErrUnknownShape } }
+	if cache != nil { return cache.Get(&shape.shape, offsetX, offsetY) }
+	return shape.RasterizeFract(offsetX, offsetY)
+}
+
+// Like [Library.Rasterize](), but with the shape rescaled by the given
+// factor (see [Shape.Rescale]()), which must be positive and finite.
+// The rescaled shape is kept by the library, so each (name, scale) pair
+// is only rescaled once and gets its own cache entries. Meant for a few
+// fixed sizes (e.g., 1x and 2x icons), as rescaled shapes are never
+// released.
+//
+// Returns a [*ShapeNameError] matching [ErrUnknownShape] if there's
+// no shape with the given name, or wrapping [ErrCoordinateOverflow] if
+// the rescaled shape doesn't fit in 26.6 coordinates.
+func (self *Library) RasterizeScaled(name string, scale float64, offsetX, offsetY Fract) (*image.Alpha, error) {
+	if !(scale > 0) || math.IsInf(scale, 1) {
+		return nil, errors.New("scale must be a positive finite value")
+	}
+	if scale == 1 { return self.Rasterize(name, offsetX, offsetY) }
+
+	key := scaledShapeKey{ name, scale }
+	self.mutex.RLock()
+	shape, found := self.scaled[key]
+	original, registered := self.shapes[name]
+	cache := self.cache
+	self.mutex.RUnlock()
+	if !found {
+		if !registered { return nil, &ShapeNameError{ Name: name, Err: ErrUnknownShape } }
+		rescaled := original.shape.Clone()
+		rescaled.Rescale(scale)
+		if rescaled.err != nil { return nil, &ShapeNameError{ Name: name, Err: rescaled.err } }
+		shape = rescaled.Freeze()
+		self.mutex.Lock()
+		if self.scaled == nil { self.scaled = make(map[scaledShapeKey]*FrozenShape) }
+		if existing, found := self.scaled[key]; found { // concurrent miss
+			shape = existing
+		} else {
+			self.scaled[key] = shape
+		}
+		self.mutex.Unlock()
+	}
+	if cache != nil { return cache.Get(&shape.shape, offsetX, offsetY) }
+	return shape.RasterizeFract(offsetX, offsetY)
+}
+
+// Registers a shape for each file in fsys matching the given pattern
+// (see [fs.Glob]()), decoding the file contents with the given function.
+// Shapes are named after the file names without directories nor
+// extensions, so "icons/arrow.svgpath" is registered as "arrow". Use
+// [os.DirFS]() to load the files of a directory.
+//
+// This package doesn't define any file format on its own, so decode
+// is in charge of parsing the data (e.g., SVG path data or JSON) into
+// a shape. Loading stops at the first error, which is returned along
+// the file path. Shapes registered before the error are kept.
+func (self *Library) LoadFS(fsys fs.FS, pattern string, decode func(data []byte) (*Shape, error)) error {
+	paths, err := fs.Glob(fsys, pattern)
+	if err != nil { return err }
+	for _, filePath := range paths {
+		data, err := fs.ReadFile(fsys, filePath)
+		if err != nil { return err }
+		shape, err := decode(data)
+		if err == nil {
+			base := path.Base(filePath)
+			err = self.Register(strings.TrimSuffix(base, path.Ext(base)), shape)
+		}
+		if err != nil { return &loadError{ path: filePath, err: err } }
+	}
+	return nil
+}
+
+type loadError struct {
+	path string
+	err error
+}
+
+func (self *loadError) Error() string { return self.path + ": " + self.err.Error() }
+func (self *loadError) Unwrap() error { return self.err }
@@ -0,0 +1,109 @@
+package sfntshape
+
+import "bytes"
+import "embed"
+import "errors"
+import "strconv"
+import "strings"
+import "testing"
+
+// Decodes polygons written as "x,y x,y ...", one per file.
+func decodeTestPolygon(data []byte) (*Shape, error) {
+	shape := New()
+	for i, field := range strings.Fields(string(data)) {
+		coords := strings.Split(field, ",")
+		if len(coords) != 2 { return nil, errors.New("invalid point " + field) }
+		x, errX := strconv.Atoi(coords[0])
+		y, errY := strconv.Atoi(coords[1])
+		if errX != nil || errY != nil { return nil, errors.New("invalid point " + field) }
+		if i == 0 { shape.MoveTo(x, y) } else { shape.LineTo(x, y) }
+	}
+	return &shape, nil
+}
+
+//go:embed testdata/icons
+var testIconsFS embed.FS
+
+func TestLibrary(t *testing.T) {
+	var library Library
+	err := library.LoadFS(testIconsFS, "testdata/icons/*.poly", decodeTestPolygon)
+	if err != nil { t.Fatal(err) }
+	triangle := New()
+	testTriangle(&triangle)
+	if err := library.Register("custom", &triangle); err != nil { t.Fatal(err) }
+	triangle.LineTo(40, 40) // must not affect the registered shape
+
+	names := library.Names()
+	if strings.Join(names, " ") != "custom square triangle" || library.Len() != 3 {
+		t.Fatalf("unexpected names %v", names)
+	}
+	square, found := library.Get("square")
+	if !found { t.Fatal("expected to find \"square\"") }
+	mask, err := square.Rasterize()
+	if err != nil { t.Fatal(err) }
+	if mask.Rect.Dx() != 8 || mask.Rect.Dy() != 8 || mask.Pix[4*8 + 4] != 255 {
+		t.Fatalf("unexpected square mask %v", mask.Rect)
+	}
+
+	// unknown and duplicate names
+	if _, found := library.Get("circle"); found { t.Fatal("unexpected \"circle\"") }
+	_, err = library.Rasterize("circle", 0, 0)
+	var nameErr *ShapeNameError
+	if !errors.Is(err, ErrUnknownShape) || !errors.As(err, &nameErr) || nameErr.Name != "circle" {
+		t.Fatalf("expected unknown shape error, got %v", err)
+	}
+	err = library.LoadFS(testIconsFS, "testdata/icons/tri*", decodeTestPolygon)
+	if !errors.Is(err, ErrDuplicateShape) || !strings.Contains(err.Error(), "testdata/icons/triangle.poly") {
+		t.Fatalf("expected duplicate shape error, got %v", err)
+	}
+	err = library.LoadFS(testIconsFS, "testdata/icons/*.txt", decodeTestPolygon)
+	if err == nil || library.Len() != 3 { t.Fatal("expected decoding error") }
+
+	// shapes with recorded errors
+	overflow := New()
+	testTriangle(&overflow)
+	overflow.LineTo(1 << 30, 0)
+	err = library.Register("overflow", &overflow)
+	if !errors.Is(err, ErrCoordinateOverflow) || !errors.As(err, &nameErr) || nameErr.Name != "overflow" {
+		t.Fatalf("expected overflow error, got %v", err)
+	}
+	if _, found := library.Get("overflow"); found || library.Len() != 3 { t.Fatal("unexpected \"overflow\"") }
+
+	// rasterization with and without cache
+	expected, err := library.Rasterize("custom", 0, 32)
+	if err != nil { t.Fatal(err) }
+	cache := NewMaskCache(0)
+	library.SetCache(cache)
+	for i := 0; i < 2; i++ {
+		mask, err := library.Rasterize("custom", 0, 32)
+		if err != nil { t.Fatal(err) }
+		if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+			t.Fatal("cached mask doesn't match")
+		}
+	}
+	if hits, misses := cache.Stats(); hits != 1 || misses != 1 {
+		t.Fatalf("expected 1 hit and 1 miss, got %d and %d", hits, misses)
+	}
+
+	// rescaled shapes are cached separately
+	scaled := New()
+	testTriangle(&scaled)
+	scaled.Rescale(2)
+	expected, err = scaled.RasterizeFract(0, 32)
+	if err != nil { t.Fatal(err) }
+	for i := 0; i < 2; i++ {
+		mask, err := library.RasterizeScaled("custom", 2, 0, 32)
+		if err != nil { t.Fatal(err) }
+		if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+			t.Fatal("rescaled mask doesn't match")
+		}
+	}
+	if hits, misses := cache.Stats(); hits != 2 || misses != 2 {
+		t.Fatalf("expected 2 hits and 2 misses, got %d and %d", hits, misses)
+	}
+	_, err = library.RasterizeScaled("circle", 2, 0, 0)
+	if !errors.Is(err, ErrUnknownShape) { t.Fatalf("expected unknown shape error, got %v", err) }
+	_, err = library.RasterizeScaled("custom", 1 << 24, 0, 0)
+	if !errors.Is(err, ErrCoordinateOverflow) { t.Fatalf("expected overflow error, got %v", err) }
+	if _, err = library.RasterizeScaled("custom", 0, 0, 0); err == nil { t.Fatal("expected error for zero scale") }
+}
@@ -0,0 +1,68 @@
+package sfntshape
+
+import "image"
+
+// Operations for [CombineMasks]().
+type MaskOp uint8
+const (
+	// Keeps the maximum of both coverages. Unlike saturating addition
+	// (see [GroupAdd]), this doesn't make overlapping antialiased edges
+	// heavier, and combining a mask with itself leaves it unchanged.
+	MaskUnion MaskOp = iota
+
+	// Keeps the minimum of both coverages.
+	MaskIntersect
+
+	// Subtracts the coverage of b from a, saturating at zero.
+	MaskSubtract
+
+	// Keeps the absolute difference of both coverages, so areas
+	// covered by a single mask are kept and overlapping areas cleared.
+	MaskXor
+)
+
+// Combines the two masks with the given operation into a new mask,
+// considering pixels outside a mask's Rect transparent (nil masks are
+// fully transparent). The result covers the union of both Rects for
+// [MaskUnion] and [MaskXor], their intersection for [MaskIntersect] and
+// the Rect of a for [MaskSubtract], since there can't be any coverage
+// outside those. Invalid operations return nil.
+func CombineMasks(a, b *image.Alpha, op MaskOp) *image.Alpha {
+	rectA, rectB := maskBounds(a), maskBounds(b)
+	var rect image.Rectangle
+	switch op {
+	case MaskUnion, MaskXor: rect = rectA.Union(rectB)
+	case MaskIntersect: rect = rectA.Intersect(rectB)
+	case MaskSubtract: rect = rectA
+	default:
+		return nil
+	}
+	result := image.NewAlpha(rect)
+	if sub := rectA.Intersect(rect); !sub.Empty() {
+		copyMask(result, a.SubImage(sub).(*image.Alpha))
+	}
+
+	overlap := rectB.Intersect(rect)
+	width := overlap.Dx()
+	for y := overlap.Min.Y; y < overlap.Max.Y; y++ {
+		srcRow := b.Pix[b.PixOffset(overlap.Min.X, y) : ][ : width]
+		dstRow := result.Pix[result.PixOffset(overlap.Min.X, y) : ][ : width]
+		for x, value := range srcRow {
+			current := dstRow[x]
+			switch op {
+			case MaskUnion: dstRow[x] = maxUint8(current, value)
+			case MaskIntersect: dstRow[x] = minUint8(current, value)
+			case MaskSubtract:
+				if value >= current { dstRow[x] = 0 } else { dstRow[x] = current - value }
+			case MaskXor:
+				dstRow[x] = maxUint8(current, value) - minUint8(current, value)
+			}
+		}
+	}
+	return result
+}
+
+func maskBounds(mask *image.Alpha) image.Rectangle {
+	if mask == nil { return image.Rectangle{} }
+	return mask.Rect
+}
@@ -0,0 +1,42 @@
+package sfntshape
+
+import "image"
+import "testing"
+
+func TestCombineMasks(t *testing.T) {
+	a := image.NewAlpha(image.Rect(0, 0, 10, 10))
+	b := image.NewAlpha(image.Rect(5, -3, 15, 7))
+	for i := range a.Pix { a.Pix[i] = 200 }
+	for i := range b.Pix { b.Pix[i] = 100 }
+
+	tests := []struct {
+		op MaskOp
+		rect image.Rectangle
+		onlyA, both, onlyB uint8 // at (2, 2), (7, 2) and (12, 2)
+	}{
+		{ MaskUnion, image.Rect(0, -3, 15, 10), 200, 200, 100 },
+		{ MaskIntersect, image.Rect(5, 0, 10, 7), 0, 100, 0 },
+		{ MaskSubtract, image.Rect(0, 0, 10, 10), 200, 100, 0 },
+		{ MaskXor, image.Rect(0, -3, 15, 10), 200, 100, 100 },
+	}
+	for _, test := range tests {
+		result := CombineMasks(a, b, test.op)
+		if result.Rect != test.rect { t.Fatalf("op %d: expected bounds %v, got %v", test.op, test.rect, result.Rect) }
+		got := [3]uint8{ result.AlphaAt(2, 2).A, result.AlphaAt(7, 2).A, result.AlphaAt(12, 2).A }
+		if got != [3]uint8{ test.onlyA, test.both, test.onlyB } {
+			t.Fatalf("op %d: unexpected values %v", test.op, got)
+		}
+		// outside both masks (but inside the union of their rects)
+		if value := result.AlphaAt(12, 9).A; value != 0 { t.Fatalf("op %d: expected 0 outside the masks, got %d", test.op, value) }
+	}
+
+	disjoint := image.NewAlpha(image.Rect(20, 20, 30, 30))
+	for i := range disjoint.Pix { disjoint.Pix[i] = 255 }
+	if result := CombineMasks(a, disjoint, MaskIntersect); !result.Rect.Empty() {
+		t.Fatalf("expected empty intersection, got %v", result.Rect)
+	}
+	if result := CombineMasks(nil, b, MaskUnion); result.Rect != b.Rect || result.AlphaAt(7, 2).A != 100 {
+		t.Fatal("unexpected union with a nil mask")
+	}
+	if CombineMasks(a, b, MaskOp(9)) != nil { t.Fatal("expected nil for an invalid op") }
+}
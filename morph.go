@@ -0,0 +1,121 @@
+package sfntshape
+
+import "math"
+import "image"
+
+// Returns a new mask where each pixel has the maximum coverage found
+// within a disc of the given radius around it in the original mask
+// (the structuring element includes the offsets (dx, dy) with
+// dx*dx + dy*dy <= radius*radius). This grows the shape by radius
+// pixels in every direction, so the returned mask's Rect is the
+// original one enlarged by radius on each side, still in the same
+// coordinates. A common use is creating outlines by subtracting the
+// original mask from the dilated one.
+//
+// Pixels outside the mask are considered transparent. A radius <= 0
+// returns an unmodified copy of the mask.
+func DilateMask(mask *image.Alpha, radius int) *image.Alpha {
+	if radius < 0 { radius = 0 }
+	src := image.NewAlpha(mask.Rect.Inset(-radius))
+	copyMask(src, mask)
+	if radius == 0 { return src }
+	return morphMask(src, radius, true)
+}
+
+// Like [DilateMask](), but taking the minimum instead of the maximum,
+// which shrinks the shape by radius pixels in every direction. The
+// returned mask has the same Rect as the original one. Since pixels
+// outside the mask are considered transparent, shapes touching the
+// mask borders are also eroded from them.
+func ErodeMask(mask *image.Alpha, radius int) *image.Alpha {
+	src := image.NewAlpha(mask.Rect)
+	copyMask(src, mask)
+	if radius <= 0 { return src }
+	return morphMask(src, radius, false)
+}
+
+// Copies the pixels of src into dst, which must contain src.Rect.
+func copyMask(dst, src *image.Alpha) {
+	width := src.Rect.Dx()
+	for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+		copy(dst.Pix[dst.PixOffset(src.Rect.Min.X, y) : ][ : width], src.Pix[src.PixOffset(src.Rect.Min.X, y) : ])
+	}
+}
+
+// Applies a dilation (maximum) or erosion (minimum) with a disc of the
+// given radius to a compact mask. Each row of the disc is handled as a
+// 1D window, computed in linear time with the van Herk/Gil-Werman
+// algorithm, so the cost is proportional to the mask area times
+// 2*radius + 1.
+func morphMask(src *image.Alpha, radius int, dilate bool) *image.Alpha {
+	dst := image.NewAlpha(src.Rect)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	halfWidths := make([]int, 2*radius + 1)
+	for dy := -radius; dy <= radius; dy++ {
+		halfWidths[dy + radius] = int(math.Sqrt(float64(radius*radius - dy*dy)))
+	}
+
+	window := make([]uint8, width)
+	scratch := make([]uint8, 3*(width + 2*radius))
+	for y := 0; y < height; y++ {
+		row := dst.Pix[y*width : (y + 1)*width]
+		if !dilate {
+			for x := range row { row[x] = 0xFF }
+		}
+		for dy := -radius; dy <= radius; dy++ {
+			sy := y + dy
+			if sy < 0 || sy >= height {
+				if dilate { continue } // transparent rows don't change the max
+				for x := range row { row[x] = 0 }
+				break
+			}
+			slidingExtreme(window, src.Pix[sy*width : (sy + 1)*width], halfWidths[dy + radius], dilate, scratch)
+			if dilate {
+				for x, value := range window { row[x] = maxUint8(row[x], value) }
+			} else {
+				for x, value := range window { row[x] = minUint8(row[x], value) }
+			}
+		}
+	}
+	return dst
+}
+
+// Writes to dst[i] the maximum (or minimum) of src[i - w : i + w + 1],
+// with values out of src considered zero. The scratch buffer must have
+// at least 3*(len(src) + 2*w) values.
+func slidingExtreme(dst, src []uint8, w int, max bool, scratch []uint8) {
+	n, k := len(src) + 2*w, 2*w + 1
+	padded, prefix, suffix := scratch[0 : n], scratch[n : 2*n], scratch[2*n : 3*n]
+	for i := 0; i < w; i++ { padded[i], padded[n - 1 - i] = 0, 0 }
+	copy(padded[w : ], src)
+
+	pick := minUint8
+	if max { pick = maxUint8 }
+	for i := 0; i < n; i++ {
+		if i % k == 0 {
+			prefix[i] = padded[i]
+		} else {
+			prefix[i] = pick(prefix[i - 1], padded[i])
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if i == n - 1 || (i + 1) % k == 0 {
+			suffix[i] = padded[i]
+		} else {
+			suffix[i] = pick(suffix[i + 1], padded[i])
+		}
+	}
+	for i := range dst[ : len(src)] {
+		dst[i] = pick(suffix[i], prefix[i + k - 1])
+	}
+}
+
+func maxUint8(a, b uint8) uint8 {
+	if a > b { return a }
+	return b
+}
+
+func minUint8(a, b uint8) uint8 {
+	if a < b { return a }
+	return b
+}
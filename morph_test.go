@@ -0,0 +1,73 @@
+package sfntshape
+
+import "image"
+import "testing"
+
+func TestDilateMask(t *testing.T) {
+	for radius := 0; radius <= 6; radius++ {
+		dot := image.NewAlpha(image.Rect(-3, 5, -2, 6))
+		dot.Pix[0] = 0xFF
+		dilated := DilateMask(dot, radius)
+		if dilated.Rect != dot.Rect.Inset(-radius) {
+			t.Fatalf("radius %d: unexpected Rect %v", radius, dilated.Rect)
+		}
+
+		expected := 0
+		for dy := -radius; dy <= radius; dy++ {
+			for dx := -radius; dx <= radius; dx++ {
+				inside := dx*dx + dy*dy <= radius*radius
+				if inside { expected += 1 }
+				value := dilated.AlphaAt(-3 + dx, 5 + dy).A
+				if inside != (value == 0xFF) {
+					t.Fatalf("radius %d: unexpected value %d at offset (%d, %d)", radius, value, dx, dy)
+				}
+			}
+		}
+		count := 0
+		for _, value := range dilated.Pix {
+			if value != 0 { count += 1 }
+		}
+		if count != expected {
+			t.Fatalf("radius %d: expected %d pixels, got %d", radius, expected, count)
+		}
+
+		eroded := ErodeMask(dilated, radius)
+		if eroded.Rect != dilated.Rect { t.Fatalf("radius %d: erosion changed the Rect", radius) }
+		if eroded.AlphaAt(-3, 5).A != 0xFF { t.Fatalf("radius %d: erosion lost the center", radius) }
+	}
+}
+
+func TestErodeDilate(t *testing.T) {
+	shape := testPolygon(24, 64)
+	mask, err := shape.RasterizeFract(20, 40)
+	if err != nil { t.Fatal(err) }
+	for _, radius := range []int{ 1, 3, 5 } {
+		closed := ErodeMask(DilateMask(mask, radius), radius)
+		opened := DilateMask(ErodeMask(mask, radius), radius)
+		for _, result := range []*image.Alpha{ closed, opened } {
+			differences := 0
+			for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+				for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+					diff := int(mask.AlphaAt(x, y).A) - int(result.AlphaAt(x, y).A)
+					if diff > 64 || diff < -64 { differences += 1 }
+				}
+			}
+			if differences > mask.Rect.Dx()/4 {
+				t.Fatalf("radius %d: too many differences (%d)", radius, differences)
+			}
+		}
+
+		// the interior must be eroded exactly by the radius
+		eroded := ErodeMask(mask, radius)
+		if eroded.AlphaAt(0, 0).A != 0xFF || eroded.AlphaAt(mask.Rect.Min.X + radius - 1, 0).A != 0 {
+			t.Fatalf("radius %d: unexpected erosion", radius)
+		}
+	}
+}
+
+func BenchmarkDilateMask(b *testing.B) {
+	shape := testPolygon(100, 64)
+	mask, err := shape.Rasterize()
+	if err != nil { b.Fatal(err) }
+	for i := 0; i < b.N; i++ { _ = DilateMask(mask, 8) }
+}
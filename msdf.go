@@ -0,0 +1,380 @@
+package sfntshape
+
+import "math"
+import "image"
+import "errors"
+import "image/color"
+
+import "golang.org/x/image/font/sfnt"
+
+// Generates a multi-channel signed distance field (MSDF) for the shape,
+// following the technique by Viktor Chlumsky (msdfgen). Unlike plain SDFs
+// (see [Shape.SDF]()), MSDFs preserve sharp corners when magnified.
+//
+// Edges are assigned to channels (edge coloring) so that the two edges
+// meeting at each corner never share all their channels, and each of the
+// R, G and B channels stores the signed pseudo-distance to the closest
+// edge of that channel, encoded like [Shape.SDF]() does: 128 at the
+// outline, higher values inside. The A channel is always 255. To
+// reconstruct the shape at any scale, sample the image with bilinear
+// filtering and take the median of the three channels:
+//   inside := median(r, g, b) >= 128
+//
+// Like in msdfgen, the distance signs are derived from the orientation
+// of the edges, so holes must be wound in the opposite direction to
+// their containing contour (as they must for the non-zero fill rule
+// anyway). No error correction pass is applied, so some artifacts
+// may remain on edges that are very close to each other.
+//
+// The returned image's Rect (also returned separately for convenience)
+// covers the shape bounds expanded by ceil(spread) pixels on each side.
+// For an empty shape, the image is nil.
+func (self *Shape) MSDF(spread float64) (*image.NRGBA, image.Rectangle, error) {
+	if !(spread > 0) || math.IsInf(spread, 0) {
+		return nil, image.Rectangle{}, errors.New("MSDF spread must be positive and finite")
+	}
+	if self.err != nil { return nil, image.Rectangle{}, self.err }
+	if self.IsEmpty() { return nil, image.Rectangle{}, nothingToDraw() }
+
+	rect := sdfRect(self, spread)
+	if err := checkMaskSize(rect.Dx(), rect.Dy()); err != nil {
+		return nil, image.Rectangle{}, err
+	}
+	contours := buildEdgeContours(self.segments, flattenTolerance)
+	orientation := edgeOrientation(contours)
+	for i := range contours { colorEdges(&contours[i]) }
+
+	msdf := image.NewNRGBA(rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		cy := float64(y) + 0.5
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			cx := float64(x) + 0.5
+			r, g, b := multiChannelDistance(contours, cx, cy, orientation)
+			msdf.SetNRGBA(x, y, color.NRGBA{
+				R: encodeDistance(r, spread),
+				G: encodeDistance(g, spread),
+				B: encodeDistance(b, spread),
+				A: 255,
+			})
+		}
+	}
+	return msdf, rect, nil
+}
+
+// Edge colors as channel bitmasks.
+const (
+	edgeRed     uint8 = 1
+	edgeGreen   uint8 = 2
+	edgeBlue    uint8 = 4
+	edgeYellow  uint8 = edgeRed | edgeGreen
+	edgeMagenta uint8 = edgeRed | edgeBlue
+	edgeCyan    uint8 = edgeGreen | edgeBlue
+	edgeWhite   uint8 = edgeRed | edgeGreen | edgeBlue
+)
+
+// An edge of a contour, flattened into a polyline. The start and end
+// directions are the tangents of the original segment, as the ones of
+// the polyline would turn every junction of a flattened curve into a
+// corner.
+type colorEdge struct {
+	points []PointF
+	start, end PointF // unit directions at the start and end
+	color uint8
+}
+
+// Returns the unit direction at the start of the edge.
+func (self *colorEdge) startDir() PointF { return self.start }
+
+// Returns the unit direction at the end of the edge.
+func (self *colorEdge) endDir() PointF { return self.end }
+
+// Creates an edge for the given polyline, with the tangents at its
+// endpoints determined by the given control points (the first and
+// last distinct ones are used).
+func newColorEdge(points []PointF, controls ...PointF) colorEdge {
+	edge := colorEdge{ points: points }
+	for i := 1; i < len(controls) && edge.start == (PointF{}); i++ {
+		edge.start = unitDir(controls[0], controls[i])
+	}
+	last := len(controls) - 1
+	for i := last - 1; i >= 0 && edge.end == (PointF{}); i-- {
+		edge.end = unitDir(controls[i], controls[last])
+	}
+	return edge
+}
+
+func unitDir(a, b PointF) PointF {
+	dx, dy := b.X - a.X, b.Y - a.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 { return PointF{} }
+	return PointF{ dx/length, dy/length }
+}
+
+// Splits the segments into contours of edges, one edge per non
+// degenerate segment, closing contours implicitly when necessary.
+func buildEdgeContours(segments []sfnt.Segment, tolerance float64) [][]colorEdge {
+	var contours [][]colorEdge
+	var current []colorEdge
+	var pen, start PointF
+	closeContour := func() {
+		if len(current) > 0 && (pen.X != start.X || pen.Y != start.Y) {
+			current = append(current, newColorEdge([]PointF{ pen, start }, pen, start))
+		}
+		if len(current) > 0 { contours = append(contours, current) }
+		current = nil
+	}
+	for _, segment := range segments {
+		if segment.Op == sfnt.SegmentOpMoveTo {
+			closeContour()
+			pen = pointFromFixed(segment.Args[0])
+			start = pen
+			continue
+		}
+		if segment.Op != sfnt.SegmentOpLineTo && segment.Op != sfnt.SegmentOpQuadTo && segment.Op != sfnt.SegmentOpCubeTo {
+			continue
+		}
+		points, end := flattenSegment([]PointF{ pen }, pen, segment, tolerance)
+		points = dedupPoints(points)
+		if len(points) >= 2 {
+			controls := []PointF{ pen }
+			for i := 0; i < segmentArgsCount(segment.Op); i++ {
+				controls = append(controls, pointFromFixed(segment.Args[i]))
+			}
+			current = append(current, newColorEdge(points, controls...))
+		}
+		pen = end
+	}
+	closeContour()
+	return contours
+}
+
+// Removes consecutive duplicated points.
+func dedupPoints(points []PointF) []PointF {
+	out := points[ : 1]
+	for _, point := range points[1 : ] {
+		last := out[len(out) - 1]
+		if point.X != last.X || point.Y != last.Y { out = append(out, point) }
+	}
+	return out
+}
+
+// Returns +1 if the area to the left of the edges is the filled one
+// (based on the overall signed area of the contours), -1 otherwise.
+func edgeOrientation(contours [][]colorEdge) float64 {
+	area := 0.0
+	for _, contour := range contours {
+		for _, edge := range contour {
+			for i := 1; i < len(edge.points); i++ {
+				a, b := edge.points[i - 1], edge.points[i]
+				area += a.X*b.Y - b.X*a.Y
+			}
+		}
+	}
+	if area < 0 { return -1 }
+	return 1
+}
+
+// Returns whether the junction between two edges with the given
+// directions is a corner, using msdfgen's default angle threshold.
+func isCorner(aDir, bDir PointF) bool {
+	const crossThreshold = 0.14112000805986721 // sin(3)
+	dot := aDir.X*bDir.X + aDir.Y*bDir.Y
+	cross := aDir.X*bDir.Y - aDir.Y*bDir.X
+	return dot <= 0 || math.Abs(cross) > crossThreshold
+}
+
+// Assigns colors to the edges of a contour. Port of msdfgen's
+// edgeColoringSimple with a zero seed.
+func colorEdges(contour *[]colorEdge) {
+	edges := *contour
+	var corners []int
+	for i := range edges {
+		prev := edges[(i + len(edges) - 1) % len(edges)]
+		if isCorner(prev.endDir(), edges[i].startDir()) { corners = append(corners, i) }
+	}
+
+	switch len(corners) {
+	case 0: // smooth contour
+		for i := range edges { edges[i].color = edgeWhite }
+	case 1: // teardrop, split in three parts
+		if len(edges) < 3 {
+			edges = splitEdges(edges, corners[0])
+			*contour = edges
+			corners[0] = 0
+		}
+		colors := [3]uint8{ edgeCyan, edgeWhite, edgeMagenta }
+		m := len(edges)
+		for i := 0; i < m; i++ {
+			third := int(3 + 2.875*float64(i)/float64(m - 1) - 1.4375 + 0.5) - 3
+			edges[(corners[0] + i) % m].color = colors[1 + third]
+		}
+	default:
+		color := edgeCyan
+		initial := color
+		spline := 0
+		m := len(edges)
+		for i := 0; i < m; i++ {
+			index := (corners[0] + i) % m
+			if spline + 1 < len(corners) && corners[spline + 1] == index {
+				spline += 1
+				var banned uint8
+				if spline == len(corners) - 1 { banned = initial }
+				color = switchEdgeColor(color, banned)
+			}
+			edges[index].color = color
+		}
+	}
+}
+
+// Switches to the next two-channel color, avoiding the banned one.
+func switchEdgeColor(color, banned uint8) uint8 {
+	combined := color & banned
+	if combined == edgeRed || combined == edgeGreen || combined == edgeBlue {
+		return combined ^ edgeWhite
+	}
+	shifted := color << 1
+	return (shifted | shifted >> 3) & edgeWhite
+}
+
+// Splits the edges of a contour with less than three edges into three or
+// more edges, starting from the given index (which becomes index 0).
+func splitEdges(edges []colorEdge, start int) []colorEdge {
+	parts := 3
+	if len(edges) == 2 { parts = 2 }
+	var result []colorEdge
+	for i := range edges {
+		edge := edges[(start + i) % len(edges)]
+		result = append(result, splitEdge(edge, parts)...)
+	}
+	return result
+}
+
+// Splits an edge into the given number of parts of similar length.
+func splitEdge(edge colorEdge, parts int) []colorEdge {
+	points := edge.points
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += math.Hypot(points[i].X - points[i - 1].X, points[i].Y - points[i - 1].Y)
+	}
+
+	edges := make([]colorEdge, 0, parts)
+	current := []PointF{ points[0] }
+	walked, target := 0.0, total/float64(parts)
+	for i := 1; i < len(points); i++ {
+		a, b := points[i - 1], points[i]
+		length := math.Hypot(b.X - a.X, b.Y - a.Y)
+		for len(edges) < parts - 1 && walked + length >= target && length > 0 {
+			t := (target - walked)/length
+			mid := PointF{ a.X + (b.X - a.X)*t, a.Y + (b.Y - a.Y)*t }
+			current = append(current, mid)
+			dir := unitDir(a, b)
+			edges = append(edges, colorEdge{ points: dedupPoints(current), start: edge.start, end: dir })
+			edge.start = dir
+			current = []PointF{ mid }
+			target += total/float64(parts)
+		}
+		current = append(current, b)
+		walked += length
+	}
+	return append(edges, colorEdge{ points: dedupPoints(current), start: edge.start, end: edge.end })
+}
+
+// Distance from a point to an edge, with the information required
+// to break ties between edges sharing a corner.
+type edgeDistance struct {
+	dist float64 // true unsigned distance
+	dot float64 // alignment with the edge direction at the closest endpoint
+	edge *colorEdge
+}
+
+// Returns whether the distance is smaller than the other. Like in msdfgen,
+// ties (common around corners, where the closest point is the endpoint
+// shared by two edges) are broken in favor of the edge which is more
+// orthogonal to the direction towards the point.
+func (self edgeDistance) lessThan(other edgeDistance) bool {
+	const epsilon = 1e-9
+	if math.Abs(self.dist - other.dist) > epsilon { return self.dist < other.dist }
+	return self.dot < other.dot
+}
+
+// Returns the true distance from the point to the edge.
+func trueEdgeDistance(edge *colorEdge, x, y float64) edgeDistance {
+	n := len(edge.points)
+	result := edgeDistance{ dist: math.Inf(1), edge: edge }
+	for i := 1; i < n; i++ {
+		a, b := edge.points[i - 1], edge.points[i]
+		dx, dy := b.X - a.X, b.Y - a.Y
+		t := ((x - a.X)*dx + (y - a.Y)*dy)/(dx*dx + dy*dy)
+		clamped := math.Max(0, math.Min(1, t))
+		dist := math.Hypot(x - (a.X + clamped*dx), y - (a.Y + clamped*dy))
+		if dist >= result.dist { continue }
+		result.dist, result.dot = dist, 0
+		if i == 1 && t < 0 && dist > 0 {
+			dir := edge.startDir()
+			result.dot = math.Abs(dir.X*(x - a.X) + dir.Y*(y - a.Y))/dist
+		} else if i == n - 1 && t > 1 && dist > 0 {
+			dir := edge.endDir()
+			result.dot = math.Abs(dir.X*(x - b.X) + dir.Y*(y - b.Y))/dist
+		}
+	}
+	return result
+}
+
+// Returns the signed pseudo-distances for each of the three channels
+// at the given point.
+func multiChannelDistance(contours [][]colorEdge, x, y, orientation float64) (float64, float64, float64) {
+	var best [3]edgeDistance
+	var closest edgeDistance
+	for i := range best { best[i].dist = math.Inf(1) }
+	closest.dist = math.Inf(1)
+
+	for c := range contours {
+		for e := range contours[c] {
+			edge := &contours[c][e]
+			distance := trueEdgeDistance(edge, x, y)
+			if distance.lessThan(closest) { closest = distance }
+			for channel := 0; channel < 3; channel++ {
+				if edge.color & (1 << channel) != 0 && distance.lessThan(best[channel]) {
+					best[channel] = distance
+				}
+			}
+		}
+	}
+
+	var out [3]float64
+	for channel := range best {
+		if best[channel].edge == nil { best[channel] = closest }
+		out[channel] = orientation*signedPseudoDistance(best[channel].edge, x, y)
+	}
+	return out[0], out[1], out[2]
+}
+
+// Returns the signed distance from the point to the edge (positive on
+// the left side), using the pseudo-distance (distance to the extension
+// of the edge along its end tangents) beyond the edge endpoints.
+func signedPseudoDistance(edge *colorEdge, x, y float64) float64 {
+	n := len(edge.points)
+	bestDist, bestIndex, bestT := math.Inf(1), 1, 0.0
+	for i := 1; i < n; i++ {
+		a, b := edge.points[i - 1], edge.points[i]
+		dx, dy := b.X - a.X, b.Y - a.Y
+		t := ((x - a.X)*dx + (y - a.Y)*dy)/(dx*dx + dy*dy)
+		clamped := math.Max(0, math.Min(1, t))
+		dist := math.Hypot(x - (a.X + clamped*dx), y - (a.Y + clamped*dy))
+		if dist < bestDist { bestDist, bestIndex, bestT = dist, i, t }
+	}
+
+	a, b := edge.points[bestIndex - 1], edge.points[bestIndex]
+	sign := 1.0
+	if crossSign(a, b, x, y) < 0 { sign = -1 }
+	if bestIndex == 1 && bestT < 0 { // before the start
+		dir := edge.startDir()
+		pseudo := dir.X*(y - a.Y) - dir.Y*(x - a.X)
+		if math.Abs(pseudo) <= bestDist { return pseudo }
+	} else if bestIndex == n - 1 && bestT > 1 { // past the end
+		dir := edge.endDir()
+		pseudo := dir.X*(y - b.Y) - dir.Y*(x - b.X)
+		if math.Abs(pseudo) <= bestDist { return pseudo }
+	}
+	return sign*bestDist
+}
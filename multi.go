@@ -0,0 +1,71 @@
+package sfntshape
+
+import "image"
+import "errors"
+import "image/draw"
+import "image/color"
+
+import "golang.org/x/image/font/sfnt"
+
+// Like [Shape.PaintChecked](), but filling each subpath of the shape
+// with its own color: colors[i] applies to the i-th subpath with drawing
+// commands, and the number of colors must match [Shape.NumSubpaths]()
+// (consecutive or trailing MoveTo commands don't take any color).
+//
+// Consecutive subpaths with the same color are rasterized together, so
+// holes work as expected as long as they have the same color as the
+// contour they cut (e.g., an "o" with a single color). Each group of
+// subpaths is then composited over the previous ones in order, starting
+// from the background. Notice that a hole with a different color from
+// its contour is rasterized on its own and thus filled.
+//
+// The image covers the bounds of the whole shape, like [Shape.Paint]().
+func (self *Shape) PaintMulti(colors []color.Color, backColor color.Color) (*image.RGBA, error) {
+	if self.err != nil { return nil, self.err }
+	segments := self.Segments()
+	if !hasDrawingOps(segments) { return nil, nothingToDraw() }
+	if len(colors) != countSubpaths(segments) {
+		return nil, errors.New("number of colors doesn't match the number of subpaths")
+	}
+	for _, fill := range colors {
+		if fill == nil { return nil, errors.New("nil subpath color") }
+	}
+	width, height, _, _, rectOffset := figureOutBounds(self.bounds, 0, 0)
+	if err := checkMaskSize(width, height); err != nil { return nil, err }
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height).Add(rectOffset))
+	draw.Draw(rgba, rgba.Rect, image.NewUniform(backColor), image.Point{}, draw.Src)
+
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	var scratch image.Alpha
+	paintGroup := func(group []sfnt.Segment, fill color.Color) error {
+		rect, err := RasterizeInto(&scratch, group, rasterizer, 0, 0)
+		if err != nil && !errors.Is(err, ErrNothingToDraw) { return err }
+		if rect.Empty() { return nil }
+		mask, _ := self.paintCoverage(&scratch, nil)
+		drawMask(rgba, mask, image.Point{}, fill, draw.Over)
+		return nil
+	}
+
+	// group consecutive subpaths with the same color
+	start, subpath, drawing := 0, -1, false
+	for i, segment := range segments {
+		if segment.Op == sfnt.SegmentOpMoveTo { drawing = false; continue }
+		if drawing { continue }
+		drawing, subpath = true, subpath + 1
+		if subpath > 0 && !sameColor(colors[subpath - 1], colors[subpath]) {
+			// i > 0, as the subpath comes after another, so the group
+			// ends right before the MoveTo that starts this subpath
+			if err := paintGroup(segments[start : i - 1], colors[subpath - 1]); err != nil { return nil, err }
+			start = i - 1
+		}
+	}
+	if err := paintGroup(segments[start : ], colors[subpath]); err != nil { return nil, err }
+	return rgba, nil
+}
+
+func sameColor(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
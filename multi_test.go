@@ -0,0 +1,67 @@
+package sfntshape
+
+import "bytes"
+import "image/color"
+import "testing"
+
+func TestPaintMulti(t *testing.T) {
+	// target: ring (outer circle + hole) and a center dot
+	shape := New()
+	shape.InvertY(true)
+	testCircle(&shape, 0, 0, 30)
+	shape.InvertY(false) // mirrored, so the hole goes in the opposite direction
+	testCircle(&shape, 0, 0, 20)
+	shape.InvertY(true)
+	testCircle(&shape, 0, 0, 8)
+	red, blue := color.RGBA{ 255, 0, 0, 255 }, color.RGBA{ 0, 0, 255, 255 }
+	back := color.RGBA{ 0, 0, 0, 255 }
+
+	img, err := shape.PaintMulti([]color.Color{ red, red, blue }, back)
+	if err != nil { t.Fatal(err) }
+	expected := map[[2]int]color.RGBA{ { 25, 0 }: red, { -25, 0 }: red, { 14, 0 }: back, { 0, 14 }: back, { 0, 0 }: blue, { 3, -3 }: blue }
+	for point, want := range expected {
+		if got := img.RGBAAt(point[0], point[1]); got != want {
+			t.Fatalf("pixel %v: expected %v, got %v", point, want, got)
+		}
+	}
+
+	// a hole with its own color is rasterized alone and filled
+	img, err = shape.PaintMulti([]color.Color{ red, color.White, blue }, back)
+	if err != nil { t.Fatal(err) }
+	if got := img.RGBAAt(14, 0); got != (color.RGBA{ 255, 255, 255, 255 }) {
+		t.Fatalf("expected the separately colored hole to be filled, got %v", got)
+	}
+
+	// a single color is the same as Paint
+	img, err = shape.PaintMulti([]color.Color{ red, red, red }, back)
+	if err != nil { t.Fatal(err) }
+	if painted := shape.Paint(red, back); img.Rect != painted.Rect || !bytes.Equal(img.Pix, painted.Pix) {
+		t.Fatal("single color PaintMulti differs from Paint")
+	}
+
+	if _, err := shape.PaintMulti([]color.Color{ red, blue }, back); err == nil {
+		t.Fatal("expected error for a colors/subpaths mismatch")
+	}
+
+	// MoveTo-only subpaths don't take colors, like in NumSubpaths
+	expectedImg, err := shape.PaintMulti([]color.Color{ red, red, blue }, back)
+	if err != nil { t.Fatal(err) }
+	shape.Reset()
+	shape.MoveTo(5, 5) // consecutive MoveTo
+	shape.InvertY(true)
+	testCircle(&shape, 0, 0, 30)
+	shape.InvertY(false)
+	testCircle(&shape, 0, 0, 20)
+	shape.MoveTo(0, 0)
+	shape.InvertY(true)
+	testCircle(&shape, 0, 0, 8)
+	shape.MoveTo(7, 7) // trailing MoveTo
+	colors := make([]color.Color, shape.NumSubpaths())
+	if len(colors) != 3 { t.Fatalf("expected 3 subpaths, got %d", len(colors)) }
+	colors[0], colors[1], colors[2] = red, red, blue
+	img, err = shape.PaintMulti(colors, back)
+	if err != nil { t.Fatal(err) }
+	if img.Rect != expectedImg.Rect || !bytes.Equal(img.Pix, expectedImg.Pix) {
+		t.Fatal("MoveTo-only subpaths changed the PaintMulti results")
+	}
+}
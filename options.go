@@ -0,0 +1,323 @@
+package sfntshape
+
+import "math"
+import "image"
+import "errors"
+import "context"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Fill rules determine which regions enclosed by an outline are
+// considered inside it, based on the winding number of each point
+// (the number of times the outline winds around it, with signs
+// depending on the direction).
+type FillRule uint8
+const (
+	// Points with a non-zero winding number are inside. This is the
+	// default rule and the one used by [vector.Rasterizer] and fonts.
+	FillNonZero FillRule = iota
+
+	// Points with an odd winding number are inside. Commonly used by
+	// SVG data (fill-rule="evenodd"). With this rule, the direction of
+	// the subpaths doesn't matter; overlapping areas are always holes.
+	FillEvenOdd
+)
+
+// Options for [RasterizeWithOptions]() and [Shape.RasterizeOpts]().
+// The zero value is valid and equivalent to the default [Rasterize]()
+// behavior: options with only the offsets set produce exactly the
+// same masks as [Rasterize](), so new rasterization features are added
+// here instead of as new Rasterize variants. Invalid values and
+// incompatible combinations (e.g., HardEdges with Supersample) are
+// reported as errors before rasterizing anything.
+type RasterizeOptions struct {
+	// Fractional offset to apply to the outline. See [Rasterize]().
+	OffsetX, OffsetY Fract
+
+	// If not empty, only the region of the mask within the viewport is
+	// rasterized, like [RasterizeClipped]() does for its clip rectangle
+	// (same coordinates as the mask Rect). Additionally, segments lying
+	// far outside the viewport are replaced by cheap substitutes along
+	// its borders before rasterizing, so stray segments shooting far
+	// off-screen don't cost flattening work. The winding of the visible
+	// pixels is preserved, so fills covering the viewport from outside
+	// still work, but results may differ by ±1 on antialiased pixels
+	// compared to cropping the full mask.
+	Viewport image.Rectangle
+
+	// Fill rule to use. Defaults to [FillNonZero].
+	FillRule FillRule
+
+	// Supersampling factor. If 2 or 4, the outline is rasterized at 2x
+	// or 4x the size and then downsampled by averaging NxN blocks, which
+	// results in smoother curves at small sizes (curves are flattened
+	// into more line segments). The resulting mask has the same bounds
+	// as without supersampling. 0 and 1 disable supersampling.
+	Supersample int
+
+	// If true, antialiasing is disabled: pixels are fully opaque if
+	// their coverage reaches HardEdgeCutoff, and fully transparent
+	// otherwise. The thresholding happens before quantization,
+	// and pixels split exactly at the cutoff are considered covered,
+	// so adjacent shapes sharing an edge never leave gaps between them.
+	// Can't be combined with supersampling.
+	HardEdges bool
+
+	// Coverage cutoff for HardEdges, in (0, 1]. Zero defaults to 0.5.
+	HardEdgeCutoff float64
+
+	// If not 0 or 1, each coverage value c of the resulting mask is
+	// mapped to pow(c, 1/Gamma). Values above 1 make partially covered
+	// pixels more opaque. See [RecommendedDarkOnLightGamma].
+	Gamma float64
+
+	// Number of fully transparent pixels to add on every side of the
+	// mask, which is useful to leave room for post-processing effects
+	// like blurs. The mask Rect is enlarged accordingly, so the geometry
+	// stays at the same absolute coordinates. Must not be negative.
+	Padding int
+
+	// Subpixel quantization of the fractional part of the offset, which
+	// is rounded to the nearest multiple of the given quantum (ties round
+	// up), e.g. 16 for quarter-pixel positioning or 64 to snap to whole
+	// pixels. This improves the hit rate of mask caches. If the rounding
+	// reaches the next pixel, the mask Rect is displaced by one pixel
+	// accordingly. Quantums must divide 64; 0 and 1 disable quantization.
+	QuantizeX, QuantizeY Fract
+
+	// If true, the outline is mirrored vertically at rasterization time,
+	// without modifying the segments. The mirroring axis is the center of
+	// the pixel rows covered by the outline bounds, so with integer offsets
+	// the resulting mask has the same Rect as without flipping and its
+	// contents are the vertical mirror of the unflipped mask.
+	//
+	// Unlike [Shape.InvertY](), which is applied to the coordinates as
+	// they are added to the shape (and thus also negates the position of
+	// the mask Rect), FlipY only affects the contents of the mask, so the
+	// same shape can be rendered for both y-down and y-up pipelines. For a
+	// shape with InvertY active, FlipY results in the same pixels the
+	// shape would have without it, but still within the Rect given by
+	// the stored coordinates (and vice versa).
+	FlipY bool
+
+	// If true, the mask is computed in a way that's bit-for-bit stable
+	// across platforms, for reproducible outputs and golden tests. By
+	// default, results are tuned to match [vector.Rasterizer], whose
+	// order of operations differs between amd64 (SIMD) and other
+	// architectures, which can lead to ±1 coverage differences on
+	// some antialiased pixels. In deterministic mode, the outline is
+	// always rasterized with an internal rasterizer (the given or the
+	// shape's rasterizer are not used) that follows a fixed order of
+	// operations and rounds every float32 operation explicitly, so
+	// fused multiply-adds can't be used on any architecture either.
+	//
+	// Guarantees: the same segments and options produce the same mask
+	// on every GOARCH, GOAMD64 (or similar) level and compiler version
+	// following the Go spec's floating point rules. This covers all the
+	// other options, except for Gamma, whose table is computed with
+	// [math.Pow] (portable everywhere except s390x, where an assembly
+	// implementation is used). Results may differ from the default mode
+	// by ±1 on some pixels when it uses floating point math too, and a
+	// bit more for shapes up to 512x512 pixels, which [vector.Rasterizer]
+	// rasterizes with fixed point math. Rasterization is also somewhat
+	// slower for small shapes.
+	Deterministic bool
+
+	// Optional callback to report the progress of long rasterizations.
+	// The total work is the number of outline segments plus the number of
+	// rows to accumulate (multiplied by Supersample, if any), and done
+	// increases monotonically until reaching it. Calls happen on the
+	// rasterizing goroutine every few thousand segments and rows, but only
+	// while the rasterization is still alive; see [RasterizeCtx]().
+	Progress func(done, total int)
+}
+
+// Returns an error if the options are invalid.
+func (self *RasterizeOptions) validate() error {
+	if self.FillRule != FillNonZero && self.FillRule != FillEvenOdd {
+		return errors.New("invalid fill rule")
+	}
+	switch self.Supersample {
+	case 0, 1, 2, 4: // valid
+	default:
+		return errors.New("invalid supersampling factor (must be 1, 2 or 4)")
+	}
+	if self.HardEdgeCutoff < 0 || self.HardEdgeCutoff > 1 {
+		return errors.New("HardEdgeCutoff must be in (0, 1]")
+	}
+	if self.Gamma < 0 || math.IsNaN(self.Gamma) || math.IsInf(self.Gamma, 0) {
+		return errors.New("Gamma must be a positive finite value")
+	}
+	if self.Padding < 0 {
+		return errors.New("Padding can't be negative")
+	}
+	if !isValidQuantum(self.QuantizeX) || !isValidQuantum(self.QuantizeY) {
+		return errors.New("quantums must be 0 or divide 64")
+	}
+	if self.HardEdges && self.Supersample > 1 {
+		return errors.New("HardEdges can't be combined with Supersample")
+	}
+	return nil
+}
+
+func isValidQuantum(quantum Fract) bool {
+	return quantum == 0 || (quantum > 0 && quantum <= 64 && 64 % quantum == 0)
+}
+
+// Rounds the fractional part of the offset to the nearest multiple of
+// the quantum. Returns the quantized offset and whether the rounding
+// reached the next pixel (as 0 or 1).
+func quantizeOffset(offset, quantum Fract) (Fract, int) {
+	if quantum <= 1 { return offset, 0 }
+	fract := (fixedFract(offset) + quantum/2)/quantum*quantum
+	return fixedFloor(offset) + fract, int(fract >> 6)
+}
+
+// Appends the segments mirrored vertically around the center of the
+// pixel rows covered by the given bounds to dst.
+func flipSegmentsY(dst, segments []sfnt.Segment, bounds fixed.Rectangle26_6) []sfnt.Segment {
+	axis := fixedFloor(bounds.Min.Y) + fixedCeil(bounds.Max.Y) // twice the center
+	for _, segment := range segments {
+		for i := 0; i < segmentArgsCount(segment.Op); i++ {
+			segment.Args[i].Y = axis - segment.Args[i].Y
+		}
+		dst = append(dst, segment)
+	}
+	return dst
+}
+
+// Returns the bounds of the segments returned by flipSegmentsY for
+// segments with the given bounds.
+func flipBoundsY(bounds fixed.Rectangle26_6) fixed.Rectangle26_6 {
+	axis := fixedFloor(bounds.Min.Y) + fixedCeil(bounds.Max.Y)
+	bounds.Min.Y, bounds.Max.Y = axis - bounds.Max.Y, axis - bounds.Min.Y
+	return bounds
+}
+
+// Returns the cutoff to use for hard edges.
+func (self *RasterizeOptions) hardEdgeCutoff() float32 {
+	if self.HardEdgeCutoff == 0 { return 0.5 }
+	return float32(self.HardEdgeCutoff)
+}
+
+// Returns whether the options can be handled by the legacy
+// [Rasterize]() path (post-processing options aside).
+func (self *RasterizeOptions) isDefault() bool {
+	return self.FillRule == FillNonZero && self.Supersample <= 1 && !self.HardEdges && !self.Deterministic
+}
+
+// Like [Rasterize](), but with additional configuration options.
+// The given rasterizer is only used if the options don't require
+// anything beyond what [Rasterize]() can do (fill rules, supersampling,
+// hard edges and deterministic mode are handled by an internal
+// rasterizer instead).
+func RasterizeWithOptions(outline sfnt.Segments, rasterizer Rasterizer, opts RasterizeOptions) (*image.Alpha, error) {
+	return RasterizeCtx(context.Background(), outline, rasterizer, opts)
+}
+
+// Returns the bounds expanded by the given number of pixels on each
+// side, so the masks rasterized with them include that padding.
+func padBounds(bounds fixed.Rectangle26_6, padding int) (fixed.Rectangle26_6, error) {
+	pad := int64(padding) << 6
+	minX, minY := int64(bounds.Min.X) - pad, int64(bounds.Min.Y) - pad
+	maxX, maxY := int64(bounds.Max.X) + pad, int64(bounds.Max.Y) + pad
+	if minX < math.MinInt32 || minY < math.MinInt32 || maxX > math.MaxInt32 || maxY > math.MaxInt32 {
+		return bounds, ErrCoordinateOverflow
+	}
+	return fixed.Rectangle26_6 {
+		Min: fixed.Point26_6{ X: Fract(minX), Y: Fract(minY) },
+		Max: fixed.Point26_6{ X: Fract(maxX), Y: Fract(maxY) },
+	}, nil
+}
+
+// Like etxtLikeRasterize, but using our own accumulator so we can
+// control the fill rule and other options.
+func accumulatorRasterize(task *rasterTask, outline sfnt.Segments, bounds fixed.Rectangle26_6, opts RasterizeOptions) (*image.Alpha, error) {
+	width, height, normOffsetX, normOffsetY, rectOffset := figureOutBounds(bounds, opts.OffsetX, opts.OffsetY)
+	rect := image.Rect(0, 0, width, height).Add(rectOffset)
+	return accumulatorRasterizeRect(task, outline, opts, rect, normOffsetX, normOffsetY)
+}
+
+// Like accumulatorRasterize, but rasterizing into a mask with the given
+// rect, with the outline translated by the given offsets (which must
+// place the rect's origin at (0, 0)).
+func accumulatorRasterizeRect(task *rasterTask, outline sfnt.Segments, opts RasterizeOptions, rect image.Rectangle, normOffsetX, normOffsetY Fract) (*image.Alpha, error) {
+	width, height := rect.Dx(), rect.Dy()
+	factor := opts.Supersample
+	if factor < 1 { factor = 1 }
+	if err := checkMaskSize(width*factor, height*factor); err != nil { return nil, err }
+	acc := getAccumulator(width*factor, height*factor)
+	defer releaseAccumulator(acc)
+	acc.portable = opts.Deterministic
+
+	task.total = len(outline) + height*factor
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	if factor == 1 {
+		err := processOutlineTask(task, acc, outline, normOffsetX, normOffsetY)
+		if err != nil { return nil, err }
+		cutoff := opts.hardEdgeCutoff()
+		err = accumulateRowsTask(task, acc, mask.Pix, func(rows *accumulator, dst []uint8, from float32) {
+			if opts.HardEdges {
+				rows.AccumulateThresholdIntoFrom(dst, opts.FillRule, cutoff, from)
+			} else {
+				rows.AccumulateIntoFrom(dst, opts.FillRule, from)
+			}
+		})
+		if err != nil { return nil, err }
+	} else {
+		processor := &scaledProcessor{ acc, float32(factor) }
+		err := processOutlineTask(task, processor, outline, normOffsetX, normOffsetY)
+		if err != nil { return nil, err }
+		hiRes := acc.Scratch(width*height*factor*factor)
+		err = accumulateRowsTask(task, acc, hiRes, func(rows *accumulator, dst []uint8, from float32) {
+			rows.AccumulateIntoFrom(dst, opts.FillRule, from)
+		})
+		if err != nil { return nil, err }
+		downsampleBlocks(mask.Pix, hiRes, width, height, factor)
+	}
+	mask.Rect = rect
+	return mask, nil
+}
+
+// Averages factor x factor blocks of src into dst, which has the
+// given width and height (src is factor times bigger in each axis).
+func downsampleBlocks(dst, src []uint8, width, height, factor int) {
+	srcStride := width*factor
+	area := uint32(factor*factor)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sum uint32
+			offset := y*factor*srcStride + x*factor
+			for sy := 0; sy < factor; sy++ {
+				row := src[offset + sy*srcStride : ][ : factor]
+				for _, value := range row { sum += uint32(value) }
+			}
+			dst[y*width + x] = uint8((sum + area/2)/area)
+		}
+	}
+}
+
+// A pathProcessor wrapper that scales all coordinates.
+type scaledProcessor struct {
+	processor pathProcessor
+	scale float32
+}
+
+func (self *scaledProcessor) MoveTo(x, y float32) {
+	self.processor.MoveTo(x*self.scale, y*self.scale)
+}
+
+func (self *scaledProcessor) LineTo(x, y float32) {
+	self.processor.LineTo(x*self.scale, y*self.scale)
+}
+
+func (self *scaledProcessor) QuadTo(bx, by, cx, cy float32) {
+	k := self.scale
+	self.processor.QuadTo(bx*k, by*k, cx*k, cy*k)
+}
+
+func (self *scaledProcessor) CubeTo(bx, by, cx, cy, dx, dy float32) {
+	k := self.scale
+	self.processor.CubeTo(bx*k, by*k, cx*k, cy*k, dx*k, dy*k)
+}
@@ -0,0 +1,455 @@
+package sfntshape
+
+import "math"
+import "image"
+import "bytes"
+import "errors"
+import "hash/crc32"
+import "testing"
+import "image/color"
+
+import "golang.org/x/image/vector"
+
+import "github.com/tinne26/sfntshape/internal/maskcmp"
+
+// Returns the mean absolute error of the mask against the analytic
+// coverage of a circle, computed with dense point sampling.
+func circleMaskError(mask *image.Alpha, cx, cy, radius float64) float64 {
+	const samples = 16
+	var totalError float64
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			inside := 0
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					px := float64(x) + (float64(sx) + 0.5)/samples - cx
+					py := float64(y) + (float64(sy) + 0.5)/samples - cy
+					if px*px + py*py < radius*radius { inside += 1 }
+				}
+			}
+			expected := float64(inside)/(samples*samples)
+			totalError += math.Abs(float64(mask.AlphaAt(x, y).A)/255 - expected)
+		}
+	}
+	return totalError/float64(mask.Rect.Dx()*mask.Rect.Dy())
+}
+
+func TestSupersample(t *testing.T) {
+	shape := New()
+	testCircle(&shape, 8, 8, 7.3) // 16px icon-like circle
+
+	base, err := shape.RasterizeOpts(RasterizeOptions{})
+	if err != nil { t.Fatal(err) }
+	prevError := circleMaskError(base, 8, -8, 7.3)
+	for _, factor := range []int{2, 4} {
+		mask, err := shape.RasterizeOpts(RasterizeOptions{ Supersample: factor })
+		if err != nil { t.Fatal(err) }
+		if mask.Rect != base.Rect {
+			t.Fatalf("supersample %d: expected rect %v, got %v", factor, base.Rect, mask.Rect)
+		}
+		maskError := circleMaskError(mask, 8, -8, 7.3)
+		if maskError >= prevError {
+			t.Fatalf("supersample %d: expected edges closer to the ideal circle (error %.5f vs %.5f)", factor, maskError, prevError)
+		}
+		prevError = maskError
+	}
+
+	// rectangles at integer coordinates are exact either way
+	shape.Reset()
+	shape.MoveTo(0, 0)
+	shape.LineTo(10, 0)
+	shape.LineTo(10, 6)
+	shape.LineTo(0, 6)
+	shape.LineTo(0, 0)
+	base, _ = shape.Rasterize()
+	mask, err := shape.RasterizeOpts(RasterizeOptions{ Supersample: 4, FillRule: FillEvenOdd })
+	if err != nil { t.Fatal(err) }
+	if maskcmp.Compare(base, mask, 0, 0) != nil {
+		t.Fatal("expected identical results for axis-aligned rectangles")
+	}
+
+	_, err = shape.RasterizeOpts(RasterizeOptions{ Supersample: 3 })
+	if err == nil { t.Fatal("expected error for invalid supersampling factor") }
+}
+
+func benchmarkSupersample(b *testing.B, factor int) {
+	shape := New()
+	testCircle(&shape, 8, 8, 7.3)
+	opts := RasterizeOptions{ Supersample: factor }
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = shape.RasterizeOpts(opts)
+	}
+}
+
+func BenchmarkSupersample1(b *testing.B) { benchmarkSupersample(b, 1) }
+func BenchmarkSupersample2(b *testing.B) { benchmarkSupersample(b, 2) }
+func BenchmarkSupersample4(b *testing.B) { benchmarkSupersample(b, 4) }
+
+func TestHardEdges(t *testing.T) {
+	shape := New()
+	shape.MoveTo(2, 3)
+	shape.LineTo(20, 3)
+	shape.LineTo(20, 11)
+	shape.LineTo(2, 11)
+	shape.LineTo(2, 3)
+	base, _ := shape.Rasterize()
+	hard, err := shape.RasterizeOpts(RasterizeOptions{ HardEdges: true })
+	if err != nil { t.Fatal(err) }
+	if maskcmp.Compare(base, hard, 0, 0) != nil {
+		t.Fatal("expected identical results for rectangles at integer coordinates")
+	}
+
+	// rotated 40x20 rectangle
+	shape.Reset()
+	sin, cos := math.Sincos(math.Pi/6)
+	corners := [][2]float64{ {0, 0}, {40, 0}, {40, 20}, {0, 20}, {0, 0} }
+	for i, corner := range corners {
+		x := fixedFromFloat64(corner[0]*cos - corner[1]*sin)
+		y := fixedFromFloat64(corner[0]*sin + corner[1]*cos)
+		if i == 0 { shape.MoveToFract(x, y) } else { shape.LineToFract(x, y) }
+	}
+	hard, err = shape.RasterizeOpts(RasterizeOptions{ HardEdges: true })
+	if err != nil { t.Fatal(err) }
+	filled := 0
+	for _, value := range hard.Pix {
+		if value != 0 && value != 255 { t.Fatalf("unexpected alpha %d", value) }
+		if value == 255 { filled += 1 }
+	}
+	if filled < 784 || filled > 816 {
+		t.Fatalf("expected around 800 filled pixels, got %d", filled)
+	}
+
+	// two triangles splitting a square along its diagonal leave no gaps
+	left, right := New(), New()
+	left.MoveToFract(0, 0)
+	left.LineToFract(640, 0)
+	left.LineToFract(0, 640)
+	left.LineToFract(0, 0)
+	right.MoveToFract(640, 0)
+	right.LineToFract(640, 640)
+	right.LineToFract(0, 640)
+	right.LineToFract(640, 0)
+	dst := image.NewAlpha(image.Rect(0, -10, 10, 0))
+	for _, triangle := range []*Shape{&left, &right} {
+		mask, err := triangle.RasterizeOpts(RasterizeOptions{ HardEdges: true })
+		if err != nil { t.Fatal(err) }
+		for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+			for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+				if mask.AlphaAt(x, y).A == 255 { dst.SetAlpha(x, y, mask.AlphaAt(x, y)) }
+			}
+		}
+	}
+	for _, value := range dst.Pix {
+		if value != 255 { t.Fatal("expected no seams between adjacent hard-edged shapes") }
+	}
+
+	_, err = shape.RasterizeOpts(RasterizeOptions{ HardEdges: true, Supersample: 2 })
+	if err == nil { t.Fatal("expected error combining HardEdges and Supersample") }
+}
+
+func TestGamma(t *testing.T) {
+	for _, gamma := range []float64{0.5, 1, RecommendedDarkOnLightGamma, 2.2} {
+		lut := getGammaLUT(gamma)
+		if lut[0] != 0 || lut[255] != 255 {
+			t.Fatalf("gamma %f: bad LUT endpoints %d, %d", gamma, lut[0], lut[255])
+		}
+		for i := 1; i < 256; i++ {
+			if lut[i] < lut[i - 1] { t.Fatalf("gamma %f: LUT not monotonic at %d", gamma, i) }
+		}
+	}
+
+	shape := New()
+	testCircle(&shape, 0, 0, 10)
+	base, _ := shape.Rasterize()
+	same, err := shape.RasterizeOpts(RasterizeOptions{ Gamma: 1 })
+	if err != nil { t.Fatal(err) }
+	if maskcmp.Compare(base, same, 0, 0) != nil { t.Fatal("expected gamma 1 to be byte-identical") }
+
+	corrected, err := shape.RasterizeOpts(RasterizeOptions{ Gamma: RecommendedDarkOnLightGamma })
+	if err != nil { t.Fatal(err) }
+	for i, value := range base.Pix {
+		if value != 0 && value != 255 && corrected.Pix[i] <= value {
+			t.Fatalf("expected gamma > 1 to increase partial coverage (%d -> %d)", value, corrected.Pix[i])
+		}
+	}
+
+	_, err = shape.RasterizeOpts(RasterizeOptions{ Gamma: -1 })
+	if err == nil { t.Fatal("expected error for negative gamma") }
+}
+
+func TestPadding(t *testing.T) {
+	shape := testStar(12)
+	full, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	viewport := full.Rect.Inset(full.Rect.Dx()/4) // clipped segments must not leak into the padding
+	for _, opts := range []RasterizeOptions{
+		{}, { FillRule: FillEvenOdd }, { OffsetX: 20, Gamma: 2 },
+		{ Viewport: viewport }, { Viewport: viewport, HardEdges: true },
+	} {
+		base, err := shape.RasterizeOpts(opts)
+		if err != nil { t.Fatal(err) }
+		opts.Padding = 3
+		padded, err := shape.RasterizeOpts(opts)
+		if err != nil { t.Fatal(err) }
+		if padded.Rect != base.Rect.Inset(-3) {
+			t.Fatalf("expected rect %v, got %v", base.Rect.Inset(-3), padded.Rect)
+		}
+		for y := padded.Rect.Min.Y; y < padded.Rect.Max.Y; y++ {
+			for x := padded.Rect.Min.X; x < padded.Rect.Max.X; x++ {
+				value := padded.AlphaAt(x, y).A
+				if image.Pt(x, y).In(base.Rect) {
+					if value != base.AlphaAt(x, y).A { t.Fatalf("padded interior differs at (%d, %d)", x, y) }
+				} else if value != 0 {
+					t.Fatalf("expected transparent padding at (%d, %d), got %d", x, y, value)
+				}
+			}
+		}
+	}
+
+	_, err = shape.RasterizeOpts(RasterizeOptions{ Padding: -1 })
+	if err == nil { t.Fatal("expected error for negative padding") }
+}
+
+func TestQuantizeOffset(t *testing.T) {
+	shape := testStar(12)
+	rasterize := func(offsetX, offsetY, quantum Fract) *image.Alpha {
+		mask, err := shape.RasterizeOpts(RasterizeOptions{
+			OffsetX: offsetX, OffsetY: offsetY, QuantizeX: quantum, QuantizeY: quantum,
+		})
+		if err != nil { t.Fatal(err) }
+		return mask
+	}
+	sameMask := func(a, b *image.Alpha) bool {
+		return maskcmp.Compare(a, b, 0, 0) == nil
+	}
+
+	// 0.2px and 0.3px round to 0.25px (16/64)
+	quarter := rasterize(16, 16, 0)
+	if !sameMask(rasterize(13, 19, 16), quarter) || !sameMask(rasterize(19, 13, 16), quarter) {
+		t.Fatal("offsets in the same quantum produced different masks")
+	}
+	if !sameMask(rasterize(6, 13, 32), rasterize(0, 0, 0)) { // 0.1px and 0.2px round to 0
+		t.Fatal("offsets in the same quantum produced different masks")
+	}
+	if sameMask(rasterize(13, 0, 0), quarter) || sameMask(rasterize(13, 0, 1), quarter) {
+		t.Fatal("unquantized offsets must not be rounded")
+	}
+
+	// full pixel snapping, rounding up to the next pixel when needed
+	base := rasterize(0, 0, 0)
+	if !sameMask(rasterize(64*3 + 20, 31, 64), base) { t.Fatal("quantum 64 must snap to whole pixels") }
+	snapped := rasterize(40, 64*2 + 32, 64)
+	if snapped.Rect != base.Rect.Add(image.Pt(1, 1)) {
+		t.Fatalf("expected mask displaced by one pixel, got Rect %v", snapped.Rect)
+	}
+	for y := base.Rect.Min.Y; y < base.Rect.Max.Y; y++ {
+		for x := base.Rect.Min.X; x < base.Rect.Max.X; x++ {
+			if base.AlphaAt(x, y) != snapped.AlphaAt(x + 1, y + 1) { t.Fatal("displaced mask differs") }
+		}
+	}
+
+	if _, err := shape.RasterizeOpts(RasterizeOptions{ QuantizeX: 12 }); err == nil {
+		t.Fatal("expected error for a quantum not dividing 64")
+	}
+}
+
+func BenchmarkGammaNone(b *testing.B) {
+	shape := New()
+	testCircle(&shape, 0, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ { _, _ = shape.RasterizeOpts(RasterizeOptions{}) }
+}
+
+func BenchmarkGammaLUT(b *testing.B) {
+	shape := New()
+	testCircle(&shape, 0, 0, 64)
+	opts := RasterizeOptions{ Gamma: RecommendedDarkOnLightGamma }
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ { _, _ = shape.RasterizeOpts(opts) }
+}
+
+func TestDeterministic(t *testing.T) {
+	// (integer coordinates only, as math.Sin and others may differ across platforms)
+	pointy := New()
+	pointy.MoveTo(0, 0)
+	pointy.CubeTo(30, 70, 40, -10, 60, 60)
+	pointy.QuadTo(10, 50, 0, 0)
+	shapes := []Shape{ bigTestShape(100), bigTestShape(700), pointy }
+	for i := range shapes {
+		for _, opts := range []RasterizeOptions{
+			{ Deterministic: true, OffsetX: 13, OffsetY: 51 },
+			{ Deterministic: true, Supersample: 2, FillRule: FillEvenOdd },
+		} {
+			mask, err := shapes[i].RasterizeOpts(opts)
+			if err != nil { t.Fatal(err) }
+
+			// the platform dependent accumulation order must not matter
+			accumulateLikeSIMD = !accumulateLikeSIMD
+			other, err := shapes[i].RasterizeOpts(opts)
+			accumulateLikeSIMD = !accumulateLikeSIMD
+			if err != nil { t.Fatal(err) }
+			if !bytes.Equal(mask.Pix, other.Pix) { t.Fatalf("shape %d: results depend on the accumulation order", i) }
+
+			// close to the default results when they use floating point math
+			opts.Deterministic = false
+			if opts.isDefault() && !usesFloatingPoint(shapes[i].Bounds(), opts) { continue }
+			regular, err := shapes[i].RasterizeOpts(opts)
+			if err != nil { t.Fatal(err) }
+			if mask.Rect != regular.Rect { t.Fatalf("shape %d: unexpected Rect %v", i, mask.Rect) }
+			for j, value := range mask.Pix {
+				if absInt(int(value) - int(regular.Pix[j])) > 1 {
+					t.Fatalf("shape %d: expected at most ±1 differences, got %d vs %d", i, value, regular.Pix[j])
+				}
+			}
+		}
+	}
+
+	// checksums of the portable results, which must be the same on all
+	// platforms and GOAMD64 levels (GOAMD64=v3 enables fused multiply-adds)
+	expected := []uint32{ 0xBDF2D51B, 0xE62963EC, 0x8AF7CBBA }
+	for i := range shapes {
+		mask, err := shapes[i].RasterizeOpts(RasterizeOptions{ Deterministic: true, OffsetX: 7, OffsetY: 33 })
+		if err != nil { t.Fatal(err) }
+		if sum := crc32.ChecksumIEEE(mask.Pix); sum != expected[i] {
+			t.Fatalf("shape %d: expected checksum 0x%08X, got 0x%08X", i, expected[i], sum)
+		}
+	}
+}
+
+func TestShapeDeterministic(t *testing.T) {
+	shape := bigTestShape(700)
+	expected, err := shape.RasterizeOpts(RasterizeOptions{ Deterministic: true })
+	if err != nil { t.Fatal(err) }
+	shape.SetDeterministic(true)
+	if !shape.IsDeterministic() { t.Fatal("expected deterministic mode") }
+	mask, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	if !bytes.Equal(mask.Pix, expected.Pix) { t.Fatal("expected deterministic results from Rasterize") }
+
+	// paint and stroke results must not depend on the accumulation order
+	paint := func() ([]uint8, []uint8) {
+		stroke, err := shape.RasterizeStroke(3.5, CapRound, JoinRound)
+		if err != nil { t.Fatal(err) }
+		return shape.Paint(color.White, color.Black).Pix, stroke.Pix
+	}
+	paintPix, strokePix := paint()
+	accumulateLikeSIMD = !accumulateLikeSIMD
+	otherPaintPix, otherStrokePix := paint()
+	accumulateLikeSIMD = !accumulateLikeSIMD
+	if !bytes.Equal(paintPix, otherPaintPix) || !bytes.Equal(strokePix, otherStrokePix) {
+		t.Fatal("painted results depend on the accumulation order")
+	}
+}
+
+// Defines a "b"-like shape: a stem with a bowl at its bottom right.
+func testLetterB(shape *Shape) {
+	shape.MoveTo(0, 0)
+	shape.LineTo(4, 0)
+	shape.LineTo(4, 12)
+	shape.QuadTo(14, 10, 14, 18)
+	shape.QuadTo(14, 26, 4, 24)
+	shape.LineTo(4, 25)
+	shape.LineTo(0, 25)
+	shape.LineTo(0, 0)
+	shape.MoveTo(4, 15)
+	shape.QuadTo(10, 14, 10, 18)
+	shape.QuadTo(10, 22, 4, 21)
+	shape.LineTo(4, 15)
+}
+
+func TestFlipY(t *testing.T) {
+	shape := New()
+	testLetterB(&shape)
+	for _, opts := range []RasterizeOptions{ {}, { OffsetX: 21 }, { Supersample: 4 }, { Deterministic: true } } {
+		mask, err := shape.RasterizeOpts(opts)
+		if err != nil { t.Fatal(err) }
+		opts.FlipY = true
+		flipped, err := shape.RasterizeOpts(opts)
+		if err != nil { t.Fatal(err) }
+		if flipped.Rect != mask.Rect { t.Fatalf("expected Rect %v, got %v", mask.Rect, flipped.Rect) }
+
+		width, height := mask.Rect.Dx(), mask.Rect.Dy()
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				a, b := mask.Pix[y*width + x], flipped.Pix[(height - 1 - y)*width + x]
+				if absInt(int(a) - int(b)) > 1 {
+					t.Fatalf("%+v: expected mirrored masks, got %d vs %d at (%d, %d)", opts, a, b, x, y)
+				}
+			}
+		}
+		if bytes.Equal(mask.Pix, flipped.Pix) { t.Fatal("expected an asymmetric shape") }
+	}
+
+	// FlipY undoes InvertY within the stored Rect
+	inverted := New()
+	inverted.InvertY(true)
+	testLetterB(&inverted)
+	mask, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	flipped, err := inverted.RasterizeOpts(RasterizeOptions{ FlipY: true })
+	if err != nil { t.Fatal(err) }
+	if flipped.Rect != mask.Rect.Add(image.Pt(0, 26)) { t.Fatalf("unexpected Rect %v", flipped.Rect) }
+	if !bytes.Equal(flipped.Pix, mask.Pix) { t.Fatal("expected FlipY to undo InvertY") }
+}
+
+func TestDefaultOptions(t *testing.T) {
+	pointy := New()
+	pointy.MoveTo(0, 0)
+	pointy.CubeTo(30, 70, 40, -10, 60, 60)
+	pointy.QuadTo(10, 50, 0, 0)
+	shapes := []Shape{ bigTestShape(100), bigTestShape(700), pointy } // 700 uses floating point
+	offsets := [][2]Fract{ { 0, 0 }, { 13, 51 }, { -100, 7 } }
+	for i := range shapes {
+		outline := shapes[i].Segments()
+		for _, offset := range offsets {
+			legacy, err := Rasterize(outline, vector.NewRasterizer(0, 0), offset[0], offset[1])
+			if err != nil { t.Fatal(err) }
+			opts := RasterizeOptions{ OffsetX: offset[0], OffsetY: offset[1] }
+
+			withOpts, err := RasterizeWithOptions(outline, vector.NewRasterizer(0, 0), opts)
+			if err != nil { t.Fatal(err) }
+			fromShape, err := shapes[i].RasterizeFract(offset[0], offset[1])
+			if err != nil { t.Fatal(err) }
+			for _, mask := range []*image.Alpha{ withOpts, fromShape } {
+				if mask.Rect != legacy.Rect || mask.Stride != legacy.Stride || !bytes.Equal(mask.Pix, legacy.Pix) {
+					t.Fatalf("shape %d, offset %v: default options differ from Rasterize", i, offset)
+				}
+			}
+		}
+	}
+
+	// same errors for empty outlines
+	empty := New()
+	empty.MoveTo(5, 5)
+	for _, enabled := range []bool{ false, true } {
+		SetNothingToDrawErrors(enabled)
+		legacy, legacyErr := Rasterize(empty.Segments(), vector.NewRasterizer(0, 0), 0, 0)
+		mask, err := empty.RasterizeOpts(RasterizeOptions{})
+		if mask != nil || legacy != nil || legacyErr != err {
+			t.Fatalf("expected the same result for empty outlines, got %v and %v", legacyErr, err)
+		}
+		if enabled && !errors.Is(err, ErrNothingToDraw) { t.Fatalf("expected ErrNothingToDraw, got %v", err) }
+	}
+	SetNothingToDrawErrors(false)
+}
+
+func TestInvalidOptions(t *testing.T) {
+	shape := New()
+	testTriangle(&shape)
+	for _, opts := range []RasterizeOptions{
+		{ FillRule: 7 },
+		{ Supersample: 3 },
+		{ HardEdges: true, HardEdgeCutoff: 1.5 },
+		{ Gamma: math.Inf(1) },
+		{ Gamma: -2 },
+		{ Padding: -1 },
+		{ QuantizeX: 24 },
+		{ HardEdges: true, Supersample: 4 },
+	} {
+		if _, err := RasterizeWithOptions(shape.Segments(), vector.NewRasterizer(0, 0), opts); err == nil {
+			t.Fatalf("expected an error for %+v", opts)
+		}
+		if _, err := shape.RasterizeOpts(opts); err == nil { t.Fatalf("expected an error for %+v", opts) }
+	}
+}
@@ -0,0 +1,73 @@
+package sfntshape
+
+import "image"
+import "errors"
+import "image/color"
+
+// Like [Shape.Paint](), but mapping each pixel to the nearest color of
+// the given palette (as with [color.Palette.Index]()), for GIF encoding
+// and similar. Returns nil if the palette is empty or has more than 256
+// colors, or for the same reasons as [Shape.Paint]().
+//
+// Antialiased edges tend to band into contours with small palettes; see
+// [Shape.PaintPalettedDithered]() for an alternative.
+func (self *Shape) PaintPaletted(palette color.Palette, drawColor, backColor color.Color) *image.Paletted {
+	paletted, err := self.paintPaletted(palette, drawColor, backColor, false)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewPaletted(image.Rectangle{}, palette) }
+	if err != nil { return nil }
+	return paletted
+}
+
+// Like [Shape.PaintPaletted](), but with ordered dithering (4x4 Bayer
+// matrix, aligned to the mask's coordinate space) applied to the
+// coverage of partially covered pixels. The coverage is perturbed by up
+// to the coverage distance between consecutive palette colors along the
+// draw color to background ramp, so edges alternate between those two
+// colors in proportion to their coverage instead of banding. Pixels that
+// are fully covered or uncovered are never dithered.
+func (self *Shape) PaintPalettedDithered(palette color.Palette, drawColor, backColor color.Color) *image.Paletted {
+	paletted, err := self.paintPaletted(palette, drawColor, backColor, true)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewPaletted(image.Rectangle{}, palette) }
+	if err != nil { return nil }
+	return paletted
+}
+
+func (self *Shape) paintPaletted(palette color.Palette, drawColor, backColor color.Color, dither bool) (*image.Paletted, error) {
+	if len(palette) == 0 || len(palette) > 256 {
+		return nil, errors.New("palettes must have between 1 and 256 colors")
+	}
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+
+	// palette index for each coverage value, and number of index
+	// changes along the ramp for the dithering amplitude
+	back := color.RGBAModel.Convert(backColor).(color.RGBA)
+	back64 := color.RGBA64{ R: uint16(back.R)*0x101, G: uint16(back.G)*0x101, B: uint16(back.B)*0x101, A: uint16(back.A)*0x101 }
+	var indices [256]uint8
+	changes := 0
+	for i, colors := range paintLUT(drawColor, back64, self.linearBlending) {
+		blended := color.RGBA{ uint8(colors[0] >> 8), uint8(colors[1] >> 8), uint8(colors[2] >> 8), uint8(colors[3] >> 8) }
+		indices[i] = uint8(palette.Index(blended))
+		if i > 0 && indices[i] != indices[i - 1] { changes += 1 }
+	}
+	step := 255.0
+	if changes > 1 { step = 255.0/float64(changes) }
+
+	paletted := image.NewPaletted(mask.Rect, palette)
+	width := mask.Rect.Dx()
+	for y := 0; y < mask.Rect.Dy(); y++ {
+		maskRow := mask.Pix[y*mask.Stride : ][ : width]
+		dstRow := paletted.Pix[y*paletted.Stride : ][ : width]
+		bayerRow := bayerMatrix[(mask.Rect.Min.Y + y) & 3]
+		for x, value := range maskRow {
+			if dither && value != 0 && value != 255 {
+				threshold := (float64(bayerRow[(mask.Rect.Min.X + x) & 3]) + 0.5)/16
+				dithered := float64(value) + (threshold - 0.5)*step + 0.5
+				if dithered < 0 { dithered = 0 } else if dithered > 255 { dithered = 255 }
+				value = uint8(dithered)
+			}
+			dstRow[x] = indices[value]
+		}
+	}
+	return paletted, nil
+}
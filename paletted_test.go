@@ -0,0 +1,53 @@
+package sfntshape
+
+import "image/color"
+import "testing"
+
+func TestPaintPaletted(t *testing.T) {
+	shape := New()
+	testCircle(&shape, 0, 0, 20)
+	palette := color.Palette{ color.Black, color.White, color.RGBA{ 255, 0, 0, 255 }, color.RGBA{ 128, 0, 0, 255 } }
+	for _, dither := range []bool{ false, true } {
+		img := shape.PaintPaletted(palette, color.RGBA{ 255, 0, 0, 255 }, color.White)
+		if dither { img = shape.PaintPalettedDithered(palette, color.RGBA{ 255, 0, 0, 255 }, color.White) }
+		if img == nil { t.Fatal("unexpected nil image") }
+		for i, index := range img.Pix {
+			if int(index) >= len(palette) { t.Fatalf("pixel %d: index %d out of the palette", i, index) }
+		}
+		if img.ColorIndexAt(0, 0) != 2 || img.ColorIndexAt(img.Rect.Min.X, img.Rect.Min.Y) != 1 {
+			t.Fatal("unexpected colors for interior or exterior pixels")
+		}
+	}
+
+	// rectangle with a column at 50% coverage on the right edge
+	shape.Reset()
+	shape.MoveTo(0, 0)
+	shape.LineToFract(10*64 + 32, 0)
+	shape.LineToFract(10*64 + 32, 16*64)
+	shape.LineTo(0, 16)
+	shape.LineTo(0, 0)
+	blackWhite := color.Palette{ color.Black, color.White }
+	img := shape.PaintPalettedDithered(blackWhite, color.White, color.Black)
+	mask, _ := shape.Rasterize()
+	whites := 0
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		if coverage := mask.AlphaAt(10, y).A; coverage < 126 || coverage > 129 {
+			t.Fatalf("expected 50%% coverage at (10, %d), got %d", y, coverage)
+		}
+		expected := uint8(0)
+		if bayerMatrix[y & 3][10 & 3] >= 8 { expected = 1 }
+		if got := img.ColorIndexAt(10, y); got != expected {
+			t.Fatalf("pixel (10, %d): expected index %d per the dither matrix, got %d", y, expected, got)
+		}
+		whites += int(img.ColorIndexAt(10, y))
+		if img.ColorIndexAt(5, y) != 1 { t.Fatalf("interior pixel (5, %d) was dithered", y) }
+	}
+	if whites != 8 { t.Fatalf("expected half of the edge pixels to be white, got %d of 16", whites) }
+
+	// without dithering, the whole edge gets the same color
+	img = shape.PaintPaletted(blackWhite, color.White, color.Black)
+	for y := img.Rect.Min.Y + 1; y < img.Rect.Max.Y; y++ {
+		if img.ColorIndexAt(10, y) != img.ColorIndexAt(10, img.Rect.Min.Y) { t.Fatal("unexpected dithering") }
+	}
+	if shape.PaintPaletted(nil, color.White, color.Black) != nil { t.Fatal("expected nil image for an empty palette") }
+}
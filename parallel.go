@@ -0,0 +1,91 @@
+package sfntshape
+
+import "sync"
+import "image"
+import "runtime"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/vector"
+
+// Like [Rasterize]() with a zero origin, but splitting the work into
+// horizontal bands that are rasterized concurrently by the given number
+// of workers (if workers <= 0, runtime.GOMAXPROCS(0) is used instead).
+// The outline is only read, so it's safe to share between goroutines.
+//
+// Results are pixel-identical to [Rasterize](). Since vector's floating
+// point accumulation runs across the whole image, bands are processed in
+// three stages: rasterizing the area contributions of each band (parallel),
+// chaining the accumulated values between bands (sequential, but only
+// over the few non-zero contributions) and finally accumulating and
+// quantizing the coverage of each band (parallel).
+//
+// Shapes that fit within 512x512 pixels are rasterized with fixed point
+// math by [vector.Rasterizer] and are small enough to not benefit from
+// parallelism, so they are always rasterized serially.
+func RasterizeParallel(outline sfnt.Segments, workers int) (*image.Alpha, error) {
+	if err := validateOps(outline); err != nil { return nil, err }
+	if !hasDrawingOps(outline) { return nil, nothingToDraw() }
+	if workers <= 0 { workers = runtime.GOMAXPROCS(0) }
+	width, height, normOffsetX, normOffsetY, rectOffset := figureOutBounds(outline.Bounds(), 0, 0)
+	if workers == 1 || (width <= vectorFloatingPointThreshold && height <= vectorFloatingPointThreshold) {
+		return Rasterize(outline, vector.NewRasterizer(0, 0), 0, 0)
+	}
+	if err := checkMaskSize(width, height); err != nil { return nil, err }
+	if workers > height { workers = height }
+
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	accs := make([]*accumulator, workers)
+	sums := make([][]float32, workers)
+	rowStart := func(band int) int {
+		if band == workers { return height }
+		row := band*height/workers
+		if accumulateLikeSIMD { // keep SIMD groups aligned
+			for (row*width) & 3 != 0 { row -= 1 }
+		}
+		return row
+	}
+
+	// rasterize area contributions for each band
+	var group sync.WaitGroup
+	for band := 0; band < workers; band++ {
+		group.Add(1)
+		go func(band int) {
+			defer group.Done()
+			minY, maxY := rowStart(band), rowStart(band + 1)
+			acc := getAccumulator(0, 0)
+			acc.ResetBand(width, minY, maxY)
+			processOutline(acc, outline, normOffsetX, normOffsetY)
+			accs[band], sums[band] = acc, compactSums(nil, acc.buffer)
+		}(band)
+	}
+	group.Wait()
+
+	// chain the accumulated values between bands, in the same order
+	// as a serial accumulation (adding zeros doesn't change the sum)
+	starts := make([]float32, workers)
+	for band := 1; band < workers; band++ {
+		starts[band] = accumulateSum(sums[band - 1], starts[band - 1])
+	}
+
+	// accumulate and quantize each band
+	for band := 0; band < workers; band++ {
+		group.Add(1)
+		go func(band int) {
+			defer group.Done()
+			pix := mask.Pix[rowStart(band)*width : rowStart(band + 1)*width]
+			accs[band].AccumulateIntoFrom(pix, FillNonZero, starts[band])
+			releaseAccumulator(accs[band])
+		}(band)
+	}
+	group.Wait()
+
+	mask.Rect = mask.Rect.Add(rectOffset)
+	return mask, nil
+}
+
+// Like [Shape.Rasterize](), but using multiple workers.
+// See [RasterizeParallel]() for details.
+func (self *Shape) RasterizeParallel(workers int) (*image.Alpha, error) {
+	if self.err != nil { return nil, self.err }
+	return RasterizeParallel(self.Segments(), workers)
+}
@@ -0,0 +1,41 @@
+package sfntshape
+
+import "bytes"
+import "image"
+import "testing"
+import "image/draw"
+import "image/color"
+
+func TestPixelsPremult(t *testing.T) {
+	shape := testPolygon(20, 37)
+	for _, fill := range []color.Color{
+		color.White, color.RGBA{ 255, 128, 3, 255 }, color.NRGBA{ 200, 100, 50, 255 },
+	}{
+		pix, bounds, err := shape.PixelsPremult(fill)
+		if err != nil { t.Fatal(err) }
+		painted := shape.Paint(fill, color.Transparent)
+		if bounds != painted.Rect { t.Fatalf("expected bounds %v, got %v", painted.Rect, bounds) }
+		if len(pix) != bounds.Dx()*bounds.Dy()*4 { t.Fatalf("unexpected buffer size %d", len(pix)) }
+		for i := 0; i < len(pix); i += 4 {
+			if !bytes.Equal(pix[i : i + 4], painted.Pix[i : i + 4]) {
+				t.Fatalf("fill %v, pixel %d: expected %v, got %v", fill, i/4, painted.Pix[i : i + 4], pix[i : i + 4])
+			}
+		}
+	}
+
+	// translucent colors match drawing over a transparent image
+	fill := color.NRGBA{ 200, 100, 50, 128 }
+	pix, bounds, err := shape.PixelsPremult(fill)
+	if err != nil { t.Fatal(err) }
+	expected := image.NewRGBA(bounds)
+	if err := shape.Draw(expected, image.Point{}, fill, draw.Src); err != nil { t.Fatal(err) }
+	if !bytes.Equal(pix, expected.Pix) { t.Fatalf("translucent fill mismatch") }
+	for i := 0; i < len(pix); i += 4 {
+		if pix[i] > pix[i + 3] { t.Fatalf("pixel %d is not premultiplied: %v", i/4, pix[i : i + 4]) }
+	}
+}
+
+func BenchmarkPixelsPremult(b *testing.B) {
+	shape := testPolygon(128, 64)
+	for i := 0; i < b.N; i++ { _, _, _ = shape.PixelsPremult(color.White) }
+}
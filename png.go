@@ -0,0 +1,36 @@
+package sfntshape
+
+import "io"
+import "os"
+import "image/png"
+import "image/color"
+
+// Paints the shape like [Shape.Paint]() and encodes the result as a PNG
+// image into w. Rasterization and encoding errors are returned as they
+// are, and empty shapes (nothing to draw) always return [ErrNothingToDraw],
+// even if [SetNothingToDrawErrors]() is disabled, instead of writing an
+// empty or placeholder image.
+func (self *Shape) EncodePNG(w io.Writer, fill, backColor color.Color) error {
+	rgba, err := self.PaintChecked(fill, backColor)
+	if err != nil { return err }
+	if rgba == nil { return ErrNothingToDraw }
+	return png.Encode(w, rgba)
+}
+
+// Like [Shape.EncodePNG](), but writing the image to the file at the
+// given path, which is created or truncated. The file is only created
+// after the shape is painted successfully, and it's removed if the
+// image can't be written.
+func (self *Shape) SavePNG(path string, fill, backColor color.Color) error {
+	rgba, err := self.PaintChecked(fill, backColor)
+	if err != nil { return err }
+	if rgba == nil { return ErrNothingToDraw }
+
+	file, err := os.Create(path)
+	if err != nil { return err }
+	err = png.Encode(file, rgba)
+	closeErr := file.Close()
+	if err == nil { err = closeErr }
+	if err != nil { _ = os.Remove(path) }
+	return err
+}
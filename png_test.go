@@ -0,0 +1,59 @@
+package sfntshape
+
+import "bytes"
+import "errors"
+import "image/png"
+import "image/color"
+import "path/filepath"
+import "os"
+import "testing"
+
+func TestEncodePNG(t *testing.T) {
+	shape := New()
+	testCircle(&shape, 20.3, 20.7, 15.2)
+	fill, back := color.RGBA{ 200, 40, 90, 255 }, color.RGBA{ 20, 30, 250, 255 }
+	expected := shape.Paint(fill, back)
+	compare := func(data []byte) {
+		t.Helper()
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil { t.Fatal(err) }
+		bounds := img.Bounds()
+		if bounds.Dx() != expected.Rect.Dx() || bounds.Dy() != expected.Rect.Dy() { t.Fatalf("unexpected size %v", bounds) }
+		for y := 0; y < bounds.Dy(); y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				got := color.RGBAModel.Convert(img.At(bounds.Min.X + x, bounds.Min.Y + y))
+				if want := expected.At(expected.Rect.Min.X + x, expected.Rect.Min.Y + y); got != want {
+					t.Fatalf("pixel (%d, %d): expected %v, got %v", x, y, want, got)
+				}
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := shape.EncodePNG(&buffer, fill, back); err != nil { t.Fatal(err) }
+	compare(buffer.Bytes())
+
+	path := filepath.Join(t.TempDir(), "circle.png")
+	if err := shape.SavePNG(path, fill, back); err != nil { t.Fatal(err) }
+	data, err := os.ReadFile(path)
+	if err != nil { t.Fatal(err) }
+	compare(data)
+
+	// empty shapes and errors
+	empty := New()
+	if err := empty.EncodePNG(&buffer, fill, back); !errors.Is(err, ErrNothingToDraw) {
+		t.Fatalf("expected ErrNothingToDraw, got %v", err)
+	}
+	emptyPath := filepath.Join(t.TempDir(), "empty.png")
+	if err := empty.SavePNG(emptyPath, fill, back); !errors.Is(err, ErrNothingToDraw) {
+		t.Fatalf("expected ErrNothingToDraw, got %v", err)
+	}
+	if _, err := os.Stat(emptyPath); err == nil { t.Fatal("expected no file for empty shapes") }
+	if err := shape.SavePNG(filepath.Join(t.TempDir(), "missing", "x.png"), fill, back); err == nil {
+		t.Fatal("expected error for invalid path")
+	}
+	if err := shape.EncodePNG(failingWriter{}, fill, back); err == nil { t.Fatal("expected write error") }
+}
+
+type failingWriter struct{}
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
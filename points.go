@@ -0,0 +1,31 @@
+package sfntshape
+
+// Calls fn with the on-curve points of the shape (the end of each
+// segment, MoveTo commands included) in order, with the index of the
+// segment they belong to, in pixels and exactly as stored (like
+// [Shape.Segments]()). Stops early if fn returns false. See also
+// [Shape.Points]() for the iterator version, available since Go 1.23.
+func (self *Shape) ForEachPoint(fn func(index int, point PointF) bool) {
+	self.visitPoints(false, fn)
+}
+
+// Like [Shape.ForEachPoint](), but for the off-curve control points of
+// QuadTo and CubeTo segments. Together, both cover all the points that
+// define the [Shape.Bounds](). See also [Shape.ControlPoints]().
+func (self *Shape) ForEachControlPoint(fn func(index int, point PointF) bool) {
+	self.visitPoints(true, fn)
+}
+
+func (self *Shape) visitPoints(control bool, fn func(int, PointF) bool) {
+	for i, segment := range self.segments {
+		n := segmentArgsCount(segment.Op)
+		if n == 0 { continue } // unknown op
+		if !control {
+			if !fn(i, pointFromFixed(segment.Args[n - 1])) { return }
+			continue
+		}
+		for j := 0; j < n - 1; j++ {
+			if !fn(i, pointFromFixed(segment.Args[j])) { return }
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build go1.23
+
+package sfntshape
+
+import "iter"
+
+// Returns an iterator over the on-curve points of the shape and the
+// indices of their segments, like [Shape.ForEachPoint]():
+//   for i, point := range shape.Points() { ... }
+func (self *Shape) Points() iter.Seq2[int, PointF] {
+	return func(yield func(int, PointF) bool) { self.ForEachPoint(yield) }
+}
+
+// Like [Shape.Points](), but for the control points of the curves, like
+// [Shape.ForEachControlPoint]().
+func (self *Shape) ControlPoints() iter.Seq2[int, PointF] {
+	return func(yield func(int, PointF) bool) { self.ForEachControlPoint(yield) }
+}
@@ -0,0 +1,50 @@
+//go:build go1.23
+
+package sfntshape
+
+import "math"
+import "testing"
+
+func TestPointsIterators(t *testing.T) {
+	shape := bigTestShape(100)
+	shape.MoveTo(-20, 7)
+	shape.CubeToFract(-33*64 - 5, 2, 900, 300*64 + 1, 0, 0)
+	shape.QuadTo(-40, -10, -20, 7)
+
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	expand := func(point PointF) {
+		minX, maxX = math.Min(minX, point.X), math.Max(maxX, point.X)
+		minY, maxY = math.Min(minY, point.Y), math.Max(maxY, point.Y)
+	}
+	count := 0
+	for i, point := range shape.Points() {
+		if i != count { t.Fatalf("expected segment index %d, got %d", count, i) }
+		count += 1
+		expand(point)
+	}
+	if count != shape.Len() { t.Fatalf("expected %d points, got %d", shape.Len(), count) }
+	controls := 0
+	for _, point := range shape.ControlPoints() {
+		controls += 1
+		expand(point)
+	}
+	if controls == 0 { t.Fatal("expected some control points") }
+	bounds := shape.Bounds()
+	if minX != fixedToF64(bounds.Min.X) || minY != fixedToF64(bounds.Min.Y) || maxX != fixedToF64(bounds.Max.X) || maxY != fixedToF64(bounds.Max.Y) {
+		t.Fatalf("iterated bounds (%v, %v)-(%v, %v) don't match %v", minX, minY, maxX, maxY, bounds)
+	}
+
+	// early breaks
+	seen := 0
+	for range shape.Points() {
+		seen += 1
+		if seen == 5 { break }
+	}
+	if seen != 5 { t.Fatalf("expected to stop after 5 points, got %d", seen) }
+	seen = 0
+	for range shape.ControlPoints() {
+		seen += 1
+		if seen == 3 { break }
+	}
+	if seen != 3 { t.Fatalf("expected to stop after 3 control points, got %d", seen) }
+}
@@ -0,0 +1,40 @@
+package sfntshape
+
+import "testing"
+
+func TestForEachPoint(t *testing.T) {
+	shape := New()
+	shape.MoveTo(1, 2)
+	shape.QuadToFract(32, 64, 3*64, 0)
+	shape.CubeTo(4, 5, 6, 7, 8, 9)
+	var points, controls []PointF
+	var indices []int
+	shape.ForEachPoint(func(index int, point PointF) bool {
+		indices = append(indices, index)
+		points = append(points, point)
+		return true
+	})
+	shape.ForEachControlPoint(func(index int, point PointF) bool {
+		indices = append(indices, index)
+		controls = append(controls, point)
+		return true
+	})
+	expectPoints := []PointF{ { 1, -2 }, { 3, 0 }, { 8, -9 } }
+	expectControls := []PointF{ { 0.5, -1 }, { 4, -5 }, { 6, -7 } }
+	expectIndices := []int{ 0, 1, 2, 1, 2, 2 }
+	for i := range expectPoints {
+		if points[i] != expectPoints[i] || controls[i] != expectControls[i] {
+			t.Fatalf("unexpected points %v and control points %v", points, controls)
+		}
+	}
+	for i := range expectIndices {
+		if indices[i] != expectIndices[i] { t.Fatalf("unexpected indices %v", indices) }
+	}
+
+	calls := 0
+	shape.ForEachPoint(func(int, PointF) bool {
+		calls += 1
+		return calls < 2
+	})
+	if calls != 2 { t.Fatalf("expected to stop after 2 calls, got %d", calls) }
+}
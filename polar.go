@@ -0,0 +1,61 @@
+package sfntshape
+
+import "math"
+
+import "golang.org/x/image/math/fixed"
+
+// Appends a line going the given distance, in pixels, from the current
+// position (see [Shape.Pos]()) in the direction of the given angle, in
+// radians (see [Deg]()). Angles grow from +X towards +Y in the
+// coordinates passed to the shape commands, so with the default Y axis
+// (pointing up, see [Shape.InvertY]()) they go counterclockwise on
+// screen. The end point is computed in float64 and rounded once, and
+// then the shape's scale is applied like in [Shape.LineToFract]().
+// Combined with [Shape.Heading](), this allows turtle-like
+// construction. The relative mode (see [Shape.SetRelative]()) doesn't
+// matter, as the command is already relative.
+func (self *Shape) LineToAngle(distance, radians float64) {
+	x, y := self.polarTarget(distance, radians)
+	self.lineToFract(x, y)
+}
+
+// Like [Shape.LineToAngle](), but moving the current position instead.
+func (self *Shape) MoveByAngle(distance, radians float64) {
+	x, y := self.polarTarget(distance, radians)
+	self.moveToFract(x, y)
+}
+
+func (self *Shape) polarTarget(distance, radians float64) (Fract, Fract) {
+	x, y, _ := self.Pos()
+	sin, cos := sincos(radians)
+	return self.saturateFloat(float64(x) + distance*cos*64), self.saturateFloat(float64(y) + distance*sin*64)
+}
+
+// Returns the direction of the last segment at its end point, in
+// radians within [-Pi, Pi], using the same convention as
+// [Shape.LineToAngle](). For curves, that's the direction of the tangent
+// at the end (from the last control point not coinciding with it), and
+// for MoveTo commands the direction of the move. Returns 0 if the shape
+// is empty or the direction is undefined (zero-length segments).
+//
+// The direction is computed from the stored segments, mapped back with
+// the current InvertY configuration and the sign of the current scale,
+// so it may be wrong if those changed after the segment was added.
+func (self *Shape) Heading() float64 {
+	last := len(self.segments) - 1
+	if last < 0 { return 0 }
+	segment := self.segments[last]
+	end := segmentEnd(segment)
+	var from fixed.Point26_6
+	if last > 0 { from = segmentEnd(self.segments[last - 1]) }
+	for i := segmentArgsCount(segment.Op) - 2; i >= 0; i-- {
+		if segment.Args[i] == end { continue }
+		from = segment.Args[i]
+		break
+	}
+	dx, dy := float64(end.X - from.X), float64(end.Y - from.Y)
+	if !self.invertY { dy = -dy }
+	if self.GetScale() < 0 { dx, dy = -dx, -dy }
+	if dx == 0 && dy == 0 { return 0 }
+	return math.Atan2(dy, dx)
+}
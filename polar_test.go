@@ -0,0 +1,51 @@
+package sfntshape
+
+import "math"
+import "testing"
+
+func TestLineToAngle(t *testing.T) {
+	// regular pentagon with 72 degree turns
+	const side = 40.0
+	for _, invertY := range []bool{ false, true } {
+		shape := New()
+		shape.InvertY(invertY)
+		shape.SetScale(1.5)
+		shape.MoveTo(10, 10)
+		shape.LineToAngle(side, 0)
+		for i := 1; i < 5; i++ { shape.LineToAngle(side, shape.Heading() + 72*math.Pi/180) }
+
+		expected := New()
+		expected.InvertY(invertY)
+		expected.SetScale(1.5)
+		x, y := 10.0, 10.0
+		expected.MoveTo(10, 10)
+		for i := 0; i < 5; i++ {
+			angle := float64(i)*2*math.Pi/5
+			x, y = x + side*math.Cos(angle), y + side*math.Sin(angle)
+			expected.LineToFract(Fract(math.Round(x*64)), Fract(math.Round(y*64)))
+		}
+		if !shape.ApproxEqual(&expected, 2) {
+			t.Fatalf("invertY %t: pentagon differs:\n%v\n%v", invertY, &shape, &expected)
+		}
+		end := shape.positions[5]
+		if absFract(end.X - 640) > 2 || absFract(end.Y - 640) > 2 {
+			t.Fatalf("invertY %t: expected the pentagon to close at (640, 640), got %v", invertY, end)
+		}
+	}
+}
+
+func TestHeading(t *testing.T) {
+	shape := New()
+	if shape.Heading() != 0 { t.Fatal("expected heading 0 for empty shapes") }
+	shape.MoveByAngle(10, math.Pi/2)
+	if x, y, _ := shape.Pos(); x != 0 || y != 640 { t.Fatalf("unexpected position (%d, %d)", x, y) }
+	if math.Abs(shape.Heading() - math.Pi/2) > 1e-9 { t.Fatalf("unexpected move heading %f", shape.Heading()) }
+	shape.LineTo(0, 0)
+	if math.Abs(shape.Heading() + math.Pi/2) > 1e-9 { t.Fatalf("unexpected line heading %f", shape.Heading()) }
+	shape.QuadTo(10, 0, 10, 10) // tangent at the end points up
+	if math.Abs(shape.Heading() - math.Pi/2) > 1e-9 { t.Fatalf("unexpected quad heading %f", shape.Heading()) }
+	shape.CubeTo(20, 10, 10, 0, 10, 0) // last control point coincides with the end
+	if math.Abs(shape.Heading() - (-math.Pi*3/4)) > 1e-9 { t.Fatalf("unexpected cube heading %f", shape.Heading()) }
+	shape.LineTo(10, 0)
+	if shape.Heading() != 0 { t.Fatal("expected heading 0 for zero-length segments") }
+}
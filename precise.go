@@ -0,0 +1,143 @@
+package sfntshape
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// A shape builder that keeps all the coordinates in float64 and only
+// quantizes them to 26.6 values at the end, with [PreciseBuilder.Commit]()
+// or [PreciseBuilder.Segments](). Each command of [Shape] is quantized
+// to 1/64th of a pixel, so long chains of small relative moves (e.g.,
+// computed from [Shape.Pos]()) accumulate visible drift, while here the
+// current position stays exact.
+//
+// The commands mirror the ones of [Shape], with float64 coordinates in
+// pixels. Coordinates go through the builder's state in this order:
+// scaled (see [PreciseBuilder.SetScale]()), rotated around (0, 0) (see
+// [PreciseBuilder.SetRotation]()), translated by the origin (see
+// [PreciseBuilder.SetOrigin]()) and finally Y inverted as in [Shape]
+// (unless [PreciseBuilder.InvertY]() is set), all in float64. State
+// changes only affect subsequent commands.
+//
+// The zero value is ready to use, with no scaling, rotation or offset.
+type PreciseBuilder struct {
+	segments []preciseSegment
+	scaleOffset float64 // scale - 1, so the zero value is unscaled
+	sin, cosOffset float64 // cosOffset is cos - 1, for the same reason
+	originX, originY float64
+	invertY bool
+	pos [2]float64 // current position, in user coordinates
+	hasPos bool
+}
+
+type preciseSegment struct {
+	op sfnt.SegmentOp
+	args [3][2]float64 // in stored coordinates, in pixels
+	end [2]float64 // in user coordinates, for Shape.Pos
+}
+
+// Returns the current scaling factor.
+func (self *PreciseBuilder) GetScale() float64 { return self.scaleOffset + 1 }
+
+// Sets a scaling factor for the coordinates of subsequent commands,
+// like [Shape.SetScale]().
+func (self *PreciseBuilder) SetScale(scale float64) { self.scaleOffset = scale - 1 }
+
+// Sets a rotation, in radians (see [Deg]()), for the coordinates of
+// subsequent commands. Angles grow from +X towards +Y in the
+// coordinates passed to the commands, so with the default Y axis
+// (pointing up) rotations are counterclockwise on screen.
+func (self *PreciseBuilder) SetRotation(angle float64) {
+	sin, cos := sincos(angle)
+	self.sin, self.cosOffset = sin, cos - 1
+}
+
+// Sets an offset, in pixels, added to the coordinates of subsequent
+// commands after scaling and rotating them.
+func (self *PreciseBuilder) SetOrigin(x, y float64) { self.originX, self.originY = x, y }
+
+// Returns whether [PreciseBuilder.InvertY] is active or inactive.
+func (self *PreciseBuilder) HasInvertY() bool { return self.invertY }
+
+// Like [Shape.InvertY]().
+func (self *PreciseBuilder) InvertY(active bool) { self.invertY = active }
+
+// Returns the current position, in the coordinates passed to the last
+// command, without any rounding, or false if the builder is empty.
+// Like [Shape.Pos](), the builder's state is not applied.
+func (self *PreciseBuilder) Pos() (float64, float64, bool) {
+	return self.pos[0], self.pos[1], self.hasPos
+}
+
+// Returns the number of segments in the builder.
+func (self *PreciseBuilder) Len() int { return len(self.segments) }
+
+// Removes all the segments, keeping the builder's state and capacity.
+func (self *PreciseBuilder) Reset() {
+	self.segments = self.segments[ : 0]
+	self.pos, self.hasPos = [2]float64{}, false
+}
+
+// Like [Shape.MoveTo](), with float64 coordinates.
+func (self *PreciseBuilder) MoveTo(x, y float64) {
+	self.append(sfnt.SegmentOpMoveTo, x, y)
+}
+
+// Like [Shape.LineTo](), with float64 coordinates.
+func (self *PreciseBuilder) LineTo(x, y float64) {
+	self.append(sfnt.SegmentOpLineTo, x, y)
+}
+
+// Like [Shape.QuadTo](), with float64 coordinates.
+func (self *PreciseBuilder) QuadTo(ctrlX, ctrlY, x, y float64) {
+	self.append(sfnt.SegmentOpQuadTo, ctrlX, ctrlY, x, y)
+}
+
+// Like [Shape.CubeTo](), with float64 coordinates.
+func (self *PreciseBuilder) CubeTo(cx1, cy1, cx2, cy2, x, y float64) {
+	self.append(sfnt.SegmentOpCubeTo, cx1, cy1, cx2, cy2, x, y)
+}
+
+func (self *PreciseBuilder) append(op sfnt.SegmentOp, coords ...float64) {
+	segment := preciseSegment{ op: op }
+	scale, cos := self.scaleOffset + 1, self.cosOffset + 1
+	for i := 0; i < len(coords); i += 2 {
+		x, y := coords[i]*scale, coords[i + 1]*scale
+		x, y = x*cos - y*self.sin + self.originX, x*self.sin + y*cos + self.originY
+		if !self.invertY { y = -y }
+		segment.args[i/2] = [2]float64{ x, y }
+	}
+	n := len(coords)
+	segment.end = [2]float64{ coords[n - 2], coords[n - 1] }
+	self.segments = append(self.segments, segment)
+	self.pos, self.hasPos = segment.end, true
+}
+
+// Appends the builder's segments to the shape, rounding the coordinates
+// to 26.6 values according to the shape's rounding mode (see
+// [Shape.SetRounding]()). The shape's scale and InvertY are not
+// applied (the builder's own state has already been applied), and
+// coordinates out of range are saturated, recording an
+// [ErrCoordinateOverflow] on the shape. The positions reported by
+// [Shape.Pos]() are the rounded coordinates passed to the builder.
+// The builder is not modified, so it can be committed multiple times.
+func (self *PreciseBuilder) Commit(shape *Shape) {
+	shape.Grow(len(self.segments))
+	for _, precise := range self.segments {
+		segment := sfnt.Segment{ Op: precise.op }
+		for i := 0; i < segmentArgsCount(precise.op); i++ {
+			arg := precise.args[i]
+			segment.Args[i] = fixed.Point26_6{ X: shape.saturateFloat(arg[0]*64), Y: shape.saturateFloat(arg[1]*64) }
+		}
+		end := fixed.Point26_6{ X: shape.saturateFloat(precise.end[0]*64), Y: shape.saturateFloat(precise.end[1]*64) }
+		shape.appendSegment(end, segment)
+	}
+}
+
+// Returns the builder's segments rounded to 26.6 values, like
+// [PreciseBuilder.Commit]() would append them (but silently saturating
+// coordinates out of range).
+func (self *PreciseBuilder) Segments() sfnt.Segments {
+	shape := NewWithCapacity(len(self.segments))
+	self.Commit(&shape)
+	return shape.Segments()
+}
@@ -0,0 +1,96 @@
+package sfntshape
+
+import "math"
+import "reflect"
+import "testing"
+
+func TestPreciseBuilderDrift(t *testing.T) {
+	const steps = 200
+	const dx, dy = 0.123, 0.0
+	exactX := 10 + steps*dx
+
+	// relative moves on a shape: each step is rounded to 1/64th of a pixel
+	shape := New()
+	shape.MoveTo(10, 0)
+	for i := 0; i < steps; i++ {
+		x, y, _ := shape.Pos()
+		shape.LineToFract(x + Fract(math.Round(dx*64)), y + Fract(math.Round(dy*64)))
+	}
+	end := segmentEnd(shape.Segments()[steps])
+	drift := math.Abs(float64(end.X)/64 - exactX)
+	if drift < 0.3 { t.Fatalf("expected the shape to drift, got %.4f pixels", drift) }
+
+	// the precise builder only rounds at the end
+	var builder PreciseBuilder
+	builder.MoveTo(10, 0)
+	for i := 0; i < steps; i++ {
+		x, y, _ := builder.Pos()
+		builder.LineTo(x + dx, y + dy)
+	}
+	end = segmentEnd(builder.Segments()[steps])
+	if math.Abs(float64(end.X) - exactX*64) > 0.5 {
+		t.Fatalf("precise end at %d fract, expected %.3f", end.X, exactX*64)
+	}
+}
+
+func TestPreciseBuilderState(t *testing.T) {
+	// without rotation or origin, equivalent to the shape commands
+	for _, invertY := range []bool{ false, true } {
+		shape := New()
+		shape.InvertY(invertY)
+		shape.SetScale(2)
+		shape.MoveTo(1, 2)
+		shape.LineTo(10, 2)
+		shape.QuadTo(12, 6, 10, 10)
+		shape.CubeTo(8, 12, 2, 12, 1, 2)
+
+		var builder PreciseBuilder
+		builder.InvertY(invertY)
+		builder.SetScale(2)
+		builder.MoveTo(1, 2)
+		builder.LineTo(10, 2)
+		builder.QuadTo(12, 6, 10, 10)
+		builder.CubeTo(8, 12, 2, 12, 1, 2)
+		if !reflect.DeepEqual(shape.Segments(), builder.Segments()) {
+			t.Fatalf("invertY %t: segments differ:\n%v\n%v", invertY, shape.Segments(), builder.Segments())
+		}
+
+		committed := New()
+		builder.Commit(&committed)
+		if !committed.Equal(&shape) { t.Fatalf("invertY %t: committed shape differs", invertY) }
+		x, y, ok := committed.Pos()
+		if !ok || x != 64 || y != 128 { t.Fatalf("invertY %t: unexpected position (%d, %d)", invertY, x, y) }
+	}
+
+	// scale, rotate, then translate, all before rounding
+	var builder PreciseBuilder
+	builder.InvertY(true)
+	builder.SetScale(1.0/3)
+	builder.SetRotation(math.Pi/2)
+	builder.SetOrigin(5, 5)
+	builder.MoveTo(3, 0)
+	builder.SetRotation(0)
+	builder.LineTo(3, 0)
+	segments := builder.Segments()
+	if got := segments[0].Args[0]; got.X != 5*64 || got.Y != 6*64 {
+		t.Fatalf("expected rotated move to (320, 384), got %v", got)
+	}
+	if got := segments[1].Args[0]; got.X != 6*64 || got.Y != 5*64 {
+		t.Fatalf("expected line to (384, 320), got %v", got)
+	}
+
+	builder.Reset()
+	if builder.Len() != 0 { t.Fatal("expected an empty builder after Reset") }
+	if _, _, ok := builder.Pos(); ok { t.Fatal("expected no position after Reset") }
+	if math.Abs(builder.GetScale() - 1.0/3) > 1e-12 { t.Fatal("expected Reset to keep the state") }
+}
+
+func TestPreciseBuilderOverflow(t *testing.T) {
+	var builder PreciseBuilder
+	builder.MoveTo(0, 0)
+	builder.LineTo(1e12, 0)
+	shape := New()
+	builder.Commit(&shape)
+	if shape.Err() == nil { t.Fatal("expected an overflow error") }
+	if len(builder.Segments()) != 2 { t.Fatal("expected saturated segments") }
+}
@@ -1,6 +1,7 @@
 package sfntshape
 
 import "image"
+import "errors"
 import "image/draw"
 
 import "golang.org/x/image/font/sfnt"
@@ -8,27 +9,68 @@ import "golang.org/x/image/math/fixed"
 import "golang.org/x/image/vector"
 
 // Rasterize an outline into a single-channel image.
-func Rasterize(outline sfnt.Segments, rasterizer *vector.Rasterizer, originX, originY Fract) (*image.Alpha, error) {
+func Rasterize(outline sfnt.Segments, rasterizer Rasterizer, originX, originY Fract) (*image.Alpha, error) {
+	if err := validateOps(outline); err != nil { return nil, err }
 	// return nil if the outline don't include lines or curves
 	for _, segment := range outline {
 		if segment.Op == sfnt.SegmentOpMoveTo { continue }
 		return etxtLikeRasterize(outline, rasterizer, originX, originY)
 	}
-	return nil, nil // nothing to draw
+	return nil, nothingToDraw()
+}
+
+// Returns whether the outline includes any lines or curves.
+func hasDrawingOps(outline sfnt.Segments) bool {
+	for _, segment := range outline {
+		if segment.Op != sfnt.SegmentOpMoveTo { return true }
+	}
+	return false
+}
+
+// The interface required by [Rasterize]() and related functions to
+// rasterize outlines. [*vector.Rasterizer] implements it and is the
+// default rasterizer used by shapes, but custom backends can also be
+// provided for different antialiasing characteristics or to reuse
+// other rasterizers (see [Shape.SetRasterizer]()).
+//
+// Rasterizers are reset to the size of the mask, fed the outline with
+// coordinates relative to it, and then asked to Draw the coverage into
+// the mask at (0, 0), with [image.Opaque] as the src. Masks are always
+// cleared before drawing, so rasterizers may either replace or composite
+// over the existing mask contents.
+type Rasterizer interface {
+	Reset(width, height int)
+	MoveTo(x, y float32)
+	LineTo(x, y float32)
+	QuadTo(bx, by, cx, cy float32)
+	CubeTo(bx, by, cx, cy, dx, dy float32)
+	Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point)
+}
+
+// Common interface for rasterizers and our own accumulator, used
+// to feed outlines to them.
+type pathProcessor interface {
+	MoveTo(x, y float32)
+	LineTo(x, y float32)
+	QuadTo(bx, by, cx, cy float32)
+	CubeTo(bx, by, cx, cy, dx, dy float32)
 }
 
 // Code adapted from etxt's mask.DefaultRasterizer.
-func etxtLikeRasterize(outline sfnt.Segments, rasterizer *vector.Rasterizer, originX, originY Fract) (*image.Alpha, error) {
-	// get outline bounds
-	bounds := outline.Bounds()
+func etxtLikeRasterize(outline sfnt.Segments, rasterizer Rasterizer, originX, originY Fract) (*image.Alpha, error) {
+	return boundedRasterize(outline, outline.Bounds(), rasterizer, originX, originY)
+}
 
+// Like etxtLikeRasterize, but with the outline bounds already known
+// (e.g., cached by a shape).
+func boundedRasterize(outline sfnt.Segments, bounds fixed.Rectangle26_6, rasterizer Rasterizer, originX, originY Fract) (*image.Alpha, error) {
 	// prepare rasterizer
 	width, height, normOffsetX, normOffsetY, rectOffset := figureOutBounds(bounds, originX, originY)
-	rasterizer.Reset(width, height)
-	rasterizer.DrawOp = draw.Src
+	if err := checkMaskSize(width, height); err != nil { return nil, err }
+	resetRasterizer(rasterizer, width, height)
 
 	// allocate glyph mask
-	mask := image.NewAlpha(rasterizer.Bounds())
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
 
 	// process outline
 	processOutline(rasterizer, outline, normOffsetX, normOffsetY)
@@ -43,6 +85,233 @@ func etxtLikeRasterize(outline sfnt.Segments, rasterizer *vector.Rasterizer, ori
 	return mask, nil
 }
 
+// Like [Rasterize](), but writing the result into the given mask instead
+// of allocating a new one. The dst mask is reconfigured to match the
+// rasterized bounds: its Pix buffer is reused if its capacity is large
+// enough (and only reallocated otherwise), and its Stride and Rect are
+// overwritten. All the pixels within the new dst.Rect are written, so no
+// stale coverage from previous uses remains. The new dst.Rect is also
+// returned for convenience.
+//
+// Since dst.Pix is taken over, dst must not be a sub-image of a bigger
+// image. If the outline has nothing to draw, dst.Rect is set to an empty
+// rectangle.
+func RasterizeInto(dst *image.Alpha, outline sfnt.Segments, rasterizer Rasterizer, originX, originY Fract) (image.Rectangle, error) {
+	if dst == nil { return image.Rectangle{}, errors.New("nil dst mask") }
+	if err := validateOps(outline); err != nil { return image.Rectangle{}, err }
+	if !hasDrawingOps(outline) {
+		dst.Pix, dst.Stride, dst.Rect = dst.Pix[0 : 0], 0, image.Rectangle{}
+		return dst.Rect, nothingToDraw()
+	}
+
+	bounds := outline.Bounds()
+	width, height, normOffsetX, normOffsetY, rectOffset := figureOutBounds(bounds, originX, originY)
+	if err := checkMaskSize(width, height); err != nil { return image.Rectangle{}, err }
+	resetRasterizer(rasterizer, width, height)
+
+	reuseMask(dst, width, height)
+	if !isVectorRasterizer(rasterizer) { // vector uses draw.Src
+		for i := range dst.Pix { dst.Pix[i] = 0 }
+	}
+	processOutline(rasterizer, outline, normOffsetX, normOffsetY)
+	rasterizer.Draw(dst, dst.Rect, image.Opaque, image.Point{})
+	dst.Rect = dst.Rect.Add(rectOffset)
+	return dst.Rect, nil
+}
+
+// Like [Rasterize](), but returning the coverage as an [*image.Gray]
+// instead. Since both image types share the same Pix layout, the mask
+// is rasterized directly into the buffer of the returned image, with
+// no copies or conversions involved.
+func RasterizeGray(outline sfnt.Segments, rasterizer Rasterizer, originX, originY Fract) (*image.Gray, error) {
+	mask, err := Rasterize(outline, rasterizer, originX, originY)
+	if err != nil || mask == nil { return nil, err }
+	return &image.Gray{ Pix: mask.Pix, Stride: mask.Stride, Rect: mask.Rect }, nil
+}
+
+// Rasterizes the outline at multiple subpixel phases, as commonly needed
+// for glyph caches. The returned slice contains phasesX*phasesY masks, in
+// row-major order: the mask at index y*phasesX + x is the outline
+// rasterized with a fractional origin of (x/phasesX, y/phasesY) pixels,
+// truncated to 1/64ths. Each mask is the same that [Rasterize]() would
+// return for that origin, Rect included, so they can be used
+// interchangeably. The bounds computation is shared and all masks are
+// backed by a single allocation.
+//
+// Phases must be between 1 and 64. If the outline has nothing to draw,
+// the result is nil.
+func RasterizePhases(outline sfnt.Segments, rasterizer Rasterizer, phasesX, phasesY int) ([]*image.Alpha, error) {
+	if phasesX < 1 || phasesX > 64 || phasesY < 1 || phasesY > 64 {
+		return nil, errors.New("phases must be between 1 and 64")
+	}
+	if err := validateOps(outline); err != nil { return nil, err }
+	if !hasDrawingOps(outline) { return nil, nothingToDraw() }
+
+	type phase struct {
+		width, height int
+		normOffsetX, normOffsetY Fract
+		rectOffset image.Point
+	}
+	bounds := outline.Bounds()
+	phases := make([]phase, phasesX*phasesY)
+	total := 0
+	for y := 0; y < phasesY; y++ {
+		for x := 0; x < phasesX; x++ {
+			p := &phases[y*phasesX + x]
+			originX, originY := Fract(x*64/phasesX), Fract(y*64/phasesY)
+			p.width, p.height, p.normOffsetX, p.normOffsetY, p.rectOffset = figureOutBounds(bounds, originX, originY)
+			if err := checkMaskSize(p.width, p.height); err != nil { return nil, err }
+			total += p.width*p.height
+		}
+	}
+
+	pix := make([]uint8, total)
+	masks := make([]*image.Alpha, len(phases))
+	for i, p := range phases {
+		n := p.width*p.height
+		mask := &image.Alpha{
+			Pix: pix[0 : n : n],
+			Stride: p.width,
+			Rect: image.Rect(0, 0, p.width, p.height),
+		}
+		pix = pix[n : ]
+		resetRasterizer(rasterizer, p.width, p.height)
+		processOutline(rasterizer, outline, p.normOffsetX, p.normOffsetY)
+		rasterizer.Draw(mask, mask.Rect, image.Opaque, image.Point{})
+		mask.Rect = mask.Rect.Add(p.rectOffset)
+		masks[i] = mask
+	}
+	return masks, nil
+}
+
+// Like [Rasterize](), but only the region of the result within the given
+// clip rectangle is rasterized, so the mask is sized to the intersection
+// of the outline bounds and the clip rectangle. If they don't intersect,
+// the function returns (nil, nil) without doing any work (or
+// [ErrNothingToDraw], see [SetNothingToDrawErrors]()).
+//
+// The clip rectangle is expressed in the same coordinates as the Rect of
+// the resulting masks. Geometry outside the clip rectangle still affects
+// the coverage inside it, so the result is the same as rasterizing the
+// whole outline and then cropping the mask (except for tiny rounding
+// differences on antialiased edges, as numerical origins don't match).
+func RasterizeClipped(outline sfnt.Segments, rasterizer Rasterizer, clip image.Rectangle, originX, originY Fract) (*image.Alpha, error) {
+	if err := validateOps(outline); err != nil { return nil, err }
+	if !hasDrawingOps(outline) { return nil, nothingToDraw() }
+	bounds := outline.Bounds()
+	width, height, normOffsetX, normOffsetY, rectOffset := figureOutBounds(bounds, originX, originY)
+	fullRect := image.Rect(0, 0, width, height).Add(rectOffset)
+	rect := fullRect.Intersect(clip)
+	if rect.Empty() { return nil, nothingToDraw() }
+	if err := checkMaskSize(rect.Dx(), rect.Dy()); err != nil { return nil, err }
+
+	// shift the outline so the clipped region starts at (0, 0)
+	normOffsetX -= Fract((rect.Min.X - fullRect.Min.X) << 6)
+	normOffsetY -= Fract((rect.Min.Y - fullRect.Min.Y) << 6)
+
+	// vector.Rasterizer switches between fixed and floating point math
+	// depending on the size, so if the full rasterization would have used
+	// floating point math we use our own accumulator to stay consistent
+	bigMask := (width > vectorFloatingPointThreshold || height > vectorFloatingPointThreshold)
+	if bigMask && isVectorRasterizer(rasterizer) {
+		acc := getAccumulator(rect.Dx(), rect.Dy())
+		defer releaseAccumulator(acc)
+		mask := image.NewAlpha(rect)
+		processOutline(acc, outline, normOffsetX, normOffsetY)
+		acc.AccumulateInto(mask.Pix, FillNonZero)
+		return mask, nil
+	}
+
+	resetRasterizer(rasterizer, rect.Dx(), rect.Dy())
+	mask := image.NewAlpha(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	processOutline(rasterizer, outline, normOffsetX, normOffsetY)
+	rasterizer.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	mask.Rect = rect
+	return mask, nil
+}
+
+// Compositing operations for [AccumulateInto]().
+type AccumulateOp uint8
+const (
+	// Standard "over" compositing: dst + src*(1 - dst). Repeatedly
+	// accumulating the same semi-transparent coverage approaches full
+	// opacity asymptotically, like stacking translucent films.
+	AccumulateOver AccumulateOp = iota
+
+	// Saturating addition: min(dst + src, 1). Adjacent shapes sharing
+	// an edge sum to full coverage along the seam, but overlapping
+	// antialiased edges may look slightly heavier.
+	AccumulateAdd
+)
+
+// Rasterizes the outline and composites the resulting coverage over the
+// existing contents of dst using the given operation, instead of replacing
+// them like [Rasterize]() would. This allows building complex masks from
+// multiple outlines. The outline is placed using the same coordinates as
+// dst.Rect, and anything falling outside dst's bounds is ignored.
+func AccumulateInto(dst *image.Alpha, outline sfnt.Segments, rasterizer Rasterizer, originX, originY Fract, op AccumulateOp) error {
+	if dst == nil { return errors.New("nil dst mask") }
+	if op != AccumulateOver && op != AccumulateAdd {
+		return errors.New("invalid accumulate op")
+	}
+	mask, err := RasterizeClipped(outline, rasterizer, dst.Rect, originX, originY)
+	if errors.Is(err, ErrNothingToDraw) { return nil }
+	if err != nil || mask == nil { return err }
+
+	width := mask.Rect.Dx()
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		srcRow := mask.Pix[mask.PixOffset(mask.Rect.Min.X, y) : ][ : width]
+		dstRow := dst.Pix[dst.PixOffset(mask.Rect.Min.X, y) : ][ : width]
+		for x, sa := range srcRow {
+			if sa == 0 { continue }
+			if op == AccumulateAdd {
+				dstRow[x] = uint8N(uint32(dstRow[x]) + uint32(sa))
+			} else { // AccumulateOver, like image/draw's Over
+				da, ma := uint32(dstRow[x])*0x101, uint32(sa)*0x101
+				dstRow[x] = uint8((da*(0xFFFF - ma)/0xFFFF + ma) >> 8)
+			}
+		}
+	}
+	return nil
+}
+
+// clamping from uint32 to uint8 values
+func uint8N(value uint32) uint8 {
+	if value > 255 { return 255 }
+	return uint8(value)
+}
+
+// Resets the rasterizer for a mask of the given size.
+func resetRasterizer(rasterizer Rasterizer, width, height int) {
+	rasterizer.Reset(width, height)
+	if vecRast, isVector := rasterizer.(*vector.Rasterizer); isVector {
+		vecRast.DrawOp = draw.Src
+	}
+}
+
+// Returns whether the rasterizer is a [*vector.Rasterizer], whose
+// results our own accumulator can reproduce.
+func isVectorRasterizer(rasterizer Rasterizer) bool {
+	_, isVector := rasterizer.(*vector.Rasterizer)
+	return isVector
+}
+
+// Size above which [vector.Rasterizer] uses floating point math.
+const vectorFloatingPointThreshold = 512
+
+// Reconfigures the mask to have the given size with its origin at
+// (0, 0), reusing the Pix buffer when possible.
+func reuseMask(mask *image.Alpha, width, height int) {
+	n := width*height
+	if cap(mask.Pix) < n {
+		mask.Pix = make([]uint8, n)
+	} else {
+		mask.Pix = mask.Pix[0 : n]
+	}
+	mask.Stride = width
+	mask.Rect = image.Rect(0, 0, width, height)
+}
+
 // (copied/adapted from etxt v0.0.9 mask/helper_funcs.go)
 // 
 // Given the glyph bounds and an origin position indicating the subpixel
@@ -66,7 +335,7 @@ func figureOutBounds(bounds fixed.Rectangle26_6, originX, originY Fract) (int, i
 }
 
 // (copied/adapted from etxt v0.0.9 mask/rasterizer.go)
-func processOutline(rasterizer *vector.Rasterizer, outline sfnt.Segments, offsetX, offsetY fixed.Int26_6) {
+func processOutline(rasterizer pathProcessor, outline sfnt.Segments, offsetX, offsetY fixed.Int26_6) {
 	for _, segment := range outline {
 		switch segment.Op {
 		case sfnt.SegmentOpMoveTo:
@@ -0,0 +1,455 @@
+package sfntshape
+
+import "image"
+import "context"
+import "bytes"
+import "strconv"
+import "runtime"
+import "sync"
+import "testing"
+import "image/draw"
+
+import "golang.org/x/image/vector"
+
+import "github.com/tinne26/sfntshape/internal/maskcmp"
+
+func TestFillRules(t *testing.T) {
+	star := testStar(40)
+	nonZero, err := star.RasterizeOpts(RasterizeOptions{ FillRule: FillNonZero })
+	if err != nil { t.Fatal(err) }
+	evenOdd, err := star.RasterizeOpts(RasterizeOptions{ FillRule: FillEvenOdd })
+	if err != nil { t.Fatal(err) }
+	if nonZero.Rect != evenOdd.Rect {
+		t.Fatalf("expected same rects, got %v and %v", nonZero.Rect, evenOdd.Rect)
+	}
+
+	if a := nonZero.AlphaAt(0, 0).A; a != 255 {
+		t.Fatalf("expected non-zero rule to fill the star center, got alpha %d", a)
+	}
+	if a := evenOdd.AlphaAt(0, 0).A; a != 0 {
+		t.Fatalf("expected even-odd rule to leave the star center empty, got alpha %d", a)
+	}
+
+	// a point on one of the star arms must be filled with both rules
+	if nonZero.AlphaAt(0, -30).A != 255 || evenOdd.AlphaAt(0, -30).A != 255 {
+		t.Fatal("expected star arms to be filled with both rules")
+	}
+
+	_, err = star.RasterizeOpts(RasterizeOptions{ FillRule: 255 })
+	if err == nil { t.Fatal("expected error for invalid fill rule") }
+}
+
+func TestAccumulatorMatchesVector(t *testing.T) {
+	// vector.Rasterizer only uses floating point math above 512px
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.CubeTo(300, 700, 400, -100, 600, 600)
+	shape.QuadTo(100, 500, 0, 0)
+
+	mask, err := Rasterize(shape.Segments(), vector.NewRasterizer(0, 0), 0, 0)
+	if err != nil { t.Fatal(err) }
+	accMask, err := accumulatorRasterize(&rasterTask{ ctx: context.Background() }, shape.Segments(), shape.Bounds(), RasterizeOptions{})
+	if err != nil { t.Fatal(err) }
+	if mask.Rect != accMask.Rect || !bytes.Equal(mask.Pix, accMask.Pix) {
+		t.Fatal("expected accumulator to match vector.Rasterizer floating point results")
+	}
+}
+
+// Test double that records the calls made to the rasterizer.
+type recordingRasterizer struct {
+	*vector.Rasterizer
+	calls []string
+}
+
+func (self *recordingRasterizer) Reset(width, height int) {
+	self.calls = append(self.calls, "Reset")
+	self.Rasterizer.Reset(width, height)
+}
+func (self *recordingRasterizer) MoveTo(x, y float32) {
+	self.calls = append(self.calls, "MoveTo")
+	self.Rasterizer.MoveTo(x, y)
+}
+func (self *recordingRasterizer) LineTo(x, y float32) {
+	self.calls = append(self.calls, "LineTo")
+	self.Rasterizer.LineTo(x, y)
+}
+func (self *recordingRasterizer) QuadTo(bx, by, cx, cy float32) {
+	self.calls = append(self.calls, "QuadTo")
+	self.Rasterizer.QuadTo(bx, by, cx, cy)
+}
+func (self *recordingRasterizer) CubeTo(bx, by, cx, cy, dx, dy float32) {
+	self.calls = append(self.calls, "CubeTo")
+	self.Rasterizer.CubeTo(bx, by, cx, cy, dx, dy)
+}
+func (self *recordingRasterizer) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	self.calls = append(self.calls, "Draw")
+	self.Rasterizer.Draw(dst, r, src, sp)
+}
+
+// Alternative backend that produces aliased masks.
+type aliasedRasterizer struct { *vector.Rasterizer }
+
+func (self aliasedRasterizer) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	self.Rasterizer.Draw(dst, r, src, sp)
+	mask := dst.(*image.Alpha)
+	for i, value := range mask.Pix {
+		if value >= 128 { mask.Pix[i] = 255 } else { mask.Pix[i] = 0 }
+	}
+}
+
+func TestCustomRasterizer(t *testing.T) {
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.LineTo(10, 0)
+	shape.QuadTo(15, 5, 10, 10)
+	shape.CubeTo(8, 12, 2, 12, 0, 10)
+	expected, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+
+	recorder := &recordingRasterizer{ Rasterizer: vector.NewRasterizer(0, 0) }
+	shape.SetRasterizer(recorder)
+	if shape.GetRasterizer() != Rasterizer(recorder) { t.Fatal("expected rasterizer to be set") }
+	mask, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+		t.Fatal("expected recording rasterizer to produce the default results")
+	}
+	calls := []string{ "Reset", "MoveTo", "LineTo", "QuadTo", "CubeTo", "Draw" }
+	if len(recorder.calls) != len(calls) { t.Fatalf("expected calls %v, got %v", calls, recorder.calls) }
+	for i, call := range calls {
+		if recorder.calls[i] != call { t.Fatalf("expected calls %v, got %v", calls, recorder.calls) }
+	}
+
+	// a different backend gives a differently filtered mask
+	shape.SetRasterizer(aliasedRasterizer{ vector.NewRasterizer(0, 0) })
+	dirty := image.NewAlpha(image.Rect(0, 0, 64, 64))
+	for i := range dirty.Pix { dirty.Pix[i] = 77 }
+	for _, rasterize := range []func() (*image.Alpha, error) {
+		shape.Rasterize,
+		func() (*image.Alpha, error) { _, err := shape.RasterizeInto(dirty, 0, 0); return dirty, err },
+	} {
+		aliased, err := rasterize()
+		if err != nil { t.Fatal(err) }
+		if aliased.Rect != expected.Rect { t.Fatalf("expected rect %v, got %v", expected.Rect, aliased.Rect) }
+		if bytes.Equal(aliased.Pix, expected.Pix) { t.Fatal("expected aliased mask to differ") }
+		for i, value := range aliased.Pix {
+			if value != 0 && value != 255 { t.Fatalf("unexpected aliased value %d", value) }
+			if (value == 255) != (expected.Pix[i] >= 128) { t.Fatalf("unexpected aliased value at %d", i) }
+		}
+	}
+
+	shape.SetRasterizer(nil)
+	if shape.GetRasterizer() != nil { t.Fatal("expected nil to restore the pooled rasterizers") }
+}
+
+func TestConcurrentRasterize(t *testing.T) {
+	shape := bigTestShape(96)
+	expected, err := shape.RasterizeFract(0, 0)
+	if err != nil { t.Fatal(err) }
+
+	const workers = 8
+	masks := make([]*image.Alpha, workers)
+	errs := make([]error, workers)
+	var group sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		group.Add(1)
+		go func(i int) {
+			defer group.Done()
+			for j := 0; j < 20; j++ {
+				masks[i], errs[i] = shape.RasterizeFract(0, 0)
+				if errs[i] != nil { return }
+			}
+		}(i)
+	}
+	group.Wait()
+	for i, mask := range masks {
+		if errs[i] != nil { t.Fatal(errs[i]) }
+		if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+			t.Fatalf("worker %d produced a different mask", i)
+		}
+	}
+}
+
+// Defines a 40x40 square with a moving diamond hole.
+func animatedSquare(shape *Shape, frame int) {
+	shape.Reset()
+	shape.MoveTo( 0,  0)
+	shape.LineTo(40,  0)
+	shape.LineTo(40, 40)
+	shape.LineTo( 0, 40)
+	shape.LineTo( 0,  0)
+	offset := frame % 20
+	shape.MoveTo(10 + offset, 5)
+	shape.LineTo( 5 + offset, 10)
+	shape.LineTo(10 + offset, 15)
+	shape.LineTo(15 + offset, 10)
+	shape.LineTo(10 + offset, 5)
+}
+
+func TestRasterizeInto(t *testing.T) {
+	shape := New()
+	var dst image.Alpha
+	for frame := 0; frame < 4; frame++ {
+		animatedSquare(&shape, frame*7)
+		rect, err := shape.RasterizeInto(&dst, 32, 0)
+		if err != nil { t.Fatal(err) }
+		expected, err := shape.RasterizeFract(32, 0)
+		if err != nil { t.Fatal(err) }
+		if rect != expected.Rect || dst.Rect != rect {
+			t.Fatalf("expected rect %v, got %v (dst.Rect %v)", expected.Rect, rect, dst.Rect)
+		}
+		if !bytes.Equal(dst.Pix, expected.Pix) {
+			t.Fatalf("frame %d: RasterizeInto result differs from RasterizeFract", frame)
+		}
+	}
+
+	// reuse a dirty, bigger buffer
+	dirty := image.NewAlpha(image.Rect(0, 0, 100, 100))
+	for i := range dirty.Pix { dirty.Pix[i] = 77 }
+	shape.Reset()
+	shape.MoveTo(0, 0)
+	shape.LineTo(4, 0)
+	shape.LineTo(4, 4)
+	shape.LineTo(0, 0)
+	_, err := shape.RasterizeInto(dirty, 0, 0)
+	if err != nil { t.Fatal(err) }
+	expected, _ := shape.Rasterize()
+	if dirty.Rect != expected.Rect || !bytes.Equal(dirty.Pix, expected.Pix) {
+		t.Fatal("expected stale pixels to be overwritten")
+	}
+	if cap(dirty.Pix) != 100*100 {
+		t.Fatal("expected dst buffer to be reused")
+	}
+
+	// nothing to draw
+	shape.Reset()
+	shape.MoveTo(3, 3)
+	rect, err := shape.RasterizeInto(dirty, 0, 0)
+	if err != nil || !rect.Empty() || !dirty.Rect.Empty() {
+		t.Fatalf("expected empty rect and no error, got %v, %v", rect, err)
+	}
+}
+
+func TestRasterizeGray(t *testing.T) {
+	star := testStar(20)
+	circle := New()
+	testCircle(&circle, 3, -2, 9)
+	for _, shape := range []*Shape{ &star, &circle } {
+		for _, offset := range []Fract{ 0, 17, 45 } {
+			alpha, err := shape.RasterizeFract(offset, offset)
+			if err != nil { t.Fatal(err) }
+			gray, err := shape.RasterizeGray(offset, offset)
+			if err != nil { t.Fatal(err) }
+			if gray.Rect != alpha.Rect || gray.Stride != alpha.Stride {
+				t.Fatalf("expected rect %v, got %v", alpha.Rect, gray.Rect)
+			}
+			if !bytes.Equal(gray.Pix, alpha.Pix) {
+				t.Fatal("expected RasterizeGray bytes to match RasterizeFract")
+			}
+		}
+	}
+
+	empty := New()
+	empty.MoveTo(1, 1)
+	gray, err := empty.RasterizeGray(0, 0)
+	if gray != nil || err != nil {
+		t.Fatalf("expected (nil, nil) for empty shape, got (%v, %v)", gray, err)
+	}
+}
+
+func TestRasterizeIntoAllocs(t *testing.T) {
+	shape := New()
+	var dst image.Alpha
+	animatedSquare(&shape, 0)
+	_, _ = shape.RasterizeInto(&dst, 0, 0) // warm up
+	frame := 0
+	allocs := testing.AllocsPerRun(50, func() {
+		frame += 1
+		animatedSquare(&shape, frame)
+		_, _ = shape.RasterizeInto(&dst, 0, 0)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected zero allocations in steady state, got %.2f", allocs)
+	}
+}
+
+func BenchmarkRasterizeInto(b *testing.B) {
+	shape := New()
+	var dst image.Alpha
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		animatedSquare(&shape, i)
+		_, _ = shape.RasterizeInto(&dst, 0, 0)
+	}
+}
+
+func BenchmarkRasterizeFract(b *testing.B) {
+	shape := New()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		animatedSquare(&shape, i)
+		_, _ = shape.RasterizeFract(0, 0)
+	}
+}
+
+func TestRasterizeClipped(t *testing.T) {
+	// vector.Rasterizer fixed point math is less precise, so it has
+	// a bigger tolerance when the origin is displaced
+	tests := []struct{ size int; maxDelta uint8 }{ {400, 8}, {600, 1}, {1500, 1} }
+	for _, test := range tests {
+		size := test.size
+		shape := bigTestShape(size)
+		full, err := shape.RasterizeFract(13, 40)
+		if err != nil { t.Fatal(err) }
+		clips := []image.Rectangle{
+			image.Rect(-32, -32, 32, 32),
+			image.Rect(full.Rect.Min.X - 10, full.Rect.Min.Y - 10, full.Rect.Min.X + 54, full.Rect.Min.Y + 54),
+			image.Rect(full.Rect.Max.X - 40, 0, full.Rect.Max.X + 24, 64),
+			image.Rect(size/5, -size/7, size/5 + 64, -size/7 + 64),
+		}
+		for _, clip := range clips {
+			clipped, err := shape.RasterizeClipped(clip, 13, 40)
+			if err != nil { t.Fatal(err) }
+			expected := full.SubImage(clip).(*image.Alpha)
+			if clipped.Rect != expected.Rect {
+				t.Fatalf("size %d, clip %v: expected rect %v, got %v", size, clip, expected.Rect, clipped.Rect)
+			}
+			if err := maskcmp.Compare(clipped, expected, test.maxDelta, 0); err != nil {
+				t.Fatalf("size %d, clip %v: %s", size, clip, err)
+			}
+		}
+
+		outside := image.Rect(size*2, size*2, size*3, size*3)
+		mask, err := shape.RasterizeClipped(outside, 0, 0)
+		if mask != nil || err != nil {
+			t.Fatalf("expected (nil, nil) for non-intersecting clip, got (%v, %v)", mask, err)
+		}
+	}
+}
+
+func TestRasterizeClippedHuge(t *testing.T) {
+	shape := bigTestShape(10000)
+	clip := image.Rect(2000, -3000, 2064, -2936)
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	allocated := stats.TotalAlloc
+	mask, err := shape.RasterizeClipped(clip, 0, 0)
+	if err != nil { t.Fatal(err) }
+	runtime.ReadMemStats(&stats)
+	allocated = stats.TotalAlloc - allocated
+
+	if mask.Rect != clip || len(mask.Pix) != 64*64 {
+		t.Fatalf("expected a 64x64 mask at %v, got %v", clip, mask.Rect)
+	}
+	if allocated > 1 << 20 {
+		t.Fatalf("expected small allocations for a clipped rasterization, got %d bytes", allocated)
+	}
+	for _, value := range mask.Pix { // region fully inside the ring band
+		if value != 255 { t.Fatal("expected full coverage within the clip region") }
+	}
+}
+
+func TestAccumulateInto(t *testing.T) {
+	a, b := New(), New()
+	testCircle(&a, 20, 20, 15)
+	testCircle(&b, 40, 20, 15)
+	maskA, _ := a.Rasterize()
+	maskB, _ := b.Rasterize()
+
+	for _, op := range []AccumulateOp{AccumulateOver, AccumulateAdd} {
+		dst := image.NewAlpha(image.Rect(0, -40, 60, 0))
+		if err := a.AccumulateInto(dst, 0, 0, op); err != nil { t.Fatal(err) }
+		if err := b.AccumulateInto(dst, 0, 0, op); err != nil { t.Fatal(err) }
+		if dst.AlphaAt(30, -20).A != 255 {
+			t.Fatalf("op %d: expected full opacity in the overlap", op)
+		}
+
+		// feathering outside the overlap must be preserved on both circles
+		for y := -40; y < 0; y++ {
+			for x := 0; x < 60; x++ {
+				aa, ba := maskA.AlphaAt(x, y).A, maskB.AlphaAt(x, y).A
+				got := dst.AlphaAt(x, y).A
+				if (ba == 0 && got != aa) || (aa == 0 && got != ba) {
+					t.Fatalf("op %d: bad feathering at (%d, %d): got %d, expected %d or %d", op, x, y, got, aa, ba)
+				}
+				if got < aa || got < ba {
+					t.Fatalf("op %d: accumulated coverage decreased at (%d, %d)", op, x, y)
+				}
+			}
+		}
+	}
+
+	err := a.AccumulateInto(image.NewAlpha(image.Rect(0, 0, 1, 1)), 0, 0, AccumulateOp(9))
+	if err == nil { t.Fatal("expected error on invalid op") }
+}
+
+func TestRasterizeParallel(t *testing.T) {
+	for _, size := range []int{ 300, 700, 1500 } {
+		shape := bigTestShape(size)
+		expected, err := shape.Rasterize()
+		if err != nil { t.Fatal(err) }
+		for _, workers := range []int{ 0, 1, 2, 3, 4, 7 } {
+			mask, err := shape.RasterizeParallel(workers)
+			if err != nil { t.Fatal(err) }
+			if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+				t.Fatalf("size %d, %d workers: expected result identical to serial rasterization", size, workers)
+			}
+		}
+	}
+
+	shape := New()
+	shape.MoveTo(1, 1)
+	mask, err := shape.RasterizeParallel(4)
+	if mask != nil || err != nil { t.Fatal("expected (nil, nil) for empty shape") }
+}
+
+func BenchmarkRasterizeParallel(b *testing.B) {
+	shape := bigTestShape(4000)
+	for _, workers := range []int{ 1, 2, 4 } {
+		b.Run("workers" + strconv.Itoa(workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = shape.RasterizeParallel(workers)
+			}
+		})
+	}
+}
+
+func TestRasterizePhases(t *testing.T) {
+	shape := testStar(13.3)
+	for _, phases := range [][2]int{ {1, 1}, {4, 1}, {4, 4}, {3, 2} } {
+		masks, err := shape.RasterizePhases(phases[0], phases[1])
+		if err != nil { t.Fatal(err) }
+		if len(masks) != phases[0]*phases[1] { t.Fatalf("expected %d masks, got %d", phases[0]*phases[1], len(masks)) }
+		for y := 0; y < phases[1]; y++ {
+			for x := 0; x < phases[0]; x++ {
+				expected, err := shape.RasterizeFract(Fract(x*64/phases[0]), Fract(y*64/phases[1]))
+				if err != nil { t.Fatal(err) }
+				mask := masks[y*phases[0] + x]
+				if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+					t.Fatalf("phases %v: mask (%d, %d) differs from RasterizeFract", phases, x, y)
+				}
+			}
+		}
+	}
+
+	if _, err := shape.RasterizePhases(0, 4); err == nil { t.Fatal("expected error for invalid phases") }
+	if _, err := shape.RasterizePhases(4, 65); err == nil { t.Fatal("expected error for invalid phases") }
+}
+
+func BenchmarkRasterizePhases(b *testing.B) {
+	shape := testStar(24)
+	b.Run("Batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ { _, _ = shape.RasterizePhases(4, 4) }
+	})
+	b.Run("Loop", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 4; x++ {
+					_, _ = shape.RasterizeFract(Fract(x*16), Fract(y*16))
+				}
+			}
+		}
+	})
+}
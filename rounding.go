@@ -0,0 +1,48 @@
+package sfntshape
+
+import "math"
+
+// Rounding modes for the conversion of float64 values to 26.6 fixed
+// point values, as set through [Shape.SetRounding]().
+type Rounding uint8
+const (
+	// Rounds to the nearest value, with ties away from zero, except for
+	// [Shape.SetScale](), which rounds ties up (towards positive infinity).
+	// This is the default.
+	RoundNearest Rounding = iota
+	RoundHalfEven // rounds to the nearest value, with ties to the even one
+	RoundFloor // rounds towards negative infinity
+	RoundCeil // rounds towards positive infinity
+)
+
+// Returns the rounding mode set with [Shape.SetRounding]().
+func (self *Shape) GetRounding() Rounding { return self.rounding }
+
+// Sets the rounding mode used to convert float64 values to 26.6
+// fixed point values in [Shape.SetScale](), the float64 based commands
+// ([Shape.LineToAngle](), [Shape.MoveByAngle]() and
+// [PreciseBuilder.Commit]()) and the re-quantization of coordinates in
+// [Shape.Transform]() and [Shape.Rescale](). Existing segments are
+// not modified. Useful to match the quantization of other systems
+// exactly. Unknown modes behave like [RoundNearest].
+func (self *Shape) SetRounding(mode Rounding) { self.rounding = mode }
+
+// Rounds the value to an integer according to the rounding mode.
+func (self Rounding) round(value float64) float64 {
+	switch self {
+	case RoundHalfEven: return math.RoundToEven(value)
+	case RoundFloor: return math.Floor(value)
+	case RoundCeil: return math.Ceil(value)
+	default: return math.Round(value)
+	}
+}
+
+// Converts a value in pixels to a 26.6 value according to the rounding
+// mode, as done by [Shape.SetScale](). RoundNearest and unknown modes
+// round ties up, like fixedFromFloat64, instead of away from zero.
+func (self Rounding) toFract(value float64) Fract {
+	switch self {
+	case RoundHalfEven, RoundFloor, RoundCeil: return Fract(self.round(value*64))
+	default: return fixedFromFloat64(value)
+	}
+}
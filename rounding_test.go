@@ -0,0 +1,72 @@
+package sfntshape
+
+import "math"
+import "testing"
+
+func TestRoundingModes(t *testing.T) {
+	// sweep with exact ties, values around the 1/128 threshold of
+	// fixedFromFloat64 and some regular values
+	var values []float64
+	for k := -300; k <= 300; k++ {
+		tie := float64(k)/128
+		values = append(values, tie, math.Nextafter(tie, -1), math.Nextafter(tie, 1))
+		values = append(values, tie - 1e-9, tie + 1e-9, float64(k)*0.0173)
+	}
+
+	tiesUp := func(value float64) float64 {
+		floor := math.Floor(value)
+		if value - floor >= 0.5 { floor += 1 }
+		return floor
+	}
+	modes := []struct {
+		mode Rounding
+		coords func(float64) float64
+		scale func(float64) float64
+	}{
+		{ RoundNearest, math.Round, tiesUp },
+		{ RoundHalfEven, math.RoundToEven, math.RoundToEven },
+		{ RoundFloor, math.Floor, math.Floor },
+		{ RoundCeil, math.Ceil, math.Ceil },
+	}
+	for _, test := range modes {
+		for _, value := range values {
+			shape := New()
+			shape.SetRounding(test.mode)
+			if shape.GetRounding() != test.mode { t.Fatal("unexpected rounding mode") }
+			shape.SetScale(value)
+			if got, expected := shape.GetScale(), Fract(test.scale(value*64)); got != expected {
+				t.Fatalf("mode %d, SetScale(%v): got %d, expected %d", test.mode, value, got, expected)
+			}
+
+			expected := Fract(test.coords(value*64))
+			var builder PreciseBuilder
+			builder.MoveTo(value, 0)
+			builder.Commit(&shape)
+			if got := shape.Segments()[0].Args[0].X; got != expected {
+				t.Fatalf("mode %d, PreciseBuilder.MoveTo(%v): got %d, expected %d", test.mode, value, got, expected)
+			}
+
+			shape.Reset()
+			shape.MoveToFract(0, 0)
+			shape.MoveByAngle(value, 0)
+			if got, _, _ := shape.Pos(); got != expected {
+				t.Fatalf("mode %d, MoveByAngle(%v): got %d, expected %d", test.mode, value, got, expected)
+			}
+
+			shape.Reset()
+			shape.SetScale(1)
+			shape.MoveToFract(64, 0)
+			shape.Rescale(value)
+			if got := shape.Segments()[0].Args[0].X; got != expected {
+				t.Fatalf("mode %d, Rescale(%v): got %d, expected %d", test.mode, value, got, expected)
+			}
+		}
+	}
+
+	// the default is unchanged
+	for _, value := range values {
+		if Fract(tiesUp(value*64)) != fixedFromFloat64(value) {
+			t.Fatalf("fixedFromFloat64(%v) = %d doesn't round ties up", value, fixedFromFloat64(value))
+		}
+	}
+}
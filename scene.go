@@ -0,0 +1,72 @@
+package sfntshape
+
+import "image"
+import "errors"
+import "image/draw"
+import "image/color"
+
+// A list of shapes to paint together, in insertion order (later
+// entries are painted on top). The zero value is an empty scene.
+//
+// Shapes are referenced, not copied, so they must not be modified
+// while rendering, but they can be modified between renders (e.g.,
+// to animate them).
+type Scene struct {
+	entries []sceneEntry
+	scratch image.Alpha
+}
+
+type sceneEntry struct {
+	shape *Shape
+	at image.Point
+	fill color.Color
+}
+
+// Adds the shape to the scene, with its origin anchored at the given
+// point and filled with the given color, as in [Shape.PaintOnto]().
+func (self *Scene) Add(shape *Shape, at image.Point, fill color.Color) {
+	self.entries = append(self.entries, sceneEntry{ shape, at, fill })
+}
+
+// Removes all the entries from the scene.
+func (self *Scene) Reset() {
+	for i := range self.entries { self.entries[i] = sceneEntry{} }
+	self.entries = self.entries[ : 0]
+}
+
+// Renders the scene into a new image with the given bounds, filled
+// with the background color and then with each entry composited over
+// the previous ones, like successive calls to [Shape.PaintOnto]().
+// Entries are rasterized one at a time into a mask shared with the
+// previous renders, and entries falling completely outside the bounds
+// are skipped without rasterizing them.
+//
+// Returns nil if any entry can't be rasterized. Use
+// [Scene.RenderChecked]() if you need to know the cause.
+func (self *Scene) Render(bounds image.Rectangle, backColor color.Color) *image.RGBA {
+	rgba, err := self.RenderChecked(bounds, backColor)
+	if err != nil { return nil }
+	return rgba
+}
+
+// Like [Scene.Render](), but also returning any rasterization error.
+// Entries with nothing to draw are not considered an error.
+func (self *Scene) RenderChecked(bounds image.Rectangle, backColor color.Color) (*image.RGBA, error) {
+	rgba := image.NewRGBA(bounds)
+	draw.Draw(rgba, bounds, image.NewUniform(backColor), image.Point{}, draw.Src)
+	for _, entry := range self.entries {
+		if entry.shape == nil { return nil, errors.New("nil shape in scene entry") }
+		if entry.shape.err != nil { return nil, entry.shape.err }
+		segments := entry.shape.Segments()
+		if !hasDrawingOps(segments) { continue }
+		width, height, _, _, rectOffset := figureOutBounds(segments.Bounds(), 0, 0)
+		if !image.Rect(0, 0, width, height).Add(rectOffset).Add(entry.at).Overlaps(bounds) { continue }
+
+		rect, err := entry.shape.RasterizeInto(&self.scratch, 0, 0)
+		if err != nil && !errors.Is(err, ErrNothingToDraw) { return nil, err }
+		if rect.Empty() { continue }
+		mask, _ := entry.shape.paintCoverage(&self.scratch, nil)
+		drawMask(rgba, mask, entry.at, entry.fill, draw.Over)
+	}
+	return rgba, nil
+}
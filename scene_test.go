@@ -0,0 +1,47 @@
+package sfntshape
+
+import "bytes"
+import "image"
+import "image/color"
+import "testing"
+
+func TestScene(t *testing.T) {
+	back, square, star := New(), testSquare(40), testStar(25)
+	testCircle(&back, 0, 0, 30)
+	huge := New() // would exceed the mask size limits if rasterized
+	huge.MoveTo(0, 0)
+	huge.LineTo(100000, 0)
+	huge.LineTo(100000, 100000)
+	huge.LineTo(0, 0)
+
+	var scene Scene
+	scene.Add(&back, image.Pt(30, 30), color.RGBA{ 0, 0, 200, 255 })
+	scene.Add(&square, image.Pt(20, 60), color.NRGBA{ 200, 0, 0, 128 })
+	scene.Add(&huge, image.Pt(-200000, 0), color.White) // fully outside
+	scene.Add(&star, image.Pt(50, 50), color.NRGBA{ 0, 220, 0, 160 })
+	bounds := image.Rect(0, 0, 90, 80)
+	img, err := scene.RenderChecked(bounds, color.Black)
+	if err != nil { t.Fatal(err) }
+
+	expected := image.NewRGBA(bounds)
+	for i := 3; i < len(expected.Pix); i += 4 { expected.Pix[i] = 255 }
+	for _, entry := range []struct { shape *Shape; at image.Point; fill color.Color }{
+		{ &back, image.Pt(30, 30), color.RGBA{ 0, 0, 200, 255 } },
+		{ &square, image.Pt(20, 60), color.NRGBA{ 200, 0, 0, 128 } },
+		{ &star, image.Pt(50, 50), color.NRGBA{ 0, 220, 0, 160 } },
+	} {
+		if err := entry.shape.PaintOnto(expected, entry.at, entry.fill); err != nil { t.Fatal(err) }
+	}
+	if !bytes.Equal(img.Pix, expected.Pix) { t.Fatal("scene differs from sequential PaintOnto calls") }
+
+	// rendering again reuses the scratch mask and gives the same result
+	if again := scene.Render(bounds, color.Black); again == nil || !bytes.Equal(again.Pix, img.Pix) {
+		t.Fatal("expected the same result rendering again")
+	}
+	scene.Add(&huge, image.Pt(0, 50), color.White)
+	if scene.Render(bounds, color.Black) != nil { t.Fatal("expected nil image for a failed entry") }
+	scene.Reset()
+	if img := scene.Render(image.Rect(0, 0, 2, 2), color.White); img == nil || img.RGBAAt(1, 1) != (color.RGBA{ 255, 255, 255, 255 }) {
+		t.Fatal("expected only the background after Reset")
+	}
+}
@@ -0,0 +1,160 @@
+package sfntshape
+
+import "math"
+import "image"
+import "image/color"
+import "testing"
+
+func TestSDF(t *testing.T) {
+	const radius, spread = 20.0, 4.0
+	shape := New()
+	testCircle(&shape, 0, 0, radius)
+	sdf, rect, err := shape.SDF(spread)
+	if err != nil { t.Fatal(err) }
+	if sdf.Rect != rect || rect.Min.X != -24 || rect.Max.X != 24 || rect.Min.Y != -24 || rect.Max.Y != 24 {
+		t.Fatalf("unexpected SDF rect %v (image rect %v)", rect, sdf.Rect)
+	}
+
+	// radial line along the x axis
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		centerDist := math.Abs(float64(x) + 0.5)
+		expected := radius - math.Hypot(centerDist, 0.5)
+		if math.Abs(expected) >= spread { continue }
+		decoded := (float64(sdf.GrayAt(x, 0).Y) - 128)*spread/128
+		if math.Abs(decoded - expected) > 1 {
+			t.Fatalf("at x = %d: expected distance %.3f, decoded %.3f", x, expected, decoded)
+		}
+	}
+
+	// signs must match Contains
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			value := sdf.GrayAt(x, y).Y
+			if value == 128 { continue }
+			inside := shape.Contains(float64(x) + 0.5, float64(y) + 0.5)
+			if inside != (value > 128) {
+				t.Fatalf("sign mismatch at (%d, %d): value %d, contains %t", x, y, value, inside)
+			}
+		}
+	}
+
+	if _, _, err := shape.SDF(0); err == nil {
+		t.Fatal("expected error for zero spread")
+	}
+	empty := New()
+	if img, _, err := empty.SDF(2); img != nil || err != nil {
+		t.Fatal("expected nil image and no error for empty shapes")
+	}
+}
+
+func TestContains(t *testing.T) {
+	star := testStar(40)
+	if !star.Contains(0, 0) || !star.Contains(0, -30) || star.Contains(35, -35) {
+		t.Fatal("unexpected Contains results on star")
+	}
+}
+
+// Bilinearly samples the given channel at the given continuous
+// position (pixel centers are at x + 0.5, y + 0.5).
+func sampleBilinear(img image.Image, x, y float64, channel func(color.Color) float64) float64 {
+	x, y = x - 0.5, y - 0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x - x0, y - y0
+	ix, iy := int(x0), int(y0)
+	v00 := channel(img.At(ix, iy))
+	v10 := channel(img.At(ix + 1, iy))
+	v01 := channel(img.At(ix, iy + 1))
+	v11 := channel(img.At(ix + 1, iy + 1))
+	return (v00*(1 - fx) + v10*fx)*(1 - fy) + (v01*(1 - fx) + v11*fx)*fy
+}
+
+func median3(a, b, c float64) float64 {
+	return math.Max(math.Min(a, b), math.Min(math.Max(a, b), c))
+}
+
+func TestMSDFCorners(t *testing.T) {
+	shape := New()
+	shape.MoveTo(-6, -6) // square
+	shape.LineTo( 6, -6)
+	shape.LineTo( 6,  6)
+	shape.LineTo(-6,  6)
+	shape.LineTo(-6, -6)
+	shape.MoveTo(10, -6) // triangle
+	shape.LineTo(22, -6)
+	shape.LineTo(10,  6)
+	shape.LineTo(10, -6)
+
+	const spread = 3.0
+	sdf, rect, err := shape.SDF(spread)
+	if err != nil { t.Fatal(err) }
+	msdf, msdfRect, err := shape.MSDF(spread)
+	if err != nil { t.Fatal(err) }
+	if rect != msdfRect { t.Fatalf("expected same rects, got %v and %v", rect, msdfRect) }
+
+	gray := func(c color.Color) float64 { return float64(c.(color.Gray).Y) }
+	red   := func(c color.Color) float64 { return float64(c.(color.NRGBA).R) }
+	green := func(c color.Color) float64 { return float64(c.(color.NRGBA).G) }
+	blue  := func(c color.Color) float64 { return float64(c.(color.NRGBA).B) }
+
+	// reconstruct at 4x and count mismatches against the exact shape,
+	// ignoring points too close to the outline to be decided reliably
+	const scale = 4
+	contours := flattenSegments(shape.segments, flattenTolerance)
+	sdfErrors, msdfErrors := 0, 0
+	for y := rect.Min.Y*scale; y < rect.Max.Y*scale; y++ {
+		for x := rect.Min.X*scale; x < rect.Max.X*scale; x++ {
+			px, py := (float64(x) + 0.5)/scale, (float64(y) + 0.5)/scale
+			if px < float64(rect.Min.X) + 1 || px > float64(rect.Max.X) - 1 { continue }
+			if py < float64(rect.Min.Y) + 1 || py > float64(rect.Max.Y) - 1 { continue }
+			if math.Abs(signedDistance(contours, px, py, spread)) < 0.1 { continue }
+			inside := shape.Contains(px, py)
+			sdfInside := sampleBilinear(sdf, px, py, gray) >= 128
+			msdfInside := median3(
+				sampleBilinear(msdf, px, py, red),
+				sampleBilinear(msdf, px, py, green),
+				sampleBilinear(msdf, px, py, blue),
+			) >= 128
+			if sdfInside != inside { sdfErrors += 1 }
+			if msdfInside != inside { msdfErrors += 1 }
+		}
+	}
+	if sdfErrors == 0 { t.Fatal("expected SDF to round some corners") }
+	if msdfErrors*2 > sdfErrors {
+		t.Fatalf("expected MSDF to preserve corners better than SDF (%d vs %d errors)", msdfErrors, sdfErrors)
+	}
+
+	// smooth contours use the same distance in all channels
+	shape.Reset()
+	testCircle(&shape, 0, 0, 8)
+	msdf, _, err = shape.MSDF(spread)
+	if err != nil { t.Fatal(err) }
+	for i := 0; i < len(msdf.Pix); i += 4 {
+		r, g, b := int(msdf.Pix[i]), int(msdf.Pix[i + 1]), int(msdf.Pix[i + 2])
+		if r != g || g != b { t.Fatalf("expected equal channels for a circle, got (%d, %d, %d)", r, g, b) }
+	}
+}
+
+func TestEdgeColoring(t *testing.T) {
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.LineTo(10, 0)
+	shape.LineTo(10, 10)
+	shape.LineTo(0, 10)
+	shape.LineTo(0, 0)
+	shape.MoveTo(20, 0) // teardrop with a single edge
+	shape.CubeTo(40, 10, 40, -10, 20, 0)
+	contours := buildEdgeContours(shape.Segments(), flattenTolerance)
+	if len(contours) != 2 { t.Fatalf("expected 2 contours, got %d", len(contours)) }
+	for i := range contours {
+		colorEdges(&contours[i])
+		edges := contours[i]
+		if len(edges) < 3 { t.Fatalf("contour %d: expected at least 3 edges, got %d", i, len(edges)) }
+		for j := range edges {
+			next := edges[(j + 1) % len(edges)]
+			if !isCorner(edges[j].endDir(), next.startDir()) { continue }
+			if edges[j].color == next.color || edges[j].color & next.color == 0 {
+				t.Fatalf("contour %d: bad colors %d and %d at corner", i, edges[j].color, next.color)
+			}
+		}
+	}
+}
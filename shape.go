@@ -1,6 +1,10 @@
 package sfntshape
 
+import "math"
 import "image"
+import "errors"
+import "sync"
+import "context"
 import "image/color"
 
 import "golang.org/x/image/font/sfnt"
@@ -13,7 +17,6 @@ type Fract = fixed.Int26_6
 // TODO: add some ArcTo method to draw quarter circles based on
 //       cubic bézier curves? so we can (from 0, 0) ArcTo(0, 10, 10, 10)
 //       instead of CubeTo(0, 5, 5, 10, 10, 10)
-// TODO: add closing func? like rasterizer.ClosePath()?
 
 // A helper type to assist the creation of shapes that can later be
 // converted to [sfnt.Segments] and rasterized with [etxt/mask.Rasterize](),
@@ -35,38 +38,169 @@ type Fract = fixed.Int26_6
 // square. If you define them following opposite directions, instead,
 // the result will be the difference between the two squares.
 type Shape struct {
-	rasterizer *vector.Rasterizer
+	custom *customRasterizer // shared by copies, nil to use pooled rasterizers
 	segments []sfnt.Segment
-	scale Fract
+	positions []fixed.Point26_6 // end of each segment, in user coordinates (see Pos)
+	bounds fixed.Rectangle26_6 // cached segments bounds (see Bounds)
+	anchors map[string]fixed.Point26_6 // in stored coordinates, like segments
+	scaleOffset Fract // scale - 64, so the zero value is unscaled
 	invertY bool // but rasterizers already invert coords, so this is negated
+	err error // first error recorded while adding segments
+	linearBlending bool // see SetLinearBlending
+	strict bool // see SetStrict
+	deterministic bool // see SetDeterministic
+	hook func(sfnt.SegmentOp, [3]fixed.Point26_6) bool // see SetHook
+	relative bool // see SetRelative
+	rounding Rounding // see SetRounding
+	transparency float64 // 1 - opacity, so the zero value is opaque
+	dirty dirtyRegion // only used after BeginTracking
 }
 
-// Creates a new Shape object.
+// Creates a new Shape object, with room for a few segments. The zero
+// value of Shape is also ready to use, with the same defaults (no
+// scaling, no InvertY). Shapes don't hold any rasterizer unless set
+// with [Shape.SetRasterizer](), they take them from a pool only while
+// rasterizing, so shapes that are only built and exported (e.g., with
+// [Shape.Segments]()) never allocate one.
 func New() Shape {
 	return Shape {
-		rasterizer: vector.NewRasterizer(0, 0),
 		segments: make([]sfnt.Segment, 0, 8),
+		positions: make([]fixed.Point26_6, 0, 8),
 		invertY: false,
-		scale: 64,
 	}
 }
 
+// Like [New](), but with room for n segments, so appending them
+// doesn't need to grow the underlying slices.
+func NewWithCapacity(n int) Shape {
+	if n < 0 { n = 0 }
+	return Shape {
+		segments: make([]sfnt.Segment, 0, n),
+		positions: make([]fixed.Point26_6, 0, n),
+	}
+}
+
+// Ensures the shape has room for at least n more segments without
+// growing the underlying slices again, like [strings.Builder.Grow]().
+// Negative values are ignored.
+func (self *Shape) Grow(n int) {
+	if n <= 0 { return }
+	if cap(self.segments) - len(self.segments) < n {
+		segments := make([]sfnt.Segment, len(self.segments), len(self.segments) + n)
+		copy(segments, self.segments)
+		self.segments = segments
+	}
+	if cap(self.positions) - len(self.positions) < n {
+		positions := make([]fixed.Point26_6, len(self.positions), len(self.positions) + n)
+		copy(positions, self.positions)
+		self.positions = positions
+	}
+}
+
+// Returns an independent copy of the shape. Unlike copying the Shape
+// value, which shares the underlying segments, the clone's segments are
+// a copy, so both shapes can be modified, reset and rasterized
+// independently (and concurrently). All the settings are preserved,
+// except for custom rasterizers (see [Shape.SetRasterizer]()), which
+// can't be used concurrently: the clone always uses pooled ones. If the
+// original is tracking changes, the clone is too, but its next
+// incremental rasterization will be a full one.
+func (self *Shape) Clone() Shape {
+	clone := *self
+	clone.segments = append(make([]sfnt.Segment, 0, len(self.segments)), self.segments...)
+	clone.positions = append(make([]fixed.Point26_6, 0, len(self.positions)), self.positions...)
+	clone.custom = nil
+	if self.anchors != nil {
+		clone.anchors = make(map[string]fixed.Point26_6, len(self.anchors))
+		for name, point := range self.anchors { clone.anchors[name] = point }
+	}
+	clone.dirty.markAll()
+	return clone
+}
+
 // Returns the current scaling factor.
 func (self *Shape) GetScale() Fract {
-	return self.scale
+	return self.scaleOffset + 64
 }
 
 // Sets a scaling factor to be applied to the coordinates of
 // subsequent [Shape.MoveTo](), [Shape.LineTo]() and similar
-// commands.
+// commands. Existing segments are not modified, see [Shape.Rescale]()
+// for that. The scale is rounded to a 26.6 value according to the
+// rounding mode (see [Shape.SetRounding]()).
 func (self *Shape) SetScale(scale float64) {
-	self.SetScaleFract(fixedFromFloat64(scale))
+	self.SetScaleFract(self.rounding.toFract(scale))
 }
 
 // Like [Shape.SetScale](), but expecting a Fract value
 // instead of a float64.
 func (self *Shape) SetScaleFract(scale Fract) {
-	self.scale = scale
+	self.scaleOffset = scale - 64
+}
+
+// Returns the custom rasterizer set with [Shape.SetRasterizer](), or
+// nil if there's none. By default, shapes take [*vector.Rasterizer]
+// values from an internal pool for each rasterization, so they can be
+// rasterized concurrently, and there's no rasterizer to return.
+func (self *Shape) GetRasterizer() Rasterizer {
+	if self.custom == nil { return nil }
+	return self.custom.rasterizer
+}
+
+// Sets the rasterizer to be used by [Shape.Rasterize]() and similar
+// methods. See [Rasterizer] for details on how custom rasterizers are
+// used. If nil, the shape goes back to using the internal pool of
+// [*vector.Rasterizer] values (the default).
+//
+// A single rasterizer can't be used by multiple goroutines at once, so
+// the shape and its copies by value (which share the rasterizer) guard
+// it with an in-use flag: if they are rasterized concurrently, the
+// rasterizations that find it busy use a pooled rasterizer instead when
+// the custom one is a *vector.Rasterizer (the results are the same), or
+// wait for it otherwise. Setting the same rasterizer on different shapes
+// (instead of copying one) is not guarded, so those shapes must not be
+// rasterized concurrently.
+func (self *Shape) SetRasterizer(rasterizer Rasterizer) {
+	if rasterizer == nil {
+		self.custom = nil
+	} else {
+		self.custom = &customRasterizer{ rasterizer: rasterizer }
+	}
+}
+
+// A custom rasterizer and its in-use flag, shared by shape copies.
+type customRasterizer struct {
+	rasterizer Rasterizer
+	inUse sync.Mutex
+}
+
+// Pool of vector rasterizers used by shapes without custom rasterizers.
+var vectorRasterizers = sync.Pool {
+	New: func() any { return vector.NewRasterizer(0, 0) },
+}
+
+// Returns the rasterizer to use for a rasterization, which must be
+// released with releaseRasterizer afterwards, and whether it's the
+// custom rasterizer (in which case its in-use lock is held).
+func (self *Shape) acquireRasterizer() (Rasterizer, bool) {
+	custom := self.custom
+	if custom == nil { return vectorRasterizers.Get().(*vector.Rasterizer), false }
+	if custom.inUse.TryLock() { return custom.rasterizer, true }
+	if _, isVector := custom.rasterizer.(*vector.Rasterizer); isVector {
+		return vectorRasterizers.Get().(*vector.Rasterizer), false
+	}
+	custom.inUse.Lock()
+	return custom.rasterizer, true
+}
+
+// Releases a rasterizer obtained from acquireRasterizer. Rasterizers
+// are not compared, as custom ones may not be comparable.
+func (self *Shape) releaseRasterizer(rasterizer Rasterizer, custom bool) {
+	if custom {
+		self.custom.inUse.Unlock()
+	} else {
+		vectorRasterizers.Put(rasterizer.(*vector.Rasterizer))
+	}
 }
 
 // Returns whether [Shape.InvertY] is active or inactive.
@@ -86,31 +220,181 @@ func (self *Shape) HasInvertY() bool { return self.invertY }
 // their center at (0, 0).
 func (self *Shape) InvertY(active bool) { self.invertY = active }
 
+// Returns whether [Shape.SetLinearBlending]() is active or inactive.
+func (self *Shape) HasLinearBlending() bool { return self.linearBlending }
+
+// By default, [Shape.Paint]() and similar methods blend the draw color
+// with the background directly on sRGB values, like image/draw. This
+// makes antialiased edges look darker than they should, so dark shapes
+// on light backgrounds look thicker than light shapes on dark ones.
+//
+// With linear blending active, the colors are converted to linear light
+// before blending them with the coverage of each pixel, and the result
+// is converted back to sRGB, so edges have the same perceived weight
+// regardless of the colors. This applies to [Shape.Paint](),
+// [Shape.Paint64]() and [Shape.PaintPaletted](). Alpha values are not
+// affected. Disabled by default.
+func (self *Shape) SetLinearBlending(active bool) { self.linearBlending = active }
+
+// Returns the opacity set with [Shape.SetOpacity](), 1 by default.
+func (self *Shape) GetOpacity() float64 { return 1 - self.transparency }
+
+// Sets a global opacity for the painted shape, in [0, 1] (values outside
+// the range are clamped, and NaN is treated as 1). The coverage of each
+// pixel is scaled by the opacity before blending, so fills, strokes,
+// gradients, textures and effects are all faded in the same way, which
+// is not always the case when scaling the alpha of the colors instead.
+//
+// The opacity is applied by all the Paint* methods, [Shape.Draw]() and
+// the methods based on it, [Shape.PixelsPremult]() and [Scene], but not
+// by the rasterization methods, which always return the raw coverage
+// (nor by [Shape.PaintHeatmap]()).
+func (self *Shape) SetOpacity(opacity float64) {
+	if !(opacity < 1) { opacity = 1 } // also catches NaNs
+	if opacity < 0 { opacity = 0 }
+	self.transparency = 1 - opacity
+}
+
+// Scales the coverage of a mask rasterized for painting by the opacity
+// of the shape, in place. Every paint path passes its masks through
+// here, so they are all faded the same way. Takes the results of the
+// rasterization as they are, and returns them unchanged on errors.
+func (self *Shape) paintCoverage(mask *image.Alpha, err error) (*image.Alpha, error) {
+	if err != nil || mask == nil || self.transparency == 0 { return mask, err }
+	var lut [256]uint8
+	opacity := 1 - self.transparency
+	for i := range lut { lut[i] = uint8(math.Round(float64(i)*opacity)) }
+	for i, value := range mask.Pix { mask.Pix[i] = lut[value] }
+	return mask, nil
+}
+
+// Returns whether [Shape.SetDeterministic]() is active or inactive.
+func (self *Shape) IsDeterministic() bool { return self.deterministic }
+
+// When active, the shape is always rasterized as if the Deterministic
+// option of [RasterizeOptions] was set, so the results are the same on
+// every platform. This applies to [Shape.RasterizeOpts]() and the methods
+// based on it (including [Shape.Rasterize]() and the paint methods) and
+// to [Shape.RasterizeStroke](). Mostly useful for golden tests of painted
+// images, as the paint methods don't take rasterization options.
+func (self *Shape) SetDeterministic(active bool) { self.deterministic = active }
+
+// Returns whether [Shape.SetStrict]() is active or inactive.
+func (self *Shape) IsStrict() bool { return self.strict }
+
+// In strict mode, drawing commands (LineTo, QuadTo and CubeTo) issued
+// before any MoveTo (on a new shape or right after [Shape.Reset]())
+// record an [ErrMissingMoveTo] error in [Shape.Err](), so rasterization
+// and paint methods refuse to run until the shape is reset. This helps
+// catch shapes relying on the implicit (0, 0) starting point, which
+// rasterizers don't handle consistently. The segments are still added.
+// Disabled by default.
+func (self *Shape) SetStrict(active bool) { self.strict = active }
+
+// Sets a function to be called for each segment added by the shape
+// commands ([Shape.MoveTo](), [Shape.LineTo]() and similar, including
+// the ones from [Builder] and [PreciseBuilder.Commit]()), right before
+// storing it. The hook receives the op and the points of the segment
+// in their final coordinates, with the scale and InvertY already
+// applied (like [Shape.Segments]() would return them; unused points
+// are zero), and the segment is only stored if it returns true. This
+// can be used to log or mirror the commands, or to enforce segment
+// budgets. Hooks may read the shape (e.g., its last segment), but
+// must not modify it. The hook is kept by [Shape.Reset]() and copied
+// by [Shape.Clone](). If nil, the hook is removed.
+func (self *Shape) SetHook(hook func(op sfnt.SegmentOp, points [3]fixed.Point26_6) bool) {
+	self.hook = hook
+}
+
 // Gets the shape information as [sfnt.Segments]. The underlying data
 // is referenced both by the Shape and the sfnt.Segments, so be
-// careful what you do with it.
+// careful what you do with it: after [Shape.Reset](), new commands
+// overwrite the returned segments. Use [Shape.SegmentsCopy]() or
+// [Shape.TakeSegments]() if you need to keep them around, and
+// [Shape.SetSegment]() to modify them (direct modifications are not
+// seen by the cached [Shape.Bounds]()).
 func (self *Shape) Segments() sfnt.Segments {
 	return sfnt.Segments(self.segments)
 }
 
+// Like [Shape.Segments](), but returning an independent copy that
+// further changes to the shape won't affect.
+func (self *Shape) SegmentsCopy() sfnt.Segments {
+	return append(make([]sfnt.Segment, 0, len(self.segments)), self.segments...)
+}
+
+// Returns the shape segments and resets the shape like [Shape.Reset](),
+// but handing the underlying storage over to the caller instead of
+// reusing it, so the returned segments are never overwritten by the
+// shape. This avoids the copy of [Shape.SegmentsCopy]() when the shape
+// is done (or will be rebuilt from scratch).
+func (self *Shape) TakeSegments() sfnt.Segments {
+	segments := self.segments
+	self.segments = nil
+	self.Reset()
+	return segments
+}
+
+// Returns the number of segments in the shape.
+func (self *Shape) Len() int { return len(self.segments) }
+
+// Returns the number of subpaths in the shape that contain any drawing
+// commands. MoveTo commands not followed by any LineTo, QuadTo or CubeTo
+// (consecutive or trailing ones) don't count, while drawing commands
+// before the first MoveTo form a subpath starting at (0, 0), so this is
+// the number of contours the rasterizers will actually see.
+func (self *Shape) NumSubpaths() int { return countSubpaths(self.segments) }
+
+// Returns whether the shape has nothing to draw: it has no segments or
+// they are all MoveTo commands. Rasterizing or painting empty shapes
+// results in nil masks or [ErrNothingToDraw] (see [SetNothingToDrawErrors]()).
+func (self *Shape) IsEmpty() bool { return !hasDrawingOps(self.segments) }
+
+// Returns whether [Shape.SetRelative]() is active or inactive.
+func (self *Shape) GetRelative() bool { return self.relative }
+
+// In relative mode, the coordinates passed to [Shape.MoveTo](),
+// [Shape.LineTo](), [Shape.QuadTo](), [Shape.CubeTo]() and their Fract
+// variants are deltas from the current position (see [Shape.Pos]()),
+// like the lowercase commands of SVG paths: all the points of a curve,
+// including the control points, are relative to the start of the
+// segment. On empty shapes, deltas are relative to (0, 0).
+//
+// Deltas are added to the current position before applying the scale
+// and InvertY, so they are scaled and Y-inverted like absolute
+// coordinates, and the resulting segments are exactly the same as when
+// passing the absolute coordinates in regular mode. Disabled by default.
+func (self *Shape) SetRelative(active bool) { self.relative = active }
+
+// Converts coordinates passed to the shape commands to absolute ones,
+// depending on the relative mode.
+func (self *Shape) absolute(x, y Fract) (Fract, Fract) {
+	if !self.relative { return x, y }
+	penX, penY, _ := self.Pos()
+	return self.saturate(int64(x) + int64(penX)), self.saturate(int64(y) + int64(penY))
+}
+
 // Moves the current position to (x, y).
 // See [vector.Rasterizer] operations and [sfnt.Segment].
 func (self *Shape) MoveTo(x, y int) {
-	self.MoveToFract(Fract(x << 6), Fract(y << 6))
+	self.MoveToFract(self.fractFromInt(x), self.fractFromInt(y))
 }
 
 // Like [Shape.MoveTo], but with fractional coordinates.
 func (self *Shape) MoveToFract(x, y Fract) {
-	if !self.invertY { y = -y }
-	if self.scale != 64 {
-		x = x.Mul(self.scale)
-		y = y.Mul(self.scale)
-	}
-	self.segments = append(self.segments,
+	x, y = self.absolute(x, y)
+	self.moveToFract(x, y)
+}
+
+// Like [Shape.MoveToFract](), ignoring the relative mode.
+func (self *Shape) moveToFract(x, y Fract) {
+	end := fixed.Point26_6{ X: x, Y: y }
+	x, y = self.transform(x, y)
+	self.appendSegment(end,
 		sfnt.Segment {
 			Op: sfnt.SegmentOpMoveTo,
 			Args: [3]fixed.Point26_6 {
-				fixed.Point26_6{x, y},
+				fixed.Point26_6{X: x, Y: y},
 				fixed.Point26_6{},
 				fixed.Point26_6{},
 			},
@@ -120,21 +404,24 @@ func (self *Shape) MoveToFract(x, y Fract) {
 // Creates a straight boundary from the current position to (x, y).
 // See [vector.Rasterizer] operations and [sfnt.Segment].
 func (self *Shape) LineTo(x, y int) {
-	self.LineToFract(Fract(x << 6), Fract(y << 6))
+	self.LineToFract(self.fractFromInt(x), self.fractFromInt(y))
 }
 
 // Like [Shape.LineTo], but with fractional coordinates.
 func (self *Shape) LineToFract(x, y Fract) {
-	if !self.invertY { y = -y }
-	if self.scale != 64 {
-		x = x.Mul(self.scale)
-		y = y.Mul(self.scale)
-	}
-	self.segments = append(self.segments,
+	x, y = self.absolute(x, y)
+	self.lineToFract(x, y)
+}
+
+// Like [Shape.LineToFract](), ignoring the relative mode.
+func (self *Shape) lineToFract(x, y Fract) {
+	end := fixed.Point26_6{ X: x, Y: y }
+	x, y = self.transform(x, y)
+	self.appendSegment(end,
 		sfnt.Segment {
 			Op: sfnt.SegmentOpLineTo,
 			Args: [3]fixed.Point26_6 {
-				fixed.Point26_6{x, y},
+				fixed.Point26_6{X: x, Y: y},
 				fixed.Point26_6{},
 				fixed.Point26_6{},
 			},
@@ -146,25 +433,23 @@ func (self *Shape) LineToFract(x, y Fract) {
 // See [vector.Rasterizer] operations and [sfnt.Segment].
 func (self *Shape) QuadTo(ctrlX, ctrlY, x, y int) {
 	self.QuadToFract(
-		Fract(ctrlX << 6), Fract(ctrlY << 6),
-		Fract(x     << 6), Fract(y     << 6))
+		self.fractFromInt(ctrlX), self.fractFromInt(ctrlY),
+		self.fractFromInt(x    ), self.fractFromInt(y    ))
 }
 
 // Like [Shape.QuadTo], but with fractional coordinates.
 func (self *Shape) QuadToFract(ctrlX, ctrlY, x, y Fract) {
-	if !self.invertY { ctrlY, y = -ctrlY, -y }
-	if self.scale != 64 {
-		ctrlX = ctrlX.Mul(self.scale)
-		ctrlY = ctrlY.Mul(self.scale)
-		x = x.Mul(self.scale)
-		y = y.Mul(self.scale)
-	}
-	self.segments = append(self.segments,
+	ctrlX, ctrlY = self.absolute(ctrlX, ctrlY)
+	x, y = self.absolute(x, y)
+	end := fixed.Point26_6{ X: x, Y: y }
+	ctrlX, ctrlY = self.transform(ctrlX, ctrlY)
+	x, y = self.transform(x, y)
+	self.appendSegment(end,
 		sfnt.Segment {
 			Op: sfnt.SegmentOpQuadTo,
 			Args: [3]fixed.Point26_6 {
-				fixed.Point26_6{ctrlX, ctrlY},
-				fixed.Point26_6{    x,     y},
+				fixed.Point26_6{X: ctrlX, Y: ctrlY},
+				fixed.Point26_6{X:     x, Y:     y},
 				fixed.Point26_6{},
 			},
 		})
@@ -176,36 +461,198 @@ func (self *Shape) QuadToFract(ctrlX, ctrlY, x, y Fract) {
 // [golang.org/x/image/font/sfnt.Segment].
 func (self *Shape) CubeTo(cx1, cy1, cx2, cy2, x, y int) {
 	self.CubeToFract(
-		Fract(cx1 << 6), Fract(cy1 << 6),
-		Fract(cx2 << 6), Fract(cy2 << 6),
-		Fract(x   << 6), Fract(y   << 6))
+		self.fractFromInt(cx1), self.fractFromInt(cy1),
+		self.fractFromInt(cx2), self.fractFromInt(cy2),
+		self.fractFromInt(x  ), self.fractFromInt(y  ))
 }
 
 // Like [Shape.CubeTo], but with fractional coordinates.
 func (self *Shape) CubeToFract(cx1, cy1, cx2, cy2, x, y Fract) {
-	if !self.invertY { cy1, cy2, y = -cy1, -cy2, -y }
-	if self.scale != 64 {
-		cx1 = cx1.Mul(self.scale)
-		cx2 = cx2.Mul(self.scale)
-		cy1 = cy1.Mul(self.scale)
-		cy2 = cy2.Mul(self.scale)
-		x = x.Mul(self.scale)
-		y = y.Mul(self.scale)
-	}
-	self.segments = append(self.segments,
+	cx1, cy1 = self.absolute(cx1, cy1)
+	cx2, cy2 = self.absolute(cx2, cy2)
+	x, y = self.absolute(x, y)
+	end := fixed.Point26_6{ X: x, Y: y }
+	cx1, cy1 = self.transform(cx1, cy1)
+	cx2, cy2 = self.transform(cx2, cy2)
+	x, y = self.transform(x, y)
+	self.appendSegment(end,
 		sfnt.Segment {
 			Op: sfnt.SegmentOpCubeTo,
 			Args: [3]fixed.Point26_6 {
-				fixed.Point26_6{cx1, cy1},
-				fixed.Point26_6{cx2, cy2},
-				fixed.Point26_6{  x,   y},
+				fixed.Point26_6{X: cx1, Y: cy1},
+				fixed.Point26_6{X: cx2, Y: cy2},
+				fixed.Point26_6{X:   x, Y:   y},
 			},
 		})
 }
 
-// Resets the shape segments. Be careful to not be holding the segments
-// from [Shape.Segments]() when calling this (they may be overriden soon).
-func (self *Shape) Reset() { self.segments = self.segments[0 : 0] }
+// Resets the shape segments and anchors (see [Shape.SetAnchor]()) and
+// clears any error recorded in [Shape.Err](). Be careful to not be
+// holding the segments from [Shape.Segments]() when calling this (they
+// may be overriden soon), see [Shape.TakeSegments]().
+func (self *Shape) Reset() {
+	self.segments = self.segments[0 : 0]
+	self.positions = self.positions[0 : 0]
+	self.bounds = fixed.Rectangle26_6{}
+	self.anchors = nil
+	self.err = nil
+	self.dirty.markAll()
+}
+
+// Like [Shape.Reset](), which keeps all the capacity of the shape for
+// the next commands. This is the fast path for shapes that are rebuilt
+// repeatedly with similar sizes.
+func (self *Shape) Clear() { self.Reset() }
+
+// Like [Shape.Reset](), but if the shape has room for more than
+// maxRetainSegments segments, its storage is reallocated with capacity
+// for maxRetainSegments instead, so a single huge shape doesn't pin its
+// memory for the lifetime of a reused (e.g., pooled) Shape. Negative
+// values are treated as zero.
+func (self *Shape) ResetAndShrink(maxRetainSegments int) {
+	if maxRetainSegments < 0 { maxRetainSegments = 0 }
+	if cap(self.segments) > maxRetainSegments {
+		self.segments = make([]sfnt.Segment, 0, maxRetainSegments)
+	}
+	if cap(self.positions) > maxRetainSegments {
+		self.positions = make([]fixed.Point26_6, 0, maxRetainSegments)
+	}
+	self.Reset()
+}
+
+// Removes the last segment of the shape, returning false if the shape
+// was already empty. The current position (see [Shape.Pos]()) goes back
+// to the end of the previous segment, and the removal is tracked by
+// [Shape.RasterizeIncremental](). Errors recorded in [Shape.Err]() are
+// not cleared.
+func (self *Shape) Pop() bool {
+	if len(self.segments) == 0 { return false }
+	if self.touchesBounds(self.pop()) { self.recomputeBounds() }
+	return true
+}
+
+// Like [Shape.Pop](), but removing the whole last subpath: all the
+// segments back to and including the most recent MoveTo (or all of
+// them, if there's none). Returns the number of segments removed.
+func (self *Shape) PopSubpath() int {
+	removed, shrink := 0, false
+	for len(self.segments) > 0 {
+		segment := self.pop()
+		shrink = shrink || self.touchesBounds(segment)
+		removed += 1
+		if segment.Op == sfnt.SegmentOpMoveTo { break }
+	}
+	if shrink { self.recomputeBounds() }
+	return removed
+}
+
+// Removes the last segment, which must exist, and returns it. The
+// cached bounds are not updated.
+func (self *Shape) pop() sfnt.Segment {
+	last := len(self.segments) - 1
+	if self.dirty.tracking {
+		var from fixed.Point26_6
+		if last > 0 { from = segmentEnd(self.segments[last - 1]) }
+		self.dirty.addSegment(from, self.segments[last])
+	}
+	removed := self.segments[last]
+	self.segments = self.segments[ : last]
+	self.positions = self.positions[ : last]
+	return removed
+}
+
+// Appends a segment in stored coordinates, tracking the changes, unless
+// the hook suppresses it. The end point is the segment's end in user
+// coordinates, for Pos.
+func (self *Shape) appendSegment(end fixed.Point26_6, segment sfnt.Segment) {
+	if self.hook != nil && !self.hook(segment.Op, segment.Args) { return }
+	if self.strict && len(self.segments) == 0 && segment.Op != sfnt.SegmentOpMoveTo && self.err == nil {
+		self.err = ErrMissingMoveTo
+	}
+	if self.dirty.tracking && segment.Op != sfnt.SegmentOpMoveTo {
+		self.dirty.addSegment(self.pen(), segment)
+	}
+	self.expandBounds(segment)
+	self.segments = append(self.segments, segment)
+	self.positions = append(self.positions, end)
+}
+
+// Returns the current position: the end point of the last segment
+// appended with [Shape.MoveTo](), [Shape.LineTo]() and similar methods,
+// in the coordinates passed to them (for the Fract variants, or 64 times
+// that for the int ones). That is, before applying the shape's scale and
+// InvertY, so the result doesn't depend on them, and it's not affected
+// retroactively if they change later. If the shape is empty, it returns
+// (0, 0, false).
+//
+// Segments replaced with [Shape.SetSegment]() or modified through
+// [Shape.Segments]() are not accounted for, as their stored coordinates
+// can't be mapped back to the coordinates that were originally used.
+func (self *Shape) Pos() (Fract, Fract, bool) {
+	if len(self.positions) == 0 { return 0, 0, false }
+	end := self.positions[len(self.positions) - 1]
+	return end.X, end.Y, true
+}
+
+// Returns the end point of the last segment, or (0, 0) if there
+// are no segments.
+func (self *Shape) pen() fixed.Point26_6 {
+	if len(self.segments) == 0 { return fixed.Point26_6{} }
+	return segmentEnd(self.segments[len(self.segments) - 1])
+}
+
+// Returns the first error recorded while adding segments to the shape,
+// or nil if there's none. The possible errors are [ErrCoordinateOverflow],
+// recorded when an integer coordinate passed to [Shape.MoveTo]() and
+// similar methods or the result of applying [Shape.SetScale]() to a
+// coordinate falls outside the range of 26.6 fixed point values (roughly
+// ±(1 << 25) pixels), [ErrMissingMoveTo] in strict mode (see
+// [Shape.SetStrict]()) and [*InvalidCommandError] when setting segments
+// with unknown ops through [Shape.SetSegment](). The offending coordinates are saturated, but
+// since the resulting geometry won't be what was requested, rasterization
+// methods refuse to run and return the recorded error instead. The error
+// is cleared by [Shape.Reset]().
+func (self *Shape) Err() error { return self.err }
+
+// Largest integer coordinate that can be converted to a Fract
+// without overflowing.
+const maxFractInt = math.MaxInt32 >> 6
+
+// Converts an integer coordinate to a Fract, recording an
+// [ErrCoordinateOverflow] and saturating if it's out of range.
+func (self *Shape) fractFromInt(value int) Fract {
+	if value > maxFractInt { return self.saturate(math.MaxInt64) }
+	if value < -maxFractInt { return self.saturate(math.MinInt64) }
+	return Fract(value << 6)
+}
+
+// Applies Y inversion and scaling to the given coordinates, recording
+// an [ErrCoordinateOverflow] and saturating if the results are out of
+// range.
+func (self *Shape) transform(x, y Fract) (Fract, Fract) {
+	ox, oy := int64(x), int64(y)
+	if !self.invertY { oy = -oy }
+	if self.scaleOffset != 0 { // same rounding as Fract.Mul
+		scale := int64(self.GetScale())
+		ox = (ox*scale + 32) >> 6
+		oy = (oy*scale + 32) >> 6
+	}
+	return self.saturate(ox), self.saturate(oy)
+}
+
+// Values are saturated to ±math.MaxInt32 instead of math.MinInt32 so
+// they can always be negated safely.
+func (self *Shape) saturate(value int64) Fract {
+	if value > math.MaxInt32 {
+		if self.err == nil { self.err = ErrCoordinateOverflow }
+		return math.MaxInt32
+	}
+	if value < -math.MaxInt32 {
+		if self.err == nil { self.err = ErrCoordinateOverflow }
+		return -math.MaxInt32
+	}
+	return Fract(value)
+}
 
 // A helper method to rasterize the current shape into an [*image.Alpha].
 func (self *Shape) Rasterize() (*image.Alpha, error) {
@@ -213,11 +660,94 @@ func (self *Shape) Rasterize() (*image.Alpha, error) {
 }
 
 // A helper method to rasterize the current shape displaced by the given
-// fractional offset into an [*image.Alpha].
+// fractional offset into an [*image.Alpha]. Equivalent to
+// [Shape.RasterizeOpts]() with only the offsets set.
 func (self *Shape) RasterizeFract(offsetX, offsetY Fract) (*image.Alpha, error) {
-	segments := self.Segments()
-	if len(segments) == 0 { return nil, nil }
-	return Rasterize(segments, self.rasterizer, offsetX, offsetY)
+	return self.RasterizeOpts(RasterizeOptions{ OffsetX: offsetX, OffsetY: offsetY })
+}
+
+// Like [Shape.RasterizeFract](), but writing the result into a
+// caller-provided mask whose buffer is reused when possible. This
+// is useful to avoid allocations when rasterizing shapes each frame.
+// See [RasterizeInto]() for the details.
+func (self *Shape) RasterizeInto(dst *image.Alpha, offsetX, offsetY Fract) (image.Rectangle, error) {
+	if self.err != nil { return image.Rectangle{}, self.err }
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	return RasterizeInto(dst, self.Segments(), rasterizer, offsetX, offsetY)
+}
+
+// Like [Shape.RasterizeFract](), but returning an [*image.Gray].
+// See [RasterizeGray]() for details.
+func (self *Shape) RasterizeGray(offsetX, offsetY Fract) (*image.Gray, error) {
+	if self.err != nil { return nil, self.err }
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	return RasterizeGray(self.Segments(), rasterizer, offsetX, offsetY)
+}
+
+// Rasterizes the shape as if its coordinates were font units, scaled to
+// the given size in pixels per em (ppem) for a font with the given units
+// per em. The shape is not modified: each point is scaled by ppem/unitsPerEm
+// and rounded to the nearest 26.6 value (ties away from zero) just for
+// this rasterization, so the same shape can be rendered at any size.
+func (self *Shape) RasterizeAtSize(ppem Fract, unitsPerEm int) (*image.Alpha, error) {
+	if self.err != nil { return nil, self.err }
+	if ppem <= 0 || unitsPerEm <= 0 {
+		return nil, errors.New("ppem and unitsPerEm must be positive")
+	}
+	scaled := scaleSegments(make([]sfnt.Segment, 0, len(self.segments)), self.segments, ppem, unitsPerEm)
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	return Rasterize(scaled, rasterizer, 0, 0)
+}
+
+// Appends the segments scaled by ppem/unitsPerEm to dst.
+func scaleSegments(dst, segments []sfnt.Segment, ppem Fract, unitsPerEm int) []sfnt.Segment {
+	den := int64(unitsPerEm) << 6
+	for _, segment := range segments {
+		for i := 0; i < segmentArgsCount(segment.Op); i++ {
+			segment.Args[i].X = fixedMulDivRound(segment.Args[i].X, ppem, den)
+			segment.Args[i].Y = fixedMulDivRound(segment.Args[i].Y, ppem, den)
+		}
+		dst = append(dst, segment)
+	}
+	return dst
+}
+
+// Rasterizes the shape at multiple subpixel phases in one go.
+// See [RasterizePhases]() for details.
+func (self *Shape) RasterizePhases(phasesX, phasesY int) ([]*image.Alpha, error) {
+	if self.err != nil { return nil, self.err }
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	return RasterizePhases(self.Segments(), rasterizer, phasesX, phasesY)
+}
+
+// Like [Shape.RasterizeFract](), but only rasterizing the region of the
+// shape within the given clip rectangle. Useful for big shapes that
+// extend far beyond the viewport. See [RasterizeClipped]() for details.
+func (self *Shape) RasterizeClipped(clip image.Rectangle, offsetX, offsetY Fract) (*image.Alpha, error) {
+	if self.err != nil { return nil, self.err }
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	return RasterizeClipped(self.Segments(), rasterizer, clip, offsetX, offsetY)
+}
+
+// Rasterizes the shape and composites its coverage over the existing
+// contents of dst. See [AccumulateInto]() for details.
+func (self *Shape) AccumulateInto(dst *image.Alpha, offsetX, offsetY Fract, op AccumulateOp) error {
+	if self.err != nil { return self.err }
+	rasterizer, custom := self.acquireRasterizer()
+	defer self.releaseRasterizer(rasterizer, custom)
+	return AccumulateInto(dst, self.Segments(), rasterizer, offsetX, offsetY, op)
+}
+
+// Like [Shape.RasterizeFract](), but with additional configuration
+// options. See [RasterizeOptions]. Uses the cached bounds of the shape
+// (see [Shape.Bounds]()) instead of computing them again.
+func (self *Shape) RasterizeOpts(opts RasterizeOptions) (*image.Alpha, error) {
+	return self.RasterizeCtx(context.Background(), opts)
 }
 
 // A helper method to rasterize the current shape with the given
@@ -225,42 +755,202 @@ func (self *Shape) RasterizeFract(offsetX, offsetY Fract) (*image.Alpha, error)
 //   file, _ := os.Create("my_ugly_shape.png")
 //   _ = png.Encode(file, shape.Paint(color.White, color.Black))
 //   // ...maybe even checking errors and closing the file ;)
+//
+// (Or just use [Shape.SavePNG]() or [Shape.EncodePNG]() instead.)
+//
+// The draw color is composited over backColor with standard source-over
+// math, like [draw.DrawMask] would, so translucent colors blend correctly.
+//
+// Returns nil if the shape is empty or can't be rasterized (e.g., it
+// exceeds the [SetMaxRasterSize]() limits). Use [Shape.PaintChecked]()
+// if you need to know the cause. If [SetNothingToDrawErrors]() is
+// enabled, empty shapes result in an empty image instead of nil.
 func (self *Shape) Paint(drawColor, backColor color.Color) *image.RGBA {
-	segments := self.Segments()
-	if len(segments) == 0 { return nil }
-	mask, err := Rasterize(segments, self.rasterizer, 0, 0)
-	if err != nil { panic(err) } // default rasterizer doesn't return errors
-	rgba := image.NewRGBA(mask.Rect)
+	rgba, err := self.PaintChecked(drawColor, backColor)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return rgba
+}
+
+// Like [Shape.Paint](), but also returning any rasterization error.
+func (self *Shape) PaintChecked(drawColor, backColor color.Color) (*image.RGBA, error) {
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+	return paintMask(mask, drawColor, backColor, self.linearBlending), nil
+}
+
+// Like [Shape.Paint](), but with 16 bits per channel. All the blending
+// is done in 16 bits (only the coverage has 8 bits), so there's no
+// banding between colors closer than 1/255. Converting the result to
+// 8 bits per channel gives the same result as [Shape.Paint]() for 8-bit
+// colors.
+func (self *Shape) Paint64(drawColor, backColor color.Color) *image.RGBA64 {
+	rgba, err := self.Paint64Checked(drawColor, backColor)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA64(image.Rectangle{}) }
+	if err != nil { return nil }
+	return rgba
+}
+
+// Like [Shape.Paint64](), but also returning any rasterization error.
+func (self *Shape) Paint64Checked(drawColor, backColor color.Color) (*image.RGBA64, error) {
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+	br, bg, bb, ba := backColor.RGBA()
+	lut := paintLUT(drawColor, color.RGBA64{ R: uint16(br), G: uint16(bg), B: uint16(bb), A: uint16(ba) }, self.linearBlending)
+	var pixels [256][8]uint8 // big endian, like the Pix of RGBA64
+	for i, colors := range lut {
+		for c, channel := range colors { pixels[i][c*2], pixels[i][c*2 + 1] = uint8(channel >> 8), uint8(channel) }
+	}
+	rgba := image.NewRGBA64(mask.Rect)
+	for i, value := range mask.Pix {
+		copy(rgba.Pix[i*8 : i*8 + 8], pixels[value][ : ])
+	}
+	return rgba, nil
+}
+
+// Like [Shape.Paint](), but storing the result as non-premultiplied
+// colors. The blending is computed directly on non-premultiplied values,
+// so translucent pixels (e.g., the edges of shapes painted over
+// transparent backgrounds) keep the exact colors instead of losing
+// precision when premultiplied and rounded to 8 bits and then converted
+// back. Linear blending is not supported, [Shape.SetLinearBlending]()
+// is ignored.
+func (self *Shape) PaintNRGBA(drawColor, backColor color.Color) *image.NRGBA {
+	nrgba, err := self.PaintNRGBAChecked(drawColor, backColor)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewNRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return nrgba
+}
+
+// Like [Shape.PaintNRGBA](), but also returning any rasterization error.
+func (self *Shape) PaintNRGBAChecked(drawColor, backColor color.Color) (*image.NRGBA, error) {
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+	lut := nrgbaPaintLUT(drawColor, backColor)
+	nrgba := image.NewNRGBA(mask.Rect)
+	for i, value := range mask.Pix {
+		copy(nrgba.Pix[i*4 : i*4 + 4], lut[value][ : ])
+	}
+	return nrgba, nil
+}
 
-	r, g, b, a := drawColor.RGBA()
-	nrgba := color.NRGBA64 { R: uint16(r), G: uint16(g), B: uint16(b), A: 0 }
-	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
-		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
-			nrgba.A = uint16((a*uint32(mask.AlphaAt(x, y).A))/255)
-			rgba.Set(x, y, mixColors(nrgba, backColor))
+// Like paintLUT, but returning the non-premultiplied 8-bit colors. The
+// source-over math is done in float64 on the non-premultiplied 16-bit
+// colors, and only the final values are rounded. Fully transparent
+// results are stored as transparent black.
+func nrgbaPaintLUT(drawColor, backColor color.Color) [256][4]uint8 {
+	var lut [256][4]uint8
+	src, dst := straightNRGBA64(drawColor), straightNRGBA64(backColor)
+	srcColor := [3]float64{ float64(src.R), float64(src.G), float64(src.B) }
+	dstColor := [3]float64{ float64(dst.R), float64(dst.G), float64(dst.B) }
+	srcAlpha, dstAlpha := float64(src.A)/65535.0, float64(dst.A)/65535.0
+	for i := range lut {
+		alpha := srcAlpha*float64(i)/255.0
+		backAlpha := dstAlpha*(1 - alpha)
+		outAlpha := alpha + backAlpha
+		out := &lut[i]
+		out[3] = uint8(math.Round(outAlpha*255))
+		if out[3] == 0 { continue }
+		for c := 0; c < 3; c++ {
+			value := (srcColor[c]*alpha + dstColor[c]*backAlpha)/outAlpha
+			out[c] = uint8(math.Round(value*255/65535))
 		}
 	}
+	return lut
+}
+
+// Like color.NRGBA64Model.Convert, but without going through the
+// premultiplied values for colors that are already non-premultiplied.
+func straightNRGBA64(c color.Color) color.NRGBA64 {
+	switch typed := c.(type) {
+	case color.NRGBA64: return typed
+	case color.NRGBA: return color.NRGBA64{ uint16(typed.R)*0x101, uint16(typed.G)*0x101, uint16(typed.B)*0x101, uint16(typed.A)*0x101 }
+	default: return color.NRGBA64Model.Convert(c).(color.NRGBA64)
+	}
+}
+
+// Rasterizes the shape for the paint methods, with the coverage
+// scaled by the opacity (see [Shape.SetOpacity]()).
+func (self *Shape) rasterizeForPaint() (*image.Alpha, error) {
+	return self.paintCoverage(self.rasterizeCoverage())
+}
+
+// Like rasterizeForPaint, but without applying the opacity.
+func (self *Shape) rasterizeCoverage() (*image.Alpha, error) {
+	return self.RasterizeOpts(RasterizeOptions{})
+}
+
+// Paints the mask with the given colors for [Shape.PaintChecked](). The
+// result is the same as filling the image with the background color and
+// then using [draw.DrawMask] with the draw color and the mask. The mask
+// must be tightly packed (stride equal to its width), like the ones
+// [Rasterize]() returns. If linear is set, blending is done in linear
+// light instead (see [Shape.SetLinearBlending]()).
+func paintMask(mask *image.Alpha, drawColor, backColor color.Color, linear bool) *image.RGBA {
+	// like image/draw, the background is 8-bit before compositing
+	back := color.RGBAModel.Convert(backColor).(color.RGBA)
+	back64 := color.RGBA64{ R: uint16(back.R)*0x101, G: uint16(back.G)*0x101, B: uint16(back.B)*0x101, A: uint16(back.A)*0x101 }
+	var lut [256][4]uint8
+	for i, colors := range paintLUT(drawColor, back64, linear) {
+		for c, channel := range colors { lut[i][c] = uint8(channel >> 8) }
+	}
+	rgba := image.NewRGBA(mask.Rect)
+	for i, value := range mask.Pix {
+		copy(rgba.Pix[i*4 : i*4 + 4], lut[value][ : ])
+	}
 	return rgba
 }
 
-// Helper method for [Shape.Paint](). The same as mixOverFunc
-// on the generic version of etxt (see etxt/ebiten_no.go).
-func mixColors(draw color.Color, back color.Color) color.Color {
-	dr, dg, db, da := draw.RGBA()
-	if da == 0xFFFF { return draw }
-	if da == 0      { return back }
-	br, bg, bb, ba := back.RGBA()
-	if ba == 0      { return draw }
-	return color.RGBA64 {
-		R: uint16N((dr*0xFFFF + br*(0xFFFF - da))/0xFFFF),
-		G: uint16N((dg*0xFFFF + bg*(0xFFFF - da))/0xFFFF),
-		B: uint16N((db*0xFFFF + bb*(0xFFFF - da))/0xFFFF),
-		A: uint16N((da*0xFFFF + ba*(0xFFFF - da))/0xFFFF),
+// Returns the 16-bit premultiplied colors resulting from compositing
+// the draw color over the background with each coverage value, with
+// standard source-over math (same formulas as image/draw). Each pixel's
+// color only depends on its coverage, so painting only needs to resolve
+// the 256 possible colors once. If linear is set, the colors are
+// blended in linear light instead (see linearPaintLUT).
+func paintLUT(drawColor color.Color, back color.RGBA64, linear bool) [256][4]uint16 {
+	if linear { return linearPaintLUT(drawColor, back) }
+	var lut [256][4]uint16
+	const m = 0xFFFF
+	sr, sg, sb, sa := drawColor.RGBA()
+	br, bg, bb, ba := uint32(back.R), uint32(back.G), uint32(back.B), uint32(back.A)
+	for i := range lut {
+		ma := uint32(i)*0x101
+		a := m - (sa*ma/m)
+		lut[i] = [4]uint16{
+			uint16((br*a + sr*ma)/m),
+			uint16((bg*a + sg*ma)/m),
+			uint16((bb*a + sb*ma)/m),
+			uint16((ba*a + sa*ma)/m),
+		}
 	}
+	return lut
 }
 
-// clamping from uint32 to uint16 values
-func uint16N(value uint32) uint16 {
-	if value > 65535 { return 65535 }
-	return uint16(value)
+// Like [Shape.Paint](), but painting the shape over a transparent
+// background, which is better for later composition. The result is
+// non-premultiplied: every pixel has the RGB components of the draw
+// color, even the ones with zero coverage (so bilinear sampling doesn't
+// bleed black around the edges), and an alpha equal to the coverage
+// multiplied by the draw color's alpha.
+func (self *Shape) PaintAlpha(drawColor color.Color) *image.NRGBA {
+	nrgba, err := self.PaintAlphaChecked(drawColor)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewNRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return nrgba
+}
+
+// Like [Shape.PaintAlpha](), but also returning any rasterization error.
+func (self *Shape) PaintAlphaChecked(drawColor color.Color) (*image.NRGBA, error) {
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+	nrgba := image.NewNRGBA(mask.Rect)
+
+	fill := color.NRGBAModel.Convert(drawColor).(color.NRGBA)
+	alpha := uint32(fill.A)
+	for i, value := range mask.Pix {
+		pixel := nrgba.Pix[i*4 : i*4 + 4 : i*4 + 4]
+		pixel[0], pixel[1], pixel[2] = fill.R, fill.G, fill.B
+		pixel[3] = uint8((uint32(value)*alpha + 127)/255)
+	}
+	return nrgba, nil
 }
@@ -1,10 +1,22 @@
 package sfntshape
 
+import "math"
+import "bytes"
+import "math/big"
+import "image"
+import "errors"
+import "image/draw"
 import "image/color"
+import "sync"
+import "reflect"
 import "testing"
 
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
 import "golang.org/x/image/vector"
 
+import "github.com/tinne26/sfntshape/internal/maskcmp"
+
 func TestShape(t *testing.T) {
 	shape := New()
 
@@ -69,3 +81,884 @@ func TestShape(t *testing.T) {
 		t.Fatal("expected zero segments after reset")
 	}
 }
+
+func TestPaintAlpha(t *testing.T) {
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.CubeTo(20, 30, 40, -10, 60, 40)
+	shape.QuadTo(10, 50, 0, 0)
+
+	fill := color.NRGBA{ 200, 40, 90, 160 }
+	img := shape.PaintAlpha(fill)
+	if img == nil { t.Fatal("unexpected nil image") }
+	mask, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	if img.Rect != mask.Rect { t.Fatalf("expected bounds %v, got %v", mask.Rect, img.Rect) }
+	corner := img.NRGBAAt(img.Rect.Max.X - 1, img.Rect.Min.Y)
+	if mask.AlphaAt(img.Rect.Max.X - 1, img.Rect.Min.Y).A != 0 { t.Fatal("expected an empty corner") }
+	if corner != (color.NRGBA{ 200, 40, 90, 0 }) {
+		t.Fatalf("expected transparent corner with the draw color's RGB, got %v", corner)
+	}
+
+	// composited over a solid color, it must match Paint
+	opaque := color.NRGBA{ 200, 40, 90, 255 }
+	back := color.RGBA{ 10, 130, 250, 255 }
+	img, painted := shape.PaintAlpha(opaque), shape.Paint(opaque, back)
+	backPix := [3]int{ int(back.R), int(back.G), int(back.B) }
+	for i := 0; i < len(img.Pix); i += 4 {
+		alpha := int(img.Pix[i + 3])
+		for c := 0; c < 3; c++ {
+			composed := (int(img.Pix[i + c])*alpha + backPix[c]*(255 - alpha) + 127)/255
+			if absInt(composed - int(painted.Pix[i + c])) > 1 {
+				t.Fatalf("pixel %d: composed %d, but Paint gives %d", i/4, composed, painted.Pix[i + c])
+			}
+		}
+	}
+}
+
+// Reference implementation of Paint using image/draw.
+func paintMaskReference(mask *image.Alpha, drawColor, backColor color.Color) *image.RGBA {
+	rgba := image.NewRGBA(mask.Rect)
+	draw.Draw(rgba, rgba.Rect, image.NewUniform(backColor), image.Point{}, draw.Src)
+	draw.DrawMask(rgba, rgba.Rect, image.NewUniform(drawColor), image.Point{}, mask, mask.Rect.Min, draw.Over)
+	return rgba
+}
+
+func TestPaintMask(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(-3, 5, 13, 21))
+	for i := range mask.Pix { mask.Pix[i] = uint8(i) }
+	colors := []color.Color{
+		color.White, color.Black, color.Transparent, color.RGBA{ 200, 100, 50, 255 },
+		color.NRGBA{ 30, 200, 90, 128 }, color.RGBA{ 64, 0, 32, 64 }, color.Gray16{ 0x1234 },
+		color.NRGBA{ 255, 0, 0, 1 }, color.NRGBA{ 255, 0, 0, 254 }, color.NRGBA64{ 0x8000, 0xFFFF, 0, 0x7FFF },
+	}
+	for _, drawColor := range colors {
+		for _, backColor := range colors {
+			got, expected := paintMask(mask, drawColor, backColor, false), paintMaskReference(mask, drawColor, backColor)
+			if got.Rect != expected.Rect || !bytes.Equal(got.Pix, expected.Pix) {
+				t.Fatalf("paintMask(%v, %v) differs from image/draw", drawColor, backColor)
+			}
+		}
+	}
+
+	// 50% red over white must be pink, not a darker red
+	shape := testPolygon(10, 8)
+	img := shape.Paint(color.NRGBA{ 255, 0, 0, 128 }, color.White)
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{ 255, 127, 127, 255 }) {
+		t.Fatalf("expected source-over result, got %v", got)
+	}
+}
+
+func TestPaint64(t *testing.T) {
+	shape := New()
+	testCircle(&shape, 0, 0, 20)
+	for _, drawColor := range []color.Color{ color.White, color.NRGBA{ 30, 200, 90, 128 }, color.RGBA{ 64, 0, 32, 64 } } {
+		img64, img := shape.Paint64(drawColor, color.RGBA{ 10, 20, 200, 255 }), shape.Paint(drawColor, color.RGBA{ 10, 20, 200, 255 })
+		if img64 == nil || img64.Rect != img.Rect { t.Fatal("unexpected Paint64 bounds") }
+		for i := range img.Pix {
+			if img64.Pix[i*2] != img.Pix[i] { // high byte
+				t.Fatalf("%v: pixel %d, channel %d differs from Paint", drawColor, i/4, i % 4)
+			}
+		}
+	}
+
+	// colors differing only in the low bytes must stay different
+	a := shape.Paint64(color.RGBA64{ 0x8000, 0x8000, 0x8000, 0xFFFF }, color.Black)
+	b := shape.Paint64(color.RGBA64{ 0x8040, 0x8000, 0x8000, 0xFFFF }, color.Black)
+	if a.RGBA64At(0, 0).R != 0x8000 || b.RGBA64At(0, 0).R != 0x8040 {
+		t.Fatalf("expected 16-bit colors to be preserved, got %v and %v", a.RGBA64At(0, 0), b.RGBA64At(0, 0))
+	}
+}
+
+func benchmarkPaintMask(b *testing.B, paint func(*image.Alpha, color.Color, color.Color) *image.RGBA) {
+	shape := New()
+	testCircle(&shape, 256, 256, 256)
+	mask, err := shape.Rasterize()
+	if err != nil { b.Fatal(err) }
+	drawColor := color.RGBA{ 200, 100, 50, 255 }
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ { _ = paint(mask, drawColor, color.Black) }
+}
+
+func BenchmarkPaintMask(b *testing.B) {
+	benchmarkPaintMask(b, func(mask *image.Alpha, drawColor, backColor color.Color) *image.RGBA {
+		return paintMask(mask, drawColor, backColor, false)
+	})
+}
+func BenchmarkPaintMaskLinear(b *testing.B) {
+	benchmarkPaintMask(b, func(mask *image.Alpha, drawColor, backColor color.Color) *image.RGBA {
+		return paintMask(mask, drawColor, backColor, true)
+	})
+}
+func BenchmarkPaintMaskReference(b *testing.B) { benchmarkPaintMask(b, paintMaskReference) }
+
+func TestRasterizeAtSize(t *testing.T) {
+	// glyph-like "o" in font units (2048 units per em)
+	points := [][2]int{ {200, 0}, {1000, 0}, {1000, 1100}, {200, 1100} }
+	hole := [][2]int{ {400, 200}, {400, 900}, {800, 900}, {800, 200} }
+	shape := New()
+	build := func(target *Shape, scale func(int) Fract) {
+		for _, contour := range [][][2]int{ points, hole } {
+			target.MoveToFract(scale(contour[0][0]), scale(contour[0][1]))
+			for _, point := range contour[1 : ] {
+				target.LineToFract(scale(point[0]), scale(point[1]))
+			}
+			target.QuadToFract(scale(contour[0][0] - 77), scale(contour[0][1] + 33), scale(contour[0][0]), scale(contour[0][1]))
+		}
+	}
+	build(&shape, func(units int) Fract { return Fract(units << 6) })
+	segments := append([]sfnt.Segment(nil), shape.segments...)
+
+	for _, ppem := range []Fract{ 12 << 6, 200 << 6, 17*64 + 32 } {
+		mask, err := shape.RasterizeAtSize(ppem, 2048)
+		if err != nil { t.Fatal(err) }
+		prescaled := New()
+		build(&prescaled, func(units int) Fract {
+			return Fract(math.Round(float64(units)*float64(ppem)/2048))
+		})
+		expected, err := prescaled.Rasterize()
+		if err != nil { t.Fatal(err) }
+		if mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+			t.Fatalf("ppem %v: expected result to match pre-scaled shape", ppem)
+		}
+	}
+	if !sameSegments(segments, shape.segments) { t.Fatal("expected shape to remain unmodified") }
+
+	if _, err := shape.RasterizeAtSize(0, 2048); err == nil { t.Fatal("expected error for zero ppem") }
+	if _, err := shape.RasterizeAtSize(12 << 6, 0); err == nil { t.Fatal("expected error for zero units per em") }
+}
+
+func TestCoordinateOverflow(t *testing.T) {
+	const limit = (1 << 25) - 1
+
+	// legitimate large values
+	shape := New()
+	shape.MoveTo(-limit, -limit)
+	shape.LineTo(limit, -limit)
+	shape.QuadTo(limit, limit, 0, limit)
+	shape.CubeTo(-limit, limit, -limit, 0, -limit, -limit)
+	shape.InvertY(true)
+	shape.LineToFract(-math.MaxInt32, math.MaxInt32)
+	if shape.Err() != nil { t.Fatalf("unexpected error: %v", shape.Err()) }
+	args := shape.Segments()[1].Args[0]
+	if args.X != limit << 6 || args.Y != limit << 6 {
+		t.Fatalf("unexpected LineTo args %v", args)
+	}
+
+	shape.Reset()
+	shape.InvertY(false)
+	shape.SetScale(2)
+	shape.MoveTo(limit/2, -(limit/2))
+	if shape.Err() != nil { t.Fatalf("unexpected error: %v", shape.Err()) }
+
+	// out of range values
+	tests := map[string]func(*Shape) {
+		"MoveTo": func(shape *Shape) { shape.MoveTo(limit + 1, 0) },
+		"LineTo": func(shape *Shape) { shape.LineTo(0, -limit - 1) },
+		"QuadTo": func(shape *Shape) { shape.QuadTo(0, 0, limit*2, 0) },
+		"CubeTo": func(shape *Shape) { shape.CubeTo(0, math.MinInt, 0, 0, 0, 0) },
+		"LineToFract": func(shape *Shape) { shape.LineToFract(0, math.MinInt32) },
+		"SetScale": func(shape *Shape) {
+			shape.SetScale(2)
+			shape.LineTo(limit/2 + 1, 0)
+		},
+		"SetScaleFract": func(shape *Shape) {
+			shape.SetScaleFract(math.MaxInt32)
+			shape.LineTo(0, 2)
+		},
+	}
+	for name, fn := range tests {
+		shape := New()
+		shape.MoveTo(0, 0)
+		fn(&shape)
+		shape.LineTo(8, 8)
+		shape.LineTo(0, 8)
+		if !errors.Is(shape.Err(), ErrCoordinateOverflow) {
+			t.Fatalf("%s: expected ErrCoordinateOverflow, got %v", name, shape.Err())
+		}
+		for _, segment := range shape.Segments() {
+			for _, point := range segment.Args {
+				if point.X == math.MinInt32 || point.Y == math.MinInt32 {
+					t.Fatalf("%s: coordinates not saturated", name)
+				}
+			}
+		}
+		if _, err := shape.Rasterize(); !errors.Is(err, ErrCoordinateOverflow) {
+			t.Fatalf("%s: expected Rasterize to fail, got %v", name, err)
+		}
+		if _, err := shape.RasterizeInto(&image.Alpha{}, 0, 0); !errors.Is(err, ErrCoordinateOverflow) {
+			t.Fatalf("%s: expected RasterizeInto to fail, got %v", name, err)
+		}
+		if err := shape.AccumulateInto(image.NewAlpha(image.Rect(0, 0, 8, 8)), 0, 0, AccumulateAdd); !errors.Is(err, ErrCoordinateOverflow) {
+			t.Fatalf("%s: expected AccumulateInto to fail, got %v", name, err)
+		}
+
+		shape.Reset()
+		if shape.Err() != nil { t.Fatalf("%s: Reset didn't clear the error", name) }
+	}
+}
+
+func TestLinearBlending(t *testing.T) {
+	shape := New()
+	testCircle(&shape, 20.3, 20.7, 15.2)
+	if shape.HasLinearBlending() { t.Fatal("expected linear blending to be disabled by default") }
+	white, black := color.RGBA{ 255, 255, 255, 255 }, color.RGBA{ 0, 0, 0, 255 }
+	mask, _ := shape.Rasterize()
+
+	// the light added by a white shape on black must match the light
+	// removed by a black shape on white with the same coverage
+	asymmetry := func(whiteOnBlack, blackOnWhite *image.RGBA64, x, y int) float64 {
+		light := decodeSRGB16(uint32(whiteOnBlack.RGBA64At(x, y).R))
+		removed := 1 - decodeSRGB16(uint32(blackOnWhite.RGBA64At(x, y).R))
+		return math.Abs(light - removed)
+	}
+	srgbMax := 0.0
+	wob, bow := shape.Paint64(white, black), shape.Paint64(black, white)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			srgbMax = math.Max(srgbMax, asymmetry(wob, bow, x, y))
+		}
+	}
+	if srgbMax < 0.3 { t.Fatalf("expected sRGB blending to be asymmetric, max asymmetry %f", srgbMax) }
+
+	shape.SetLinearBlending(true)
+	wob, bow = shape.Paint64(white, black), shape.Paint64(black, white)
+	wob8, bow8 := shape.Paint(white, black), shape.Paint(black, white)
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			coverage := float64(mask.AlphaAt(x, y).A)/255
+			if light := decodeSRGB16(uint32(wob.RGBA64At(x, y).R)); math.Abs(light - coverage) > 0.0005 {
+				t.Fatalf("pixel (%d, %d): expected linear value %f, got %f", x, y, coverage, light)
+			}
+			if diff := asymmetry(wob, bow, x, y); diff > 0.001 {
+				t.Fatalf("pixel (%d, %d): linear blending asymmetry %f", x, y, diff)
+			}
+			light := srgb8ToLinear[wob8.RGBAAt(x, y).R]
+			removed := 1 - srgb8ToLinear[bow8.RGBAAt(x, y).R]
+			if math.Abs(light - removed) > 0.01 { // about an 8-bit step near white
+				t.Fatalf("pixel (%d, %d): 8-bit linear blending asymmetry %f", x, y, math.Abs(light - removed))
+			}
+		}
+	}
+
+	// a 50% coverage pixel is the same mid gray in both cases
+	for _, colors := range [][2]color.RGBA{ { white, black }, { black, white } } {
+		lut := linearPaintLUT(colors[0], color.RGBA64{ R: uint16(colors[1].R)*0x101, G: uint16(colors[1].G)*0x101, B: uint16(colors[1].B)*0x101, A: 0xFFFF })
+		mid := (decodeSRGB16(uint32(lut[127][0])) + decodeSRGB16(uint32(lut[128][0])))/2
+		if math.Abs(mid - 0.5) > 0.0005 { t.Fatalf("expected linear mid gray 0.5, got %f", mid) }
+	}
+
+	// fully covered and uncovered pixels keep their exact colors
+	fill := color.RGBA{ 200, 50, 100, 255 }
+	lut := linearPaintLUT(fill, color.RGBA64{ R: 10*0x101, G: 200*0x101, B: 30*0x101, A: 0xFFFF })
+	if lut[0] != [4]uint16{ 10*0x101, 200*0x101, 30*0x101, 0xFFFF } { t.Fatalf("unexpected back %v", lut[0]) }
+	if lut[255] != [4]uint16{ 200*0x101, 50*0x101, 100*0x101, 0xFFFF } { t.Fatalf("unexpected fill %v", lut[255]) }
+	lut = linearPaintLUT(color.RGBA{ 0, 0, 0, 0 }, color.RGBA64{})
+	if lut[200] != [4]uint16{} { t.Fatalf("expected transparent result, got %v", lut[200]) }
+}
+
+func TestPaintNRGBA(t *testing.T) {
+	// exact non-premultiplied source-over for 8-bit colors, rounded
+	exact := func(src, dst color.NRGBA, coverage int) color.NRGBA {
+		alpha := big.NewRat(int64(src.A)*int64(coverage), 255*255)
+		backAlpha := new(big.Rat).Mul(big.NewRat(int64(dst.A), 255), new(big.Rat).Sub(big.NewRat(1, 1), alpha))
+		outAlpha := new(big.Rat).Add(alpha, backAlpha)
+		round := func(value *big.Rat) uint8 {
+			rounded, _ := new(big.Rat).Add(value, big.NewRat(1, 2)).Float64()
+			return uint8(math.Floor(rounded))
+		}
+		result := color.NRGBA{ A: round(new(big.Rat).Mul(outAlpha, big.NewRat(255, 1))) }
+		if result.A == 0 { return result }
+		channel := func(s, d uint8) uint8 {
+			value := new(big.Rat).Mul(big.NewRat(int64(s), 1), alpha)
+			value.Add(value, new(big.Rat).Mul(big.NewRat(int64(d), 1), backAlpha))
+			return round(value.Quo(value, outAlpha))
+		}
+		result.R, result.G, result.B = channel(src.R, dst.R), channel(src.G, dst.G), channel(src.B, dst.B)
+		return result
+	}
+
+	backs := []color.NRGBA{ { 0, 0, 0, 0 }, { 10, 240, 90, 20 }, { 255, 255, 255, 255 } }
+	fills := []color.NRGBA{ { 201, 99, 37, 255 }, { 13, 177, 250, 128 }, { 255, 0, 64, 7 } }
+	premultLosses := 0
+	for _, back := range backs {
+		for _, fill := range fills {
+			lut := nrgbaPaintLUT(fill, back)
+			rgbaLUT := paintLUT(fill, color.RGBA64Model.Convert(back).(color.RGBA64), false)
+			for coverage := 0; coverage <= 255; coverage++ {
+				expected := exact(fill, back, coverage)
+				got := color.NRGBA{ lut[coverage][0], lut[coverage][1], lut[coverage][2], lut[coverage][3] }
+				if got != expected {
+					t.Fatalf("%v over %v, coverage %d: expected %v, got %v", fill, back, coverage, expected, got)
+				}
+				if coverage < 1 || coverage > 10 { continue }
+				premult := color.RGBA{ uint8(rgbaLUT[coverage][0] >> 8), uint8(rgbaLUT[coverage][1] >> 8), uint8(rgbaLUT[coverage][2] >> 8), uint8(rgbaLUT[coverage][3] >> 8) }
+				if color.NRGBAModel.Convert(premult).(color.NRGBA) != expected { premultLosses += 1 }
+			}
+		}
+	}
+	if premultLosses == 0 { t.Fatal("expected the premultiplied round trip to lose precision on low alpha pixels") }
+
+	// end to end
+	shape := New()
+	testCircle(&shape, 20.3, 20.7, 15.2)
+	mask, _ := shape.Rasterize()
+	nrgba := shape.PaintNRGBA(fills[0], backs[0])
+	if nrgba.Rect != mask.Rect { t.Fatalf("expected rect %v, got %v", mask.Rect, nrgba.Rect) }
+	lut := nrgbaPaintLUT(fills[0], backs[0])
+	for i, value := range mask.Pix {
+		if !bytes.Equal(nrgba.Pix[i*4 : i*4 + 4], lut[value][ : ]) { t.Fatalf("pixel %d differs from the LUT", i) }
+	}
+}
+
+func TestOpacity(t *testing.T) {
+	shape := New()
+	testCircle(&shape, 20.3, 20.7, 15.2)
+	if shape.GetOpacity() != 1 || (&Shape{}).GetOpacity() != 1 { t.Fatal("expected default opacity 1") }
+	fill, back := color.RGBA{ 200, 40, 90, 255 }, color.RGBA{ 20, 30, 250, 255 }
+	gradient := LinearGradient{ X0: 0, Y0: 0, X1: 40, Y1: 0, Stops: []GradientStop{ { 0, fill }, { 1, color.White } } }
+	tex := image.NewUniform(color.RGBA{ 10, 200, 10, 255 })
+	paints := map[string]func() *image.RGBA {
+		"Paint": func() *image.RGBA { return shape.Paint(fill, back) },
+		"PaintGradient": func() *image.RGBA { return shape.PaintGradient(gradient, back) },
+		"PaintTexture": func() *image.RGBA { return shape.PaintTexture(tex, TileRepeat, AffineIdentity(), back) },
+		"PaintFillStroke": func() *image.RGBA {
+			img, err := shape.PaintFillStroke(fill, color.White, 3, back)
+			if err != nil { t.Fatal(err) }
+			return img
+		},
+	}
+	opaque := make(map[string]*image.RGBA)
+	for name, paint := range paints { opaque[name] = paint() }
+
+	shape.SetOpacity(1.5) // clamped
+	if shape.GetOpacity() != 1 { t.Fatalf("expected clamped opacity 1, got %f", shape.GetOpacity()) }
+	for name, paint := range paints {
+		if !bytes.Equal(paint().Pix, opaque[name].Pix) { t.Fatalf("%s: opacity 1 differs from the default", name) }
+	}
+
+	shape.SetOpacity(0)
+	for name, paint := range paints {
+		img := paint()
+		for i := 0; i < len(img.Pix); i += 4 {
+			if !bytes.Equal(img.Pix[i : i + 4], []uint8{ back.R, back.G, back.B, back.A }) {
+				t.Fatalf("%s: expected pure background at opacity 0", name)
+			}
+		}
+	}
+
+	// half opacity is the same as compositing at half coverage
+	shape.SetOpacity(0.5)
+	mask, _ := shape.Rasterize()
+	half := image.NewAlpha(mask.Rect)
+	for i, value := range mask.Pix { half.Pix[i] = uint8(math.Round(float64(value)*0.5)) }
+	if !bytes.Equal(shape.Paint(fill, back).Pix, paintMask(half, fill, back, false).Pix) {
+		t.Fatal("expected opacity 0.5 to match painting at half coverage")
+	}
+	onto := image.NewRGBA(mask.Rect)
+	if err := shape.PaintOnto(onto, image.Point{}, fill); err != nil { t.Fatal(err) }
+	if !bytes.Equal(onto.Pix, paintMask(half, fill, color.Transparent, false).Pix) {
+		t.Fatal("expected PaintOnto to honor the opacity")
+	}
+	if raw, _ := shape.Rasterize(); !bytes.Equal(raw.Pix, mask.Pix) || raw.Pix[len(raw.Pix)/2] != 255 {
+		t.Fatal("expected rasterization to ignore the opacity")
+	}
+}
+
+func TestClone(t *testing.T) {
+	shape := New()
+	shape.SetScale(2)
+	shape.InvertY(true)
+	testCircle(&shape, 20.3, 20.7, 15.2)
+	original := append([]sfnt.Segment(nil), shape.Segments()...)
+	mask, _ := shape.Rasterize()
+
+	clone := shape.Clone()
+	if clone.GetScale() != shape.GetScale() || !clone.HasInvertY() { t.Fatal("expected the clone to keep the settings") }
+	if !reflect.DeepEqual(clone.Segments(), shape.Segments()) { t.Fatal("expected equal segments") }
+
+	clone.SetSegment(0, sfnt.Segment{ Op: sfnt.SegmentOpMoveTo })
+	clone.LineTo(100, 100)
+	if !reflect.DeepEqual(shape.Segments(), sfnt.Segments(original)) { t.Fatal("modifying the clone changed the original") }
+	clone.Reset()
+	testTriangle(&clone)
+	if !reflect.DeepEqual(shape.Segments(), sfnt.Segments(original)) { t.Fatal("resetting the clone changed the original") }
+	after, _ := shape.Rasterize()
+	if !bytes.Equal(after.Pix, mask.Pix) || after.Rect != mask.Rect { t.Fatal("original rasterization changed") }
+
+	// custom rasterizers are not shared
+	shape.SetRasterizer(vector.NewRasterizer(0, 0))
+	if shape.Clone().custom != nil { t.Fatal("expected the clone to use pooled rasterizers") }
+
+	// concurrent rasterization of both (meaningful with -race)
+	var group sync.WaitGroup
+	for _, target := range []*Shape{ &shape, &clone } {
+		group.Add(1)
+		go func(target *Shape) {
+			defer group.Done()
+			for i := 0; i < 20; i++ {
+				if _, err := target.Rasterize(); err != nil { t.Error(err) }
+				target.LineTo(i, i)
+			}
+		}(target)
+	}
+	group.Wait()
+}
+
+func TestPos(t *testing.T) {
+	shape := New()
+	if x, y, ok := shape.Pos(); ok || x != 0 || y != 0 { t.Fatal("expected no position for empty shapes") }
+	expect := func(x, y Fract) {
+		t.Helper()
+		gotX, gotY, ok := shape.Pos()
+		if !ok || gotX != x || gotY != y { t.Fatalf("expected (%d, %d), got (%d, %d, %t)", x, y, gotX, gotY, ok) }
+	}
+
+	shape.SetScale(2.5)
+	shape.InvertY(true)
+	shape.MoveTo(3, -4)
+	expect(3*64, -4*64)
+	shape.LineToFract(100, 37)
+	expect(100, 37)
+	shape.QuadTo(1, 2, 5, 6)
+	expect(5*64, 6*64)
+	shape.CubeToFract(1, 2, 3, 4, -5, -6)
+	expect(-5, -6)
+	shape.SetScale(1) // no retroactive changes
+	shape.InvertY(false)
+	expect(-5, -6)
+	shape.Builder().Close() // back to the MoveTo, as given by the user
+	expect(3*64, -4*64)
+	if end := segmentEnd(shape.Segments()[len(shape.Segments()) - 1]); end != shape.Segments()[0].Args[0] {
+		t.Fatal("expected Close to end at the subpath start")
+	}
+	shape.LineTo(9, 9)
+	expect(9*64, 9*64)
+
+	clone := shape.Clone()
+	shape.Reset()
+	if _, _, ok := shape.Pos(); ok { t.Fatal("expected no position after Reset") }
+	clone.LineTo(-1, 0)
+	if _, _, ok := shape.Pos(); ok { t.Fatal("expected no position sharing with clones") }
+}
+
+func TestPop(t *testing.T) {
+	shape, reference := New(), New()
+	if shape.Pop() || shape.PopSubpath() != 0 { t.Fatal("expected nothing to pop on empty shapes") }
+	shape.MoveTo(0, 0)
+	shape.LineTo(10, 0)
+	shape.LineTo(99, 99) // undone
+	if !shape.Pop() { t.Fatal("expected Pop to succeed") }
+	shape.LineTo(10, 10)
+	shape.MoveTo(50, 50) // whole subpath undone
+	shape.QuadTo(60, 40, 70, 50)
+	shape.CubeTo(70, 60, 60, 70, 50, 50)
+	if removed := shape.PopSubpath(); removed != 3 { t.Fatalf("expected 3 segments removed, got %d", removed) }
+	shape.LineToFract(0, 5)
+	shape.CubeTo(1, 2, 3, 4, 5, 6) // undone
+	shape.Pop()
+
+	reference.MoveTo(0, 0)
+	reference.LineTo(10, 0)
+	reference.LineTo(10, 10)
+	reference.LineToFract(0, 5)
+	if !reflect.DeepEqual(shape.Segments(), reference.Segments()) { t.Fatalf("unexpected segments:\n%v\n%v", &shape, &reference) }
+	x, y, _ := shape.Pos()
+	refX, refY, _ := reference.Pos()
+	if x != refX || y != refY { t.Fatalf("expected position (%d, %d), got (%d, %d)", refX, refY, x, y) }
+
+	// no MoveTo: everything goes away
+	shape.Reset()
+	shape.LineTo(5, 5)
+	shape.LineTo(0, 5)
+	if removed := shape.PopSubpath(); removed != 2 || len(shape.Segments()) != 0 { t.Fatalf("expected everything removed, got %d", removed) }
+	if _, _, ok := shape.Pos(); ok { t.Fatal("expected no position after popping everything") }
+
+	// pops are tracked by incremental rasterization
+	shape = testSquare(20)
+	shape.BeginTracking()
+	prev, _ := shape.RasterizeIncremental(nil)
+	shape.MoveTo(2, 2) // hole
+	shape.LineTo(2, 8)
+	shape.LineTo(8, 8)
+	shape.LineTo(2, 2)
+	prev, _ = shape.RasterizeIncremental(prev)
+	shape.PopSubpath()
+	prev, _ = shape.RasterizeIncremental(prev)
+	full, _ := shape.Rasterize()
+	if maskcmp.Compare(prev, full, 1, 0) != nil { t.Fatal("expected popped segments to be re-rasterized") }
+}
+
+func TestShapeCounts(t *testing.T) {
+	shape := New()
+	if shape.Len() != 0 || shape.NumSubpaths() != 0 || !shape.IsEmpty() { t.Fatal("unexpected counts for new shapes") }
+	shape.MoveTo(0, 0)
+	shape.MoveTo(5, 5)
+	if shape.Len() != 2 || shape.NumSubpaths() != 0 || !shape.IsEmpty() { t.Fatal("expected MoveTo-only shapes to be empty") }
+	if mask, err := shape.Rasterize(); mask != nil || err != nil { t.Fatal("expected nothing to draw") }
+
+	shape.Reset()
+	shape.LineTo(10, 0) // implicit subpath from (0, 0)
+	shape.LineTo(10, 10)
+	if shape.Len() != 2 || shape.NumSubpaths() != 1 || shape.IsEmpty() { t.Fatal("unexpected counts for leading LineTo") }
+	shape.MoveTo(20, 20)
+	shape.MoveTo(30, 30)
+	shape.QuadTo(40, 30, 40, 40)
+	shape.MoveTo(50, 50) // trailing
+	if shape.Len() != 6 || shape.NumSubpaths() != 2 { t.Fatalf("expected 6 segments and 2 subpaths, got %d, %d", shape.Len(), shape.NumSubpaths()) }
+	star := testStar(20)
+	if star.NumSubpaths() != 1 || star.IsEmpty() { t.Fatal("unexpected star counts") }
+}
+
+func TestStrict(t *testing.T) {
+	shape := New()
+	if shape.IsStrict() { t.Fatal("expected strict mode to be disabled by default") }
+	shape.LineTo(10, 0) // non-strict: accepted
+	shape.LineTo(0, 10)
+	if shape.Err() != nil { t.Fatalf("unexpected error %v", shape.Err()) }
+
+	drawCommands := map[string]func(*Shape) {
+		"LineTo": func(shape *Shape) { shape.LineTo(10, 0) },
+		"LineToFract": func(shape *Shape) { shape.LineToFract(640, 0) },
+		"QuadTo": func(shape *Shape) { shape.QuadTo(10, 0, 10, 10) },
+		"CubeTo": func(shape *Shape) { shape.CubeTo(10, 0, 10, 5, 10, 10) },
+	}
+	for name, draw := range drawCommands {
+		shape := New()
+		shape.SetStrict(true)
+		draw(&shape)
+		shape.LineTo(0, 10)
+		shape.LineTo(0, 0)
+		if !errors.Is(shape.Err(), ErrMissingMoveTo) { t.Fatalf("%s: expected ErrMissingMoveTo, got %v", name, shape.Err()) }
+	}
+
+	shape = New()
+	shape.SetStrict(true)
+	shape.LineTo(20, 0)
+	shape.LineTo(20, 20)
+	shape.LineTo(0, 0)
+	bg := image.NewRGBA(image.Rect(0, -40, 40, 0))
+	entryPoints := map[string]func() error {
+		"Rasterize": func() error { _, err := shape.Rasterize(); return err },
+		"RasterizeFract": func() error { _, err := shape.RasterizeFract(3, 3); return err },
+		"RasterizeOpts": func() error { _, err := shape.RasterizeOpts(RasterizeOptions{ FillRule: FillEvenOdd }); return err },
+		"RasterizeInto": func() error { _, err := shape.RasterizeInto(&image.Alpha{}, 0, 0); return err },
+		"RasterizeStroke": func() error { _, err := shape.RasterizeStroke(2, CapButt, JoinMiter); return err },
+		"RasterizeTransformed": func() error { _, err := shape.RasterizeTransformed(AffineIdentity()); return err },
+		"Coverage": func() error { _, _, err := shape.Coverage(); return err },
+		"PaintChecked": func() error { _, err := shape.PaintChecked(color.White, color.Black); return err },
+		"Paint64Checked": func() error { _, err := shape.Paint64Checked(color.White, color.Black); return err },
+		"PaintNRGBAChecked": func() error { _, err := shape.PaintNRGBAChecked(color.White, color.Black); return err },
+		"PaintAlphaChecked": func() error { _, err := shape.PaintAlphaChecked(color.White); return err },
+		"PaintGradientChecked": func() error {
+			_, err := shape.PaintGradientChecked(LinearGradient{ Stops: []GradientStop{ { 0, color.White } } }, color.Black)
+			return err
+		},
+		"PaintTextureFiltered": func() error {
+			_, err := shape.PaintTextureFiltered(bg, TileRepeat, FilterNearest, AffineIdentity(), color.Black)
+			return err
+		},
+		"PaintFillStroke": func() error { _, err := shape.PaintFillStroke(color.White, color.Black, 2, color.Black); return err },
+		"PaintGlow": func() error { _, err := shape.PaintGlow(color.White, color.Black, 2, true, color.Black); return err },
+		"PaintWithShadowChecked": func() error { _, err := shape.PaintWithShadowChecked(color.White, color.Black, 1, 1, 1, color.Black); return err },
+		"PaintMulti": func() error { _, err := shape.PaintMulti([]color.Color{ color.White }, color.Black); return err },
+		"PaintOverChecked": func() error { _, err := shape.PaintOverChecked(bg, image.Point{}, color.White); return err },
+		"PaintOnto": func() error { return shape.PaintOnto(bg, image.Point{}, color.White) },
+		"PaintBlend": func() error { return shape.PaintBlend(bg, image.Point{}, color.White, BlendMultiply) },
+		"EncodePNG": func() error { return shape.EncodePNG(&bytes.Buffer{}, color.White, color.Black) },
+	}
+	for name, fn := range entryPoints {
+		if err := fn(); !errors.Is(err, ErrMissingMoveTo) { t.Fatalf("%s: expected ErrMissingMoveTo, got %v", name, err) }
+	}
+	if shape.Paint(color.White, color.Black) != nil { t.Fatal("expected Paint to return nil") }
+
+	// Reset clears the error, and proper shapes don't record any
+	shape.Reset()
+	if shape.Err() != nil { t.Fatal("expected Reset to clear the error") }
+	shape.MoveTo(0, 0)
+	shape.LineTo(20, 0)
+	shape.LineTo(20, 20)
+	shape.LineTo(0, 0)
+	if shape.Err() != nil { t.Fatalf("unexpected error %v", shape.Err()) }
+	for name, fn := range entryPoints {
+		if err := fn(); err != nil { t.Fatalf("%s: unexpected error %v", name, err) }
+	}
+}
+
+func TestZeroValueShape(t *testing.T) {
+	var zero Shape
+	if zero.GetScale() != 64 || zero.HasInvertY() { t.Fatal("unexpected zero value defaults") }
+	fromNew := New()
+	for _, shape := range []*Shape{ &zero, &fromNew } {
+		shape.MoveTo(0, 0)
+		shape.LineTo(20, 0)
+		shape.LineTo(20, 20)
+		shape.LineTo(0, 0)
+	}
+	if !zero.Equal(&fromNew) { t.Fatalf("expected the same segments:\n%v\n%v", &zero, &fromNew) }
+	mask, err := zero.RasterizeFract(16, 16)
+	if err != nil || mask == nil { t.Fatalf("zero value rasterization failed: %v", err) }
+	expected, _ := fromNew.RasterizeFract(16, 16)
+	if !bytes.Equal(mask.Pix, expected.Pix) { t.Fatal("expected the same rasterization") }
+	if zero.Paint(color.White, color.Black) == nil { t.Fatal("zero value paint failed") }
+	zero.SetScaleFract(math.MinInt32) // wraps around consistently
+	if zero.GetScale() != math.MinInt32 { t.Fatal("unexpected scale round trip") }
+
+	// building and exporting doesn't allocate rasterizers: only the
+	// initial segments and positions slices are allocated
+	allocs := testing.AllocsPerRun(100, func() {
+		shape := New()
+		shape.MoveTo(0, 0)
+		shape.LineTo(20, 0)
+		shape.LineTo(20, 20)
+		_ = shape.Segments()
+		if shape.custom != nil { t.Fatal("unexpected rasterizer") }
+	})
+	if allocs > 2 { t.Fatalf("expected at most 2 allocations, got %f", allocs) }
+}
+
+func TestShapeCapacity(t *testing.T) {
+	const n = 1000
+	appendLines := func(shape *Shape) {
+		shape.MoveTo(0, 0)
+		for i := 1; i < n; i++ { shape.LineTo(i, i & 7) }
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		shape := NewWithCapacity(n)
+		appendLines(&shape)
+	})
+	if allocs > 2 { t.Fatalf("expected at most 2 allocations, got %f", allocs) }
+	allocs = testing.AllocsPerRun(10, func() {
+		var shape Shape
+		shape.LineTo(1, 1)
+		shape.Grow(n)
+		appendLines(&shape)
+	})
+	if allocs > 4 { t.Fatalf("expected at most 4 allocations after Grow, got %f", allocs) }
+
+	shape := NewWithCapacity(n)
+	appendLines(&shape)
+	reference := New()
+	appendLines(&reference)
+	if !shape.Equal(&reference) { t.Fatal("expected the same segments") }
+	shape.Reset()
+	if cap(shape.Segments()) < n { t.Fatal("expected Reset to keep the capacity") }
+	shape.Grow(-5)
+	shape.Grow(10)
+	if cap(shape.Segments()) < n { t.Fatal("expected Grow to keep the capacity") }
+}
+
+func benchmarkAppendLines(b *testing.B, presized bool) {
+	const n = 100000
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var shape Shape
+		if presized { shape = NewWithCapacity(n) }
+		shape.MoveTo(0, 0)
+		for j := 1; j < n; j++ { shape.LineTo(j, j & 7) }
+	}
+}
+
+func BenchmarkAppendLines(b *testing.B) { benchmarkAppendLines(b, false) }
+func BenchmarkAppendLinesPresized(b *testing.B) { benchmarkAppendLines(b, true) }
+
+func TestSegmentsAliasing(t *testing.T) {
+	shape := New()
+	testTriangle(&shape)
+	aliased := shape.Segments()
+	copied := shape.SegmentsCopy()
+	expected := append([]sfnt.Segment(nil), aliased...)
+	if !reflect.DeepEqual(copied, aliased) { t.Fatal("expected the copy to match the segments") }
+
+	// the hazard: after Reset, new commands overwrite the aliased segments
+	shape.Reset()
+	shape.MoveTo(100, 100)
+	shape.LineTo(200, 100)
+	if reflect.DeepEqual(aliased, sfnt.Segments(expected)) { t.Fatal("expected the aliased segments to be overwritten") }
+	if !reflect.DeepEqual(copied, sfnt.Segments(expected)) { t.Fatal("the copy was modified by the shape") }
+
+	// taking the segments leaves the shape empty without sharing storage
+	taken := shape.TakeSegments()
+	if len(taken) != 2 || shape.Len() != 0 || !shape.IsEmpty() { t.Fatal("unexpected segments after TakeSegments") }
+	if _, _, ok := shape.Pos(); ok { t.Fatal("expected no position after TakeSegments") }
+	takenCopy := append([]sfnt.Segment(nil), taken...)
+	testTriangle(&shape)
+	if !reflect.DeepEqual(taken, sfnt.Segments(takenCopy)) { t.Fatal("the taken segments were modified by the shape") }
+	if !reflect.DeepEqual(shape.Segments(), sfnt.Segments(expected)) { t.Fatal("unexpected segments after reuse") }
+}
+
+func TestResetAndShrink(t *testing.T) {
+	fill := func(shape *Shape) {
+		shape.SetAnchor("tip", 3, 3)
+		shape.MoveTo(0, 0)
+		for i := 0; i < 1000; i++ { shape.LineTo(i, i%7) }
+		shape.LineTo(0, 0)
+	}
+	expectCleared := func(shape *Shape, name string) {
+		t.Helper()
+		if shape.Len() != 0 || len(shape.positions) != 0 { t.Fatalf("%s: expected no segments", name) }
+		if _, _, ok := shape.Pos(); ok { t.Fatalf("%s: expected no position", name) }
+		if shape.Err() != nil { t.Fatalf("%s: expected no error", name) }
+		if len(shape.Anchors()) != 0 { t.Fatalf("%s: expected no anchors", name) }
+	}
+
+	shape := New()
+	fill(&shape)
+	shape.saturate(math.MaxInt64) // record an error
+	capacity := cap(shape.segments)
+	shape.Clear()
+	expectCleared(&shape, "Clear")
+	if cap(shape.segments) != capacity { t.Fatal("expected Clear to keep the capacity") }
+
+	fill(&shape)
+	shape.ResetAndShrink(capacity) // within the limit, nothing to shrink
+	expectCleared(&shape, "ResetAndShrink")
+	if cap(shape.segments) != capacity { t.Fatal("expected the capacity to be kept below the limit") }
+
+	fill(&shape)
+	shape.saturate(math.MaxInt64)
+	shape.ResetAndShrink(16)
+	expectCleared(&shape, "ResetAndShrink")
+	if cap(shape.segments) != 16 || cap(shape.positions) != 16 {
+		t.Fatalf("expected capacity 16, got %d and %d", cap(shape.segments), cap(shape.positions))
+	}
+	shape.ResetAndShrink(-1)
+	if cap(shape.segments) != 0 || cap(shape.positions) != 0 { t.Fatal("expected no capacity") }
+
+	// still usable after shrinking
+	testTriangle(&shape)
+	if shape.Len() != 4 { t.Fatal("expected the shape to be usable after shrinking") }
+}
+
+func TestShapeHook(t *testing.T) {
+	// the hook sees the final coordinates
+	shape := New()
+	shape.SetScale(2)
+	var seen []sfnt.Segment
+	shape.SetHook(func(op sfnt.SegmentOp, points [3]fixed.Point26_6) bool {
+		seen = append(seen, sfnt.Segment{ Op: op, Args: points })
+		return true
+	})
+	testTriangle(&shape)
+	shape.Builder().QuadTo(1, 2, 3, 4).Close()
+	if !reflect.DeepEqual(sfnt.Segments(seen), shape.Segments()) {
+		t.Fatalf("hook points differ from the stored segments:\n%v\n%v", seen, shape.Segments())
+	}
+
+	// segment budget
+	budget := New()
+	count := 0
+	budget.SetHook(func(sfnt.SegmentOp, [3]fixed.Point26_6) bool {
+		count += 1
+		return count <= 5
+	})
+	testTriangle(&budget)
+	testTriangle(&budget)
+	if budget.Len() != 5 || count != 8 { t.Fatalf("expected 5 segments out of 8, got %d out of %d", budget.Len(), count) }
+	if x, y, _ := budget.Pos(); x != 0 || y != 0 { t.Fatalf("expected the position of the last stored segment, got (%d, %d)", x, y) }
+
+	// filtering zero-length lines
+	filtered := New()
+	filtered.SetHook(func(op sfnt.SegmentOp, points [3]fixed.Point26_6) bool {
+		return op != sfnt.SegmentOpLineTo || filtered.Len() == 0 || points[0] != filtered.pen()
+	})
+	filtered.MoveTo(0, 0)
+	filtered.LineTo(10, 0)
+	filtered.LineTo(10, 0)
+	filtered.LineTo(10, 10)
+	filtered.LineTo(10, 10)
+	filtered.LineTo(0, 0)
+	if filtered.Len() != 4 { t.Fatalf("expected 4 segments, got %v", &filtered) }
+
+	filtered.SetHook(nil)
+	filtered.LineTo(0, 0)
+	if filtered.Len() != 5 { t.Fatal("expected the hook to be removed") }
+}
+
+func TestRelativeMode(t *testing.T) {
+	for _, invertY := range []bool{ false, true } {
+		for _, scale := range []float64{ 1, 1.37, -2 } {
+			absolute, relative := New(), New()
+			for _, shape := range []*Shape{ &absolute, &relative } {
+				shape.InvertY(invertY)
+				shape.SetScale(scale)
+			}
+			absolute.LineTo(3, 4) // before any MoveTo, relative to (0, 0)
+			absolute.MoveTo(10, 10)
+			absolute.LineTo(30, 10)
+			absolute.QuadToFract(40*64, 15*64 + 7, 30*64, 20*64 + 3)
+			absolute.CubeTo(25, 30, 15, 30, 10, 20)
+			absolute.LineTo(10, 10)
+			absolute.LineToAngle(5, 0)
+
+			relative.SetRelative(true)
+			if !relative.GetRelative() { t.Fatal("expected relative mode") }
+			relative.LineTo(3, 4)
+			relative.MoveTo(7, 6)
+			relative.LineTo(20, 0)
+			relative.QuadToFract(10*64, 5*64 + 7, 0, 10*64 + 3)
+			relative.CubeToFract(-5*64, 10*64 - 3, -15*64, 10*64 - 3, -20*64, 0 - 3)
+			relative.LineTo(0, -10)
+			relative.LineToAngle(5, 0)
+			if !reflect.DeepEqual(absolute.Segments(), relative.Segments()) {
+				t.Fatalf("invertY %t, scale %v: segments differ:\n%v\n%v", invertY, scale, &absolute, &relative)
+			}
+			relative.Builder().MoveTo(1, 1).LineTo(1, 0).LineTo(0, 1).Close()
+			absolute.Builder().MoveTo(16, 11).LineTo(17, 11).LineTo(17, 12).Close()
+			if !reflect.DeepEqual(absolute.Segments(), relative.Segments()) {
+				t.Fatalf("invertY %t, scale %v: builder segments differ", invertY, scale)
+			}
+		}
+	}
+}
+
+func TestSharedRasterizerCopies(t *testing.T) {
+	for _, custom := range []Rasterizer{ vector.NewRasterizer(0, 0), aliasedRasterizer{ vector.NewRasterizer(0, 0) } } {
+		original := bigTestShape(120)
+		original.SetRasterizer(custom)
+		expected, err := original.Rasterize()
+		if err != nil { t.Fatal(err) }
+		copied := original // shares the custom rasterizer
+
+		var group sync.WaitGroup
+		failures := make(chan string, 2)
+		for _, shape := range []*Shape{ &original, &copied } {
+			group.Add(1)
+			go func(shape *Shape) {
+				defer group.Done()
+				for i := 0; i < 30; i++ {
+					mask, err := shape.RasterizeFract(0, 0)
+					if err != nil || mask.Rect != expected.Rect || !bytes.Equal(mask.Pix, expected.Pix) {
+						failures <- "corrupted mask from concurrent rasterization"
+						return
+					}
+				}
+			}(shape)
+		}
+		group.Wait()
+		close(failures)
+		for failure := range failures { t.Fatalf("%T: %s", custom, failure) }
+		if original.GetRasterizer() != custom || copied.GetRasterizer() != custom {
+			t.Fatal("expected the custom rasterizer to be kept")
+		}
+	}
+}
+
+// Custom rasterizer with a dynamic type that can't be compared.
+type uncomparableRasterizer struct {
+	*vector.Rasterizer
+	tags []string
+}
+
+func TestUncomparableRasterizer(t *testing.T) {
+	shape := New()
+	testTriangle(&shape)
+	expected, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+	shape.SetRasterizer(uncomparableRasterizer{ vector.NewRasterizer(0, 0), nil })
+	for i := 0; i < 2; i++ { // the second call blocks if the rasterizer isn't released
+		mask, err := shape.Rasterize()
+		if err != nil { t.Fatal(err) }
+		if !bytes.Equal(mask.Pix, expected.Pix) { t.Fatal("unexpected mask") }
+	}
+}
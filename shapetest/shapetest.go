@@ -0,0 +1,178 @@
+// Package shapetest provides helpers to test rasterized shapes,
+// mostly comparing masks with some tolerance and managing golden
+// images.
+package shapetest
+
+import "os"
+import "fmt"
+import "image"
+import "image/color"
+import "image/png"
+import "path/filepath"
+import "testing"
+
+import "github.com/tinne26/sfntshape"
+import "github.com/tinne26/sfntshape/internal/maskcmp"
+
+// Compares two masks and returns an error describing the differences if
+// they are not similar enough. Both masks must have the same bounds. A
+// pixel is considered different when the difference between the alpha
+// values exceeds maxPerPixelDelta, and the comparison fails when the
+// fraction of differing pixels exceeds maxDifferingFraction (e.g., 0.01
+// allows up to 1% of the pixels to differ).
+//
+// Two nil masks are considered equal. Nil and empty masks are also
+// considered equal.
+func CompareMasks(a, b *image.Alpha, maxPerPixelDelta uint8, maxDifferingFraction float64) error {
+	return maskcmp.Compare(a, b, maxPerPixelDelta, maxDifferingFraction)
+}
+
+// Rasterizes the given shape and compares the result with the golden
+// image stored at goldenPath, failing the test if they differ (a single
+// alpha level of difference is tolerated on up to 0.5% of the pixels). If
+// updateFlag is true, the golden image is (re)written instead.
+//
+// Shapes are rasterized in deterministic mode (see the Deterministic
+// field of [sfntshape.RasterizeOptions]), so golden images are stable
+// across platforms and Go versions. The shape's rasterizer is not used.
+//
+// Golden images are stored as 8-bit grayscale PNGs where the gray level
+// represents the mask's alpha. PNGs don't preserve the mask's origin, so
+// golden images are always compared as if they were placed at the origin
+// of the rasterized mask; only size mismatches can be detected.
+func AssertGolden(t testing.TB, shape *sfntshape.Shape, goldenPath string, updateFlag bool) {
+	t.Helper()
+	mask, err := shape.RasterizeOpts(sfntshape.RasterizeOptions{ Deterministic: true })
+	if err != nil { t.Fatalf("rasterization failed: %s", err) }
+	if maskcmp.Rect(mask).Empty() {
+		t.Fatalf("shape rasterized to an empty mask, can't compare with %s", goldenPath)
+	}
+
+	if updateFlag {
+		err := WriteGolden(goldenPath, mask)
+		if err != nil { t.Fatalf("failed to update golden image: %s", err) }
+		t.Logf("updated golden image %s", goldenPath)
+		return
+	}
+
+	golden, err := ReadGolden(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden image (maybe it has to be created with the update flag?): %s", err)
+	}
+	golden.Rect = golden.Rect.Add(mask.Rect.Min)
+	err = CompareMasks(mask, golden, 1, 0.005)
+	if err != nil { t.Fatalf("mismatch with golden image %s: %s", goldenPath, err) }
+}
+
+// Writes the given mask as an 8-bit grayscale PNG at the given path,
+// creating the parent directories if necessary.
+func WriteGolden(path string, mask *image.Alpha) error {
+	rect := maskcmp.Rect(mask)
+	gray := image.NewGray(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	for y := 0; y < rect.Dy(); y++ {
+		copy(gray.Pix[y*gray.Stride : ], mask.Pix[mask.PixOffset(rect.Min.X, rect.Min.Y + y) : ][ : rect.Dx()])
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil { return err }
+	file, err := os.Create(path)
+	if err != nil { return err }
+	err = png.Encode(file, gray)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Reads a golden image written by [WriteGolden]() as a mask with its
+// origin at (0, 0). Non-grayscale PNGs are converted using the
+// luminance of each pixel.
+func ReadGolden(path string) (*image.Alpha, error) {
+	file, err := os.Open(path)
+	if err != nil { return nil, err }
+	img, err := png.Decode(file)
+	_ = file.Close()
+	if err != nil { return nil, err }
+
+	bounds := img.Bounds()
+	mask := image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	if gray, isGray := img.(*image.Gray); isGray {
+		for y := 0; y < bounds.Dy(); y++ {
+			copy(mask.Pix[y*mask.Stride : ], gray.Pix[gray.PixOffset(bounds.Min.X, bounds.Min.Y + y) : ][ : bounds.Dx()])
+		}
+		return mask, nil
+	}
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, _ := img.At(bounds.Min.X + x, bounds.Min.Y + y).RGBA()
+			mask.Pix[y*mask.Stride + x] = uint8(((19595*r + 38470*g + 7471*b + 1<<15) >> 24))
+		}
+	}
+	return mask, nil
+}
+
+// Like [AssertGolden](), but for color images (e.g., the results of
+// [sfntshape.Shape.PaintGradient]()) stored as RGBA PNGs. Images are
+// compared in non-premultiplied RGBA, tolerating a single level of
+// difference per channel on up to 0.5% of the pixels.
+func AssertGoldenImage(t testing.TB, img image.Image, goldenPath string, updateFlag bool) {
+	t.Helper()
+	if img == nil || img.Bounds().Empty() {
+		t.Fatalf("empty image, can't compare with %s", goldenPath)
+	}
+
+	if updateFlag {
+		err := os.MkdirAll(filepath.Dir(goldenPath), 0755)
+		if err == nil { err = writePNG(goldenPath, img) }
+		if err != nil { t.Fatalf("failed to update golden image: %s", err) }
+		t.Logf("updated golden image %s", goldenPath)
+		return
+	}
+
+	file, err := os.Open(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden image (maybe it has to be created with the update flag?): %s", err)
+	}
+	golden, err := png.Decode(file)
+	_ = file.Close()
+	if err != nil { t.Fatalf("failed to decode golden image %s: %s", goldenPath, err) }
+
+	bounds, goldenBounds := img.Bounds(), golden.Bounds()
+	if bounds.Size() != goldenBounds.Size() {
+		t.Fatalf("mismatch with golden image %s: size %v vs %v", goldenPath, bounds.Size(), goldenBounds.Size())
+	}
+	differing, total := 0, bounds.Dx()*bounds.Dy()
+	var firstDiff string
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			a := color.NRGBAModel.Convert(img.At(bounds.Min.X + x, bounds.Min.Y + y)).(color.NRGBA)
+			b := color.NRGBAModel.Convert(golden.At(goldenBounds.Min.X + x, goldenBounds.Min.Y + y)).(color.NRGBA)
+			if channelDelta(a.R, b.R) > 1 || channelDelta(a.G, b.G) > 1 || channelDelta(a.B, b.B) > 1 || channelDelta(a.A, b.A) > 1 {
+				if differing == 0 { firstDiff = fmt.Sprintf("at (%d, %d): %v vs %v", x, y, a, b) }
+				differing += 1
+			}
+		}
+	}
+	if float64(differing) > 0.005*float64(total) {
+		t.Fatalf("mismatch with golden image %s: %d of %d pixels differ (first %s)", goldenPath, differing, total, firstDiff)
+	}
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil { return err }
+	err = png.Encode(file, img)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func channelDelta(a, b uint8) uint8 {
+	if a > b { return a - b }
+	return b - a
+}
+
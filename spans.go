@@ -0,0 +1,67 @@
+package sfntshape
+
+import "sync"
+import "image"
+import "errors"
+
+// Scratch masks reused by functions that walk the rasterized
+// coverage without returning a mask.
+var scratchMasks = sync.Pool {
+	New: func() any { return &image.Alpha{} },
+}
+
+// Rasterizes the shape into a pooled scratch mask and passes it to
+// the given function, which must not retain it. ErrNothingToDraw
+// is ignored, as there's nothing to walk.
+func (self *Shape) withScratchMask(fn func(mask *image.Alpha)) error {
+	if self.err != nil { return self.err }
+	mask := scratchMasks.Get().(*image.Alpha)
+	defer scratchMasks.Put(mask)
+	_, err := self.RasterizeInto(mask, 0, 0)
+	if errors.Is(err, ErrNothingToDraw) { return nil }
+	if err != nil { return err }
+	fn(mask)
+	return nil
+}
+
+// Rasterizes the shape and calls the given function for each maximal
+// horizontal run of pixels with the same nonzero coverage, row by row
+// (top to bottom, left to right), with x1 exclusive. Solid interiors
+// result in a single call per row, while antialiased edges usually
+// result in a call per pixel. Transparent pixels are skipped. Coordinates
+// are the ones the mask returned by [Shape.Rasterize]() would use.
+//
+// This is meant for custom blitters that can write spans straight into
+// their framebuffers. The coverage is rasterized into an internal buffer
+// that's reused between calls, so no mask is allocated.
+func (self *Shape) Spans(fn func(y int, x0, x1 int, coverage uint8)) error {
+	return self.withScratchMask(func(mask *image.Alpha) {
+		width := mask.Rect.Dx()
+		for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+			row := mask.Pix[mask.PixOffset(mask.Rect.Min.X, y) : ][ : width]
+			for x := 0; x < width; {
+				value := row[x]
+				end := x + 1
+				for end < width && row[end] == value { end += 1 }
+				if value != 0 { fn(y, mask.Rect.Min.X + x, mask.Rect.Min.X + end, value) }
+				x = end
+			}
+		}
+	})
+}
+
+// Rasterizes the shape and calls the given function for each pixel with
+// nonzero coverage, in row-major order. Like [Shape.Spans](), the coverage
+// is rasterized into a reused internal buffer, so this can be used to
+// compute statistics (ink area, centroids, etc.) without allocating masks.
+func (self *Shape) ForEachPixel(fn func(x, y int, coverage uint8)) error {
+	return self.withScratchMask(func(mask *image.Alpha) {
+		width := mask.Rect.Dx()
+		for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+			row := mask.Pix[mask.PixOffset(mask.Rect.Min.X, y) : ][ : width]
+			for x, value := range row {
+				if value != 0 { fn(mask.Rect.Min.X + x, y, value) }
+			}
+		}
+	})
+}
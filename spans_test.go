@@ -0,0 +1,91 @@
+package sfntshape
+
+import "bytes"
+import "image"
+import "testing"
+
+func TestSpans(t *testing.T) {
+	shapes := []Shape{ testPolygon(30, 45), testPolygon(700, 64) }
+	for _, shape := range shapes {
+		expected, err := shape.Rasterize()
+		if err != nil { t.Fatal(err) }
+		rebuilt := image.NewAlpha(expected.Rect)
+		lastY, lastX := expected.Rect.Min.Y - 1, 0
+		err = shape.Spans(func(y, x0, x1 int, coverage uint8) {
+			if y < lastY || (y == lastY && x0 < lastX) || x1 <= x0 || coverage == 0 {
+				t.Fatalf("unexpected span (%d, %d, %d, %d)", y, x0, x1, coverage)
+			}
+			if y == lastY && x0 == lastX && rebuilt.AlphaAt(x0 - 1, y).A == coverage {
+				t.Fatalf("span at (%d, %d) not merged", x0, y)
+			}
+			for x := x0; x < x1; x++ { rebuilt.Pix[rebuilt.PixOffset(x, y)] = coverage }
+			lastY, lastX = y, x1
+		})
+		if err != nil { t.Fatal(err) }
+		if !bytes.Equal(rebuilt.Pix, expected.Pix) { t.Fatalf("spans don't match Rasterize") }
+	}
+
+	// solid interiors are merged
+	rect := New()
+	rect.MoveTo(0, 0)
+	rect.LineTo(100, 0)
+	rect.LineTo(100, 50)
+	rect.LineTo(0, 50)
+	rect.LineTo(0, 0)
+	calls := 0
+	if err := rect.Spans(func(y, x0, x1 int, coverage uint8) { calls += 1 }); err != nil { t.Fatal(err) }
+	if calls != 50 { t.Fatalf("expected 50 spans, got %d", calls) }
+
+	empty := New()
+	if err := empty.Spans(func(y, x0, x1 int, coverage uint8) { t.Fatal("unexpected span") }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkSpans(b *testing.B) {
+	shape := New()
+	shape.MoveToFract(0, 0)
+	shape.LineToFract(512*64 + 20, 10)
+	shape.LineToFract(512*64 + 20, 512*64 + 20)
+	shape.LineToFract(10, 512*64 + 20)
+	shape.LineToFract(0, 0)
+	var calls int
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		calls = 0
+		_ = shape.Spans(func(y, x0, x1 int, coverage uint8) { calls += 1 })
+	}
+	b.ReportMetric(float64(calls), "calls/op")
+	b.ReportMetric(float64(513*513), "pixels/op")
+}
+
+func TestForEachPixel(t *testing.T) {
+	shape := testPolygon(40, 33)
+	mask, err := shape.RasterizeFract(0, 0)
+	if err != nil { t.Fatal(err) }
+	var expectedCount, expectedSum, expectedMoment int
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+			value := int(mask.AlphaAt(x, y).A)
+			if value == 0 { continue }
+			expectedCount += 1
+			expectedSum += value
+			expectedMoment += value*(x + 2*y)
+		}
+	}
+
+	var count, sum, moment int
+	lastX, lastY := mask.Rect.Min.X - 1, mask.Rect.Min.Y
+	err = shape.ForEachPixel(func(x, y int, coverage uint8) {
+		if y < lastY || (y == lastY && x <= lastX) { t.Fatalf("pixels not in row-major order") }
+		if coverage == 0 { t.Fatalf("transparent pixel at (%d, %d)", x, y) }
+		lastX, lastY = x, y
+		count += 1
+		sum += int(coverage)
+		moment += int(coverage)*(x + 2*y)
+	})
+	if err != nil { t.Fatal(err) }
+	if count != expectedCount || sum != expectedSum || moment != expectedMoment {
+		t.Fatalf("expected %d/%d/%d, got %d/%d/%d", expectedCount, expectedSum, expectedMoment, count, sum, moment)
+	}
+}
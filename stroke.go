@@ -0,0 +1,312 @@
+package sfntshape
+
+import "math"
+import "image"
+import "errors"
+import "image/draw"
+import "image/color"
+
+import "golang.org/x/image/font/sfnt"
+
+// Cap styles for the ends of open subpaths in [Shape.RasterizeStroke]().
+type Cap uint8
+const (
+	CapButt Cap = iota // the stroke ends exactly at the endpoint
+	CapRound // a half disc is added around the endpoint
+	CapSquare // the stroke extends half the width beyond the endpoint
+)
+
+// Join styles for the corners between segments in [Shape.RasterizeStroke]().
+type Join uint8
+const (
+	// The outer edges are extended until they meet, unless the miter
+	// length exceeds [StrokeMiterLimit] times the stroke width, in which
+	// case the corner is beveled instead.
+	JoinMiter Join = iota
+	JoinRound // a disc is added around the corner
+	JoinBevel // the outer corner is cut by a straight line
+)
+
+// Maximum ratio between the miter length and the stroke width before
+// [JoinMiter] corners are beveled. Same as the SVG default.
+const StrokeMiterLimit = 4
+
+// Rasterizes the outline of the shape stroked with the given width,
+// without filling it. Subpaths whose last point is the same as the first
+// one are considered closed and get joins at every corner, including
+// the starting one. Other subpaths are open and get caps at both ends.
+// Joins are only applied between segments; the polylines approximating
+// curves are joined smoothly.
+//
+// The returned mask's Rect includes the stroke margins (at least half
+// the width beyond the outline bounds, more for miters and square caps),
+// in the same coordinates [Shape.Rasterize]() would use.
+func (self *Shape) RasterizeStroke(width float64, cap Cap, join Join) (*image.Alpha, error) {
+	if !(width > 0) || math.IsInf(width, 0) {
+		return nil, errors.New("stroke width must be positive and finite")
+	}
+	if cap > CapSquare { return nil, errors.New("invalid stroke cap") }
+	if join > JoinBevel { return nil, errors.New("invalid stroke join") }
+	if self.err != nil { return nil, self.err }
+	if self.IsEmpty() { return nil, nothingToDraw() }
+
+	polygons := strokePolygons(self.segments, width/2, cap, join)
+	if len(polygons) == 0 { return nil, nothingToDraw() }
+	return rasterizePolygons(polygons, self.deterministic)
+}
+
+// Paints the shape filled with the fill color and stroked with the
+// stroke color over the background, compositing the background, the
+// fill and the stroke in that order. The stroke is centered on the
+// outline, as with [Shape.RasterizeStroke]() with [CapButt] and
+// [JoinMiter] (the SVG defaults), so it covers half of its width of the
+// fill's edges. The image covers the union of the fill and stroke masks.
+// Colors are blended like in [Shape.Paint]().
+func (self *Shape) PaintFillStroke(fill, stroke color.Color, strokeWidth float64, backColor color.Color) (*image.RGBA, error) {
+	strokeMask, err := self.paintCoverage(self.RasterizeStroke(strokeWidth, CapButt, JoinMiter))
+	if err != nil { return nil, err }
+	fillMask, err := self.rasterizeForPaint()
+	if err != nil && !errors.Is(err, ErrNothingToDraw) { return nil, err }
+
+	rect := strokeMask.Rect
+	if fillMask != nil { rect = rect.Union(fillMask.Rect) }
+	rgba := image.NewRGBA(rect)
+	draw.Draw(rgba, rect, image.NewUniform(backColor), image.Point{}, draw.Src)
+	if fillMask != nil { drawMask(rgba, fillMask, image.Point{}, fill, draw.Over) }
+	drawMask(rgba, strokeMask, image.Point{}, stroke, draw.Over)
+	return rgba, nil
+}
+
+// Rasterizes the union of the given convex polygons, which must all
+// have the same orientation, with the non-zero rule. If deterministic
+// is set, the accumulator works like with the Deterministic option of
+// [RasterizeOptions].
+func rasterizePolygons(polygons [][]PointF, deterministic bool) (*image.Alpha, error) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, polygon := range polygons {
+		for _, point := range polygon {
+			minX, maxX = math.Min(minX, point.X), math.Max(maxX, point.X)
+			minY, maxY = math.Min(minY, point.Y), math.Max(maxY, point.Y)
+		}
+	}
+	rect := image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+	)
+	if err := checkMaskSize(rect.Dx(), rect.Dy()); err != nil { return nil, err }
+
+	acc := getAccumulator(rect.Dx(), rect.Dy())
+	defer releaseAccumulator(acc)
+	acc.portable = deterministic
+	originX, originY := float64(rect.Min.X), float64(rect.Min.Y)
+	for _, polygon := range polygons {
+		acc.MoveTo(float32(polygon[0].X - originX), float32(polygon[0].Y - originY))
+		for _, point := range polygon[1 : ] {
+			acc.LineTo(float32(point.X - originX), float32(point.Y - originY))
+		}
+		acc.LineTo(float32(polygon[0].X - originX), float32(polygon[0].Y - originY))
+	}
+	mask := image.NewAlpha(rect)
+	acc.AccumulateInto(mask.Pix, FillNonZero)
+	return mask, nil
+}
+
+// A flattened subpath, with the points that are segment endpoints
+// (where joins apply) marked as corners.
+type strokeContour struct {
+	points []PointF
+	corners []bool
+	closed bool
+}
+
+// Flattens the segments into stroke contours, removing repeated points.
+func strokeContours(segments []sfnt.Segment) []strokeContour {
+	var contours []strokeContour
+	var current strokeContour
+	var pen PointF
+	flush := func() {
+		points := current.points
+		if len(points) > 1 && points[len(points) - 1] == points[0] {
+			current.points = points[ : len(points) - 1]
+			current.corners = current.corners[ : len(points) - 1]
+			current.closed = true
+		}
+		if len(current.points) > 0 { contours = append(contours, current) }
+		current = strokeContour{}
+	}
+	var flattened []PointF
+	for _, segment := range segments {
+		if segment.Op == sfnt.SegmentOpMoveTo {
+			flush()
+			pen = pointFromFixed(segment.Args[0])
+			current.points, current.corners = append(current.points, pen), append(current.corners, true)
+			continue
+		}
+
+		if len(current.points) == 0 {
+			current.points, current.corners = append(current.points, pen), append(current.corners, true)
+		}
+		flattened, pen = flattenSegment(flattened[ : 0], pen, segment, flattenTolerance)
+		for i, point := range flattened {
+			isCorner := (i == len(flattened) - 1)
+			last := len(current.points) - 1
+			if point == current.points[last] {
+				current.corners[last] = current.corners[last] || isCorner
+				continue
+			}
+			current.points, current.corners = append(current.points, point), append(current.corners, isCorner)
+		}
+	}
+	flush()
+	return contours
+}
+
+// Returns the convex polygons whose union is the stroke of the segments
+// with the given half width. All polygons have a positive signed area.
+func strokePolygons(segments []sfnt.Segment, halfWidth float64, cap Cap, join Join) [][]PointF {
+	var polygons [][]PointF
+	add := func(polygon ...PointF) { polygons = appendPolygon(polygons, polygon) }
+	discSides := strokeDiscSides(halfWidth)
+
+	for _, contour := range strokeContours(segments) {
+		points, n := contour.points, len(contour.points)
+		if n == 1 { // dots
+			p := points[0]
+			switch cap {
+			case CapRound:
+				polygons = appendPolygon(polygons, discPolygon(p, halfWidth, discSides))
+			case CapSquare:
+				add(
+					PointF{ p.X - halfWidth, p.Y - halfWidth }, PointF{ p.X + halfWidth, p.Y - halfWidth },
+					PointF{ p.X + halfWidth, p.Y + halfWidth }, PointF{ p.X - halfWidth, p.Y + halfWidth },
+				)
+			}
+			continue
+		}
+
+		// edge bodies
+		edges := n - 1
+		if contour.closed { edges = n }
+		for i := 0; i < edges; i++ {
+			a, b := points[i], points[(i + 1) % n]
+			nx, ny := strokeNormal(a, b, halfWidth)
+			add(
+				PointF{ a.X + nx, a.Y + ny }, PointF{ b.X + nx, b.Y + ny },
+				PointF{ b.X - nx, b.Y - ny }, PointF{ a.X - nx, a.Y - ny },
+			)
+		}
+
+		// joins
+		for i := 0; i < n; i++ {
+			if !contour.closed && (i == 0 || i == n - 1) { continue }
+			prev, next := points[(i + n - 1) % n], points[(i + 1) % n]
+			polygons = appendJoin(polygons, prev, points[i], next, halfWidth, join, contour.corners[i], discSides)
+		}
+
+		// caps
+		if contour.closed { continue }
+		for _, end := range [2][2]PointF{ { points[1], points[0] }, { points[n - 2], points[n - 1] } } {
+			from, p := end[0], end[1]
+			switch cap {
+			case CapRound:
+				polygons = appendPolygon(polygons, discPolygon(p, halfWidth, discSides))
+			case CapSquare:
+				nx, ny := strokeNormal(from, p, halfWidth)
+				dx, dy := -ny, nx // direction from -> p, scaled by halfWidth
+				add(
+					PointF{ p.X + nx, p.Y + ny }, PointF{ p.X + nx + dx, p.Y + ny + dy },
+					PointF{ p.X - nx + dx, p.Y - ny + dy }, PointF{ p.X - nx, p.Y - ny },
+				)
+			}
+		}
+	}
+	return polygons
+}
+
+// Appends the polygon filling the outer side of the corner at p between
+// the edges prev -> p and p -> next. Points that aren't corners are part
+// of flattened curves, and are joined with miters (or discs, for sharp
+// cusps) so curves look smooth.
+func appendJoin(polygons [][]PointF, prev, p, next PointF, halfWidth float64, join Join, isCorner bool, discSides int) [][]PointF {
+	n1x, n1y := strokeNormal(prev, p, halfWidth)
+	n2x, n2y := strokeNormal(p, next, halfWidth)
+	cross := n1x*n2y - n1y*n2x
+	if math.Abs(cross) < 1e-9*halfWidth*halfWidth && n1x*n2x + n1y*n2y > 0 {
+		return polygons // collinear, nothing to join
+	}
+	if cross < 0 { // make the normals point to the outer side
+		n1x, n1y, n2x, n2y = -n1x, -n1y, -n2x, -n2y
+	}
+	if !isCorner {
+		join = JoinMiter
+		if n1x*n2x + n1y*n2y < 0 { join = JoinRound }
+	}
+
+	switch join {
+	case JoinRound:
+		return appendPolygon(polygons, discPolygon(p, halfWidth, discSides))
+	case JoinMiter:
+		mx, my := n1x + n2x, n1y + n2y
+		lenSq := mx*mx + my*my
+		// the miter length over the width, 1/cos(theta/2), is 2*halfWidth/|m|
+		if lenSq > 0 && 4*halfWidth*halfWidth <= StrokeMiterLimit*StrokeMiterLimit*lenSq {
+			scale := 2*halfWidth*halfWidth/lenSq
+			return appendPolygon(polygons, []PointF{
+				p, PointF{ p.X + n1x, p.Y + n1y },
+				PointF{ p.X + mx*scale, p.Y + my*scale }, PointF{ p.X + n2x, p.Y + n2y },
+			})
+		}
+	}
+	return appendPolygon(polygons, []PointF{ p, PointF{ p.X + n1x, p.Y + n1y }, PointF{ p.X + n2x, p.Y + n2y } })
+}
+
+// Returns the normal of a -> b with the given length, rotated 90 degrees
+// counter-clockwise in a y-down coordinate system.
+func strokeNormal(a, b PointF, length float64) (float64, float64) {
+	dx, dy := b.X - a.X, b.Y - a.Y
+	scale := length/math.Hypot(dx, dy)
+	return dy*scale, -dx*scale
+}
+
+// Appends the polygon with a positive signed area, reversing it if
+// necessary. Degenerate polygons are skipped.
+func appendPolygon(polygons [][]PointF, polygon []PointF) [][]PointF {
+	area := 0.0
+	for i, a := range polygon {
+		b := polygon[(i + 1) % len(polygon)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+	if math.Abs(area) < 1e-12 { return polygons }
+	if area < 0 {
+		for i, j := 0, len(polygon) - 1; i < j; i, j = i + 1, j - 1 {
+			polygon[i], polygon[j] = polygon[j], polygon[i]
+		}
+	}
+	return append(polygons, polygon)
+}
+
+// Tolerance (in pixels) used to approximate discs with polygons. Smaller
+// than flattenTolerance, as the area lost is noticeable for small discs.
+const strokeDiscTolerance = 1.0/256.0
+
+// Returns the number of sides needed to approximate a disc of the given
+// radius within strokeDiscTolerance.
+func strokeDiscSides(radius float64) int {
+	sides := 8
+	if radius > strokeDiscTolerance {
+		sides = int(math.Ceil(math.Pi/math.Acos(1 - strokeDiscTolerance/radius)))
+		if sides < 8 { sides = 8 }
+		if sides > 1024 { sides = 1024 }
+	}
+	return sides
+}
+
+func discPolygon(center PointF, radius float64, sides int) []PointF {
+	polygon := make([]PointF, sides)
+	for i := range polygon {
+		angle := 2*math.Pi*float64(i)/float64(sides)
+		polygon[i] = PointF{ center.X + radius*math.Cos(angle), center.Y + radius*math.Sin(angle) }
+	}
+	return polygon
+}
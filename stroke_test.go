@@ -0,0 +1,106 @@
+package sfntshape
+
+import "math"
+import "image"
+import "testing"
+
+func TestRasterizeStroke(t *testing.T) {
+	line := New()
+	line.MoveTo(0, 0)
+	line.LineTo(20, 0)
+	for _, test := range []struct { cap Cap; rect image.Rectangle; area float64 }{
+		{ CapButt, image.Rect(0, -3, 20, 3), 20*6 },
+		{ CapSquare, image.Rect(-3, -3, 23, 3), 26*6 },
+		{ CapRound, image.Rect(-3, -3, 23, 3), 20*6 + math.Pi*9 },
+	}{
+		mask, err := line.RasterizeStroke(6, test.cap, JoinMiter)
+		if err != nil { t.Fatal(err) }
+		if mask.Rect != test.rect {
+			t.Fatalf("cap %d: expected Rect %v, got %v", test.cap, test.rect, mask.Rect)
+		}
+		if area := maskArea(mask); math.Abs(area - test.area) > 0.5 {
+			t.Fatalf("cap %d: expected area %.2f, got %.2f", test.cap, test.area, area)
+		}
+		for x := 0; x < 20; x++ { // the body must be fully covered
+			for y := -3; y < 3; y++ {
+				if mask.AlphaAt(x, y).A != 0xFF { t.Fatalf("cap %d: (%d, %d) not covered", test.cap, x, y) }
+			}
+		}
+		if test.cap == CapRound && mask.AlphaAt(-3, -3).A > 8 {
+			t.Fatalf("round cap corner is covered (%d)", mask.AlphaAt(-3, -3).A)
+		}
+	}
+}
+
+func TestRasterizeStrokeJoins(t *testing.T) {
+	square := New()
+	square.MoveTo(0, 0)
+	square.LineTo(10, 0)
+	square.LineTo(10, 10)
+	square.LineTo(0, 10)
+	square.LineTo(0, 0)
+	for _, test := range []struct { join Join; corner uint8; area float64 }{
+		{ JoinMiter, 0xFF, 12*12 - 8*8 },
+		{ JoinBevel, 0x80, 12*12 - 8*8 - 4*0.5 },
+		{ JoinRound, 0xC9, 12*12 - 8*8 - 4*(1 - math.Pi/4) },
+	}{
+		mask, err := square.RasterizeStroke(2, CapButt, test.join)
+		if err != nil { t.Fatal(err) }
+		if mask.Rect != image.Rect(-1, -11, 11, 1) {
+			t.Fatalf("join %d: unexpected Rect %v", test.join, mask.Rect)
+		}
+		for y := -8; y < -1; y++ {
+			for x := 2; x < 8; x++ {
+				if mask.AlphaAt(x, y).A != 0 { t.Fatalf("join %d: interior (%d, %d) covered", test.join, x, y) }
+			}
+		}
+		for _, corner := range []image.Point{ { -1, -11 }, { 10, -11 }, { -1, 0 }, { 10, 0 } } {
+			if value := mask.AlphaAt(corner.X, corner.Y).A; absInt(int(value) - int(test.corner)) > 4 {
+				t.Fatalf("join %d: expected corner %v at %d, got %d", test.join, corner, test.corner, value)
+			}
+		}
+		if area := maskArea(mask); math.Abs(area - test.area) > 0.5 {
+			t.Fatalf("join %d: expected area %.2f, got %.2f", test.join, test.area, area)
+		}
+	}
+
+	// without closing, the first corner is capped instead
+	open := New()
+	open.MoveTo(0, 0)
+	open.LineTo(10, 0)
+	open.LineTo(10, 10)
+	mask, err := open.RasterizeStroke(2, CapButt, JoinMiter)
+	if err != nil { t.Fatal(err) }
+	if mask.Rect != image.Rect(0, -10, 11, 1) { t.Fatalf("unexpected Rect %v", mask.Rect) }
+	if mask.AlphaAt(10, 0).A != 0xFF { t.Fatalf("missing miter") }
+}
+
+func TestRasterizeStrokeCurves(t *testing.T) {
+	circle := New()
+	circle.MoveTo(20, 0)
+	circle.QuadTo(20, 20, 0, 20)
+	circle.QuadTo(-20, 20, -20, 0)
+	circle.QuadTo(-20, -20, 0, -20)
+	circle.QuadTo(20, -20, 20, 0)
+	mask, err := circle.RasterizeStroke(4, CapButt, JoinBevel)
+	if err != nil { t.Fatal(err) }
+	// check points along the curves (at t = 0.5, the first quad passes through (15, -15))
+	for _, point := range []image.Point{ { 19, -1 }, { 14, -15 }, { -16, 14 }, { 0, -19 } } {
+		if value := mask.AlphaAt(point.X, point.Y).A; value != 0xFF {
+			t.Fatalf("expected (%d, %d) to be covered, got %d", point.X, point.Y, value)
+		}
+	}
+	if mask.AlphaAt(0, 0).A != 0 { t.Fatalf("center covered") }
+
+	empty := New()
+	empty.MoveTo(3, 3)
+	if _, err := empty.RasterizeStroke(2, CapRound, JoinRound); err != nil { t.Fatal(err) }
+	if _, err := circle.RasterizeStroke(0, CapRound, JoinRound); err == nil { t.Fatal("expected error") }
+}
+
+// Returns the total coverage of the mask, in pixels.
+func maskArea(mask *image.Alpha) float64 {
+	sum := 0
+	for _, value := range mask.Pix { sum += int(value) }
+	return float64(sum)/255
+}
@@ -0,0 +1,73 @@
+package sfntshape
+
+import "errors"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Returns the segment index ranges of the shape's subpaths, as
+// [start, end) pairs for [Shape.Segments](). Each MoveTo starts a new
+// subpath, and any drawing commands before the first MoveTo form a
+// subpath of their own, so the ranges are contiguous and cover all the
+// segments. Unlike [Shape.NumSubpaths](), subpaths made only of a MoveTo
+// are included too, so the indices used by [Shape.DeleteSubpath]() and
+// [Shape.ReplaceSubpath]() always match these ranges.
+func (self *Shape) SubpathRanges() [][2]int {
+	var ranges [][2]int
+	start := 0
+	for i, segment := range self.segments {
+		if segment.Op == sfnt.SegmentOpMoveTo && i > start {
+			ranges = append(ranges, [2]int{ start, i })
+			start = i
+		}
+	}
+	if start < len(self.segments) { ranges = append(ranges, [2]int{ start, len(self.segments) }) }
+	return ranges
+}
+
+// Removes the i-th subpath, as given by [Shape.SubpathRanges](). The
+// following subpaths shift down, so subpath i+1 becomes i. Returns an
+// error if the index is out of range.
+//
+// The current position ([Shape.Pos]()) is updated if the last subpath
+// is deleted, and incremental rasterizations (see [Shape.BeginTracking]())
+// are tracked as full ones.
+func (self *Shape) DeleteSubpath(i int) error {
+	return self.spliceSubpath(i, nil, nil)
+}
+
+// Replaces the i-th subpath, as given by [Shape.SubpathRanges](), with
+// all the segments of repl. The segments are copied as they are in
+// [Shape.Segments]() (scale and InvertY are not applied again), so repl
+// should be built with the same settings, and it should start with a
+// MoveTo to not be connected to the previous subpath. If repl has
+// multiple subpaths, the following subpaths shift up accordingly.
+// Returns an error if the index is out of range, repl is nil or it has
+// an error recorded (see [Shape.Err]()).
+func (self *Shape) ReplaceSubpath(i int, repl *Shape) error {
+	if repl == nil { return errors.New("nil replacement shape") }
+	if repl.err != nil { return repl.err }
+	return self.spliceSubpath(i, repl.segments, repl.positions)
+}
+
+func (self *Shape) spliceSubpath(i int, segments []sfnt.Segment, positions []fixed.Point26_6) error {
+	ranges := self.SubpathRanges()
+	if i < 0 || i >= len(ranges) { return errors.New("subpath index out of range") }
+	start, end := ranges[i][0], ranges[i][1]
+	tail := len(self.segments) - end
+	newLen := start + len(segments) + tail
+
+	// new slices, as the replacement may be the shape itself
+	newSegments := make([]sfnt.Segment, newLen)
+	copy(newSegments, self.segments[ : start])
+	copy(newSegments[start + len(segments) : ], self.segments[end : ])
+	copy(newSegments[start : ], segments)
+	newPositions := make([]fixed.Point26_6, newLen)
+	copy(newPositions, self.positions[ : start])
+	copy(newPositions[start + len(positions) : ], self.positions[end : ])
+	copy(newPositions[start : ], positions)
+	self.segments, self.positions = newSegments, newPositions
+	self.dirty.markAll()
+	self.recomputeBounds()
+	return nil
+}
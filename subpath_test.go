@@ -0,0 +1,62 @@
+package sfntshape
+
+import "testing"
+
+func TestSubpaths(t *testing.T) {
+	ring := New()
+	testCircle(&ring, 30, 30, 20)
+	ring.InvertY(true) // mirrored, so the hole is wound the other way
+	testCircle(&ring, 30, -30, 10)
+	ring.InvertY(false)
+	ranges := ring.SubpathRanges()
+	if len(ranges) != 2 || ranges[0][0] != 0 || ranges[0][1] != ranges[1][0] || ranges[1][1] != ring.Len() {
+		t.Fatalf("unexpected ranges %v", ranges)
+	}
+	if ring.Contains(30, -30) { t.Fatal("expected a hole in the ring") }
+
+	// deleting the hole makes it solid
+	solid := ring.Clone()
+	if err := solid.DeleteSubpath(1); err != nil { t.Fatal(err) }
+	if !solid.Contains(30, -30) { t.Fatal("expected a solid disk after deleting the hole") }
+	mask, _ := solid.Rasterize()
+	if mask.AlphaAt(30, -30).A != 255 { t.Fatal("expected the deleted hole to render solid") }
+	outer := New()
+	testCircle(&outer, 30, 30, 20)
+	if !solid.Equal(&outer) { t.Fatal("expected only the outer circle") }
+	if x, y, _ := solid.Pos(); x != outer.positions[outer.Len() - 1].X || y != outer.positions[outer.Len() - 1].Y {
+		t.Fatal("expected the position to follow the deletion")
+	}
+	if solid.DeleteSubpath(1) == nil || solid.DeleteSubpath(-1) == nil { t.Fatal("expected out of range errors") }
+
+	// replacing a square with a circle
+	shape := testSquare(10)
+	shape.MoveTo(40, 0)
+	shape.LineTo(50, 0)
+	shape.LineTo(50, 10)
+	shape.LineTo(40, 0)
+	circle := New()
+	testCircle(&circle, 20, 20, 8)
+	if err := shape.ReplaceSubpath(0, &circle); err != nil { t.Fatal(err) }
+	expected := New()
+	testCircle(&expected, 20, 20, 8)
+	expected.MoveTo(40, 0)
+	expected.LineTo(50, 0)
+	expected.LineTo(50, 10)
+	expected.LineTo(40, 0)
+	if index := shape.FirstDifference(&expected, 0); index != -1 { t.Fatalf("unexpected segments from %d:\n%v", index, &shape) }
+	if len(shape.SubpathRanges()) != 2 { t.Fatal("expected 2 subpaths") }
+	if err := shape.ReplaceSubpath(1, &shape); err != nil { t.Fatal(err) } // self replacement
+	if len(shape.SubpathRanges()) != 3 { t.Fatalf("expected 3 subpaths, got %v", shape.SubpathRanges()) }
+	if shape.ReplaceSubpath(0, nil) == nil { t.Fatal("expected error for nil replacement") }
+
+	// leading drawing commands and MoveTo-only subpaths
+	loose := New()
+	loose.LineTo(5, 5)
+	loose.MoveTo(1, 1)
+	loose.MoveTo(2, 2)
+	loose.LineTo(3, 3)
+	if ranges := loose.SubpathRanges(); len(ranges) != 3 || ranges[1] != [2]int{ 1, 2 } {
+		t.Fatalf("unexpected ranges %v", ranges)
+	}
+	if len((&Shape{}).SubpathRanges()) != 0 { t.Fatal("expected no ranges for empty shapes") }
+}
@@ -0,0 +1,126 @@
+package sfntshape
+
+import "math"
+import "image"
+import "errors"
+import "image/color"
+
+// How textures are extended beyond their bounds in [Shape.PaintTexture]().
+type TileMode uint8
+const (
+	// The texture repeats itself indefinitely. This is the default.
+	TileRepeat TileMode = iota
+
+	// The texture repeats itself, but mirrored on every other tile,
+	// so there are no seams between tiles.
+	TileMirror
+
+	// The texels on the edges of the texture extend indefinitely.
+	TileClamp
+)
+
+// How textures are sampled in [Shape.PaintTextureFiltered]().
+type TextureFilter uint8
+const (
+	// The texel containing the sampling point is used. Sharp, but
+	// scaled up textures look blocky. This is the default.
+	FilterNearest TextureFilter = iota
+
+	// The four texels closest to the sampling point are interpolated
+	// (in premultiplied alpha). Smoother for scaled or rotated textures.
+	FilterBilinear
+)
+
+// Like [Shape.Paint](), but filling the shape with the given texture
+// instead of a flat color. The transformation maps texture coordinates
+// (in pixels, with the texture's Bounds().Min at (0, 0)) to the mask's
+// coordinate space, so each mask pixel is colored by sampling the
+// texture at the inverse transformation of its center. For example,
+// [AffineScale](4, 4) makes every texel cover 4x4 mask pixels starting
+// at the mask's (0, 0). Texels are sampled with [FilterNearest], and
+// their alpha is modulated by the coverage of the mask pixels before
+// compositing them over backColor.
+//
+// Returns nil if the transformation is not invertible or for the same
+// reasons as [Shape.Paint](). See [Shape.PaintTextureFiltered]() for
+// bilinear sampling and error details.
+func (self *Shape) PaintTexture(tex image.Image, mode TileMode, m Affine, backColor color.Color) *image.RGBA {
+	rgba, err := self.PaintTextureFiltered(tex, mode, FilterNearest, m, backColor)
+	if errors.Is(err, ErrNothingToDraw) { return image.NewRGBA(image.Rectangle{}) }
+	if err != nil { return nil }
+	return rgba
+}
+
+// Like [Shape.PaintTexture](), but with a configurable sampling filter
+// and returning any rasterization error.
+func (self *Shape) PaintTextureFiltered(tex image.Image, mode TileMode, filter TextureFilter, m Affine, backColor color.Color) (*image.RGBA, error) {
+	if tex == nil || tex.Bounds().Empty() { return nil, errors.New("empty texture") }
+	inverse, ok := m.Invert()
+	if !ok { return nil, errors.New("texture transformation not invertible") }
+	mask, err := self.rasterizeForPaint()
+	if err != nil || mask == nil { return nil, err }
+
+	sampler := textureSampler{ tex, tex.Bounds(), mode }
+	return paintMaskWith(mask, backColor, func(x, y int) color.RGBA64 {
+		u, v := inverse.Apply(float64(x) + 0.5, float64(y) + 0.5)
+		if filter == FilterBilinear { return sampler.bilinear(u, v) }
+		return sampler.at(int(math.Floor(u)), int(math.Floor(v)))
+	}), nil
+}
+
+type textureSampler struct {
+	tex image.Image
+	bounds image.Rectangle
+	mode TileMode
+}
+
+// Returns the premultiplied color of the texel at (u, v), relative
+// to the texture bounds and tiled according to the mode.
+func (self *textureSampler) at(u, v int) color.RGBA64 {
+	u = self.mode.wrap(u, self.bounds.Dx())
+	v = self.mode.wrap(v, self.bounds.Dy())
+	r, g, b, a := self.tex.At(self.bounds.Min.X + u, self.bounds.Min.Y + v).RGBA()
+	return color.RGBA64{ R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a) }
+}
+
+// Like at, but interpolating the four texels closest to (u, v).
+// Texel centers are at integer coordinates + 0.5.
+func (self *textureSampler) bilinear(u, v float64) color.RGBA64 {
+	u, v = u - 0.5, v - 0.5
+	fu, fv := math.Floor(u), math.Floor(v)
+	tu, tv := u - fu, v - fv
+	iu, iv := int(fu), int(fv)
+	c00, c10 := self.at(iu, iv), self.at(iu + 1, iv)
+	c01, c11 := self.at(iu, iv + 1), self.at(iu + 1, iv + 1)
+	mix := func(a, b, c, d uint16) uint16 {
+		top := float64(a) + tu*(float64(b) - float64(a))
+		bottom := float64(c) + tu*(float64(d) - float64(c))
+		return uint16(math.Round(top + tv*(bottom - top)))
+	}
+	return color.RGBA64{
+		R: mix(c00.R, c10.R, c01.R, c11.R),
+		G: mix(c00.G, c10.G, c01.G, c11.G),
+		B: mix(c00.B, c10.B, c01.B, c11.B),
+		A: mix(c00.A, c10.A, c01.A, c11.A),
+	}
+}
+
+// Maps the given texel index to [0, size).
+func (self TileMode) wrap(index, size int) int {
+	switch self {
+	case TileClamp:
+		if index < 0 { return 0 }
+		if index >= size { return size - 1 }
+		return index
+	case TileMirror:
+		index = floorMod(index, 2*size)
+		if index >= size { index = 2*size - 1 - index }
+		return index
+	default:
+		return floorMod(index, size)
+	}
+}
+
+func floorMod(value, divisor int) int {
+	return value - floorDiv(value, divisor)*divisor
+}
@@ -0,0 +1,70 @@
+package sfntshape
+
+import "image"
+import "image/color"
+import "testing"
+
+func TestPaintTexture(t *testing.T) {
+	black, white := color.RGBA{ 0, 0, 0, 255 }, color.RGBA{ 255, 255, 255, 255 }
+	checker := image.NewRGBA(image.Rect(10, 10, 12, 12)) // bounds not at the origin on purpose
+	checker.SetRGBA(10, 10, white)
+	checker.SetRGBA(11, 10, black)
+	checker.SetRGBA(10, 11, black)
+	checker.SetRGBA(11, 11, white)
+
+	// repeated 2x2 checkerboard with 4x4 pixel texels
+	shape := New()
+	testCircle(&shape, 0, 0, 30)
+	back := color.RGBA{ 255, 0, 0, 255 }
+	img := shape.PaintTexture(checker, TileRepeat, AffineScale(4, 4), back)
+	if img == nil { t.Fatal("unexpected nil image") }
+	mask, _ := shape.Rasterize()
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			got := img.RGBAAt(x, y)
+			switch mask.AlphaAt(x, y).A {
+			case 0:
+				if got != back { t.Fatalf("pixel (%d, %d): expected background, got %v", x, y, got) }
+			case 255:
+				expected := white
+				if (floorDiv(x, 4) + floorDiv(y, 4)) % 2 != 0 { expected = black }
+				if got != expected { t.Fatalf("pixel (%d, %d): expected %v, got %v", x, y, expected, got) }
+			}
+		}
+	}
+
+	// clamp mode extends the edge texels
+	shape.Reset()
+	shape.MoveTo(-20, -20)
+	shape.LineTo(20, -20)
+	shape.LineTo(20, 20)
+	shape.LineTo(-20, 20)
+	shape.LineTo(-20, -20)
+	img = shape.PaintTexture(checker, TileClamp, AffineScale(4, 4), back)
+	corners := map[image.Point]color.RGBA{
+		{ -15, -15 }: white, { 15, -15 }: black, { -15, 15 }: black, { 15, 15 }: white,
+		{ 2, -18 }: white, { 6, -18 }: black, // along the top edge
+	}
+	for point, expected := range corners {
+		if got := img.RGBAAt(point.X, point.Y); got != expected {
+			t.Fatalf("clamp mode, pixel %v: expected %v, got %v", point, expected, got)
+		}
+	}
+
+	// mirror mode repeats the texels on the tile edges
+	img = shape.PaintTexture(checker, TileMirror, AffineScale(4, 4), back)
+	if img.RGBAAt(9, 1) != black || img.RGBAAt(13, 1) != white || img.RGBAAt(-3, 1) != white {
+		t.Fatal("unexpected mirror mode texels")
+	}
+
+	// bilinear sampling between texel centers
+	img, err := shape.PaintTextureFiltered(checker, TileClamp, FilterBilinear, AffineScale(4, 4), back)
+	if err != nil { t.Fatal(err) }
+	if got := img.RGBAAt(3, 1); got.R < 158 || got.R > 160 || got.R != got.G { // 5/8 white
+		t.Fatalf("expected bilinear gray between texels, got %v", got)
+	}
+
+	if shape.PaintTexture(checker, TileRepeat, Affine{}, back) != nil {
+		t.Fatal("expected nil image for a non-invertible transformation")
+	}
+}
@@ -0,0 +1,66 @@
+package sfntshape
+
+import "image"
+
+// Trims the fully transparent borders of the given mask, returning the
+// mask restricted to the tightest rectangle containing all the pixels
+// with nonzero coverage. If compact is false, the result is a sub-image
+// sharing the Pix buffer of the original mask. Otherwise, a compacted
+// copy is returned instead, which doesn't keep the original buffer alive.
+//
+// In both cases, the Rect of the result remains in the same coordinates
+// as the original mask's Rect, so positioning is preserved. The returned
+// point is the offset from the original Rect.Min to the trimmed Rect.Min,
+// for callers that work with mask-relative coordinates. If the mask is
+// fully transparent, the result has an empty Rect and the offset is zero.
+func TrimMask(mask *image.Alpha, compact bool) (*image.Alpha, image.Point) {
+	rect := opaqueBounds(mask)
+	if rect.Empty() {
+		return &image.Alpha{ Pix: mask.Pix[0 : 0], Stride: mask.Stride }, image.Point{}
+	}
+	offset := rect.Min.Sub(mask.Rect.Min)
+	if !compact { return mask.SubImage(rect).(*image.Alpha), offset }
+
+	trimmed := image.NewAlpha(rect)
+	width := rect.Dx()
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		copy(trimmed.Pix[trimmed.PixOffset(rect.Min.X, y) : ][ : width], mask.Pix[mask.PixOffset(rect.Min.X, y) : ])
+	}
+	return trimmed, offset
+}
+
+// Returns the tightest rectangle containing all the nonzero pixels
+// of the mask, or an empty rectangle if there are none.
+func opaqueBounds(mask *image.Alpha) image.Rectangle {
+	minX, minY := mask.Rect.Max.X, mask.Rect.Max.Y
+	maxX, maxY := mask.Rect.Min.X, mask.Rect.Min.Y
+	width := mask.Rect.Dx()
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		row := mask.Pix[mask.PixOffset(mask.Rect.Min.X, y) : ][ : width]
+		first := -1
+		for x, value := range row {
+			if value != 0 { first = x; break }
+		}
+		if first == -1 { continue }
+		last := first
+		for x := width - 1; x > first; x-- {
+			if row[x] != 0 { last = x; break }
+		}
+		if y < minY { minY = y }
+		maxY = y + 1
+		if mask.Rect.Min.X + first < minX { minX = mask.Rect.Min.X + first }
+		if mask.Rect.Min.X + last + 1 > maxX { maxX = mask.Rect.Min.X + last + 1 }
+	}
+	if minY >= maxY { return image.Rectangle{} }
+	return image.Rect(minX, minY, maxX, maxY)
+}
+
+// Like [Shape.Rasterize](), but trimming the fully transparent
+// borders of the result. See [TrimMask]() (used without compacting,
+// so no copies are made).
+func (self *Shape) RasterizeTrimmed() (*image.Alpha, error) {
+	mask, err := self.Rasterize()
+	if err != nil || mask == nil { return mask, err }
+	trimmed, _ := TrimMask(mask, false)
+	return trimmed, nil
+}
@@ -0,0 +1,61 @@
+package sfntshape
+
+import "math"
+import "image"
+import "testing"
+
+func TestTrimMask(t *testing.T) {
+	// cubic with faraway control points
+	shape := New()
+	shape.MoveTo(0, 0)
+	shape.CubeTo(-60, 90, 100, 90, 40, 0)
+	shape.LineTo(0, 0)
+	mask, err := shape.Rasterize()
+	if err != nil { t.Fatal(err) }
+
+	// tight bounds from densely sampling the curve (y is inverted)
+	minX, minY, maxX, maxY := 0.0, 0.0, 40.0, 0.0
+	for i := 0; i <= 10000; i++ {
+		p := cubePoint(PointF{ 0, 0 }, PointF{ -60, -90 }, PointF{ 100, -90 }, PointF{ 40, 0 }, float64(i)/10000)
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	tight := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+	if !tight.In(mask.Rect) || tight == mask.Rect { t.Fatalf("expected tight bounds %v within %v", tight, mask.Rect) }
+
+	for _, compact := range []bool{ false, true } {
+		trimmed, offset := TrimMask(mask, compact)
+		rect := trimmed.Rect
+		if absInt(rect.Min.X - tight.Min.X) > 1 || absInt(rect.Min.Y - tight.Min.Y) > 1 ||
+			absInt(rect.Max.X - tight.Max.X) > 1 || absInt(rect.Max.Y - tight.Max.Y) > 1 {
+			t.Fatalf("expected trimmed rect %v to match %v within a pixel", rect, tight)
+		}
+		if offset != rect.Min.Sub(mask.Rect.Min) { t.Fatalf("unexpected offset %v", offset) }
+		sharesPix := &trimmed.Pix[0] == &mask.Pix[mask.PixOffset(rect.Min.X, rect.Min.Y)]
+		if sharesPix == compact { t.Fatalf("compact = %t, but sharing pixels = %t", compact, sharesPix) }
+		for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+			for x := mask.Rect.Min.X; x < mask.Rect.Max.X; x++ {
+				value := mask.AlphaAt(x, y).A
+				if image.Pt(x, y).In(rect) {
+					if trimmed.AlphaAt(x, y).A != value { t.Fatalf("trimmed mask differs at (%d, %d)", x, y) }
+				} else if value != 0 {
+					t.Fatalf("trimmed away nonzero pixel at (%d, %d)", x, y)
+				}
+			}
+		}
+	}
+
+	trimmed, err := shape.RasterizeTrimmed()
+	if err != nil { t.Fatal(err) }
+	if expected, _ := TrimMask(mask, false); trimmed.Rect != expected.Rect {
+		t.Fatalf("expected RasterizeTrimmed rect %v, got %v", expected.Rect, trimmed.Rect)
+	}
+
+	empty, offset := TrimMask(image.NewAlpha(image.Rect(3, 3, 9, 9)), true)
+	if !empty.Rect.Empty() || offset != (image.Point{}) { t.Fatal("expected empty result for transparent mask") }
+}
+
+func absInt(x int) int {
+	if x < 0 { return -x }
+	return x
+}
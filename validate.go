@@ -0,0 +1,176 @@
+package sfntshape
+
+import "fmt"
+import "math"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Kinds of problems reported by [Shape.Validate]().
+type DiagnosticCode uint8
+const (
+	// The subpath doesn't end at its starting point. Rasterizers close
+	// subpaths implicitly, but stricter formats may not.
+	DiagUnclosedSubpath DiagnosticCode = iota
+
+	// The segment doesn't move the pen (all its points are equal to the
+	// current position).
+	DiagZeroLengthSegment
+
+	// The subpath has fewer than 3 distinct points (including control
+	// points), so it can't enclose any area.
+	DiagDegenerateSubpath
+
+	// The subpath lies inside another one and is wound in the same
+	// direction, so with the non-zero rule it doesn't create a hole.
+	// Often a sign of a hole defined in the wrong direction.
+	DiagSuspiciousWinding
+
+	// The segment has coordinates close to the limits of 26.6 fixed
+	// point values (beyond ±(1 << 24) pixels), so further scaling or
+	// offsets may overflow.
+	DiagNearRangeLimit
+)
+
+// Coordinates beyond this (in absolute value) trigger [DiagNearRangeLimit].
+const diagRangeLimit = fixed.Int26_6(1 << 30)
+
+// Returns the name of the diagnostic code, like "UnclosedSubpath".
+func (self DiagnosticCode) String() string {
+	switch self {
+	case DiagUnclosedSubpath: return "UnclosedSubpath"
+	case DiagZeroLengthSegment: return "ZeroLengthSegment"
+	case DiagDegenerateSubpath: return "DegenerateSubpath"
+	case DiagSuspiciousWinding: return "SuspiciousWinding"
+	case DiagNearRangeLimit: return "NearRangeLimit"
+	default: return fmt.Sprintf("DiagnosticCode(%d)", uint8(self))
+	}
+}
+
+// A problem found by [Shape.Validate](). First and Last are the indices
+// of the first and last segments involved (inclusive), as in
+// [Shape.Segments]().
+type Diagnostic struct {
+	Code DiagnosticCode
+	Message string
+	First, Last int
+}
+
+// Returns the diagnostic's code, message and segment range, in a
+// single line.
+func (self Diagnostic) String() string {
+	if self.First == self.Last { return fmt.Sprintf("%s: %s (segment %d)", self.Code, self.Message, self.First) }
+	return fmt.Sprintf("%s: %s (segments %d-%d)", self.Code, self.Message, self.First, self.Last)
+}
+
+// Analyzes the shape and reports potential problems for exporting it
+// to stricter formats (or for rasterizing it at all): unclosed subpaths,
+// zero-length segments, subpaths with fewer than 3 points, subpaths that
+// seem to be holes but are wound in the same direction as the subpath
+// containing them, and coordinates close to the 26.6 range limits.
+// Self-intersections are not detected. Coordinates in the messages are
+// in pixels, in the same space as [Shape.Segments](). Subpaths made only
+// of MoveTo commands are ignored. Returns nil for clean shapes.
+func (self *Shape) Validate() []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(code DiagnosticCode, first, last int, format string, args ...any) {
+		diagnostics = append(diagnostics, Diagnostic{ code, fmt.Sprintf(format, args...), first, last })
+	}
+
+	type subpath struct {
+		first, last int
+		contour []PointF
+		area float64 // signed, from the flattened contour
+	}
+	var closed []subpath
+	segments := self.segments
+	for first := 0; first < len(segments); {
+		last := first
+		for last + 1 < len(segments) && segments[last + 1].Op != sfnt.SegmentOpMoveTo { last += 1 }
+		var start fixed.Point26_6
+		if segments[first].Op == sfnt.SegmentOpMoveTo { start = segments[first].Args[0] }
+
+		// per segment checks, and distinct points
+		points := map[fixed.Point26_6]struct{}{ start: {} }
+		pen := start
+		for i := first; i <= last; i++ {
+			segment := segments[i]
+			count := segmentArgsCount(segment.Op)
+			zeroLength := (segment.Op != sfnt.SegmentOpMoveTo)
+			nearLimit := false
+			for _, point := range segment.Args[ : count] {
+				points[point] = struct{}{}
+				if point != pen { zeroLength = false }
+				if nearRangeLimit(point.X) || nearRangeLimit(point.Y) { nearLimit = true }
+			}
+			if zeroLength { report(DiagZeroLengthSegment, i, i, "segment doesn't move from %s", formatPointF(pen)) }
+			if nearLimit { report(DiagNearRangeLimit, i, i, "coordinates close to the 26.6 fixed point limits") }
+			pen = segmentEnd(segment)
+		}
+		if !hasDrawingOps(segments[first : last + 1]) {
+			first = last + 1
+			continue
+		}
+
+		if pen != start {
+			report(DiagUnclosedSubpath, first, last, "subpath ends at %s instead of its start %s", formatPointF(pen), formatPointF(start))
+		}
+		if len(points) < 3 {
+			report(DiagDegenerateSubpath, first, last, "subpath has only %d distinct points", len(points))
+		} else {
+			contours := flattenSegments(segments[first : last + 1], flattenTolerance)
+			if len(contours) == 1 {
+				contour := contours[0]
+				closed = append(closed, subpath{ first, last, contour, polygonArea(contour) })
+			}
+		}
+		first = last + 1
+	}
+
+	// winding of nested subpaths against their immediate containers
+	for i, inner := range closed {
+		container := -1
+		for j, outer := range closed {
+			if i == j || math.Abs(outer.area) <= math.Abs(inner.area) { continue }
+			if container != -1 && math.Abs(outer.area) >= math.Abs(closed[container].area) { continue }
+			if containsContour(outer.contour, inner.contour) { container = j }
+		}
+		if container == -1 || inner.area == 0 { continue }
+		outer := closed[container]
+		if (inner.area > 0) == (outer.area > 0) {
+			report(DiagSuspiciousWinding, inner.first, inner.last,
+				"subpath is inside the subpath at segments %d-%d and wound in the same direction", outer.first, outer.last)
+		}
+	}
+	return diagnostics
+}
+
+// Returns the signed area of the implicitly closed polygon.
+func polygonArea(contour []PointF) float64 {
+	var area float64
+	n := len(contour)
+	for i := 0; i < n; i++ {
+		a, b := contour[i], contour[(i + 1) % n]
+		area += a.X*b.Y - b.X*a.Y
+	}
+	return area/2
+}
+
+// Returns whether all the points of inner are inside outer.
+func containsContour(outer, inner []PointF) bool {
+	contours := [][]PointF{ outer }
+	for _, point := range inner {
+		if windingNumber(contours, point.X, point.Y) == 0 { return false }
+	}
+	return true
+}
+
+func formatPointF(point fixed.Point26_6) string {
+	return fmt.Sprintf("(%g, %g)", fixedToF64(point.X), fixedToF64(point.Y))
+}
+
+// Reports whether the coordinate is beyond diagRangeLimit in either
+// direction. Compared directly, as math.MinInt32 has no positive Fract.
+func nearRangeLimit(value Fract) bool {
+	return value < -diagRangeLimit || value > diagRangeLimit
+}
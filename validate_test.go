@@ -0,0 +1,90 @@
+package sfntshape
+
+import "math"
+import "strings"
+import "testing"
+
+func TestValidate(t *testing.T) {
+	codes := func(shape *Shape) map[DiagnosticCode]Diagnostic {
+		found := make(map[DiagnosticCode]Diagnostic)
+		for _, diagnostic := range shape.Validate() { found[diagnostic.Code] = diagnostic }
+		return found
+	}
+
+	// clean shapes: square with a properly wound hole, circle, star
+	clean := New()
+	clean.MoveTo(0, 0)
+	clean.LineTo(20, 0)
+	clean.LineTo(20, 20)
+	clean.LineTo(0, 20)
+	clean.LineTo(0, 0)
+	clean.MoveTo(5, 5)
+	clean.LineTo(5, 15)
+	clean.LineTo(15, 15)
+	clean.LineTo(15, 5)
+	clean.LineTo(5, 5)
+	testCircle(&clean, 60, 60, 10)
+	clean.MoveTo(0, 0) // trailing MoveTo, ignored
+	if diagnostics := clean.Validate(); diagnostics != nil { t.Fatalf("unexpected diagnostics %v", diagnostics) }
+	star := testStar(30)
+	if diagnostics := star.Validate(); diagnostics != nil { t.Fatalf("unexpected diagnostics %v", diagnostics) }
+
+	unclosed := New()
+	unclosed.MoveTo(0, 0)
+	unclosed.LineTo(10, 0)
+	unclosed.LineTo(10, 10)
+	found := codes(&unclosed)
+	if diag, ok := found[DiagUnclosedSubpath]; !ok || diag.First != 0 || diag.Last != 2 || len(found) != 1 {
+		t.Fatalf("expected only DiagUnclosedSubpath for segments 0-2, got %v", unclosed.Validate())
+	}
+
+	zero := testSquare(10)
+	zero.SetSegment(2, zero.Segments()[1]) // LineTo to the same point
+	if diag, ok := codes(&zero)[DiagZeroLengthSegment]; !ok || diag.First != 2 || diag.Last != 2 {
+		t.Fatalf("expected DiagZeroLengthSegment at 2, got %v", zero.Validate())
+	}
+
+	degenerate := New()
+	degenerate.MoveTo(0, 0)
+	degenerate.LineTo(10, 0)
+	degenerate.LineTo(0, 0)
+	found = codes(&degenerate)
+	if _, ok := found[DiagDegenerateSubpath]; !ok || len(found) != 1 {
+		t.Fatalf("expected only DiagDegenerateSubpath, got %v", degenerate.Validate())
+	}
+	curved := New() // control points count, so this is fine
+	curved.MoveTo(0, 0)
+	curved.QuadTo(5, 10, 10, 0)
+	curved.LineTo(0, 0)
+	if diagnostics := curved.Validate(); diagnostics != nil { t.Fatalf("unexpected diagnostics %v", diagnostics) }
+
+	winding := testSquare(20)
+	winding.MoveTo(5, 5) // hole wound like its container
+	winding.LineTo(15, 5)
+	winding.LineTo(15, 15)
+	winding.LineTo(5, 15)
+	winding.LineTo(5, 5)
+	found = codes(&winding)
+	if diag, ok := found[DiagSuspiciousWinding]; !ok || diag.First != 5 || diag.Last != 9 || len(found) != 1 {
+		t.Fatalf("expected only DiagSuspiciousWinding for segments 5-9, got %v", winding.Validate())
+	}
+
+	limits := New()
+	limits.MoveTo(0, 0)
+	limits.LineTo(1 << 25 - 8, 0)
+	limits.LineTo(0, 10)
+	limits.LineTo(0, 0)
+	found = codes(&limits)
+	if diag, ok := found[DiagNearRangeLimit]; !ok || diag.First != 1 || len(found) != 1 {
+		t.Fatalf("expected only DiagNearRangeLimit at 1, got %v", limits.Validate())
+	}
+	if text := found[DiagNearRangeLimit].String(); !strings.HasPrefix(text, "NearRangeLimit: ") || !strings.HasSuffix(text, "(segment 1)") {
+		t.Fatalf("unexpected diagnostic string %q", text)
+	}
+	segment := limits.Segments()[1]
+	segment.Args[0].X = math.MinInt32 // no positive counterpart
+	limits.SetSegment(1, segment)
+	if diag, ok := codes(&limits)[DiagNearRangeLimit]; !ok || diag.First != 1 {
+		t.Fatalf("expected DiagNearRangeLimit at 1 for math.MinInt32, got %v", limits.Validate())
+	}
+}
@@ -0,0 +1,124 @@
+package sfntshape
+
+import "image"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+// Pixels added around the viewport before clipping segments, so the
+// substitutes of the clipped segments never touch the visible pixels.
+const viewportMargin = 1
+
+// Like [RasterizeCtx](), for options with a non-empty Viewport. The mask
+// is sized like [RasterizeClipped]() would, and the segments are clipped
+// with clipToViewport before being fed to the rasterizer.
+func viewportRasterize(task *rasterTask, outline sfnt.Segments, bounds fixed.Rectangle26_6, rasterizer Rasterizer, opts RasterizeOptions) (*image.Alpha, error) {
+	width, height, normOffsetX, normOffsetY, rectOffset := figureOutBounds(bounds, opts.OffsetX, opts.OffsetY)
+	fullRect := image.Rect(0, 0, width, height).Add(rectOffset)
+	rect := fullRect.Intersect(opts.Viewport)
+	if rect.Empty() { return nil, nothingToDraw() }
+	padded := rect.Inset(-opts.Padding)
+	if err := checkMaskSize(padded.Dx(), padded.Dy()); err != nil { return nil, err }
+
+	// shift the outline so the padded region starts at (0, 0)
+	normOffsetX -= Fract((padded.Min.X - fullRect.Min.X) << 6)
+	normOffsetY -= Fract((padded.Min.Y - fullRect.Min.Y) << 6)
+	inner := rect.Sub(padded.Min) // the viewport region, within the mask
+	var box fixed.Rectangle26_6 // expanded viewport, in outline coordinates
+	box.Min.X = Fract((inner.Min.X - viewportMargin) << 6) - normOffsetX
+	box.Min.Y = Fract((inner.Min.Y - viewportMargin) << 6) - normOffsetY
+	box.Max.X = Fract((inner.Max.X + viewportMargin) << 6) - normOffsetX
+	box.Max.Y = Fract((inner.Max.Y + viewportMargin) << 6) - normOffsetY
+	clipped := clipToViewport(make([]sfnt.Segment, 0, len(outline)), outline, box)
+
+	// like RasterizeClipped, stay consistent with the full rasterization
+	var mask *image.Alpha
+	bigMask := (width > vectorFloatingPointThreshold || height > vectorFloatingPointThreshold)
+	if !opts.isDefault() || (bigMask && isVectorRasterizer(rasterizer)) {
+		var err error
+		mask, err = accumulatorRasterizeRect(task, clipped, opts, padded, normOffsetX, normOffsetY)
+		if err != nil { return nil, err }
+		mask.Rect = mask.Rect.Sub(padded.Min)
+	} else {
+		task.total = len(clipped) + padded.Dy()
+		resetRasterizer(rasterizer, padded.Dx(), padded.Dy())
+		mask = image.NewAlpha(image.Rect(0, 0, padded.Dx(), padded.Dy()))
+		err := processOutlineTask(task, rasterizer, clipped, normOffsetX, normOffsetY)
+		if err != nil { return nil, err }
+		rasterizer.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+		if err := task.advance(padded.Dy()); err != nil { return nil, err }
+	}
+
+	// the substitutes of the clipped segments may cover the padding
+	if opts.Padding > 0 { clearOutside(mask, inner) }
+	mask.Rect = padded
+	return mask, nil
+}
+
+// Appends the segments to dst, replacing the ones lying entirely on the
+// outer side of one of the box edges by lines between their endpoints
+// clamped to the box. The clamped replacements run along the box edges,
+// where they preserve the crossings (and thus the winding numbers) seen
+// by the points inside the box, while skipping the flattening and the
+// far away coordinates of the originals. Runs of replaced segments are
+// connected to the original endpoints with lines that stay outside the
+// box too, so subpaths keep their start and end points.
+//
+// Segments lying outside the box but not on a single side of it (e.g.
+// around a corner) are kept as they are, as their clamped endpoints
+// could be connected through the box.
+func clipToViewport(dst, segments []sfnt.Segment, box fixed.Rectangle26_6) []sfnt.Segment {
+	var pen fixed.Point26_6
+	penClamped := false // whether the last segment appended ends at clamp(pen)
+	unclamp := func() {
+		if penClamped { dst = appendLineTo(dst, pen) }
+		penClamped = false
+	}
+	for _, segment := range segments {
+		if segment.Op == sfnt.SegmentOpMoveTo {
+			unclamp()
+			dst = append(dst, segment)
+			pen = segment.Args[0]
+			continue
+		}
+
+		end := segmentEnd(segment)
+		if !isOutsideBoxSide(pen, segment, box) {
+			unclamp()
+			dst = append(dst, segment)
+			pen = end
+			continue
+		}
+		if !penClamped {
+			if start := clampToBox(pen, box); start != pen { dst = appendLineTo(dst, start) }
+			penClamped = true
+		}
+		clampedEnd := clampToBox(end, box)
+		if clampedEnd != clampToBox(pen, box) { dst = appendLineTo(dst, clampedEnd) }
+		pen = end
+	}
+	unclamp()
+	return dst
+}
+
+// Returns whether the segment starting at pen lies entirely on the
+// outer side of one of the box edges.
+func isOutsideBoxSide(pen fixed.Point26_6, segment sfnt.Segment, box fixed.Rectangle26_6) bool {
+	left, right, above, below := pen.X <= box.Min.X, pen.X >= box.Max.X, pen.Y <= box.Min.Y, pen.Y >= box.Max.Y
+	for i := 0; i < segmentArgsCount(segment.Op); i++ {
+		point := segment.Args[i]
+		left, right = left && point.X <= box.Min.X, right && point.X >= box.Max.X
+		above, below = above && point.Y <= box.Min.Y, below && point.Y >= box.Max.Y
+	}
+	return left || right || above || below
+}
+
+func clampToBox(point fixed.Point26_6, box fixed.Rectangle26_6) fixed.Point26_6 {
+	if point.X < box.Min.X { point.X = box.Min.X } else if point.X > box.Max.X { point.X = box.Max.X }
+	if point.Y < box.Min.Y { point.Y = box.Min.Y } else if point.Y > box.Max.Y { point.Y = box.Max.Y }
+	return point
+}
+
+func appendLineTo(segments []sfnt.Segment, point fixed.Point26_6) []sfnt.Segment {
+	return append(segments, sfnt.Segment{ Op: sfnt.SegmentOpLineTo, Args: [3]fixed.Point26_6{ point } })
+}
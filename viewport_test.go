@@ -0,0 +1,91 @@
+package sfntshape
+
+import "image"
+import "testing"
+
+import "golang.org/x/image/font/sfnt"
+import "golang.org/x/image/math/fixed"
+
+import "github.com/tinne26/sfntshape/internal/maskcmp"
+
+func TestViewport(t *testing.T) {
+	// a giant rectangle covering the viewport from outside, with a hole,
+	// plus a ring and a stray curve shooting far off-screen
+	shape := New()
+	shape.MoveTo(-800, -800)
+	shape.LineTo( 800, -800)
+	shape.LineTo( 800,  800)
+	shape.LineTo(-800,  800)
+	shape.LineTo(-800, -800)
+	shape.MoveTo(40, 40)
+	shape.LineTo(40, 100)
+	shape.LineTo(100, 100)
+	shape.LineTo(100, 40)
+	shape.LineTo(40, 40)
+	testRing(&shape, 120)
+	shape.MoveTo(150, 20)
+	shape.CubeTo(-1500, 900, 1200, 1500, 160, 30)
+	shape.LineTo(150, 20)
+
+	viewport := image.Rect(-20, -70, 180, 130)
+	for _, opts := range []RasterizeOptions{ {}, { OffsetX: 13, OffsetY: 40 }, { FillRule: FillEvenOdd }, { HardEdges: true } } {
+		expected, err := shape.RasterizeOpts(opts)
+		if err != nil { t.Fatal(err) }
+		expected = expected.SubImage(viewport).(*image.Alpha)
+		opts.Viewport = viewport
+		mask, err := shape.RasterizeOpts(opts)
+		if err != nil { t.Fatal(err) }
+		if mask.Rect != viewport { t.Fatalf("%+v: unexpected Rect %v", opts, mask.Rect) }
+		if err := maskcmp.Compare(mask, expected, 1, 0); err != nil {
+			t.Fatalf("%+v: %s", opts, err)
+		}
+		if opts.isDefault() { // same geometry within the viewport
+			clipped, err := shape.RasterizeClipped(viewport, opts.OffsetX, opts.OffsetY)
+			if err != nil { t.Fatal(err) }
+			if maskcmp.Compare(mask, clipped, 0, 0) != nil { t.Fatalf("%+v: expected RasterizeClipped results", opts) }
+		}
+	}
+
+	// the covering rectangle alone is made of segments outside the viewport,
+	// which must still fill it
+	cover := New()
+	cover.MoveTo(-800, -800)
+	cover.QuadTo(0, -900, 800, -800)
+	cover.LineTo( 800,  800)
+	cover.CubeTo(0, 1000, 0, 600, -800, 800)
+	cover.LineTo(-800, -800)
+	mask, err := cover.RasterizeOpts(RasterizeOptions{ Viewport: viewport, OffsetX: 7 })
+	if err != nil { t.Fatal(err) }
+	for i, value := range mask.Pix {
+		if value != 255 { t.Fatalf("expected full coverage, got %d at %d", value, i) }
+	}
+
+	// shapes outside the viewport
+	outside := bigTestShape(50)
+	mask, err = outside.RasterizeOpts(RasterizeOptions{ Viewport: image.Rect(100, 100, 200, 200) })
+	if mask != nil || err != nil { t.Fatalf("expected (nil, nil), got (%v, %v)", mask, err) }
+}
+
+func TestClipToViewport(t *testing.T) {
+	shape := New()
+	shape.MoveTo(-100, 0)
+	shape.LineTo(-90, 50)
+	shape.QuadTo(-200, 60, -95, 70)
+	shape.LineTo(-80, 80)
+	shape.LineTo(10, 10)
+	shape.LineTo(-100, 0)
+	box := fixed.R(0, -100, 100, 100)
+	clipped := clipToViewport(nil, shape.segments, box)
+
+	if clipped[0] != shape.segments[0] { t.Fatal("expected the MoveTo to be kept") }
+	last := clipped[len(clipped) - 1]
+	if last != shape.segments[len(shape.segments) - 1] { t.Fatal("expected the last segment to be kept") }
+	for _, segment := range clipped {
+		if segment.Op == sfnt.SegmentOpQuadTo { t.Fatal("expected the outside curve to be replaced") }
+	}
+	// (-80, 80) must be reconnected, as the next line enters the box
+	found := false
+	for _, segment := range clipped { found = found || segmentEnd(segment) == shape.segments[4].Args[0] }
+	if !found { t.Fatal("expected the replaced run to be reconnected") }
+	if len(clipped) >= len(shape.segments) + 3 { t.Fatalf("unexpected number of segments %d", len(clipped)) }
+}
@@ -0,0 +1,42 @@
+package sfntshape
+
+import "image"
+import "testing"
+
+func windingAt(img *image.Gray16, x, y int) int {
+	return int(img.Gray16At(x, y).Y) - WindingImageOffset
+}
+
+func TestWindingImage(t *testing.T) {
+	double := New()
+	double.InvertY(true)
+	testCircle(&double, 0, 0, 20)
+	testCircle(&double, 0, 0, 20)
+	img, rect, err := double.WindingImage()
+	if err != nil { t.Fatal(err) }
+	if rect != img.Rect || rect != image.Rect(-20, -20, 20, 20) {
+		t.Fatalf("unexpected Rect %v", rect)
+	}
+	if w := windingAt(img, 0, 0); w != 2 { t.Fatalf("expected winding 2 inside, got %d", w) }
+	if w := windingAt(img, -20, -20); w != 0 { t.Fatalf("expected winding 0 outside, got %d", w) }
+
+	ring := New()
+	ring.InvertY(true)
+	testReversedCircle(&ring, 0, 0, 20)
+	testCircle(&ring, 0, 0, 10)
+	img, rect, err = ring.WindingImage()
+	if err != nil { t.Fatal(err) }
+	if w := windingAt(img, 0, 0); w != 0 { t.Fatalf("expected winding 0 in the hole, got %d", w) }
+	if w := windingAt(img, 15, 0); w != -1 { t.Fatalf("expected winding -1 on the band, got %d", w) }
+	if w := windingAt(img, -1, -16); w != -1 { t.Fatalf("expected winding -1 on the band, got %d", w) }
+
+	// consistent with Contains
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			inside := ring.Contains(float64(x) + 0.5, float64(y) + 0.5)
+			if inside != (windingAt(img, x, y) != 0) {
+				t.Fatalf("WindingImage inconsistent with Contains at (%d, %d)", x, y)
+			}
+		}
+	}
+}